@@ -0,0 +1,347 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+// audit records an action an admin took on a user, a failure is logged since the action already happened.
+func (app *Application) audit(actor *internal.User, userID int64, action internal.AuditAction, details string) {
+	_, err := app.storage.Audit.Create(actor.ID, userID, action, details)
+	if err != nil {
+		log.Printf("failed to audit %s on user %d by user %d: %v\n", action, userID, actor.ID, err)
+	}
+}
+
+type GetAdminUsersResponse struct {
+	Users    []internal.User    `json:"users"`
+	MetaData *internal.MetaData `json:"meta_data"`
+}
+
+// getAdminUsersHandler godoc
+//
+//	@Summary		Gets a list of users
+//	@Description	gets a list of users, searching by email
+//	@Tags			admin-users
+//	@Accept			json
+//	@Produce		json
+//	@Param			email		query		string	false	"part of the email"
+//	@Param			page		query		int		false	"page number"
+//	@Param			page_size	query		int		false	"page size"
+//	@Success		200			{object}	GetAdminUsersResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		403			{object}	ResponseError
+//	@Failure		500			{object}	ResponseError
+//	@Router			/admin/users [get]
+func (app *Application) getAdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	v := NewValidator()
+	email := getQueryStringOr(r, "email", "")
+	page := getQueryIntOr(r, "page", 1, v)
+	pageSize := getQueryIntOr(r, "page_size", 20, v)
+
+	v.Check(len(email) <= 500, "email", "must be at most 500 characters")
+	v.Check(page > 0 && page <= 10_000_000, "page", "must be between 1 and 10_000_000")
+	v.Check(pageSize > 0 && pageSize <= 100, "page_size", "must be between 1 and 100")
+
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+
+	users, metaData, err := app.storage.Users.GetAll(email, page, pageSize)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetAdminUsersResponse{Users: users, MetaData: metaData}, http.StatusOK, w)
+}
+
+type GetAdminUserResponse struct {
+	User        *internal.User        `json:"user"`
+	Roles       []string              `json:"roles"`
+	Permissions []internal.Permission `json:"permissions"`
+}
+
+// writeAdminUser writes the user with the user's roles and the permissions the user ends up with.
+func (app *Application) writeAdminUser(u *internal.User, w http.ResponseWriter) {
+	roles, err := app.storage.Permissions.GetRoles(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	permissions, err := app.storage.Permissions.Get(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	slices.Sort(permissions)
+	writeJSON(GetAdminUserResponse{User: u, Roles: roles, Permissions: permissions}, http.StatusOK, w)
+}
+
+// getAdminUserHandler godoc
+//
+//	@Summary		Gets a user
+//	@Description	gets a user with the user's roles and permissions
+//	@Tags			admin-users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"user id"
+//	@Success		200	{object}	GetAdminUserResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/admin/users/{id} [get]
+func (app *Application) getAdminUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u, err := app.storage.Users.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
+	app.writeAdminUser(u, w)
+}
+
+// suspendUserHandler godoc
+//
+//	@Summary		Suspends a user
+//	@Description	suspends a user, the user is signed out right away and can't sign in until unsuspended
+//	@Tags			admin-users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"user id"
+//	@Param			reason	body		string	true	"reason of the suspension"
+//	@Success		200		{object}	GetAdminUserResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		403		{object}	ResponseError
+//	@Failure		404		{object}	ResponseMessage
+//	@Failure		409		{object}	ResponseMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/admin/users/{id}/suspension [post]
+func (app *Application) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.Reason != "", "reason", "must be provided")
+	v.Check(len(req.Reason) <= 500, "reason", "must be at most 500 characters")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	actor := getUserFromRequestContext(r)
+	if actor == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	if actor.ID == int64(id) {
+		writeJSON(ResponseMessage{Message: "you can't suspend yourself"}, http.StatusConflict, w)
+		return
+	}
+	u, err := app.storage.Users.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
+	if u.SuspendedAt != nil {
+		writeJSON(ResponseMessage{Message: "user is already suspended"}, http.StatusConflict, w)
+		return
+	}
+	err = app.storage.Users.Suspend(u, req.Reason)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.revokeAccessTokens(u.ID, nil)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	app.audit(actor, u.ID, internal.AuditActionSuspend, req.Reason)
+	app.writeAdminUser(u, w)
+}
+
+// unsuspendUserHandler godoc
+//
+//	@Summary		Unsuspends a user
+//	@Description	lifts the suspension of a user, the user has to sign in again
+//	@Tags			admin-users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"user id"
+//	@Success		200	{object}	GetAdminUserResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/admin/users/{id}/suspension [delete]
+func (app *Application) unsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	actor := getUserFromRequestContext(r)
+	if actor == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	u, err := app.storage.Users.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
+	if u.SuspendedAt == nil {
+		writeJSON(ResponseMessage{Message: "user is not suspended"}, http.StatusConflict, w)
+		return
+	}
+	err = app.storage.Users.Unsuspend(u)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	app.audit(actor, u.ID, internal.AuditActionUnsuspend, "")
+	app.writeAdminUser(u, w)
+}
+
+// forcePasswordResetHandler godoc
+//
+//	@Summary		Forces a password reset
+//	@Description	signs the user out and sends a password-reset token to the user's email,
+//	@Description	the user can't sign in until the password is reset
+//	@Tags			admin-users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"user id"
+//	@Success		200	{object}	GetAdminUserResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/admin/users/{id}/password-reset [post]
+func (app *Application) forcePasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	actor := getUserFromRequestContext(r)
+	if actor == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	u, err := app.storage.Users.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
+	u.PasswordResetRequired = true
+	err = app.storage.Users.Update(u)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.storage.Tokens.DeleteAll(u.ID, []internal.TokenScope{internal.TokenScopeAuthentication, internal.TokenScopeRefresh, internal.TokenScopePasswordReset})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.revokeAccessTokens(u.ID, nil)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	token := internal.GenerateToken()
+	_, err = app.storage.Tokens.Create(u.ID, internal.TokenScopePasswordReset, token, 10*time.Minute)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	data := map[string]any{
+		"token": token,
+	}
+	app.Go(app.SendMail(u.Email, ResetPasswordTempl, data))
+	app.audit(actor, u.ID, internal.AuditActionForcePasswordReset, "")
+	app.writeAdminUser(u, w)
+}
+
+type GetAuditEntriesResponse struct {
+	Entries  []internal.AuditEntry `json:"entries"`
+	MetaData *internal.MetaData    `json:"meta_data"`
+}
+
+// getUserAuditEntriesHandler godoc
+//
+//	@Summary		Gets the audit entries of a user
+//	@Description	gets the actions the admins took on a user, the latest first
+//	@Tags			admin-users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int	true	"user id"
+//	@Param			page		query		int	false	"page number"
+//	@Param			page_size	query		int	false	"page size"
+//	@Success		200			{object}	GetAuditEntriesResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		403			{object}	ResponseError
+//	@Failure		500			{object}	ResponseError
+//	@Router			/admin/users/{id}/audit-entries [get]
+func (app *Application) getUserAuditEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	page := getQueryIntOr(r, "page", 1, v)
+	pageSize := getQueryIntOr(r, "page_size", 20, v)
+
+	v.Check(page > 0 && page <= 10_000_000, "page", "must be between 1 and 10_000_000")
+	v.Check(pageSize > 0 && pageSize <= 100, "page_size", "must be between 1 and 100")
+
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+
+	entries, metaData, err := app.storage.Audit.GetAll(int64(id), page, pageSize)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetAuditEntriesResponse{Entries: entries, MetaData: metaData}, http.StatusOK, w)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+const maxAPIKeysPerUser = 25
+
+type CreateAPIKeyResponse struct {
+	APIKey *internal.APIKey `json:"api_key"`
+	Key    string           `json:"key"`
+}
+
+// createAPIKeyHandler godoc
+//
+//	@Summary		Creates an API key
+//	@Description	creates a key for the integrations like kiosks and partner systems limited to some of the user's permissions,
+//	@Description	it's sent as "Authorization: ApiKey <key>" and is only shown once
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			name		body		string		true	"name"
+//	@Param			permissions	body		[]string	true	"permission codes"
+//	@Param			expires_at	body		string		false	"expiration time"
+//	@Success		201			{object}	CreateAPIKeyResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		409			{object}	ResponseError
+//	@Failure		500			{object}	ResponseError
+//	@Router			/users/me/api-keys [post]
+func (app *Application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        *string               `json:"name"`
+		Permissions []internal.Permission `json:"permissions"`
+		ExpiresAt   *time.Time            `json:"expires_at"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	has, err := app.storage.Permissions.Get(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.Name != nil && *req.Name != "", "name", "must be provided")
+	if req.Name != nil {
+		v.Check(len(*req.Name) <= 100, "name", "must not be more than 100 bytes long")
+	}
+	v.Check(len(req.Permissions) != 0, "permissions", "must be provided")
+	for _, p := range req.Permissions {
+		v.Check(slices.Contains(has, p), "permissions", fmt.Sprintf("you don't have %q", p))
+	}
+	if req.ExpiresAt != nil {
+		v.Check(req.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	keys, err := app.storage.APIKeys.GetAll(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if len(keys) >= maxAPIKeysPerUser {
+		writeError(fmt.Errorf("you can't have more than %d API keys, revoke the ones you don't use", maxAPIKeysPerUser), http.StatusConflict, w)
+		return
+	}
+	slices.Sort(req.Permissions)
+	key := internal.GenerateAPIKey()
+	k, err := app.storage.APIKeys.Create(u.ID, *req.Name, key, slices.Compact(req.Permissions), req.ExpiresAt)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(CreateAPIKeyResponse{APIKey: k, Key: key}, http.StatusCreated, w)
+}
+
+type GetAPIKeysResponse struct {
+	APIKeys []internal.APIKey `json:"api_keys"`
+}
+
+// getAPIKeysHandler godoc
+//
+//	@Summary		Gets the user's API keys
+//	@Description	gets the user's API keys with their permissions and when they were last used
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetAPIKeysResponse
+//	@Failure		500	{object}	ResponseError
+//	@Router			/users/me/api-keys [get]
+func (app *Application) getAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	keys, err := app.storage.APIKeys.GetAll(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetAPIKeysResponse{APIKeys: keys}, http.StatusOK, w)
+}
+
+// deleteAPIKeyHandler godoc
+//
+//	@Summary		Revokes an API key
+//	@Description	revokes an API key, it stops working right away
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"api key id"
+//	@Success		200	{object}	ResponseMessage
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/users/me/api-keys/{id} [delete]
+func (app *Application) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	deleted, err := app.storage.APIKeys.Delete(u.ID, int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if !deleted {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "api key was revoked"}, http.StatusOK, w)
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
+)
+
+const maxBoxOfficeTickets = 20
+
+type CashDrawerResponse struct {
+	Drawer *internal.CashDrawer `json:"drawer"`
+}
+
+// openCashDrawerHandler godoc
+//
+//	@Summary		Opens a cash drawer
+//	@Description	starts the user's box office shift at a given cinema, a cashier can have one open drawer per cinema
+//	@Tags			box office
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int		true	"cinema id"
+//	@Param			opening_float	body		string	false	"cash in the drawer at the start of the shift"
+//	@Success		201				{object}	CashDrawerResponse
+//	@Failure		400				{object}	ViolationsMessage
+//	@Failure		403				{object}	ResponseError
+//	@Failure		404				{object}	ResponseMessage
+//	@Failure		409				{object}	ResponseMessage
+//	@Failure		500				{object}	ResponseError
+//	@Router			/cinemas/{id}/drawers [post]
+func (app *Application) openCashDrawerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		OpeningFloat decimal.Decimal `json:"opening_float"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.CheckCashAmount("opening_float", req.OpeningFloat)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if c == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeBoxOffice, w) {
+		return
+	}
+	d, err := app.storage.BoxOffice.OpenDrawer(c.ID, u.ID, req.OpeningFloat)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if d == nil {
+		writeJSON(ResponseMessage{Message: "you already have an open cash drawer at this cinema"}, http.StatusConflict, w)
+		return
+	}
+	writeJSON(CashDrawerResponse{Drawer: d}, http.StatusCreated, w)
+}
+
+type BoxOfficeSaleResponse struct {
+	Order  *internal.Order  `json:"order"`
+	Change *decimal.Decimal `json:"change,omitempty"`
+}
+
+// createBoxOfficeSaleHandler godoc
+//
+//	@Summary		Sells at the box office
+//	@Description	sells tickets and products at the counter paid by cash or card present, the tickets are sold immediately
+//	@Description	and the order is recorded in the cashier's drawer. taxes apply but fees are for online checkouts only.
+//	@Description	the tickets are printed from /orders/{id}/tickets.pdf
+//	@Tags			box office
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		int		true	"drawer id"
+//	@Param			ticket_ids			body		[]int	false	"ticket ids"
+//	@Param			products			body		[]int	false	"products as product_id and quantity"
+//	@Param			payment_method		body		int		true	"payment method (1 cash, 2 card present)"
+//	@Param			payment_reference	body		string	false	"reference of the card terminal payment"
+//	@Param			cash_tendered		body		string	false	"cash handed over by the customer"
+//	@Success		201					{object}	BoxOfficeSaleResponse
+//	@Failure		400					{object}	ViolationsMessage
+//	@Failure		403					{object}	ResponseError
+//	@Failure		404					{object}	ResponseMessage
+//	@Failure		409					{object}	ResponseMessage
+//	@Failure		422					{object}	ResponseMessage
+//	@Failure		500					{object}	ResponseError
+//	@Router			/drawers/{id}/sales [post]
+func (app *Application) createBoxOfficeSaleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		TicketIDs []int64 `json:"ticket_ids"`
+		Products  []struct {
+			ProductID int32 `json:"product_id"`
+			Quantity  int32 `json:"quantity"`
+		} `json:"products"`
+		PaymentMethod    internal.PaymentMethod `json:"payment_method"`
+		PaymentReference string                 `json:"payment_reference"`
+		CashTendered     *decimal.Decimal       `json:"cash_tendered"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(len(req.TicketIDs) != 0 || len(req.Products) != 0, "ticket_ids or products", "must be provided")
+	v.Check(len(req.TicketIDs) <= maxBoxOfficeTickets, "ticket_ids", fmt.Sprintf("must not have more than %d tickets", maxBoxOfficeTickets))
+	for i, ticketID := range req.TicketIDs {
+		v.Check(ticketID > 0, "ticket_ids", "must be positive")
+		v.Check(!slices.Contains(req.TicketIDs[:i], ticketID), "ticket_ids", "must be unique")
+	}
+	var productIDs []int32
+	for _, p := range req.Products {
+		v.Check(p.ProductID > 0, "products", "product_id must be provided")
+		v.Check(p.Quantity > 0 && p.Quantity <= maxProductQuantity, "products", fmt.Sprintf("quantity must be between 1 and %d", maxProductQuantity))
+		v.Check(!slices.Contains(productIDs, p.ProductID), "products", "must be unique")
+		productIDs = append(productIDs, p.ProductID)
+	}
+	v.Check(req.PaymentMethod == internal.PaymentMethodCash || req.PaymentMethod == internal.PaymentMethodCardPresent, "payment_method", "must be 1 (cash) or 2 (card present)")
+	v.Check(len(req.PaymentReference) <= 100, "payment_reference", "must not be more than 100 bytes long")
+	if req.CashTendered != nil {
+		v.Check(req.PaymentMethod == internal.PaymentMethodCash, "cash_tendered", "must only be provided for cash payments")
+		v.CheckCashAmount("cash_tendered", *req.CashTendered)
+	}
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	d, err := app.storage.BoxOffice.GetDrawer(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if d == nil {
+		writeNotFound(w)
+		return
+	}
+	if d.CashierID != u.ID {
+		writeForbidden(w)
+		return
+	}
+	if d.ClosedAt != nil {
+		writeJSON(ResponseMessage{Message: "cash drawer is closed"}, http.StatusConflict, w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(d.CinemaID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeBoxOffice, w) {
+		return
+	}
+	var items []internal.CheckoutItem
+	if len(req.TicketIDs) != 0 {
+		items, err = app.storage.BoxOffice.GetItems(c.ID, req.TicketIDs)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if len(items) != len(req.TicketIDs) {
+			writeNotFound(w)
+			return
+		}
+	}
+	for _, item := range items {
+		if item.Ticket.StateID != internal.TicketStateUnsold {
+			writeJSON(ResponseMessage{Message: fmt.Sprintf("ticket %d is not available", item.Ticket.ID)}, http.StatusConflict, w)
+			return
+		}
+	}
+	products := make([]internal.CheckoutProduct, 0, len(req.Products))
+	for _, rp := range req.Products {
+		p, _, err := app.storage.Products.GetAndCinema(rp.ProductID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if p == nil || p.CinemaID != c.ID {
+			writeNotFound(w)
+			return
+		}
+		if rp.Quantity > p.Stock {
+			writeJSON(ResponseMessage{Message: fmt.Sprintf("only %d of %s are left", p.Stock, p.Name)}, http.StatusUnprocessableEntity, w)
+			return
+		}
+		products = append(products, internal.CheckoutProduct{Product: *p, Quantity: rp.Quantity, Amount: p.Price.Mul(decimal.NewFromInt32(rp.Quantity))})
+	}
+	fees, err := app.storage.Fees.GetAllForCinema(c.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	fees = slices.DeleteFunc(fees, func(f internal.Fee) bool {
+		return f.Kind != internal.FeeKindTax
+	})
+	breakdown := internal.CalculateBreakdown(items, products, fees)
+	var change *decimal.Decimal
+	if req.CashTendered != nil {
+		if req.CashTendered.LessThan(breakdown.Total) {
+			writeJSON(ResponseMessage{Message: fmt.Sprintf("cash tendered is less than the total of %s", breakdown.Total.StringFixed(2))}, http.StatusUnprocessableEntity, w)
+			return
+		}
+		ch := req.CashTendered.Sub(breakdown.Total)
+		change = &ch
+	}
+	o, err := app.storage.BoxOffice.Sell(d, items, products, breakdown, req.PaymentMethod, req.PaymentReference)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErrTicketUnavailable), errors.Is(err, internal.ErrDrawerClosed):
+			writeJSON(ResponseMessage{Message: err.Error()}, http.StatusConflict, w)
+		case errors.Is(err, internal.ErrProductOutOfStock):
+			writeJSON(ResponseMessage{Message: err.Error()}, http.StatusUnprocessableEntity, w)
+		default:
+			writeServerErr(err, w)
+		}
+		return
+	}
+	writeJSON(BoxOfficeSaleResponse{Order: o, Change: change}, http.StatusCreated, w)
+}
+
+type ShiftReportResponse struct {
+	Report *internal.ShiftReport `json:"report"`
+}
+
+// closeCashDrawerHandler godoc
+//
+//	@Summary		Closes a cash drawer
+//	@Description	ends a box office shift with the cash counted in the drawer and returns the end of shift report,
+//	@Description	the cinema owner and managers can close the drawers of the cashiers
+//	@Tags			box office
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int		true	"drawer id"
+//	@Param			counted_cash	body		string	true	"cash counted in the drawer"
+//	@Success		200				{object}	ShiftReportResponse
+//	@Failure		400				{object}	ViolationsMessage
+//	@Failure		403				{object}	ResponseError
+//	@Failure		404				{object}	ResponseMessage
+//	@Failure		409				{object}	ResponseMessage
+//	@Failure		500				{object}	ResponseError
+//	@Router			/drawers/{id}/close [post]
+func (app *Application) closeCashDrawerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		CountedCash *decimal.Decimal `json:"counted_cash"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.CountedCash != nil, "counted_cash", "must be provided")
+	if req.CountedCash != nil {
+		v.CheckCashAmount("counted_cash", *req.CountedCash)
+	}
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	d, err := app.storage.BoxOffice.GetDrawer(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if d == nil {
+		writeNotFound(w)
+		return
+	}
+	// the cinema owner and managers can see and close the drawers of the cashiers
+	if d.CashierID != u.ID {
+		c, err := app.storage.Cinemas.GetByID(d.CinemaID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if !app.checkCinemaScope(c, u, internal.CinemaScopeCinema, w) {
+			return
+		}
+	}
+	err = app.storage.BoxOffice.CloseDrawer(d, *req.CountedCash)
+	if err != nil {
+		if errors.Is(err, internal.ErrDrawerClosed) {
+			writeJSON(ResponseMessage{Message: err.Error()}, http.StatusConflict, w)
+			return
+		}
+		writeServerErr(err, w)
+		return
+	}
+	report, err := app.storage.BoxOffice.GetReport(d)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ShiftReportResponse{Report: report}, http.StatusOK, w)
+}
+
+// getShiftReportHandler godoc
+//
+//	@Summary		Gets a shift report
+//	@Description	gets the sales of a cash drawer so far, the difference is only set once the drawer is closed
+//	@Tags			box office
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"drawer id"
+//	@Success		200	{object}	ShiftReportResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/drawers/{id}/report [get]
+func (app *Application) getShiftReportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	d, err := app.storage.BoxOffice.GetDrawer(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if d == nil {
+		writeNotFound(w)
+		return
+	}
+	// the cinema owner and managers can see and close the drawers of the cashiers
+	if d.CashierID != u.ID {
+		c, err := app.storage.Cinemas.GetByID(d.CinemaID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if !app.checkCinemaScope(c, u, internal.CinemaScopeCinema, w) {
+			return
+		}
+	}
+	report, err := app.storage.BoxOffice.GetReport(d)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ShiftReportResponse{Report: report}, http.StatusOK, w)
+}
@@ -8,24 +8,107 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/AdventurerAmer/movie-reservation-system/internal"
 	"github.com/shopspring/decimal"
 	"github.com/stripe/stripe-go/v81"
 	"github.com/stripe/stripe-go/v81/checkout/session"
+	"github.com/stripe/stripe-go/v81/coupon"
 	"github.com/stripe/stripe-go/webhook"
 )
 
 type GetCheckoutResponse struct {
-	Items []internal.CheckoutItem `json:"items"`
-	Total decimal.Decimal         `json:"price"`
+	Items     []internal.CheckoutItem    `json:"items"`
+	Products  []internal.CheckoutProduct `json:"products"`
+	Breakdown *internal.Breakdown        `json:"breakdown"`
+	Total     decimal.Decimal            `json:"price"`
+}
+
+// getCheckoutItems returns the tickets locked by the user and the products the user added priced for checkout,
+// the tickets covered by the user's memberships are free.
+func (app *Application) getCheckoutItems(userID int64) ([]internal.CheckoutItem, []internal.CheckoutProduct, *internal.Breakdown, error) {
+	items, _, err := app.storage.Checkouts.GetItems(userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(items) != 0 {
+		entitlements, err := app.storage.Memberships.GetEntitlements(userID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		internal.ApplyMemberships(items, entitlements)
+	}
+	products, err := app.storage.Products.GetCart(userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var cinemaIDs []int32
+	for _, item := range items {
+		if !slices.Contains(cinemaIDs, item.Cinema.ID) {
+			cinemaIDs = append(cinemaIDs, item.Cinema.ID)
+		}
+	}
+	for _, p := range products {
+		if !slices.Contains(cinemaIDs, p.Product.CinemaID) {
+			cinemaIDs = append(cinemaIDs, p.Product.CinemaID)
+		}
+	}
+	var fees []internal.Fee
+	if len(cinemaIDs) != 0 {
+		fees, err = app.storage.Fees.GetAllForCinemas(cinemaIDs)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return items, products, internal.CalculateBreakdown(items, products, fees), nil
+}
+
+// getCheckoutExpiry returns when a checkout session of the items expires,
+// the shortest checkout session duration of the cinemas wins.
+func (app *Application) getCheckoutExpiry(items []internal.CheckoutItem) (time.Time, error) {
+	var cinemaIDs []int32
+	for _, item := range items {
+		if !slices.Contains(cinemaIDs, item.Cinema.ID) {
+			cinemaIDs = append(cinemaIDs, item.Cinema.ID)
+		}
+	}
+	settings, err := app.storage.Settings.GetAllForCinemas(cinemaIDs)
+	if err != nil {
+		return time.Time{}, err
+	}
+	d := app.config.checkout.sessionDuration
+	for _, s := range settings {
+		d = min(d, s.CheckoutSessionDuration(app.config.checkout.sessionDuration))
+	}
+	return time.Now().Add(d).Truncate(time.Second), nil
+}
+
+const (
+	// prepaidSessionPrefix marks the checkout sessions of orders with nothing left to pay by card,
+	// they never reach the payment gateway.
+	prepaidSessionPrefix    = "prepaid_"
+	prepaidPaymentReference = "prepaid"
+	// pendingSessionPrefix marks the checkout sessions that don't have a session on the payment gateway yet.
+	pendingSessionPrefix = "pending_"
+)
+
+// minCardAmount is the smallest amount the payment gateway can charge.
+var minCardAmount = decimal.New(50, -2)
+
+func toStripeAmount(amount decimal.Decimal) (float64, error) {
+	cents, exact := amount.Mul(decimal.NewFromInt(100)).Float64()
+	if !exact {
+		return 0, fmt.Errorf("price %v is not exact", amount)
+	}
+	return cents, nil
 }
 
 // getCheckoutHandler godoc
 //
 //	@Summary		Gets checkout
-//	@Description	gets a list of checkout items
+//	@Description	gets the locked tickets and the products added to the checkout
 //	@Tags			checkouts
 //	@Accept			json
 //	@Produce		json
@@ -38,33 +121,59 @@ func (app *Application) getCheckoutHandler(w http.ResponseWriter, r *http.Reques
 		writeServerErr(errors.New("user is not authenticated"), w)
 		return
 	}
-	items, total, err := app.storage.Checkouts.GetItems(u.ID)
+	items, products, breakdown, err := app.getCheckoutItems(u.ID)
 	if err != nil {
 		writeServerErr(err, w)
 		return
 	}
-	writeJSON(GetCheckoutResponse{Items: items, Total: total}, http.StatusOK, w)
+	writeJSON(GetCheckoutResponse{Items: items, Products: products, Breakdown: breakdown, Total: breakdown.Total}, http.StatusOK, w)
 }
 
 type CheckoutResponse struct {
 	URL             string                    `json:"url"`
 	CheckoutSession *internal.CheckoutSession `json:"session"`
+	Order           *internal.Order           `json:"order"`
 }
 
 // checkoutHandler godoc
 //
 //	@Summary		Checks out a user
-//	@Description	checks out a user
+//	@Description	checks out a user with the locked tickets and the products added to the checkout, tickets covered by the user's memberships are free, part or all of the total can be paid from the wallet and with loyalty points and an order that isn't paid by card is fulfilled right away
 //	@Tags			checkouts
 //	@Accept			json
 //	@Produce		json
-//	@Success		201	{object}	GetCheckoutResponse
-//	@Success		400	{object}	ResponseMessage
-//	@Success		409	{object}	ResponseMessage
-//	@Success		422	{object}	ResponseMessage
-//	@Failure		500	{object}	ResponseError
+//	@Param			wallet_amount	body		number	false	"amount to pay from the wallet"
+//	@Param			loyalty_points	body		int		false	"loyalty points to redeem, enough points make the tickets free"
+//	@Success		201				{object}	GetCheckoutResponse
+//	@Success		400				{object}	ResponseMessage
+//	@Success		409				{object}	ResponseMessage
+//	@Success		422				{object}	ResponseMessage
+//	@Failure		500				{object}	ResponseError
 //	@Router			/checkout [get]
 func (app *Application) checkoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WalletAmount  *decimal.Decimal `json:"wallet_amount"`
+		LoyaltyPoints *int64           `json:"loyalty_points"`
+	}
+	// the body is optional for checkouts that are paid entirely by card
+	if r.ContentLength != 0 {
+		if err := readJSON(r, &req); err != nil {
+			writeBadRequest(err, w)
+			return
+		}
+	}
+	v := NewValidator()
+	if req.WalletAmount != nil {
+		v.Check(!req.WalletAmount.IsNegative(), "wallet_amount", "must not be negative")
+		v.Check(req.WalletAmount.Exponent() >= -2, "wallet_amount", "must have at most 2 decimal places")
+	}
+	if req.LoyaltyPoints != nil {
+		v.Check(*req.LoyaltyPoints >= 0, "loyalty_points", "must not be negative")
+	}
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
 	u := getUserFromRequestContext(r)
 	if u == nil {
 		writeServerErr(errors.New("user is not authenticated"), w)
@@ -79,7 +188,7 @@ func (app *Application) checkoutHandler(w http.ResponseWriter, r *http.Request)
 		writeJSON(ResponseMessage{Message: fmt.Sprintf("you already have a session with id: %v", checkoutSession.SessionID)}, http.StatusConflict, w)
 		return
 	}
-	ticketsCheckout, _, err := app.storage.Checkouts.GetItems(u.ID)
+	ticketsCheckout, products, breakdown, err := app.getCheckoutItems(u.ID)
 	if err != nil {
 		writeServerErr(err, w)
 		return
@@ -88,16 +197,87 @@ func (app *Application) checkoutHandler(w http.ResponseWriter, r *http.Request)
 		writeJSON(ResponseMessage{Message: "you didn't lock any tickets"}, http.StatusUnprocessableEntity, w)
 		return
 	}
-	lineItems := make([]*stripe.CheckoutSessionLineItemParams, len(ticketsCheckout))
+	// the limit may have been lowered after the tickets were locked
+	if maxPerOrder := app.config.checkout.lockLimits.MaxPerOrder; maxPerOrder > 0 && len(ticketsCheckout) > maxPerOrder {
+		writeJSON(ResponseMessage{Message: fmt.Sprintf("an order can't have more than %d tickets", maxPerOrder)}, http.StatusUnprocessableEntity, w)
+		return
+	}
+	// products are picked up at the cinema so they can only be ordered with tickets for it
+	for _, p := range products {
+		hasTicket := slices.ContainsFunc(ticketsCheckout, func(item internal.CheckoutItem) bool {
+			return item.Cinema.ID == p.Product.CinemaID
+		})
+		if !hasTicket {
+			writeJSON(ResponseMessage{Message: fmt.Sprintf("%s can only be ordered with tickets for its cinema", p.Product.Name)}, http.StatusUnprocessableEntity, w)
+			return
+		}
+		if p.Quantity > p.Product.Stock {
+			writeJSON(ResponseMessage{Message: fmt.Sprintf("only %d of %s are left", p.Product.Stock, p.Product.Name)}, http.StatusUnprocessableEntity, w)
+			return
+		}
+	}
+	var credits orderCredits
+	if req.LoyaltyPoints != nil && *req.LoyaltyPoints > 0 {
+		credits.loyaltyPoints = *req.LoyaltyPoints
+		credits.loyaltyDiscount = app.config.loyalty.pointValue.Mul(decimal.NewFromInt(credits.loyaltyPoints))
+		if credits.loyaltyDiscount.GreaterThan(breakdown.Total) {
+			maxPoints := breakdown.Total.Div(app.config.loyalty.pointValue).Ceil().IntPart()
+			writeJSON(ResponseMessage{Message: fmt.Sprintf("at most %d loyalty points can be redeemed for this order", maxPoints)}, http.StatusUnprocessableEntity, w)
+			return
+		}
+		account, err := app.storage.Loyalty.GetAccount(u.ID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if account.Balance < credits.loyaltyPoints {
+			writeJSON(ResponseMessage{Message: fmt.Sprintf("loyalty balance is %d points", account.Balance)}, http.StatusUnprocessableEntity, w)
+			return
+		}
+	}
+	if req.WalletAmount != nil {
+		credits.walletAmount = decimal.Min(*req.WalletAmount, breakdown.Total.Sub(credits.loyaltyDiscount))
+	}
+	if credits.walletAmount.IsPositive() {
+		balance, err := app.storage.Wallets.GetBalance(u.ID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if balance.LessThan(credits.walletAmount) {
+			writeJSON(ResponseMessage{Message: fmt.Sprintf("wallet balance is %v", balance.StringFixed(2))}, http.StatusUnprocessableEntity, w)
+			return
+		}
+	}
+	due := breakdown.Total.Sub(credits.total())
+	if due.IsPositive() && due.LessThan(minCardAmount) {
+		writeJSON(ResponseMessage{Message: fmt.Sprintf("the amount paid by card must be at least %v", minCardAmount.StringFixed(2))}, http.StatusUnprocessableEntity, w)
+		return
+	}
+	// orders covered by memberships, the wallet and loyalty points never reach the payment gateway
+	if !due.IsPositive() {
+		checkoutSession, order, err := app.checkoutPrepaid(u, ticketsCheckout, products, breakdown, credits)
+		if err != nil {
+			if isUnprocessableCheckoutErr(err) {
+				writeJSON(ResponseMessage{Message: err.Error()}, http.StatusUnprocessableEntity, w)
+				return
+			}
+			writeServerErr(err, w)
+			return
+		}
+		writeJSON(CheckoutResponse{CheckoutSession: checkoutSession, Order: order}, http.StatusCreated, w)
+		return
+	}
+	lineItems := make([]*stripe.CheckoutSessionLineItemParams, 0, len(ticketsCheckout))
 	for i := 0; i < len(ticketsCheckout); i++ {
 		c := ticketsCheckout[i]
-		price, exact := c.Ticket.Price.Mul(decimal.NewFromInt(100)).Float64()
-		if !exact {
-			writeBadRequest(fmt.Errorf("price %v is not exact", price), w)
+		price, err := toStripeAmount(c.Ticket.Price)
+		if err != nil {
+			writeBadRequest(err, w)
 			return
 		}
 		ticketStr := fmt.Sprintf("Movie: %s\nCinema: %s\nHall: %s\nSeat: %s\nTicket: %d\n %v-%v", c.Movie.Title, c.Cinema.Name, c.Hall.Name, c.Seat.Coordinates, c.Ticket.ID, c.Schedule.StartsAt, c.Schedule.EndsAt)
-		lineItems[i] = &stripe.CheckoutSessionLineItemParams{
+		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
 			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
 				Currency: stripe.String("usd"),
 				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
@@ -106,35 +286,219 @@ func (app *Application) checkoutHandler(w http.ResponseWriter, r *http.Request)
 				UnitAmountDecimal: stripe.Float64(price),
 			},
 			Quantity: stripe.Int64(1),
+		})
+	}
+	for _, p := range products {
+		price, err := toStripeAmount(p.Product.Price)
+		if err != nil {
+			writeBadRequest(err, w)
+			return
+		}
+		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency: stripe.String("usd"),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+					Name: stripe.String(p.Product.Name),
+				},
+				UnitAmountDecimal: stripe.Float64(price),
+			},
+			Quantity: stripe.Int64(int64(p.Quantity)),
+		})
+	}
+	for _, l := range breakdown.Lines() {
+		// inclusive taxes are already part of the ticket prices
+		if l.IsInclusive {
+			continue
+		}
+		amount, err := toStripeAmount(l.Amount)
+		if err != nil {
+			writeBadRequest(err, w)
+			return
+		}
+		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency: stripe.String("usd"),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+					Name: stripe.String(l.Name),
+				},
+				UnitAmountDecimal: stripe.Float64(amount),
+			},
+			Quantity: stripe.Int64(1),
+		})
+	}
+
+	expiresAt, err := app.getCheckoutExpiry(ticketsCheckout)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	var amountOff float64
+	if credits.total().IsPositive() {
+		amountOff, err = toStripeAmount(credits.total())
+		if err != nil {
+			writeBadRequest(err, w)
+			return
 		}
 	}
 
+	// the order is created and its credits are paid under a pending session before the payment gateway has a session
+	// that can be paid, it's moved to the gateway's session once there's one.
+	pendingSessionID := pendingSessionPrefix + internal.GenerateToken()
+	checkoutSession, err = app.storage.Checkouts.Create(u.ID, pendingSessionID, expiresAt)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	order, err := app.storage.Orders.Create(u.ID, pendingSessionID, ticketsCheckout, products, breakdown, credits.walletAmount, credits.loyaltyPoints, credits.loyaltyDiscount)
+	if err == nil {
+		err = app.payOrderCredits(order)
+	}
+	if err != nil {
+		if err := app.storage.Checkouts.DeleteBySessionID(pendingSessionID); err != nil {
+			log.Println(err)
+		}
+		if isUnprocessableCheckoutErr(err) {
+			writeJSON(ResponseMessage{Message: err.Error()}, http.StatusUnprocessableEntity, w)
+			return
+		}
+		writeServerErr(err, w)
+		return
+	}
+
 	url := "http://localhost:8080/static/"
 	params := &stripe.CheckoutSessionParams{
 		LineItems:  lineItems,
 		Mode:       stripe.String(string(stripe.CheckoutSessionModePayment)),
 		SuccessURL: stripe.String(url + "success.html"),
 		CancelURL:  stripe.String("http://localhost:8080/v1/checkout_sessions/cancel?session_id={CHECKOUT_SESSION_ID}"),
-		ExpiresAt:  stripe.Int64(time.Now().Add(30 * time.Minute).Unix()),
+		ExpiresAt:  stripe.Int64(expiresAt.Unix()),
+	}
+	var c *stripe.Coupon
+	if amountOff > 0 {
+		// the payment gateway has no negative line items, the part that isn't paid by card is a single use coupon.
+		c, err = coupon.New(&stripe.CouponParams{
+			AmountOff:      stripe.Int64(int64(amountOff)),
+			Currency:       stripe.String("usd"),
+			Duration:       stripe.String(string(stripe.CouponDurationOnce)),
+			MaxRedemptions: stripe.Int64(1),
+			Name:           stripe.String(credits.name()),
+		})
+		if err != nil {
+			if err := app.storage.Checkouts.DeleteBySessionID(pendingSessionID); err != nil {
+				log.Println(err)
+			}
+			writeServerErr(err, w)
+			return
+		}
+		params.Discounts = []*stripe.CheckoutSessionDiscountParams{
+			{Coupon: stripe.String(c.ID)},
+		}
 	}
 	s, err := session.New(params)
-	if err != nil {
-		writeServerErr(err, w)
-		return
+	if err == nil {
+		err = app.storage.Checkouts.SetSessionID(pendingSessionID, s.ID)
+		if err != nil {
+			if _, err := session.Expire(s.ID, nil); err != nil {
+				log.Println(err)
+			}
+		}
 	}
-	checkoutSession, err = app.storage.Checkouts.Create(u.ID, s.ID)
 	if err != nil {
-		if _, err := session.Expire(s.ID, nil); err != nil {
-			writeServerErr(err, w)
-			return
+		if c != nil {
+			if _, err := coupon.Del(c.ID, nil); err != nil {
+				log.Println(err)
+			}
+		}
+		if err := app.storage.Checkouts.DeleteBySessionID(pendingSessionID); err != nil {
+			log.Println(err)
 		}
 		writeServerErr(err, w)
 		return
 	}
+	checkoutSession.SessionID = s.ID
+	order.SessionID = s.ID
+
+	writeJSON(CheckoutResponse{URL: s.URL, CheckoutSession: checkoutSession, Order: order}, http.StatusCreated, w)
+}
+
+// orderCredits is the part of an order that isn't paid by card.
+type orderCredits struct {
+	walletAmount    decimal.Decimal
+	loyaltyPoints   int64
+	loyaltyDiscount decimal.Decimal
+}
+
+func (c orderCredits) total() decimal.Decimal {
+	return c.walletAmount.Add(c.loyaltyDiscount)
+}
+
+func (c orderCredits) name() string {
+	switch {
+	case c.walletAmount.IsPositive() && c.loyaltyPoints > 0:
+		return "Wallet balance and loyalty points"
+	case c.loyaltyPoints > 0:
+		return "Loyalty points"
+	}
+	return "Wallet balance"
+}
+
+// payOrderCredits pays the order's wallet amount and redeems its loyalty points, what was paid is released
+// when the order's checkout session is deleted.
+func (app *Application) payOrderCredits(o *internal.Order) error {
+	if o.WalletAmount.IsPositive() {
+		_, err := app.storage.Wallets.Pay(o.UserID, o.ID, o.WalletAmount)
+		if err != nil {
+			return err
+		}
+	}
+	if o.LoyaltyPoints > 0 {
+		_, err := app.storage.Loyalty.Redeem(o.UserID, o.ID, o.LoyaltyPoints)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isUnprocessableCheckoutErr reports whether the order couldn't be created or paid because of the user's balances or the products' stock.
+func isUnprocessableCheckoutErr(err error) bool {
+	return errors.Is(err, internal.ErrInsufficientBalance) || errors.Is(err, internal.ErrInsufficientPoints) || errors.Is(err, internal.ErrProductOutOfStock)
+}
 
-	writeJSON(CheckoutResponse{URL: s.URL, CheckoutSession: checkoutSession}, http.StatusCreated, w)
+// checkoutPrepaid pays for the order with nothing left to pay by card and fulfills it right away.
+func (app *Application) checkoutPrepaid(u *internal.User, items []internal.CheckoutItem, products []internal.CheckoutProduct, breakdown *internal.Breakdown, credits orderCredits) (*internal.CheckoutSession, *internal.Order, error) {
+	expiresAt, err := app.getCheckoutExpiry(items)
+	if err != nil {
+		return nil, nil, err
+	}
+	sessionID := prepaidSessionPrefix + internal.GenerateToken()
+	checkoutSession, err := app.storage.Checkouts.Create(u.ID, sessionID, expiresAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	order, err := app.storage.Orders.Create(u.ID, sessionID, items, products, breakdown, credits.walletAmount, credits.loyaltyPoints, credits.loyaltyDiscount)
+	if err == nil {
+		err = app.payOrderCredits(order)
+	}
+	if err == nil {
+		err = app.storage.Checkouts.Fulfill(sessionID, u.ID, prepaidPaymentReference)
+	}
+	if err != nil {
+		if err := app.storage.Checkouts.DeleteBySessionID(sessionID); err != nil {
+			log.Println(err)
+		}
+		return nil, nil, err
+	}
+	order, err = app.storage.Orders.GetByID(order.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return checkoutSession, order, nil
 }
 
+// paymentEventClaimTimeout is how long an event can be processing before it's considered stuck and can be claimed again.
+const paymentEventClaimTimeout = 5 * time.Minute
+
 func (app *Application) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	const MaxBodyBytes = int64(65536)
 	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
@@ -151,68 +515,145 @@ func (app *Application) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest) // Return a 400 error on a bad signature
 		return
 	}
-	switch event.Type {
+	_, err = app.storage.PaymentEvents.Create(event.ID, event.Type, event.Data.Raw)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// failed events and the ones stuck processing are retried on redelivery, processed or in progress ones are acknowledged without side effects.
+	e, err := app.storage.PaymentEvents.Claim(event.ID, []internal.PaymentEventStatus{internal.PaymentEventStatusPending, internal.PaymentEventStatusFailed}, paymentEventClaimTimeout)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if e == nil {
+		log.Println("Skipped duplicate payment event:", event.ID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	err = app.processPaymentEvent(e)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// processPaymentEvent handles a claimed payment event and records the outcome.
+func (app *Application) processPaymentEvent(e *internal.PaymentEvent) error {
+	err := app.handlePaymentEvent(e.Type, e.Data)
+	if err != nil {
+		if err := app.storage.PaymentEvents.MarkFailed(e.ID, err.Error()); err != nil {
+			log.Println(err)
+		}
+		return fmt.Errorf("payment event %s failed: %w", e.ID, err)
+	}
+	return app.storage.PaymentEvents.MarkProcessed(e.ID)
+}
+
+func (app *Application) handlePaymentEvent(eventType string, data []byte) error {
+	switch eventType {
 	case string(stripe.EventTypeCheckoutSessionCompleted), string(stripe.EventTypeCheckoutSessionAsyncPaymentSucceeded):
-		var data stripe.CheckoutSession
-		err := json.Unmarshal(event.Data.Raw, &data)
+		var cs stripe.CheckoutSession
+		err := json.Unmarshal(data, &cs)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing webhook JSON: %v\n", err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
+			return fmt.Errorf("error parsing webhook JSON: %w", err)
 		}
 
 		params := &stripe.CheckoutSessionParams{}
 		params.AddExpand("line_items")
-		cs, err := session.Get(data.ID, params)
+		params.AddExpand("subscription")
+		s, err := session.Get(cs.ID, params)
 		if err != nil {
-			log.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return err
 		}
 
 		log.Println("EventTypeCheckoutSessionCompleted|EventTypeCheckoutSessionAsyncPaymentSucceeded")
 
-		if cs.PaymentStatus != stripe.CheckoutSessionPaymentStatusUnpaid {
-			ses, err := app.storage.Checkouts.GetBySessionID(cs.ID)
+		if s.Mode == stripe.CheckoutSessionModeSubscription {
+			if s.Subscription == nil {
+				return fmt.Errorf("subscription session %s has no subscription", s.ID)
+			}
+			activated, err := app.storage.Memberships.Activate(s.ID, toMembershipSubscription(s.Subscription))
 			if err != nil {
-				log.Println(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
+				return err
+			}
+			if activated {
+				log.Println("Activated membership of session:", s.ID)
+			}
+			return nil
+		}
+
+		if s.PaymentStatus != stripe.CheckoutSessionPaymentStatusUnpaid {
+			ses, err := app.storage.Checkouts.GetBySessionID(s.ID)
+			if err != nil {
+				return err
+			}
+			paymentReference := s.ID
+			if s.PaymentIntent != nil {
+				paymentReference = s.PaymentIntent.ID
 			}
 			if ses != nil {
-				err = app.storage.Checkouts.Fulfill(cs.ID, ses.UserID)
+				err = app.storage.Checkouts.Fulfill(s.ID, ses.UserID, paymentReference)
 				if err != nil {
-					log.Println(err)
-					w.WriteHeader(http.StatusInternalServerError)
-					return
+					return err
+				}
+			} else {
+				activated, err := app.storage.GiftCards.Activate(s.ID, paymentReference)
+				if err != nil {
+					return err
+				}
+				if activated {
+					log.Println("Activated gift card of session:", s.ID)
 				}
 			}
 		}
 
 	case string(stripe.EventTypeCheckoutSessionExpired):
 		var cs stripe.CheckoutSession
-		err := json.Unmarshal(event.Data.Raw, &cs)
+		err := json.Unmarshal(data, &cs)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing webhook JSON: %v\n", err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
+			return fmt.Errorf("error parsing webhook JSON: %w", err)
 		}
 		ses, err := app.storage.Checkouts.GetBySessionID(cs.ID)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return err
 		}
 		if ses != nil {
 			err = app.storage.Checkouts.DeleteBySessionID(ses.SessionID)
 			if err != nil {
-				log.Println(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			} else {
-				log.Println("Deleted Checkout Session:", ses.SessionID)
+				return err
 			}
+			log.Println("Deleted Checkout Session:", ses.SessionID)
+		}
+		err = app.storage.Memberships.DeleteIncomplete(cs.ID)
+		if err != nil {
+			return err
+		}
+
+	// renewals, failed payments and cancellations all sync the membership with its subscription
+	case string(stripe.EventTypeInvoicePaid), string(stripe.EventTypeInvoicePaymentFailed):
+		var inv stripe.Invoice
+		err := json.Unmarshal(data, &inv)
+		if err != nil {
+			return fmt.Errorf("error parsing webhook JSON: %w", err)
+		}
+		if inv.Subscription == nil {
+			return nil
+		}
+		return app.syncMembership(inv.Subscription.ID)
+
+	case string(stripe.EventTypeCustomerSubscriptionUpdated), string(stripe.EventTypeCustomerSubscriptionDeleted):
+		var sub stripe.Subscription
+		err := json.Unmarshal(data, &sub)
+		if err != nil {
+			return fmt.Errorf("error parsing webhook JSON: %w", err)
 		}
+		return app.syncMembership(sub.ID)
 	}
+	return nil
 }
 
 func (app *Application) handleCheckoutSessionCancel(w http.ResponseWriter, r *http.Request) {
@@ -57,6 +57,11 @@ func (app *Application) createCinemaHandler(w http.ResponseWriter, r *http.Reque
 		writeServerErr(err, w)
 		return
 	}
+	err = app.syncCinemaRoles(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
 
 	writeJSON(CreateCinemaResponse{Cinema: c}, http.StatusCreated, w)
 }
@@ -208,8 +213,7 @@ func (app *Application) updateCinemaHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if c.OwnerID != u.ID {
-		writeForbidden(w)
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeCinema, w) {
 		return
 	}
 
@@ -264,8 +268,13 @@ func (app *Application) deleteCinemaHandler(w http.ResponseWriter, r *http.Reque
 		writeNotFound(w)
 		return
 	}
-	if c.OwnerID != u.ID {
-		writeForbidden(w)
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeOwner, w) {
+		return
+	}
+	// the members are removed with the cinema
+	members, err := app.storage.CinemaMembers.GetAll(c.ID)
+	if err != nil {
+		writeServerErr(err, w)
 		return
 	}
 	err = app.storage.Cinemas.Delete(c)
@@ -273,6 +282,18 @@ func (app *Application) deleteCinemaHandler(w http.ResponseWriter, r *http.Reque
 		writeServerErr(err, w)
 		return
 	}
+	err = app.syncCinemaRoles(c.OwnerID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	for _, m := range members {
+		err = app.syncCinemaRoles(m.UserID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+	}
 	writeJSON(ResponseMessage{Message: "resource deleted successfully"}, http.StatusOK, w)
 }
 
@@ -339,8 +360,7 @@ func (app *Application) createHallHandler(w http.ResponseWriter, r *http.Request
 		writeNotFound(w)
 		return
 	}
-	if c.OwnerID != u.ID {
-		writeForbidden(w)
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeHalls, w) {
 		return
 	}
 	h, err := app.storage.Halls.Create(req.Name, c.ID, req.SeatingArrangement, req.SeatPrice)
@@ -442,8 +462,7 @@ func (app *Application) updateHallHandler(w http.ResponseWriter, r *http.Request
 		writeNotFound(w)
 		return
 	}
-	if c.OwnerID != u.ID {
-		writeForbidden(w)
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeHalls, w) {
 		return
 	}
 	if req.Name != nil {
@@ -497,8 +516,7 @@ func (app *Application) deleteHallHandler(w http.ResponseWriter, r *http.Request
 		writeNotFound(w)
 		return
 	}
-	if c.OwnerID != u.ID {
-		writeForbidden(w)
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeHalls, w) {
 		return
 	}
 	err = app.storage.Halls.Delete(h)
@@ -560,8 +578,7 @@ func (app *Application) createSeatHandler(w http.ResponseWriter, r *http.Request
 		writeNotFound(w)
 		return
 	}
-	if c.OwnerID != u.ID {
-		writeForbidden(w)
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeHalls, w) {
 		return
 	}
 	seat, err := app.storage.Seats.Create(int32(id), req.Coordinates)
@@ -657,8 +674,7 @@ func (app *Application) updateSeatHandler(w http.ResponseWriter, r *http.Request
 		writeNotFound(w)
 		return
 	}
-	if c.OwnerID != u.ID {
-		writeForbidden(w)
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeHalls, w) {
 		return
 	}
 
@@ -708,8 +724,7 @@ func (app *Application) deleteSeatHandler(w http.ResponseWriter, r *http.Request
 		writeNotFound(w)
 		return
 	}
-	if c.OwnerID != u.ID {
-		writeForbidden(w)
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeHalls, w) {
 		return
 	}
 
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+// checkCinemaScope makes sure the user can act on the cinema within the scope, the owner has every scope
+// and the members have the scopes of their role. It writes the response when the user can't.
+func (app *Application) checkCinemaScope(c *internal.Cinema, u *internal.User, scope internal.CinemaScope, w http.ResponseWriter) bool {
+	if c.OwnerID == u.ID {
+		return true
+	}
+	if scope == internal.CinemaScopeOwner {
+		writeForbidden(w)
+		return false
+	}
+	role, err := app.storage.CinemaMembers.GetRole(c.ID, u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return false
+	}
+	if role == nil || !role.HasScope(scope) {
+		writeForbidden(w)
+		return false
+	}
+	return true
+}
+
+// checkScheduleScope makes sure the user can act on the cinema of the schedule within the scope.
+func (app *Application) checkScheduleScope(s *internal.Schedule, u *internal.User, scope internal.CinemaScope, w http.ResponseWriter) bool {
+	_, c, err := app.storage.Halls.GetAndCinema(s.HallID)
+	if err != nil {
+		writeServerErr(err, w)
+		return false
+	}
+	if c == nil {
+		writeError(fmt.Errorf("couldn't find hall with id %d", s.HallID), http.StatusNotFound, w)
+		return false
+	}
+	return app.checkCinemaScope(c, u, scope, w)
+}
+
+// syncCinemaRoles grants the cinema-owner and cinema-staff roles to the user while the user owns or is a member of a cinema
+// and revokes them once the user doesn't.
+func (app *Application) syncCinemaRoles(userID int64) error {
+	roles, err := app.storage.CinemaMembers.GetStaffRoles(userID)
+	if err != nil {
+		return err
+	}
+	var revoked []string
+	for _, role := range []string{internal.RoleCinemaOwner, internal.RoleCinemaStaff} {
+		if !slices.Contains(roles, role) {
+			revoked = append(revoked, role)
+		}
+	}
+	if len(roles) != 0 {
+		err = app.storage.Permissions.GrantRoles(userID, roles)
+		if err != nil {
+			return err
+		}
+	}
+	if len(revoked) != 0 {
+		return app.storage.Permissions.RevokeRoles(userID, revoked)
+	}
+	return nil
+}
+
+type GetCinemaMembersResponse struct {
+	Members []internal.CinemaMember `json:"members"`
+}
+
+// setCinemaMemberHandler godoc
+//
+//	@Summary		Sets a cinema member
+//	@Description	adds a user to the staff of a given cinema with a role or changes the role of a member.
+//	@Description	managers run the cinema, schedulers manage the schedules and box office staff sell at the counter
+//	@Tags			cinemas
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"cinema id"
+//	@Param			email	body		string	true	"email of the user"
+//	@Param			role	body		int		true	"role (0 manager, 1 scheduler, 2 box office)"
+//	@Success		200		{object}	GetCinemaMembersResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		403		{object}	ResponseError
+//	@Failure		404		{object}	ResponseMessage
+//	@Failure		409		{object}	ResponseMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/cinemas/{id}/members [post]
+func (app *Application) setCinemaMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		Email *string              `json:"email"`
+		Role  *internal.CinemaRole `json:"role"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.CheckEmail(req.Email)
+	v.Check(req.Role != nil, "role", "must be provided")
+	if req.Role != nil {
+		v.Check(*req.Role >= internal.CinemaRoleManager && *req.Role <= internal.CinemaRoleBoxOffice, "role", "must be 0 (manager), 1 (scheduler) or 2 (box office)")
+	}
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if c == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeOwner, w) {
+		return
+	}
+	member, err := app.storage.Users.GetByEmail(*req.Email)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if member == nil {
+		writeNotFound(w)
+		return
+	}
+	if member.ID == c.OwnerID {
+		writeJSON(ResponseMessage{Message: "the owner can't be a member of the cinema"}, http.StatusConflict, w)
+		return
+	}
+	err = app.storage.CinemaMembers.Set(c.ID, member.ID, *req.Role, u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.syncCinemaRoles(member.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	members, err := app.storage.CinemaMembers.GetAll(c.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetCinemaMembersResponse{Members: members}, http.StatusOK, w)
+}
+
+// getCinemaMembersHandler godoc
+//
+//	@Summary		Gets the cinema members
+//	@Description	gets the staff of a given cinema with their roles and scopes
+//	@Tags			cinemas
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"cinema id"
+//	@Success		200	{object}	GetCinemaMembersResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/cinemas/{id}/members [get]
+func (app *Application) getCinemaMembersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if c == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeCinema, w) {
+		return
+	}
+	members, err := app.storage.CinemaMembers.GetAll(c.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetCinemaMembersResponse{Members: members}, http.StatusOK, w)
+}
+
+// removeCinemaMemberHandler godoc
+//
+//	@Summary		Removes a cinema member
+//	@Description	removes a user from the staff of a given cinema, the user's open drawers can still be closed by the managers
+//	@Tags			cinemas
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"cinema id"
+//	@Param			user_id	path		int	true	"user id"
+//	@Success		200		{object}	ResponseMessage
+//	@Failure		400		{object}	ResponseError
+//	@Failure		403		{object}	ResponseError
+//	@Failure		404		{object}	ResponseMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/cinemas/{id}/members/{user_id} [delete]
+func (app *Application) removeCinemaMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	userID, err := getPathValuePositiveInt(r, "user_id")
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if c == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeOwner, w) {
+		return
+	}
+	err = app.storage.CinemaMembers.Remove(c.ID, int64(userID))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.syncCinemaRoles(int64(userID))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "resource deleted successfully"}, http.StatusOK, w)
+}
+
+// getUserCinemasHandler godoc
+//
+//	@Summary		Gets the user's cinemas
+//	@Description	gets the cinemas the user is a member of with the user's role and scopes
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetCinemaMembersResponse
+//	@Failure		500	{object}	ResponseError
+//	@Router			/users/me/cinemas [get]
+func (app *Application) getUserCinemasHandler(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	members, err := app.storage.CinemaMembers.GetAllForUser(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetCinemaMembersResponse{Members: members}, http.StatusOK, w)
+}
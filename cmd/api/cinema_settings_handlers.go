@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
+)
+
+type CinemaSettingsResponse struct {
+	Settings *internal.CinemaSettings `json:"settings"`
+}
+
+// getCinemaSettingsHandler godoc
+//
+//	@Summary		Gets cinema settings
+//	@Description	gets the settings of a given cinema, null values fall back to the global ones
+//	@Tags			cinemas
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"cinema id"
+//	@Success		200	{object}	CinemaSettingsResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/cinemas/{id}/settings [get]
+func (app *Application) getCinemaSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if c == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeCinema, w) {
+		return
+	}
+	settings, err := app.storage.Settings.Get(c.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(CinemaSettingsResponse{Settings: settings}, http.StatusOK, w)
+}
+
+// updateCinemaSettingsHandler godoc
+//
+//	@Summary		Updates cinema settings
+//	@Description	updates the settings of a given cinema, null values fall back to the global ones
+//	@Tags			cinemas
+//	@Accept			json
+//	@Produce		json
+//	@Param			id							path		int		true	"cinema id"
+//	@Param			ticket_lock_seconds			body		int		false	"ticket hold duration in seconds"
+//	@Param			checkout_session_seconds	body		int		false	"checkout session duration in seconds"
+//	@Param			allow_ticket_transfers		body		bool	false	"buyers can transfer their tickets"
+//	@Param			resale_price_cap			body		string	false	"percentage of the face value tickets can be resold for, resale is disabled if it's null"
+//	@Param			resale_fee_rate				body		string	false	"percentage of the resale price kept from the seller"
+//	@Success		200							{object}	CinemaSettingsResponse
+//	@Failure		400							{object}	ViolationsMessage
+//	@Failure		403							{object}	ResponseError
+//	@Failure		404							{object}	ResponseMessage
+//	@Failure		500							{object}	ResponseError
+//	@Router			/cinemas/{id}/settings [put]
+func (app *Application) updateCinemaSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		TicketLockSeconds      *int32           `json:"ticket_lock_seconds"`
+		CheckoutSessionSeconds *int32           `json:"checkout_session_seconds"`
+		AllowTicketTransfers   *bool            `json:"allow_ticket_transfers"`
+		ResalePriceCap         *decimal.Decimal `json:"resale_price_cap"`
+		ResaleFeeRate          *decimal.Decimal `json:"resale_fee_rate"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if c == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeCinema, w) {
+		return
+	}
+	settings, err := app.storage.Settings.Get(c.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	settings.TicketLockSeconds = req.TicketLockSeconds
+	settings.CheckoutSessionSeconds = req.CheckoutSessionSeconds
+	if req.AllowTicketTransfers != nil {
+		settings.AllowTicketTransfers = *req.AllowTicketTransfers
+	}
+	settings.ResalePriceCap = req.ResalePriceCap
+	if req.ResaleFeeRate != nil {
+		settings.ResaleFeeRate = *req.ResaleFeeRate
+	}
+	v := NewValidator()
+	v.CheckCinemaSettings(settings)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	err = app.storage.Settings.Update(settings)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(CinemaSettingsResponse{Settings: settings}, http.StatusOK, w)
+}
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
 	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
 )
 
 type Config struct {
@@ -32,6 +35,27 @@ type Config struct {
 		maxRequestPerSecond float64
 		burst               int
 	}
+	tokens struct {
+		accessDuration  time.Duration
+		refreshDuration time.Duration
+		mode            string
+	}
+	jwt struct {
+		keysDir      string
+		signingKeyID string
+		issuer       string
+	}
+	login struct {
+		accountLimits internal.LoginLimits
+		ipLimits      internal.LoginLimits
+	}
+	twoFactor struct {
+		issuer        string
+		requiredRoles []string
+	}
+	oidc struct {
+		providers []internal.OIDCProviderConfig
+	}
 	cors struct {
 		trustedOrigins []string
 	}
@@ -39,8 +63,33 @@ type Config struct {
 		key           string
 		webhookSecret string
 	}
+	checkout struct {
+		ticketLockDuration time.Duration
+		sessionDuration    time.Duration
+		lockLimits         internal.LockLimits
+		waitlistOffer      time.Duration
+	}
+	loyalty struct {
+		pointsPerDollar int
+		pointValue      decimal.Decimal
+		pointsLifetime  time.Duration
+	}
 }
 
+// the access tokens are either opaque and stored in the database or signed JWTs validated without the database.
+const (
+	accessTokenModeOpaque = "opaque"
+	accessTokenModeJWT    = "jwt"
+)
+
+var oidcProviderNameRX = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// stripe only accepts checkout sessions that expire between 30 minutes and 24 hours after their creation.
+const (
+	minCheckoutSessionDuration = 30 * time.Minute
+	maxCheckoutSessionDuration = 24 * time.Hour
+)
+
 func MustLoadConfig() *Config {
 	defer func() {
 		if err := recover(); err != nil {
@@ -73,11 +122,91 @@ func MustLoadConfig() *Config {
 	cfg.limiter.maxRequestPerSecond = MustGetFloatEnvVar("LIMITER_MAX_RPS")
 	cfg.limiter.burst = MustGetIntEnvVar("LIMITER_BURST")
 
+	cfg.tokens.accessDuration = MustGetDureationEnvVar("ACCESS_TOKEN_DURATION")
+	cfg.tokens.refreshDuration = MustGetDureationEnvVar("REFRESH_TOKEN_DURATION")
+	if cfg.tokens.refreshDuration <= cfg.tokens.accessDuration {
+		panic(`environment variable "REFRESH_TOKEN_DURATION" must be longer than "ACCESS_TOKEN_DURATION"`)
+	}
+	cfg.tokens.mode = MustGetStringEnvVar("ACCESS_TOKEN_MODE")
+	switch cfg.tokens.mode {
+	case accessTokenModeOpaque:
+	case accessTokenModeJWT:
+		cfg.jwt.keysDir = MustGetStringEnvVar("JWT_KEYS_DIR")
+		cfg.jwt.signingKeyID = MustGetStringEnvVar("JWT_SIGNING_KEY_ID")
+		cfg.jwt.issuer = MustGetStringEnvVar("JWT_ISSUER")
+	default:
+		panic(`environment variable "ACCESS_TOKEN_MODE" must be "opaque" or "jwt"`)
+	}
+
+	failureWindow := MustGetDureationEnvVar("LOGIN_FAILURE_WINDOW")
+	maxDelay := MustGetDureationEnvVar("LOGIN_MAX_DELAY")
+	lockout := MustGetDureationEnvVar("LOGIN_LOCKOUT")
+	cfg.login.accountLimits = internal.LoginLimits{
+		FailureWindow: failureWindow,
+		DelayAfter:    MustGetIntEnvVar("LOGIN_ACCOUNT_DELAY_AFTER"),
+		MaxDelay:      maxDelay,
+		LockAfter:     MustGetIntEnvVar("LOGIN_ACCOUNT_LOCK_AFTER"),
+		Lockout:       lockout,
+	}
+	cfg.login.ipLimits = internal.LoginLimits{
+		FailureWindow: failureWindow,
+		DelayAfter:    MustGetIntEnvVar("LOGIN_IP_DELAY_AFTER"),
+		MaxDelay:      maxDelay,
+		LockAfter:     MustGetIntEnvVar("LOGIN_IP_LOCK_AFTER"),
+		Lockout:       lockout,
+	}
+
+	cfg.twoFactor.issuer = MustGetStringEnvVar("TWO_FACTOR_ISSUER")
+	// no roles means the two-factor authentication is optional for everyone
+	cfg.twoFactor.requiredRoles = strings.Fields(os.Getenv("TWO_FACTOR_REQUIRED_ROLES"))
+
+	// no providers means the users can only sign in with passwords
+	providers := strings.Fields(os.Getenv("OIDC_PROVIDERS"))
+	if len(providers) != 0 {
+		redirectURL := MustGetStringEnvVar("OIDC_REDIRECT_URL")
+		for _, name := range providers {
+			if !oidcProviderNameRX.MatchString(name) {
+				panic(fmt.Sprintf("OIDC provider %q must only have lowercase letters, digits and dashes", name))
+			}
+			prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+			cfg.oidc.providers = append(cfg.oidc.providers, internal.OIDCProviderConfig{
+				Name:         name,
+				Issuer:       MustGetStringEnvVar(prefix + "ISSUER"),
+				ClientID:     MustGetStringEnvVar(prefix + "CLIENT_ID"),
+				ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+				RedirectURL:  redirectURL,
+			})
+		}
+	}
+
 	cfg.cors.trustedOrigins = strings.Fields(MustGetStringEnvVar("CORS_TRUSTED_ORIGINS"))
 
 	cfg.stripe.key = MustGetStringEnvVar("STRIPE_KEY")
 	cfg.stripe.webhookSecret = MustGetStringEnvVar("STRIPE_WEBHOOK_SECRET")
 
+	cfg.checkout.ticketLockDuration = MustGetDureationEnvVar("TICKET_LOCK_DURATION")
+	if cfg.checkout.ticketLockDuration < time.Minute {
+		panic(`environment variable "TICKET_LOCK_DURATION" must be at least 1m`)
+	}
+	cfg.checkout.sessionDuration = MustGetDureationEnvVar("CHECKOUT_SESSION_DURATION")
+	if cfg.checkout.sessionDuration < minCheckoutSessionDuration || cfg.checkout.sessionDuration > maxCheckoutSessionDuration {
+		panic(`environment variable "CHECKOUT_SESSION_DURATION" must be between 30m and 24h`)
+	}
+	cfg.checkout.lockLimits.MaxPerUser = MustGetIntEnvVar("TICKET_MAX_LOCKS_PER_USER")
+	cfg.checkout.lockLimits.MaxPerSchedule = MustGetIntEnvVar("TICKET_MAX_LOCKS_PER_SCHEDULE")
+	cfg.checkout.lockLimits.MaxPerOrder = MustGetIntEnvVar("TICKET_MAX_LOCKS_PER_ORDER")
+	cfg.checkout.lockLimits.CooldownExpirations = MustGetIntEnvVar("TICKET_LOCK_COOLDOWN_EXPIRATIONS")
+	cfg.checkout.lockLimits.CooldownWindow = MustGetDureationEnvVar("TICKET_LOCK_COOLDOWN_WINDOW")
+	cfg.checkout.lockLimits.Cooldown = MustGetDureationEnvVar("TICKET_LOCK_COOLDOWN")
+	cfg.checkout.waitlistOffer = MustGetDureationEnvVar("WAITLIST_OFFER_DURATION")
+
+	cfg.loyalty.pointsPerDollar = MustGetIntEnvVar("LOYALTY_POINTS_PER_DOLLAR")
+	cfg.loyalty.pointValue = MustGetDecimalEnvVar("LOYALTY_POINT_VALUE")
+	if !cfg.loyalty.pointValue.IsPositive() {
+		panic(`environment variable "LOYALTY_POINT_VALUE" must be positive`)
+	}
+	cfg.loyalty.pointsLifetime = MustGetDureationEnvVar("LOYALTY_POINTS_LIFETIME")
+
 	return &cfg
 }
 
@@ -124,3 +253,15 @@ func MustGetDureationEnvVar(key string) time.Duration {
 	}
 	return n
 }
+
+func MustGetDecimalEnvVar(key string) decimal.Decimal {
+	value := os.Getenv(key)
+	if value == "" {
+		panic(fmt.Sprintf(`environment variable "%s" is not specified`, key))
+	}
+	n, err := decimal.NewFromString(value)
+	if err != nil {
+		panic(fmt.Errorf(`environment variable "%s" is not valid decimal: %w`, key, err))
+	}
+	return n
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
+)
+
+func (app *Application) checkFeeHall(f *internal.Fee, w http.ResponseWriter) bool {
+	if f.HallID == nil {
+		return true
+	}
+	h, err := app.storage.Halls.Get(*f.HallID)
+	if err != nil {
+		writeServerErr(err, w)
+		return false
+	}
+	if h == nil || h.CinemaID != f.CinemaID {
+		writeError(fmt.Errorf("couldn't find hall with id %d in the cinema", *f.HallID), http.StatusNotFound, w)
+		return false
+	}
+	return true
+}
+
+type CreateFeeResponse struct {
+	Fee *internal.Fee `json:"fee"`
+}
+
+// createFeeHandler godoc
+//
+//	@Summary		Creates a fee
+//	@Description	creates a fee or a tax for a given cinema
+//	@Tags			fees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int		true	"cinema id"
+//	@Param			name			body		string	true	"name"
+//	@Param			kind			body		int		true	"kind (0 fee, 1 tax)"
+//	@Param			basis			body		int		true	"basis (0 ticket, 1 order)"
+//	@Param			rate			body		string	false	"percentage"
+//	@Param			amount			body		string	false	"fixed amount"
+//	@Param			is_inclusive	body		bool	false	"tax is included in the price"
+//	@Param			hall_id			body		int		false	"hall id"
+//	@Success		201				{object}	CreateFeeResponse
+//	@Failure		400				{object}	ViolationsMessage
+//	@Failure		403				{object}	ResponseError
+//	@Failure		404				{object}	ResponseMessage
+//	@Failure		500				{object}	ResponseError
+//	@Router			/cinemas/{id}/fees [post]
+func (app *Application) createFeeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		HallID      *int32            `json:"hall_id"`
+		Name        string            `json:"name"`
+		Kind        internal.FeeKind  `json:"kind"`
+		Basis       internal.FeeBasis `json:"basis"`
+		Rate        decimal.Decimal   `json:"rate"`
+		Amount      decimal.Decimal   `json:"amount"`
+		IsInclusive bool              `json:"is_inclusive"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	f := &internal.Fee{
+		CinemaID:    int32(id),
+		HallID:      req.HallID,
+		Name:        req.Name,
+		Kind:        req.Kind,
+		Basis:       req.Basis,
+		Rate:        req.Rate,
+		Amount:      req.Amount,
+		IsInclusive: req.IsInclusive,
+	}
+	v := NewValidator()
+	v.CheckFee(f)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if c == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopePricing, w) {
+		return
+	}
+	if !app.checkFeeHall(f, w) {
+		return
+	}
+	f, err = app.storage.Fees.Create(f.CinemaID, f.HallID, f.Name, f.Kind, f.Basis, f.Rate, f.Amount, f.IsInclusive)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(CreateFeeResponse{Fee: f}, http.StatusCreated, w)
+}
+
+type GetFeesResponse struct {
+	Fees []internal.Fee `json:"fees"`
+}
+
+// getFeesHandler godoc
+//
+//	@Summary		Gets a list of fees
+//	@Description	gets a list of fees and taxes for a given cinema
+//	@Tags			fees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"cinema id"
+//	@Success		200	{object}	GetFeesResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		500	{object}	ResponseError
+//	@Router			/cinemas/{id}/fees [get]
+func (app *Application) getFeesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	fees, err := app.storage.Fees.GetAllForCinema(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetFeesResponse{Fees: fees}, http.StatusOK, w)
+}
+
+type UpdateFeeResponse struct {
+	Fee *internal.Fee `json:"fee"`
+}
+
+// updateFeeHandler godoc
+//
+//	@Summary		Updates a fee
+//	@Description	updates a fee or a tax by id
+//	@Tags			fees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"fee id"
+//	@Success		200	{object}	UpdateFeeResponse
+//	@Failure		400	{object}	ViolationsMessage
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/fees/{id} [put]
+func (app *Application) updateFeeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		HallID      *int32             `json:"hall_id"`
+		Name        *string            `json:"name"`
+		Kind        *internal.FeeKind  `json:"kind"`
+		Basis       *internal.FeeBasis `json:"basis"`
+		Rate        *decimal.Decimal   `json:"rate"`
+		Amount      *decimal.Decimal   `json:"amount"`
+		IsInclusive *bool              `json:"is_inclusive"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	f, c, err := app.storage.Fees.GetAndCinema(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if f == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopePricing, w) {
+		return
+	}
+	if req.HallID != nil {
+		f.HallID = req.HallID
+	}
+	if req.Name != nil {
+		f.Name = *req.Name
+	}
+	if req.Kind != nil {
+		f.Kind = *req.Kind
+	}
+	if req.Basis != nil {
+		f.Basis = *req.Basis
+	}
+	if req.Rate != nil {
+		f.Rate = *req.Rate
+	}
+	if req.Amount != nil {
+		f.Amount = *req.Amount
+	}
+	if req.IsInclusive != nil {
+		f.IsInclusive = *req.IsInclusive
+	}
+	v := NewValidator()
+	v.CheckFee(f)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	if !app.checkFeeHall(f, w) {
+		return
+	}
+	err = app.storage.Fees.Update(f)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(UpdateFeeResponse{Fee: f}, http.StatusOK, w)
+}
+
+// deleteFeeHandler godoc
+//
+//	@Summary		Deletes a fee
+//	@Description	deletes a fee or a tax by id
+//	@Tags			fees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"fee id"
+//	@Success		200	{object}	ResponseMessage
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/fees/{id} [delete]
+func (app *Application) deleteFeeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	f, c, err := app.storage.Fees.GetAndCinema(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if f == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopePricing, w) {
+		return
+	}
+	err = app.storage.Fees.Delete(f)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "resource deleted successfully"}, http.StatusOK, w)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/checkout/session"
+)
+
+var (
+	minGiftCardAmount = decimal.NewFromInt(5)
+	maxGiftCardAmount = decimal.NewFromInt(500)
+)
+
+type GiftCardResponse struct {
+	GiftCard *internal.GiftCard `json:"gift_card"`
+	Code     string             `json:"code,omitempty"`
+	URL      string             `json:"url,omitempty"`
+}
+
+// issueGiftCardHandler godoc
+//
+//	@Summary		Issues a gift card
+//	@Description	issues a gift card that can be redeemed right away, the code is only returned once
+//	@Tags			gift-cards
+//	@Accept			json
+//	@Produce		json
+//	@Param			amount	body		number	true	"gift card amount"
+//	@Success		201		{object}	GiftCardResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/admin/gift-cards [post]
+func (app *Application) issueGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount *decimal.Decimal `json:"amount"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.CheckGiftCardAmount(req.Amount)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	code := internal.GenerateToken()
+	g, err := app.storage.GiftCards.Issue(code, *req.Amount, u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GiftCardResponse{GiftCard: g, Code: code}, http.StatusCreated, w)
+}
+
+// purchaseGiftCardHandler godoc
+//
+//	@Summary		Purchases a gift card
+//	@Description	creates a checkout session for a gift card, the code is only returned once and it can be redeemed after the session is paid
+//	@Tags			gift-cards
+//	@Accept			json
+//	@Produce		json
+//	@Param			amount	body		number	true	"gift card amount"
+//	@Success		201		{object}	GiftCardResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/gift-cards [post]
+func (app *Application) purchaseGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount *decimal.Decimal `json:"amount"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.CheckGiftCardAmount(req.Amount)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	amount, err := toStripeAmount(*req.Amount)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	url := "http://localhost:8080/static/"
+	params := &stripe.CheckoutSessionParams{
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+					Currency: stripe.String("usd"),
+					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+						Name: stripe.String("Gift card " + req.Amount.StringFixed(2) + " USD"),
+					},
+					UnitAmountDecimal: stripe.Float64(amount),
+				},
+				Quantity: stripe.Int64(1),
+			},
+		},
+		Mode:       stripe.String(string(stripe.CheckoutSessionModePayment)),
+		SuccessURL: stripe.String(url + "success.html"),
+		CancelURL:  stripe.String(url + "cancel.html"),
+		ExpiresAt:  stripe.Int64(time.Now().Add(app.config.checkout.sessionDuration).Unix()),
+	}
+	s, err := session.New(params)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	code := internal.GenerateToken()
+	g, err := app.storage.GiftCards.CreatePurchase(code, *req.Amount, u.ID, s.ID)
+	if err != nil {
+		if _, err := session.Expire(s.ID, nil); err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GiftCardResponse{GiftCard: g, Code: code, URL: s.URL}, http.StatusCreated, w)
+}
+
+type GetGiftCardsResponse struct {
+	GiftCards []internal.GiftCard `json:"gift_cards"`
+}
+
+// getGiftCardsHandler godoc
+//
+//	@Summary		Gets purchased gift cards
+//	@Description	gets the gift cards purchased by the user
+//	@Tags			gift-cards
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetGiftCardsResponse
+//	@Failure		500	{object}	ResponseError
+//	@Router			/gift-cards [get]
+func (app *Application) getGiftCardsHandler(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	cards, err := app.storage.GiftCards.GetAllPurchased(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetGiftCardsResponse{GiftCards: cards}, http.StatusOK, w)
+}
+
+type RedeemGiftCardResponse struct {
+	GiftCard *internal.GiftCard    `json:"gift_card"`
+	Entry    *internal.WalletEntry `json:"entry"`
+}
+
+// redeemGiftCardHandler godoc
+//
+//	@Summary		Redeems a gift card
+//	@Description	credits the amount of a gift card to the user's wallet
+//	@Tags			gift-cards
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		string	true	"gift card code"
+//	@Success		200		{object}	RedeemGiftCardResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		404		{object}	ResponseMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/gift-cards/redeem [post]
+func (app *Application) redeemGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.Code != "", "code", "must be provided")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	g, entry, err := app.storage.GiftCards.Redeem(req.Code, u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if g == nil {
+		writeJSON(ResponseMessage{Message: "invalid, unpaid or already redeemed gift card"}, http.StatusNotFound, w)
+		return
+	}
+	writeJSON(RedeemGiftCardResponse{GiftCard: g, Entry: entry}, http.StatusOK, w)
+}
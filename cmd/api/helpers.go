@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"runtime/debug"
 	"strconv"
 	"strings"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
 )
 
 var InternalServerErrorBuf bytes.Buffer
@@ -51,6 +54,21 @@ func getPathValuePositiveInt(r *http.Request, p string) (int, error) {
 	return v, nil
 }
 
+// maxUserAgentLength is how much of the User-Agent header is kept with a session.
+const maxUserAgentLength = 512
+
+func getSessionClient(r *http.Request) internal.SessionClient {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	return internal.SessionClient{IP: ip, UserAgent: userAgent}
+}
+
 func getIDFromPathValue(r *http.Request) (int, error) {
 	id, err := getPathValuePositiveInt(r, "id")
 	if err != nil {
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+// revocationList is an in-memory copy of the token revocations so the signed access tokens
+// are checked without querying the database, it's synced by the revocations service.
+type revocationList struct {
+	mu       sync.RWMutex
+	users    map[int64]time.Time
+	sessions map[int64]time.Time
+}
+
+func newRevocationList() *revocationList {
+	return &revocationList{
+		users:    make(map[int64]time.Time),
+		sessions: make(map[int64]time.Time),
+	}
+}
+
+func (l *revocationList) add(r internal.TokenRevocation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.addLocked(r)
+}
+
+func (l *revocationList) addLocked(r internal.TokenRevocation) {
+	if r.UserID != nil && r.RevokedAt.After(l.users[*r.UserID]) {
+		l.users[*r.UserID] = r.RevokedAt
+	}
+	if r.SessionID != nil && r.RevokedAt.After(l.sessions[*r.SessionID]) {
+		l.sessions[*r.SessionID] = r.RevokedAt
+	}
+}
+
+func (l *revocationList) set(revocations []internal.TokenRevocation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.users = make(map[int64]time.Time)
+	l.sessions = make(map[int64]time.Time)
+	for _, r := range revocations {
+		l.addLocked(r)
+	}
+}
+
+// isRevoked reports whether the token was issued before its user's or its session's tokens were revoked,
+// the tokens issued in the second of the revocation are revoked too since iat is in seconds.
+func (l *revocationList) isRevoked(userID int64, claims *internal.AccessClaims) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if revokedAt, ok := l.users[userID]; ok && claims.IssuedAt <= revokedAt.Unix() {
+		return true
+	}
+	if revokedAt, ok := l.sessions[claims.SessionID]; ok && claims.IssuedAt <= revokedAt.Unix() {
+		return true
+	}
+	return false
+}
+
+// storedAccessTokenDuration is how long the access tokens stored with the sessions last,
+// none are stored in the jwt mode.
+func (app *Application) storedAccessTokenDuration() time.Duration {
+	if app.jwt != nil {
+		return 0
+	}
+	return app.config.tokens.accessDuration
+}
+
+// signAccessToken gives the session a signed access token in the jwt mode.
+func (app *Application) signAccessToken(u *internal.User, t *internal.SessionTokens) error {
+	if app.jwt == nil {
+		return nil
+	}
+	permissions, err := app.storage.Permissions.Get(u.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	expiresAt := now.Add(app.config.tokens.accessDuration)
+	token, err := app.jwt.Sign(internal.AccessClaims{
+		Subject:     strconv.FormatInt(u.ID, 10),
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   expiresAt.Unix(),
+		ID:          internal.GenerateToken(),
+		SessionID:   t.SessionID,
+		Name:        u.Name,
+		Email:       u.Email,
+		IsActivated: u.IsActivated,
+		Permissions: permissions,
+	})
+	if err != nil {
+		return err
+	}
+	t.AccessToken = token
+	t.AccessTokenExpiresAt = expiresAt
+	return nil
+}
+
+// authenticateJWT returns the user and the session of a signed access token, the user only has what the token carries.
+func (app *Application) authenticateJWT(token string) (*internal.User, *internal.Session, []internal.Permission, error) {
+	claims, err := app.jwt.Verify(token)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, nil, nil, internal.ErrInvalidJWT
+	}
+	if app.revocations.isRevoked(userID, claims) {
+		return nil, nil, nil, internal.ErrInvalidJWT
+	}
+	u := &internal.User{
+		ID:          userID,
+		Name:        claims.Name,
+		Email:       claims.Email,
+		IsActivated: claims.IsActivated,
+	}
+	session := &internal.Session{
+		ID:     claims.SessionID,
+		UserID: userID,
+	}
+	return u, session, claims.Permissions, nil
+}
+
+// revokeAccessTokens revokes the signed access tokens of the user or of one of the user's sessions,
+// the stored ones are deleted with their sessions so nothing is done outside the jwt mode.
+func (app *Application) revokeAccessTokens(userID int64, sessionID *int64) error {
+	if app.jwt == nil {
+		return nil
+	}
+	var r *internal.TokenRevocation
+	var err error
+	if sessionID != nil {
+		r, err = app.storage.Revocations.Create(nil, sessionID, app.config.tokens.accessDuration)
+	} else {
+		r, err = app.storage.Revocations.Create(&userID, nil, app.config.tokens.accessDuration)
+	}
+	if err != nil {
+		return err
+	}
+	app.revocations.add(*r)
+	return nil
+}
+
+func (app *Application) RevocationsService(tickRate time.Duration) ServiceFunc {
+	return func() {
+		log.Println("Started revocations background service")
+		ticker := time.NewTicker(tickRate)
+	loop:
+		for {
+			select {
+			case <-ticker.C:
+				revocations, err := app.storage.Revocations.GetAll()
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				app.revocations.set(revocations)
+				_, err = app.storage.Revocations.DeleteAllExpired()
+				if err != nil {
+					log.Println(err)
+				}
+			case _, open := <-app.quit:
+				if !open {
+					break loop
+				}
+			}
+		}
+		log.Println("Revocations service was shut down gracefully")
+	}
+}
+
+// getJWKSHandler godoc
+//
+//	@Summary		Gets the JSON web key set
+//	@Description	gets the public keys the signed access tokens can be verified with
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	internal.JWKS
+//	@Router			/.well-known/jwks.json [get]
+func (app *Application) getJWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(app.jwt.JWKS(), http.StatusOK, w)
+}
+
+type TokenRevocationResponse struct {
+	Revocation *internal.TokenRevocation `json:"revocation"`
+}
+
+// revokeTokensHandler godoc
+//
+//	@Summary		Revokes access tokens
+//	@Description	revokes the signed access tokens of a user or of a session right away for emergencies,
+//	@Description	the sessions can still be refreshed unless they're revoked too
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id		body		int	false	"user id"
+//	@Param			session_id	body		int	false	"session id"
+//	@Success		201			{object}	TokenRevocationResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		403			{object}	ResponseError
+//	@Failure		409			{object}	ResponseMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/admin/token-revocations [post]
+func (app *Application) revokeTokensHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID    *int64 `json:"user_id"`
+		SessionID *int64 `json:"session_id"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check((req.UserID == nil) != (req.SessionID == nil), "user_id", "either user_id or session_id must be provided")
+	if req.UserID != nil {
+		v.Check(*req.UserID > 0, "user_id", "must be a positive integer")
+	}
+	if req.SessionID != nil {
+		v.Check(*req.SessionID > 0, "session_id", "must be a positive integer")
+	}
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	if app.jwt == nil {
+		writeJSON(ResponseMessage{Message: "the access tokens aren't signed, revoke the sessions instead"}, http.StatusConflict, w)
+		return
+	}
+	actor := getUserFromRequestContext(r)
+	if actor == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	rev, err := app.storage.Revocations.Create(req.UserID, req.SessionID, app.config.tokens.accessDuration)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	app.revocations.add(*rev)
+	if req.UserID != nil {
+		app.audit(actor, *req.UserID, internal.AuditActionRevokeTokens, "")
+	}
+	writeJSON(TokenRevocationResponse{Revocation: rev}, http.StatusCreated, w)
+}
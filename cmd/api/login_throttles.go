@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+// loginThrottleKey is the account's key, the emails are counted whatever their case is
+// and whether they belong to a user or not.
+func loginThrottleKey(email string) string {
+	return strings.ToLower(email)
+}
+
+// loginAttempt is a login attempt counted against the account and the IP.
+type loginAttempt struct {
+	email string
+	ip    string
+	// lockedUntil is set if the attempt locked the account.
+	lockedUntil *time.Time
+}
+
+// attemptLogin counts the attempt against the account and the IP before the password or the code is checked,
+// it rejects the login if the account or the IP is locked or has to wait after its failures.
+// It's done before the password so the guesses don't cost a bcrypt comparison.
+func (app *Application) attemptLogin(email string, ip string, w http.ResponseWriter) (*loginAttempt, bool) {
+	client, retryAfter, err := app.storage.LoginThrottles.Attempt(internal.LoginThrottleIP, ip, app.config.login.ipLimits)
+	if err != nil {
+		writeServerErr(err, w)
+		return nil, false
+	}
+	if retryAfter > 0 {
+		writeLoginRetryAfter(retryAfter, client.IsLocked(time.Now()), w)
+		return nil, false
+	}
+	account, retryAfter, err := app.storage.LoginThrottles.Attempt(internal.LoginThrottleAccount, loginThrottleKey(email), app.config.login.accountLimits)
+	if err != nil {
+		writeServerErr(err, w)
+		return nil, false
+	}
+	if retryAfter > 0 {
+		// the account's delay isn't the IP's failure
+		if err := app.storage.LoginThrottles.Forgive(internal.LoginThrottleIP, ip); err != nil {
+			log.Println(err)
+		}
+		writeLoginRetryAfter(retryAfter, account.IsLocked(time.Now()), w)
+		return nil, false
+	}
+	a := &loginAttempt{email: email, ip: ip}
+	if account.IsLocked(time.Now()) {
+		a.lockedUntil = account.LockedUntil
+	}
+	return a, true
+}
+
+func writeLoginRetryAfter(retryAfter time.Duration, locked bool, w http.ResponseWriter) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", fmt.Sprint(seconds))
+	if locked {
+		writeError(errors.New("logging in is temporarily locked because of too many failed logins, try again later or reset your password"), http.StatusTooManyRequests, w)
+		return
+	}
+	writeError(fmt.Errorf("too many failed logins, try again in %d seconds", seconds), http.StatusTooManyRequests, w)
+}
+
+// failLogin keeps the attempt as a failure, the user is notified if it locked the account.
+func (app *Application) failLogin(a *loginAttempt, u *internal.User) {
+	if a.lockedUntil == nil || u == nil {
+		return
+	}
+	data := map[string]any{
+		"name":        u.Name,
+		"lockedUntil": a.lockedUntil.UTC().Format(time.RFC1123),
+	}
+	app.Go(app.SendMail(u.Email, AccountLockedTmpl, data))
+}
+
+// passLogin takes back the IP's failure once the password or the code is right, the account's failures are kept
+// until the user is signed in.
+func (app *Application) passLogin(a *loginAttempt) error {
+	return app.storage.LoginThrottles.Forgive(internal.LoginThrottleIP, a.ip)
+}
+
+// unlockLogin forgets the account's failed logins, it's done when the user is signed in or resets the password.
+func (app *Application) unlockLogin(email string) error {
+	return app.storage.LoginThrottles.Delete(internal.LoginThrottleAccount, loginThrottleKey(email))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+type GetLoyaltyResponse struct {
+	Account  *internal.LoyaltyAccount `json:"account"`
+	Entries  []internal.LoyaltyEntry  `json:"entries"`
+	MetaData *internal.MetaData       `json:"meta_data"`
+}
+
+// getLoyaltyHandler godoc
+//
+//	@Summary		Gets the loyalty account
+//	@Description	gets the user's loyalty points balance, tier and the history of earned, redeemed and expired points
+//	@Tags			loyalty
+//	@Accept			json
+//	@Produce		json
+//	@Param			page		query		int	false	"page number"
+//	@Param			page_size	query		int	false	"page size"
+//	@Success		200			{object}	GetLoyaltyResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/users/me/loyalty [get]
+func (app *Application) getLoyaltyHandler(w http.ResponseWriter, r *http.Request) {
+	v := NewValidator()
+	page := getQueryIntOr(r, "page", 1, v)
+	pageSize := getQueryIntOr(r, "page_size", 20, v)
+	v.Check(page > 0 && page <= 10_000_000, "page", "must be between 1 and 10_000_000")
+	v.Check(pageSize > 0 && pageSize <= 100, "page_size", "must be between 1 and 100")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	account, err := app.storage.Loyalty.GetAccount(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	entries, metaData, err := app.storage.Loyalty.GetEntries(u.ID, page, pageSize)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetLoyaltyResponse{Account: account, Entries: entries, MetaData: metaData}, http.StatusOK, w)
+}
@@ -33,18 +33,24 @@ import (
 const Version = "1.0.0"
 
 type Application struct {
-	config     Config
-	storage    *internal.Storage
-	mailer     *Mailer
-	wg         sync.WaitGroup
-	servicesCh chan ServiceFunc
-	quit       chan struct{}
+	config      Config
+	storage     *internal.Storage
+	mailer      *Mailer
+	jwt         *internal.JWTSigner // signs the access tokens, it's nil in the opaque mode
+	revocations *revocationList
+	oidc        map[string]*internal.OIDCProvider
+	wg          sync.WaitGroup
+	servicesCh  chan ServiceFunc
+	quit        chan struct{}
 }
 
 //go:embed templates
 var Templates embed.FS
 var ActivateUserTmpl *template.Template
 var ResetPasswordTempl *template.Template
+var WaitlistOfferTmpl *template.Template
+var TicketTransferTmpl *template.Template
+var AccountLockedTmpl *template.Template
 
 func init() {
 	var err error
@@ -56,6 +62,18 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	WaitlistOfferTmpl, err = template.ParseFS(Templates, "templates/waitlist_offer.gotmpl")
+	if err != nil {
+		panic(err)
+	}
+	TicketTransferTmpl, err = template.ParseFS(Templates, "templates/ticket_transfer.gotmpl")
+	if err != nil {
+		panic(err)
+	}
+	AccountLockedTmpl, err = template.ParseFS(Templates, "templates/account_locked.gotmpl")
+	if err != nil {
+		panic(err)
+	}
 }
 
 func main() {
@@ -91,6 +109,25 @@ func main() {
 		quit:       make(chan struct{}),
 	}
 
+	if cfg.tokens.mode == accessTokenModeJWT {
+		app.jwt, err = internal.LoadJWTSigner(cfg.jwt.keysDir, cfg.jwt.signingKeyID, cfg.jwt.issuer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		app.revocations = newRevocationList()
+		revocations, err := app.storage.Revocations.GetAll()
+		if err != nil {
+			log.Fatal(err)
+		}
+		app.revocations.set(revocations)
+		log.Println("Signing access tokens")
+	}
+
+	app.oidc = make(map[string]*internal.OIDCProvider)
+	for _, p := range cfg.oidc.providers {
+		app.oidc[p.Name] = internal.NewOIDCProvider(p)
+	}
+
 	app.Go(func() {
 		log.Println("Started services manager")
 	loop:
@@ -110,6 +147,13 @@ func main() {
 	app.StartService(app.TokensService(time.Minute))
 	app.StartService(app.CheckoutSessionsService(100, time.Minute))
 	app.StartService(app.TicketsService(time.Minute))
+	app.StartService(app.ReconciliationService(24*time.Hour, 10*time.Minute, 5*time.Minute))
+	app.StartService(app.WaitingRoomsService(5 * time.Second))
+	app.StartService(app.ResalePayoutsService(100, 5, 5*time.Minute, time.Minute))
+	app.StartService(app.LoyaltyService(time.Minute))
+	if app.jwt != nil {
+		app.StartService(app.RevocationsService(5 * time.Second))
+	}
 
 	tlsConfig := &tls.Config{
 		MinVersion:       tls.VersionTLS12,
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/checkout/session"
+	"github.com/stripe/stripe-go/v81/price"
+	"github.com/stripe/stripe-go/v81/subscription"
+)
+
+// toMembershipSubscription maps the payment gateway's subscription to the membership's status and billing period,
+// a subscription that can't be paid anymore cancels the membership.
+func toMembershipSubscription(s *stripe.Subscription) internal.MembershipSubscription {
+	sub := internal.MembershipSubscription{
+		Reference:         s.ID,
+		PeriodStart:       time.Unix(s.CurrentPeriodStart, 0),
+		PeriodEnd:         time.Unix(s.CurrentPeriodEnd, 0),
+		CancelAtPeriodEnd: s.CancelAtPeriodEnd,
+	}
+	switch s.Status {
+	case stripe.SubscriptionStatusActive, stripe.SubscriptionStatusTrialing:
+		sub.StatusID = internal.MembershipStatusActive
+	case stripe.SubscriptionStatusPastDue, stripe.SubscriptionStatusUnpaid, stripe.SubscriptionStatusPaused:
+		sub.StatusID = internal.MembershipStatusPastDue
+	case stripe.SubscriptionStatusIncomplete:
+		sub.StatusID = internal.MembershipStatusIncomplete
+	default:
+		sub.StatusID = internal.MembershipStatusCanceled
+	}
+	return sub
+}
+
+// syncMembership updates the membership of the subscription from the payment gateway.
+func (app *Application) syncMembership(subscriptionID string) error {
+	s, err := subscription.Get(subscriptionID, nil)
+	if err != nil {
+		return err
+	}
+	synced, err := app.storage.Memberships.Sync(toMembershipSubscription(s))
+	if err != nil {
+		return err
+	}
+	if synced {
+		log.Printf("Synced membership of subscription %s: %s\n", s.ID, s.Status)
+	}
+	return nil
+}
+
+type CreateMembershipPlanResponse struct {
+	Plan *internal.MembershipPlan `json:"plan"`
+}
+
+// createMembershipPlanHandler godoc
+//
+//	@Summary		Creates a membership plan
+//	@Description	creates a monthly membership plan for a given cinema, members get tickets_per_period free tickets every month or unlimited ones if it's not provided
+//	@Tags			memberships
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		int		true	"cinema id"
+//	@Param			name				body		string	true	"name"
+//	@Param			price				body		string	true	"monthly price"
+//	@Param			tickets_per_period	body		int		false	"free tickets every month"
+//	@Success		201					{object}	CreateMembershipPlanResponse
+//	@Failure		400					{object}	ViolationsMessage
+//	@Failure		403					{object}	ResponseError
+//	@Failure		404					{object}	ResponseMessage
+//	@Failure		500					{object}	ResponseError
+//	@Router			/cinemas/{id}/membership-plans [post]
+func (app *Application) createMembershipPlanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		Name             string          `json:"name"`
+		Price            decimal.Decimal `json:"price"`
+		TicketsPerPeriod *int32          `json:"tickets_per_period"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	p := &internal.MembershipPlan{
+		CinemaID:         int32(id),
+		Name:             req.Name,
+		Price:            req.Price,
+		TicketsPerPeriod: req.TicketsPerPeriod,
+	}
+	v := NewValidator()
+	v.CheckMembershipPlan(p)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if c == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopePricing, w) {
+		return
+	}
+	amount, err := toStripeAmount(p.Price)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	sp, err := price.New(&stripe.PriceParams{
+		Currency:          stripe.String("usd"),
+		UnitAmountDecimal: stripe.Float64(amount),
+		Recurring: &stripe.PriceRecurringParams{
+			Interval: stripe.String(string(stripe.PriceRecurringIntervalMonth)),
+		},
+		ProductData: &stripe.PriceProductDataParams{
+			Name: stripe.String(c.Name + " - " + p.Name),
+		},
+	})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	p, err = app.storage.Memberships.CreatePlan(p.CinemaID, p.Name, p.Price, p.TicketsPerPeriod, sp.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(CreateMembershipPlanResponse{Plan: p}, http.StatusCreated, w)
+}
+
+type GetMembershipPlansResponse struct {
+	Plans []internal.MembershipPlan `json:"plans"`
+}
+
+// getMembershipPlansHandler godoc
+//
+//	@Summary		Gets a list of membership plans
+//	@Description	gets the membership plans that can be subscribed to for a given cinema
+//	@Tags			memberships
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"cinema id"
+//	@Success		200	{object}	GetMembershipPlansResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		500	{object}	ResponseError
+//	@Router			/cinemas/{id}/membership-plans [get]
+func (app *Application) getMembershipPlansHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	plans, err := app.storage.Memberships.GetAllPlansForCinema(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetMembershipPlansResponse{Plans: plans}, http.StatusOK, w)
+}
+
+// deleteMembershipPlanHandler godoc
+//
+//	@Summary		Deletes a membership plan
+//	@Description	stops new subscriptions to a membership plan, the existing memberships keep renewing until they are canceled
+//	@Tags			memberships
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"membership plan id"
+//	@Success		200	{object}	ResponseMessage
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/membership-plans/{id} [delete]
+func (app *Application) deleteMembershipPlanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	p, c, err := app.storage.Memberships.GetPlanAndCinema(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if p == nil || !p.IsActive {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopePricing, w) {
+		return
+	}
+	err = app.storage.Memberships.DeactivatePlan(p)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	// an archived price keeps billing its subscriptions but can't be used for new ones
+	_, err = price.Update(p.PriceReference, &stripe.PriceParams{Active: stripe.Bool(false)})
+	if err != nil {
+		log.Println(err)
+	}
+	writeJSON(ResponseMessage{Message: "resource deleted successfully"}, http.StatusOK, w)
+}
+
+type SubscribeResponse struct {
+	URL        string               `json:"url"`
+	Membership *internal.Membership `json:"membership"`
+}
+
+// subscribeHandler godoc
+//
+//	@Summary		Subscribes to a membership plan
+//	@Description	creates a subscription checkout session for a membership plan, the membership is active once the session is paid
+//	@Tags			memberships
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"membership plan id"
+//	@Success		201	{object}	SubscribeResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/membership-plans/{id}/subscribe [post]
+func (app *Application) subscribeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	p, _, err := app.storage.Memberships.GetPlanAndCinema(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if p == nil || !p.IsActive {
+		writeNotFound(w)
+		return
+	}
+	url := "http://localhost:8080/static/"
+	params := &stripe.CheckoutSessionParams{
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				Price:    stripe.String(p.PriceReference),
+				Quantity: stripe.Int64(1),
+			},
+		},
+		Mode:          stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		CustomerEmail: stripe.String(u.Email),
+		SuccessURL:    stripe.String(url + "success.html"),
+		CancelURL:     stripe.String(url + "cancel.html"),
+		ExpiresAt:     stripe.Int64(time.Now().Add(app.config.checkout.sessionDuration).Unix()),
+	}
+	s, err := session.New(params)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	m, err := app.storage.Memberships.Create(u.ID, p.ID, s.ID)
+	if err != nil {
+		if _, err := session.Expire(s.ID, nil); err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		writeServerErr(err, w)
+		return
+	}
+	if m == nil {
+		if _, err := session.Expire(s.ID, nil); err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		writeJSON(ResponseMessage{Message: "you are already subscribed to this plan"}, http.StatusConflict, w)
+		return
+	}
+	writeJSON(SubscribeResponse{URL: s.URL, Membership: m}, http.StatusCreated, w)
+}
+
+type GetMembershipsResponse struct {
+	Memberships []internal.Membership `json:"memberships"`
+}
+
+// getMembershipsHandler godoc
+//
+//	@Summary		Gets the memberships
+//	@Description	gets the user's memberships with the free tickets used during their current billing period
+//	@Tags			memberships
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetMembershipsResponse
+//	@Failure		500	{object}	ResponseError
+//	@Router			/users/me/memberships [get]
+func (app *Application) getMembershipsHandler(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	memberships, err := app.storage.Memberships.GetAllForUser(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetMembershipsResponse{Memberships: memberships}, http.StatusOK, w)
+}
+
+type CancelMembershipResponse struct {
+	Membership *internal.Membership `json:"membership"`
+}
+
+// cancelMembershipHandler godoc
+//
+//	@Summary		Cancels a membership
+//	@Description	cancels a membership at the end of its current billing period, its free tickets can be used until then
+//	@Tags			memberships
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"membership id"
+//	@Success		200	{object}	CancelMembershipResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/memberships/{id} [delete]
+func (app *Application) cancelMembershipHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	m, err := app.storage.Memberships.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if m == nil || m.UserID != u.ID || m.SubscriptionReference == nil {
+		writeNotFound(w)
+		return
+	}
+	if m.StatusID == internal.MembershipStatusCanceled || m.CancelAtPeriodEnd {
+		writeJSON(ResponseMessage{Message: "membership is already canceled"}, http.StatusConflict, w)
+		return
+	}
+	s, err := subscription.Update(*m.SubscriptionReference, &stripe.SubscriptionParams{CancelAtPeriodEnd: stripe.Bool(true)})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	_, err = app.storage.Memberships.Sync(toMembershipSubscription(s))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	m, err = app.storage.Memberships.GetByID(m.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(CancelMembershipResponse{Membership: m}, http.StatusOK, w)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
@@ -23,6 +24,23 @@ func getUserFromRequestContext(r *http.Request) *internal.User {
 	return r.Context().Value(UserRequestContextKey).(*internal.User)
 }
 
+type sessionRequestContextKey string
+
+const SessionRequestContextKey sessionRequestContextKey = "SessionContextKey"
+
+// getSessionFromRequestContext returns the session of the request's token, it's nil for the tokens without a session.
+func getSessionFromRequestContext(r *http.Request) *internal.Session {
+	session, _ := r.Context().Value(SessionRequestContextKey).(*internal.Session)
+	return session
+}
+
+type permissionsRequestContextKey string
+
+const PermissionsRequestContextKey permissionsRequestContextKey = "PermissionsContextKey"
+
+// sessionTouchInterval is how often the last use of a session is recorded so not every request writes to the database.
+const sessionTouchInterval = time.Minute
+
 func (app *Application) authenticate(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Authorization")
@@ -32,12 +50,34 @@ func (app *Application) authenticate(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 		parts := strings.Fields(authHeader)
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
 			writeError(errors.New("invalid Authorization header"), http.StatusUnauthorized, w)
 			return
 		}
 		token := parts[1]
-		u, err := app.storage.Tokens.GetUser(internal.TokenScopeAuthentication, token)
+
+		if parts[0] == "ApiKey" {
+			app.authenticateAPIKey(token, next, w, r)
+			return
+		}
+
+		// the signed access tokens are validated without the database, the opaque ones issued before switching to them still work
+		if app.jwt != nil && strings.Count(token, ".") == 2 {
+			u, session, permissions, err := app.authenticateJWT(token)
+			if err != nil {
+				writeError(errors.New("invalid token"), http.StatusUnauthorized, w)
+				return
+			}
+			ctx := context.WithValue(r.Context(), UserRequestContextKey, u)
+			ctx = context.WithValue(ctx, SessionRequestContextKey, session)
+			ctx = context.WithValue(ctx, PermissionsRequestContextKey, permissions)
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		u, session, err := app.storage.Tokens.Authenticate(token)
 		if err != nil {
 			writeServerErr(err, w)
 			return
@@ -47,13 +87,85 @@ func (app *Application) authenticate(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		if session != nil {
+			client := getSessionClient(r)
+			if time.Since(session.LastUsedAt) >= sessionTouchInterval || client.IP != session.IP || client.UserAgent != session.UserAgent {
+				err := app.storage.Tokens.TouchSession(session.ID, client)
+				if err != nil {
+					log.Println(err)
+				}
+			}
+		}
+
 		ctx := context.WithValue(r.Context(), UserRequestContextKey, u)
+		ctx = context.WithValue(ctx, SessionRequestContextKey, session)
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	}
 }
 
+type apiKeyPermissionsRequestContextKey string
+
+const APIKeyPermissionsRequestContextKey apiKeyPermissionsRequestContextKey = "APIKeyPermissionsContextKey"
+
+// allowAPIKeys lets the route be used with the API keys that have the permissions, it wraps authenticate.
+// The routes that don't allow them only accept the tokens so a key can't do more than what it was given.
+func (app *Application) allowAPIKeys(permissions []internal.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), APIKeyPermissionsRequestContextKey, permissions)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// authenticateAPIKey authenticates the request with an API key, the key only has the permissions it was given
+// that the user still has.
+func (app *Application) authenticateAPIKey(key string, next http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	required, ok := r.Context().Value(APIKeyPermissionsRequestContextKey).([]internal.Permission)
+	if !ok {
+		writeError(errors.New("API keys can't be used for this route"), http.StatusForbidden, w)
+		return
+	}
+	u, k, err := app.storage.APIKeys.Authenticate(key)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeError(errors.New("invalid API key"), http.StatusUnauthorized, w)
+		return
+	}
+	has, err := app.storage.Permissions.Get(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	permissions := make([]internal.Permission, 0, len(k.Permissions))
+	for _, p := range k.Permissions {
+		if slices.Contains(has, p) {
+			permissions = append(permissions, p)
+		}
+	}
+	for _, p := range required {
+		if !slices.Contains(permissions, p) {
+			writeForbidden(w)
+			return
+		}
+	}
+
+	if k.LastUsedAt == nil || time.Since(*k.LastUsedAt) >= sessionTouchInterval {
+		err := app.storage.APIKeys.Touch(k.ID)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), UserRequestContextKey, u)
+	ctx = context.WithValue(ctx, SessionRequestContextKey, (*internal.Session)(nil))
+	ctx = context.WithValue(ctx, PermissionsRequestContextKey, permissions)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 func (app *Application) authorize(permissions []internal.Permission, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		u := getUserFromRequestContext(r)
@@ -61,10 +173,15 @@ func (app *Application) authorize(permissions []internal.Permission, next http.H
 			writeServerErr(errors.New("user is not authenticated"), w)
 			return
 		}
-		has, err := app.storage.Permissions.Get(u.ID)
-		if err != nil {
-			writeServerErr(err, w)
-			return
+		// the signed access tokens carry the permissions and the API keys have their own
+		has, ok := r.Context().Value(PermissionsRequestContextKey).([]internal.Permission)
+		if !ok {
+			var err error
+			has, err = app.storage.Permissions.Get(u.ID)
+			if err != nil {
+				writeServerErr(err, w)
+				return
+			}
 		}
 		for _, p := range permissions {
 			if !slices.Contains(has, p) {
@@ -91,6 +208,85 @@ func (app *Application) requireUserActivation(next http.HandlerFunc) http.Handle
 	}
 }
 
+// requireAdmission makes sure the user was admitted by the waiting room of the ticket's schedule
+// using the admission token in the X-Admission-Token header, tickets without a waiting room pass through.
+func (app *Application) requireAdmission(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := getIDFromPathValue(r)
+		if err != nil {
+			writeBadRequest(err, w)
+			return
+		}
+		app.admit(int64(id), next, w, r)
+	}
+}
+
+// requireResaleAdmission is requireAdmission for the resale listings, the listed ticket goes through
+// the same waiting room as the other tickets of its schedule.
+func (app *Application) requireResaleAdmission(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := getIDFromPathValue(r)
+		if err != nil {
+			writeBadRequest(err, w)
+			return
+		}
+		listing, err := app.storage.Resales.GetByID(int64(id))
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if listing == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		app.admit(listing.TicketID, next, w, r)
+	}
+}
+
+// admit serves the request if the ticket's schedule has no waiting room or the user was admitted by it.
+func (app *Application) admit(ticketID int64, next http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	room, err := app.storage.WaitingRooms.GetForTicket(ticketID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if room == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+	// waitlisted users don't queue again for the ticket reserved for them
+	reserved, err := app.storage.Waitlists.IsReservedFor(ticketID, u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if reserved {
+		next.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Add("Vary", "X-Admission-Token")
+	token := r.Header.Get("X-Admission-Token")
+	if token == "" {
+		writeError(fmt.Errorf("schedule %d has a waiting room, join it to get an admission token", room.ScheduleID), http.StatusForbidden, w)
+		return
+	}
+	admitted, err := app.storage.WaitingRooms.IsAdmitted(room.ScheduleID, u.ID, token)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if !admitted {
+		writeError(errors.New("invalid or expired admission token"), http.StatusForbidden, w)
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
 func (app *Application) rateLimit(next http.Handler) http.HandlerFunc {
 	type client struct {
 		limiter          *rate.Limiter
@@ -168,7 +364,7 @@ func (app *Application) enableCORS(next http.Handler) http.HandlerFunc {
 					// preflight request
 					if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 						w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Admission-Token")
 						w.WriteHeader(http.StatusOK)
 						return
 					}
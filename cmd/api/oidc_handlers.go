@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	oidcStateDuration   = 10 * time.Minute
+	oidcExchangeTimeout = 15 * time.Second
+)
+
+type GetOIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// getOIDCProvidersHandler godoc
+//
+//	@Summary		Gets the OpenID Connect providers
+//	@Description	gets the identity providers the users can sign in with
+//	@Tags			oidc
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetOIDCProvidersResponse
+//	@Router			/oidc/providers [get]
+func (app *Application) getOIDCProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	providers := make([]string, 0, len(app.oidc))
+	for name := range app.oidc {
+		providers = append(providers, name)
+	}
+	slices.Sort(providers)
+	writeJSON(GetOIDCProvidersResponse{Providers: providers}, http.StatusOK, w)
+}
+
+type CreateOIDCAuthorizationResponse struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// createOIDCAuthorizationHandler godoc
+//
+//	@Summary		Starts an OpenID Connect login
+//	@Description	returns the provider's URL to send the user to, the provider redirects the user back to the
+//	@Description	redirect URL with the code and the state to complete the login at /tokens/oidc
+//	@Tags			oidc
+//	@Accept			json
+//	@Produce		json
+//	@Param			provider	path		string	true	"provider"
+//	@Success		201			{object}	CreateOIDCAuthorizationResponse
+//	@Failure		404			{object}	ResponseMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/oidc/{provider}/authorization [post]
+func (app *Application) createOIDCAuthorizationHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := app.oidc[r.PathValue("provider")]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	state := internal.GenerateToken()
+	st := internal.OIDCState{
+		Provider:     p.Name,
+		Nonce:        internal.GenerateToken(),
+		CodeVerifier: internal.GeneratePKCEVerifier(),
+		ExpiresAt:    time.Now().Add(oidcStateDuration),
+	}
+	authorizationURL, err := p.AuthorizationURL(r.Context(), state, st.Nonce, st.CodeVerifier)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.storage.Identities.CreateState(state, st, oidcStateDuration)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	res := CreateOIDCAuthorizationResponse{
+		AuthorizationURL: authorizationURL,
+		ExpiresAt:        st.ExpiresAt,
+	}
+	writeJSON(res, http.StatusCreated, w)
+}
+
+// oidcUserName returns a name that passes the users' validation for the users created from a provider.
+func oidcUserName(claims *internal.IDTokenClaims) string {
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	for len(name) > 50 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// createOIDCAuthenticationTokenHandler godoc
+//
+//	@Summary		Completes an OpenID Connect login
+//	@Description	exchanges the code the provider redirected the user back with for an auth token,
+//	@Description	the account is linked to the activated user with the same email if the provider verified it or a new user is created.
+//	@Description	A linked user is signed out everywhere and can only set a password again with the password reset.
+//	@Description	The users with the two-factor authentication get a challenge instead
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			state	body		string	true	"state"
+//	@Param			code	body		string	true	"code"
+//	@Success		201		{object}	CreateAuthenticationTokenResponse
+//	@Success		202		{object}	TwoFactorChallengeResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		401		{object}	ResponseError
+//	@Failure		403		{object}	ResponseError
+//	@Failure		409		{object}	ResponseError
+//	@Failure		502		{object}	ResponseError
+//	@Failure		500		{object}	ResponseError
+//	@Router			/tokens/oidc [post]
+func (app *Application) createOIDCAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		State *string `json:"state"`
+		Code  *string `json:"code"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.State != nil && *req.State != "", "state", "must be provided")
+	v.Check(req.Code != nil && *req.Code != "", "code", "must be provided")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	st, err := app.storage.Identities.ConsumeState(*req.State)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if st == nil {
+		writeError(errors.New("invalid or expired state"), http.StatusUnauthorized, w)
+		return
+	}
+	p, ok := app.oidc[st.Provider]
+	if !ok {
+		writeError(errors.New("invalid or expired state"), http.StatusUnauthorized, w)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), oidcExchangeTimeout)
+	defer cancel()
+	claims, err := p.Exchange(ctx, *req.Code, st.CodeVerifier, st.Nonce)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidIDToken) {
+			writeError(err, http.StatusUnauthorized, w)
+			return
+		}
+		log.Println(err)
+		writeError(fmt.Errorf("couldn't sign in with %s", p.Name), http.StatusBadGateway, w)
+		return
+	}
+
+	u, err := app.storage.Identities.GetUser(p.Name, claims.Subject)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u != nil {
+		app.writeLogin(u, w, r)
+		return
+	}
+
+	// an account is only linked by its email if the provider verified the email belongs to it
+	if claims.Email == "" || !claims.EmailVerified {
+		writeError(fmt.Errorf("%s didn't verify the email of the account", p.Name), http.StatusForbidden, w)
+		return
+	}
+	u, err = app.storage.Users.GetByEmail(claims.Email)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	// the users created from a provider get a password nobody knows, they can set one with the password reset
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(internal.GenerateToken()), bcrypt.DefaultCost)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u != nil {
+		// whoever signed up with the email without activating it might not own it, linking would let them keep the password
+		if !u.IsActivated {
+			writeError(errors.New("an account with this email isn't activated, activate it before signing in with "+p.Name), http.StatusConflict, w)
+			return
+		}
+		err = app.storage.Identities.Link(u.ID, p.Name, claims.Subject, claims.Email)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		// the linked account is signed out everywhere and its password is replaced like the users created from a provider
+		u.PasswordHash = passwordHash
+		err = app.storage.Users.Update(u)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		err = app.storage.Tokens.DeleteAll(u.ID, []internal.TokenScope{internal.TokenScopeAuthentication, internal.TokenScopeRefresh, internal.TokenScopePasswordReset})
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		err = app.revokeAccessTokens(u.ID, nil)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		app.writeLogin(u, w, r)
+		return
+	}
+
+	u, err = app.storage.Identities.CreateUser(oidcUserName(claims), claims.Email, passwordHash, p.Name, claims.Subject)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.storage.Permissions.GrantRoles(u.ID, []string{internal.RoleCustomer})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	app.writeLogin(u, w, r)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+// getOrderReceiptHandler godoc
+//
+//	@Summary		Gets an order receipt
+//	@Description	gets the receipt of a completed order as a PDF with an invoice for every cinema
+//	@Tags			orders
+//	@Produce		application/pdf
+//	@Param			id	path		int	true	"order id"
+//	@Success		200	{file}		file
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/orders/{id}/receipt.pdf [get]
+func (app *Application) getOrderReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	o, err := app.storage.Orders.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if o == nil {
+		writeNotFound(w)
+		return
+	}
+	if o.UserID != u.ID {
+		writeForbidden(w)
+		return
+	}
+	if o.StatusID != internal.OrderStatusCompleted {
+		writeJSON(ResponseMessage{Message: "order is not completed"}, http.StatusConflict, w)
+		return
+	}
+	invoices, err := app.storage.Orders.GetInvoices(o.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	cinemas := make(map[int32]*internal.Cinema)
+	for _, inv := range invoices {
+		c, err := app.storage.Cinemas.GetByID(inv.CinemaID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		cinemas[inv.CinemaID] = c
+	}
+	var b bytes.Buffer
+	err = WriteReceipt(&b, u, o, invoices, cinemas)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="receipt-%d.pdf"`, o.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
+
+// getOrderTicketsHandler godoc
+//
+//	@Summary		Gets printable order tickets
+//	@Description	gets the tickets of a completed order the user still holds as a PDF with a page for every ticket to print at the box office
+//	@Tags			orders
+//	@Produce		application/pdf
+//	@Param			id	path		int	true	"order id"
+//	@Success		200	{file}		file
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/orders/{id}/tickets.pdf [get]
+func (app *Application) getOrderTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	o, err := app.storage.Orders.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if o == nil {
+		writeNotFound(w)
+		return
+	}
+	if o.UserID != u.ID {
+		writeForbidden(w)
+		return
+	}
+	if o.StatusID != internal.OrderStatusCompleted {
+		writeJSON(ResponseMessage{Message: "order is not completed"}, http.StatusConflict, w)
+		return
+	}
+	// transferred and resold tickets have a new pass so they aren't printed.
+	passes := make(map[int64]*internal.TicketPass)
+	cinemas := make(map[int32]*internal.Cinema)
+	for _, l := range o.Lines {
+		if l.Kind != internal.OrderLineKindTicket || l.TicketID == nil {
+			continue
+		}
+		p, err := app.storage.Transfers.GetPass(*l.TicketID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if p == nil || p.UserID != u.ID || p.OrderID == nil || *p.OrderID != o.ID {
+			continue
+		}
+		passes[*l.TicketID] = p
+		if _, ok := cinemas[l.CinemaID]; !ok {
+			c, err := app.storage.Cinemas.GetByID(l.CinemaID)
+			if err != nil {
+				writeServerErr(err, w)
+				return
+			}
+			cinemas[l.CinemaID] = c
+		}
+	}
+	if len(passes) == 0 {
+		writeJSON(ResponseMessage{Message: "order has no tickets to print"}, http.StatusConflict, w)
+		return
+	}
+	var b bytes.Buffer
+	err = WriteTickets(&b, o, passes, cinemas)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="tickets-%d.pdf"`, o.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+var paymentEventStatuses = map[string]internal.PaymentEventStatus{
+	"pending":    internal.PaymentEventStatusPending,
+	"processing": internal.PaymentEventStatusProcessing,
+	"processed":  internal.PaymentEventStatusProcessed,
+	"failed":     internal.PaymentEventStatusFailed,
+}
+
+type GetPaymentEventsResponse struct {
+	PaymentEvents []internal.PaymentEvent `json:"payment_events"`
+	MetaData      *internal.MetaData      `json:"meta_data"`
+}
+
+// getPaymentEventsHandler godoc
+//
+//	@Summary		Gets a list of payment events
+//	@Description	gets a list of the payment gateway webhook events by status
+//	@Tags			payment-events
+//	@Accept			json
+//	@Produce		json
+//	@Param			status		query		string	false	"comma separated statuses (pending, processing, processed, failed) defaults to failed"
+//	@Param			page		query		int		false	"page number"
+//	@Param			page_size	query		int		false	"page size"
+//	@Success		200			{object}	GetPaymentEventsResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		403			{object}	ResponseError
+//	@Failure		500			{object}	ResponseError
+//	@Router			/admin/payment-events [get]
+func (app *Application) getPaymentEventsHandler(w http.ResponseWriter, r *http.Request) {
+	v := NewValidator()
+	statusNames := getQueryCSVOr(r, "status", []string{"failed"})
+	page := getQueryIntOr(r, "page", 1, v)
+	pageSize := getQueryIntOr(r, "page_size", 20, v)
+
+	v.Check(page > 0 && page <= 10_000_000, "page", "must be between 1 and 10_000_000")
+	v.Check(pageSize > 0 && pageSize <= 100, "page_size", "must be between 1 and 100")
+
+	var statuses []internal.PaymentEventStatus
+	for _, name := range statusNames {
+		status, ok := paymentEventStatuses[strings.TrimSpace(name)]
+		v.Check(ok, "status", "must be one of pending, processing, processed, failed")
+		statuses = append(statuses, status)
+	}
+
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+
+	events, metaData, err := app.storage.PaymentEvents.GetAll(statuses, page, pageSize)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetPaymentEventsResponse{PaymentEvents: events, MetaData: metaData}, http.StatusOK, w)
+}
+
+type ReplayPaymentEventResponse struct {
+	PaymentEvent *internal.PaymentEvent `json:"payment_event"`
+}
+
+// replayPaymentEventHandler godoc
+//
+//	@Summary		Replays a payment event
+//	@Description	processes a failed or pending payment event again, or one that has been stuck processing for too long
+//	@Tags			payment-events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"event id"
+//	@Success		200	{object}	ReplayPaymentEventResponse
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		422	{object}	ReplayPaymentEventResponse
+//	@Failure		500	{object}	ResponseError
+//	@Router			/admin/payment-events/{id}/replay [post]
+func (app *Application) replayPaymentEventHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		writeBadRequest(errors.New(`invalid path parameter "id"`), w)
+		return
+	}
+	e, err := app.storage.PaymentEvents.GetByID(id)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if e == nil {
+		writeNotFound(w)
+		return
+	}
+	e, err = app.storage.PaymentEvents.Claim(id, []internal.PaymentEventStatus{internal.PaymentEventStatusPending, internal.PaymentEventStatusFailed}, paymentEventClaimTimeout)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if e == nil {
+		writeJSON(ResponseMessage{Message: "payment event is already processed or being processed"}, http.StatusConflict, w)
+		return
+	}
+	status := http.StatusOK
+	if err := app.processPaymentEvent(e); err != nil {
+		log.Println(err)
+		status = http.StatusUnprocessableEntity
+	}
+	e, err = app.storage.PaymentEvents.GetByID(id)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ReplayPaymentEventResponse{PaymentEvent: e}, status, w)
+}
+
+type GetPaymentDiscrepanciesResponse struct {
+	Discrepancies []internal.PaymentDiscrepancy `json:"discrepancies"`
+	MetaData      *internal.MetaData            `json:"meta_data"`
+}
+
+// getPaymentDiscrepanciesHandler godoc
+//
+//	@Summary		Gets the discrepancy report
+//	@Description	gets a list of the discrepancies found between the payment gateway and the local records
+//	@Tags			payment-events
+//	@Accept			json
+//	@Produce		json
+//	@Param			resolved	query		bool	false	"resolved discrepancies, defaults to false"
+//	@Param			page		query		int		false	"page number"
+//	@Param			page_size	query		int		false	"page size"
+//	@Success		200			{object}	GetPaymentDiscrepanciesResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		403			{object}	ResponseError
+//	@Failure		500			{object}	ResponseError
+//	@Router			/admin/payment-discrepancies [get]
+func (app *Application) getPaymentDiscrepanciesHandler(w http.ResponseWriter, r *http.Request) {
+	v := NewValidator()
+	resolved := getQueryStringOr(r, "resolved", "false")
+	page := getQueryIntOr(r, "page", 1, v)
+	pageSize := getQueryIntOr(r, "page_size", 20, v)
+
+	v.Check(resolved == "true" || resolved == "false", "resolved", "must be true or false")
+	v.Check(page > 0 && page <= 10_000_000, "page", "must be between 1 and 10_000_000")
+	v.Check(pageSize > 0 && pageSize <= 100, "page_size", "must be between 1 and 100")
+
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+
+	discrepancies, metaData, err := app.storage.Discrepancies.GetAll(resolved == "true", page, pageSize)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetPaymentDiscrepanciesResponse{Discrepancies: discrepancies, MetaData: metaData}, http.StatusOK, w)
+}
+
+type ResolvePaymentDiscrepancyResponse struct {
+	Discrepancy *internal.PaymentDiscrepancy `json:"discrepancy"`
+}
+
+// resolvePaymentDiscrepancyHandler godoc
+//
+//	@Summary		Resolves a discrepancy
+//	@Description	marks a discrepancy as resolved
+//	@Tags			payment-events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"discrepancy id"
+//	@Success		200	{object}	ResolvePaymentDiscrepancyResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/admin/payment-discrepancies/{id}/resolve [post]
+func (app *Application) resolvePaymentDiscrepancyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	d, err := app.storage.Discrepancies.Resolve(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if d == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(ResolvePaymentDiscrepancyResponse{Discrepancy: d}, http.StatusOK, w)
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+type GetRolesResponse struct {
+	Roles       []internal.Role       `json:"roles"`
+	Permissions []internal.Permission `json:"permissions"`
+}
+
+// getRolesHandler godoc
+//
+//	@Summary		Gets the roles
+//	@Description	gets the roles with the permissions they bundle and all the permissions that can be granted
+//	@Tags			permissions
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetRolesResponse
+//	@Failure		403	{object}	ResponseError
+//	@Failure		500	{object}	ResponseError
+//	@Router			/admin/roles [get]
+func (app *Application) getRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := app.storage.Permissions.GetAllRoles()
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	permissions, err := app.storage.Permissions.GetAll()
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetRolesResponse{Roles: roles, Permissions: permissions}, http.StatusOK, w)
+}
+
+type UserPermissionsResponse struct {
+	UserID      int64                 `json:"user_id"`
+	Roles       []string              `json:"roles"`
+	Granted     []internal.Permission `json:"granted"`
+	Permissions []internal.Permission `json:"permissions"`
+}
+
+// writeUserPermissions writes the roles of the user, the permissions granted directly
+// and the permissions the user ends up with.
+func (app *Application) writeUserPermissions(userID int64, status int, w http.ResponseWriter) {
+	roles, err := app.storage.Permissions.GetRoles(userID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	granted, err := app.storage.Permissions.GetGranted(userID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	permissions, err := app.storage.Permissions.Get(userID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	slices.Sort(permissions)
+	writeJSON(UserPermissionsResponse{UserID: userID, Roles: roles, Granted: granted, Permissions: permissions}, status, w)
+}
+
+// getUserPermissionsHandler godoc
+//
+//	@Summary		Gets the permissions of a user
+//	@Description	gets the roles of a user, the permissions granted directly and the permissions the user ends up with
+//	@Tags			permissions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"user id"
+//	@Success		200	{object}	UserPermissionsResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/admin/users/{id}/permissions [get]
+func (app *Application) getUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u, err := app.storage.Users.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
+	app.writeUserPermissions(u.ID, http.StatusOK, w)
+}
+
+// grantRolesHandler godoc
+//
+//	@Summary		Grants roles
+//	@Description	grants roles to a user
+//	@Tags			permissions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int			true	"user id"
+//	@Param			roles	body		[]string	true	"role codes"
+//	@Success		200		{object}	UserPermissionsResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		403		{object}	ResponseError
+//	@Failure		404		{object}	ResponseMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/admin/users/{id}/roles [post]
+func (app *Application) grantRolesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		Roles []string `json:"roles"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	roles, err := app.storage.Permissions.GetAllRoles()
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(len(req.Roles) != 0, "roles", "must be provided")
+	for _, code := range req.Roles {
+		known := slices.ContainsFunc(roles, func(r internal.Role) bool {
+			return r.Code == code
+		})
+		v.Check(known, "roles", fmt.Sprintf("%q is not a role", code))
+	}
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	actor := getUserFromRequestContext(r)
+	if actor == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	u, err := app.storage.Users.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
+	err = app.storage.Permissions.GrantRoles(u.ID, req.Roles)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	app.audit(actor, u.ID, internal.AuditActionGrantRoles, strings.Join(req.Roles, ","))
+	app.writeUserPermissions(u.ID, http.StatusOK, w)
+}
+
+// revokeRoleHandler godoc
+//
+//	@Summary		Revokes a role
+//	@Description	revokes a role from a user, admins can't revoke their own admin role
+//	@Tags			permissions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"user id"
+//	@Param			role	path		string	true	"role code"
+//	@Success		200		{object}	UserPermissionsResponse
+//	@Failure		400		{object}	ResponseError
+//	@Failure		403		{object}	ResponseError
+//	@Failure		404		{object}	ResponseMessage
+//	@Failure		409		{object}	ResponseMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/admin/users/{id}/roles/{role} [delete]
+func (app *Application) revokeRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	role := r.PathValue("role")
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	if u.ID == int64(id) && role == internal.RoleAdmin {
+		writeJSON(ResponseMessage{Message: "you can't revoke your own admin role"}, http.StatusConflict, w)
+		return
+	}
+	target, err := app.storage.Users.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if target == nil {
+		writeNotFound(w)
+		return
+	}
+	err = app.storage.Permissions.RevokeRoles(target.ID, []string{role})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	app.audit(u, target.ID, internal.AuditActionRevokeRole, role)
+	// the signed access tokens carry the permissions so they're reissued without the role
+	err = app.revokeAccessTokens(target.ID, nil)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	app.writeUserPermissions(target.ID, http.StatusOK, w)
+}
+
+// grantPermissionsHandler godoc
+//
+//	@Summary		Grants permissions
+//	@Description	grants permissions to a user directly, on top of the ones of the user's roles
+//	@Tags			permissions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int			true	"user id"
+//	@Param			permissions	body		[]string	true	"permission codes"
+//	@Success		200			{object}	UserPermissionsResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		403			{object}	ResponseError
+//	@Failure		404			{object}	ResponseMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/admin/users/{id}/permissions [post]
+func (app *Application) grantPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		Permissions []internal.Permission `json:"permissions"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	permissions, err := app.storage.Permissions.GetAll()
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(len(req.Permissions) != 0, "permissions", "must be provided")
+	for _, p := range req.Permissions {
+		v.Check(slices.Contains(permissions, p), "permissions", fmt.Sprintf("%q is not a permission", p))
+	}
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	actor := getUserFromRequestContext(r)
+	if actor == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	u, err := app.storage.Users.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
+	err = app.storage.Permissions.Grant(u.ID, req.Permissions)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	codes := make([]string, len(req.Permissions))
+	for i, p := range req.Permissions {
+		codes[i] = string(p)
+	}
+	app.audit(actor, u.ID, internal.AuditActionGrantPermissions, strings.Join(codes, ","))
+	app.writeUserPermissions(u.ID, http.StatusOK, w)
+}
+
+// revokePermissionHandler godoc
+//
+//	@Summary		Revokes a permission
+//	@Description	revokes a permission granted to a user directly, the permissions of the user's roles stay
+//	@Tags			permissions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"user id"
+//	@Param			permission	path		string	true	"permission code"
+//	@Success		200			{object}	UserPermissionsResponse
+//	@Failure		400			{object}	ResponseError
+//	@Failure		403			{object}	ResponseError
+//	@Failure		404			{object}	ResponseMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/admin/users/{id}/permissions/{permission} [delete]
+func (app *Application) revokePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	permission := internal.Permission(r.PathValue("permission"))
+	actor := getUserFromRequestContext(r)
+	if actor == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	u, err := app.storage.Users.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
+	err = app.storage.Permissions.Revoke(u.ID, []internal.Permission{permission})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	app.audit(actor, u.ID, internal.AuditActionRevokePermission, string(permission))
+	err = app.revokeAccessTokens(u.ID, nil)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	app.writeUserPermissions(u.ID, http.StatusOK, w)
+}
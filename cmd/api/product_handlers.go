@@ -0,0 +1,316 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
+)
+
+const maxProductQuantity = 20
+
+type CreateProductResponse struct {
+	Product *internal.Product `json:"product"`
+}
+
+// createProductHandler godoc
+//
+//	@Summary		Creates a product
+//	@Description	adds a concession or merchandise product to the catalog of a given cinema
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"cinema id"
+//	@Param			name		body		string	true	"name"
+//	@Param			category	body		int		true	"category (0 snack, 1 drink, 2 combo, 3 merchandise)"
+//	@Param			price		body		string	true	"price"
+//	@Param			stock		body		int		true	"stock"
+//	@Success		201			{object}	CreateProductResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		403			{object}	ResponseError
+//	@Failure		404			{object}	ResponseMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/cinemas/{id}/products [post]
+func (app *Application) createProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		Name     string                   `json:"name"`
+		Category internal.ProductCategory `json:"category"`
+		Price    decimal.Decimal          `json:"price"`
+		Stock    int32                    `json:"stock"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	p := &internal.Product{
+		CinemaID: int32(id),
+		Name:     req.Name,
+		Category: req.Category,
+		Price:    req.Price,
+		Stock:    req.Stock,
+	}
+	v := NewValidator()
+	v.CheckProduct(p)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	c, err := app.storage.Cinemas.GetByID(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if c == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopePricing, w) {
+		return
+	}
+	p, err = app.storage.Products.Create(p.CinemaID, p.Category, p.Name, p.Price, p.Stock)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(CreateProductResponse{Product: p}, http.StatusCreated, w)
+}
+
+type GetProductsResponse struct {
+	Products []internal.Product `json:"products"`
+}
+
+// getProductsHandler godoc
+//
+//	@Summary		Gets a list of products
+//	@Description	gets the concessions and merchandise catalog of a given cinema
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"cinema id"
+//	@Success		200	{object}	GetProductsResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		500	{object}	ResponseError
+//	@Router			/cinemas/{id}/products [get]
+func (app *Application) getProductsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	products, err := app.storage.Products.GetAllForCinema(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetProductsResponse{Products: products}, http.StatusOK, w)
+}
+
+type UpdateProductResponse struct {
+	Product *internal.Product `json:"product"`
+}
+
+// updateProductHandler godoc
+//
+//	@Summary		Updates a product
+//	@Description	updates a product by id, stock sets the count left to sell
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"product id"
+//	@Param			name		body		string	false	"name"
+//	@Param			category	body		int		false	"category (0 snack, 1 drink, 2 combo, 3 merchandise)"
+//	@Param			price		body		string	false	"price"
+//	@Param			stock		body		int		false	"stock"
+//	@Success		200			{object}	UpdateProductResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		403			{object}	ResponseError
+//	@Failure		404			{object}	ResponseMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/products/{id} [put]
+func (app *Application) updateProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		Name     *string                   `json:"name"`
+		Category *internal.ProductCategory `json:"category"`
+		Price    *decimal.Decimal          `json:"price"`
+		Stock    *int32                    `json:"stock"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	p, c, err := app.storage.Products.GetAndCinema(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if p == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopePricing, w) {
+		return
+	}
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Category != nil {
+		p.Category = *req.Category
+	}
+	if req.Price != nil {
+		p.Price = *req.Price
+	}
+	if req.Stock != nil {
+		p.Stock = *req.Stock
+	}
+	v := NewValidator()
+	v.CheckProduct(p)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	err = app.storage.Products.Update(p)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(UpdateProductResponse{Product: p}, http.StatusOK, w)
+}
+
+// deleteProductHandler godoc
+//
+//	@Summary		Deletes a product
+//	@Description	removes a product from the catalog and from the checkouts it was added to
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"product id"
+//	@Success		200	{object}	ResponseMessage
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/products/{id} [delete]
+func (app *Application) deleteProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	p, c, err := app.storage.Products.GetAndCinema(int32(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if p == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkCinemaScope(c, u, internal.CinemaScopePricing, w) {
+		return
+	}
+	err = app.storage.Products.Delete(p)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "resource deleted successfully"}, http.StatusOK, w)
+}
+
+// setCheckoutProductHandler godoc
+//
+//	@Summary		Sets a checkout product
+//	@Description	sets the quantity of a product in the checkout, a quantity of 0 removes it. products are ordered with the tickets for their cinema
+//	@Tags			checkouts
+//	@Accept			json
+//	@Produce		json
+//	@Param			product_id	body		int	true	"product id"
+//	@Param			quantity	body		int	true	"quantity"
+//	@Success		200			{object}	GetCheckoutResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		404			{object}	ResponseMessage
+//	@Failure		409			{object}	ResponseMessage
+//	@Failure		422			{object}	ResponseMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/checkout/products [put]
+func (app *Application) setCheckoutProductHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProductID int32 `json:"product_id"`
+		Quantity  int32 `json:"quantity"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.ProductID > 0, "product_id", "must be provided")
+	v.Check(req.Quantity >= 0 && req.Quantity <= maxProductQuantity, "quantity", fmt.Sprintf("must be between 0 and %d", maxProductQuantity))
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	checkoutSession, err := app.storage.Checkouts.GetByUserID(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if checkoutSession != nil {
+		writeJSON(ResponseMessage{Message: fmt.Sprintf("you can't change products during checkout: %v", checkoutSession.SessionID)}, http.StatusConflict, w)
+		return
+	}
+	p, _, err := app.storage.Products.GetAndCinema(req.ProductID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if p == nil {
+		writeNotFound(w)
+		return
+	}
+	if req.Quantity > p.Stock {
+		writeJSON(ResponseMessage{Message: fmt.Sprintf("only %d of %s are left", p.Stock, p.Name)}, http.StatusUnprocessableEntity, w)
+		return
+	}
+	err = app.storage.Products.SetCartQuantity(u.ID, p.ID, req.Quantity)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	items, products, breakdown, err := app.getCheckoutItems(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetCheckoutResponse{Items: items, Products: products, Breakdown: breakdown, Total: breakdown.Total}, http.StatusOK, w)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/go-pdf/fpdf"
+	"github.com/shopspring/decimal"
+)
+
+// WriteReceipt renders a completed order as a PDF with a page for every invoice,
+// it uses the core fonts only so it doesn't need any font files.
+func WriteReceipt(w io.Writer, u *internal.User, o *internal.Order, invoices []internal.Invoice, cinemas map[int32]*internal.Cinema) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(fmt.Sprintf("Receipt for order %d", o.ID), true)
+	pdf.SetCreationDate(o.CreatedAt)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, inv := range invoices {
+		c := cinemas[inv.CinemaID]
+		if c == nil {
+			return fmt.Errorf("cinema %d of invoice %v is missing", inv.CinemaID, inv.String())
+		}
+
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "B", 18)
+		pdf.CellFormat(0, 10, tr(c.Name), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 5, tr(c.Location), "", 1, "L", false, 0, "")
+		pdf.Ln(6)
+
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 8, "Invoice "+inv.String(), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 5, "Date: "+inv.CreatedAt.UTC().Format("2006-01-02 15:04 MST"), "", 1, "L", false, 0, "")
+		pdf.CellFormat(0, 5, fmt.Sprintf("Order: %d", o.ID), "", 1, "L", false, 0, "")
+		pdf.CellFormat(0, 5, tr(fmt.Sprintf("Billed to: %s <%s>", u.Name, u.Email)), "", 1, "L", false, 0, "")
+		pdf.CellFormat(0, 5, "Payment reference: "+o.PaymentReference, "", 1, "L", false, 0, "")
+		if o.WalletAmount.IsPositive() {
+			pdf.CellFormat(0, 5, "Paid from wallet (order): "+o.WalletAmount.StringFixed(2)+" USD", "", 1, "L", false, 0, "")
+		}
+		if o.LoyaltyPoints > 0 {
+			pdf.CellFormat(0, 5, fmt.Sprintf("Loyalty points redeemed (order): %d for %s USD", o.LoyaltyPoints, o.LoyaltyDiscount.StringFixed(2)), "", 1, "L", false, 0, "")
+		}
+		if o.PickupCode != "" && hasProductLines(o, inv.CinemaID) {
+			pdf.SetFont("Helvetica", "B", 10)
+			pdf.CellFormat(0, 5, "Pickup code: "+o.PickupCode, "", 1, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 10)
+		}
+		pdf.Ln(6)
+
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(150, 7, "Description", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 7, "Amount", "B", 1, "R", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+
+		subtotal := decimal.Zero
+		fees := decimal.Zero
+		taxes := decimal.Zero
+		for _, l := range o.Lines {
+			if l.CinemaID != inv.CinemaID {
+				continue
+			}
+			name := l.Name
+			switch l.Kind {
+			case internal.OrderLineKindTicket, internal.OrderLineKindProduct:
+				subtotal = subtotal.Add(l.Amount)
+			case internal.OrderLineKindFee:
+				fees = fees.Add(l.Amount)
+			case internal.OrderLineKindTax:
+				if l.IsInclusive {
+					name += " (included)"
+				} else {
+					taxes = taxes.Add(l.Amount)
+				}
+			}
+			pdf.CellFormat(150, 6, tr(name), "", 0, "L", false, 0, "")
+			pdf.CellFormat(0, 6, l.Amount.StringFixed(2), "", 1, "R", false, 0, "")
+		}
+
+		pdf.Ln(2)
+		totals := []struct {
+			name   string
+			amount decimal.Decimal
+		}{
+			{"Subtotal", subtotal},
+			{"Fees", fees},
+			{"Taxes", taxes},
+		}
+		for _, t := range totals {
+			pdf.CellFormat(150, 6, t.name, "T", 0, "R", false, 0, "")
+			pdf.CellFormat(0, 6, t.amount.StringFixed(2), "T", 1, "R", false, 0, "")
+		}
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(150, 8, "Total (USD)", "T", 0, "R", false, 0, "")
+		pdf.CellFormat(0, 8, subtotal.Add(fees).Add(taxes).StringFixed(2), "T", 1, "R", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
+
+func hasProductLines(o *internal.Order, cinemaID int32) bool {
+	for _, l := range o.Lines {
+		if l.CinemaID == cinemaID && l.Kind == internal.OrderLineKindProduct {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTickets renders a page for every ticket pass of an order to print at the box office,
+// the pass code is checked at the entrance like the one of the user's pass.
+func WriteTickets(w io.Writer, o *internal.Order, passes map[int64]*internal.TicketPass, cinemas map[int32]*internal.Cinema) error {
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: 80, Ht: 120},
+	})
+	pdf.SetTitle(fmt.Sprintf("Tickets for order %d", o.ID), true)
+	pdf.SetCreationDate(o.CreatedAt)
+	pdf.SetMargins(5, 5, 5)
+	pdf.SetAutoPageBreak(false, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, l := range o.Lines {
+		if l.Kind != internal.OrderLineKindTicket || l.TicketID == nil {
+			continue
+		}
+		p := passes[*l.TicketID]
+		if p == nil {
+			continue
+		}
+		c := cinemas[l.CinemaID]
+		if c == nil {
+			return fmt.Errorf("cinema %d of ticket %d is missing", l.CinemaID, *l.TicketID)
+		}
+
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.MultiCell(0, 7, tr(c.Name), "", "C", false)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.MultiCell(0, 4, tr(c.Location), "", "C", false)
+		pdf.Ln(4)
+
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(0, 6, "ADMIT ONE", "TB", 1, "C", false, 0, "")
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "", 10)
+		for _, part := range strings.Split(l.Name, " - ") {
+			pdf.MultiCell(0, 5, tr(part), "", "C", false)
+		}
+		pdf.Ln(2)
+		pdf.CellFormat(0, 5, "Price: "+l.Amount.StringFixed(2)+" USD", "", 1, "C", false, 0, "")
+		pdf.Ln(4)
+
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 4, fmt.Sprintf("Order %d - Ticket %d", o.ID, *l.TicketID), "", 1, "C", false, 0, "")
+		pdf.SetFont("Courier", "B", 9)
+		pdf.CellFormat(0, 5, p.Code, "", 1, "C", false, 0, "")
+	}
+	if pdf.PageNo() == 0 {
+		return fmt.Errorf("order %d has no tickets to print", o.ID)
+	}
+	return pdf.Output(w)
+}
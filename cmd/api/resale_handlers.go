@@ -0,0 +1,416 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
+)
+
+type ResaleListingResponse struct {
+	Listing *internal.ResaleListing `json:"listing"`
+}
+
+// createResaleListingHandler godoc
+//
+//	@Summary		Lists a ticket for resale
+//	@Description	lists a sold ticket for resale at a price capped by the cinema as a percentage of what the seller paid for it, once it's sold the price minus the cinema's fee is refunded to the seller's card or wallet
+//	@Tags			resales
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		int		true	"ticket id"
+//	@Param			price				body		number	true	"resale price"
+//	@Param			payout_to_wallet	body		bool	false	"credit the proceeds to the wallet instead of refunding the card"
+//	@Success		201					{object}	ResaleListingResponse
+//	@Failure		400					{object}	ViolationsMessage
+//	@Failure		403					{object}	ResponseError
+//	@Failure		404					{object}	ResponseMessage
+//	@Failure		409					{object}	ResponseMessage
+//	@Failure		422					{object}	ResponseMessage
+//	@Failure		500					{object}	ResponseError
+//	@Router			/tickets/{id}/resale [post]
+func (app *Application) createResaleListingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		Price          *decimal.Decimal `json:"price"`
+		PayoutToWallet bool             `json:"payout_to_wallet"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.Price != nil, "price", "must be provided")
+	if req.Price != nil {
+		v.Check(req.Price.IsPositive(), "price", "must be greater than zero")
+		v.Check(req.Price.Exponent() >= -2, "price", "must have at most 2 decimal places")
+	}
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	p, err := app.storage.Transfers.GetPass(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if p == nil {
+		writeNotFound(w)
+		return
+	}
+	if p.UserID != u.ID {
+		writeForbidden(w)
+		return
+	}
+	if p.OrderID == nil {
+		writeJSON(ResponseMessage{Message: "ticket wasn't bought with an order that can be refunded"}, http.StatusConflict, w)
+		return
+	}
+	// the proceeds are refunded to the order's payment so only the buyer of the order can resell the ticket.
+	o, err := app.storage.Orders.GetByID(*p.OrderID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if o == nil || o.UserID != u.ID || o.StatusID != internal.OrderStatusCompleted {
+		writeJSON(ResponseMessage{Message: "only the buyer of a ticket can resell it"}, http.StatusConflict, w)
+		return
+	}
+	if o.CashierID != nil {
+		writeJSON(ResponseMessage{Message: "tickets sold at the box office can't be resold"}, http.StatusConflict, w)
+		return
+	}
+	// the price is capped by what the seller paid for the ticket, the ticket's price may have changed since
+	var paid *decimal.Decimal
+	for _, l := range o.Lines {
+		if l.Kind != internal.OrderLineKindTicket || l.TicketID == nil || *l.TicketID != p.TicketID {
+			continue
+		}
+		if l.MembershipID != nil {
+			writeJSON(ResponseMessage{Message: "tickets covered by a membership can't be resold"}, http.StatusConflict, w)
+			return
+		}
+		paid = &l.Amount
+	}
+	if paid == nil {
+		writeJSON(ResponseMessage{Message: "ticket isn't on the order it was bought with"}, http.StatusConflict, w)
+		return
+	}
+	settings, err := app.storage.Settings.GetForTicket(p.TicketID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if settings == nil {
+		writeNotFound(w)
+		return
+	}
+	if settings.ResalePriceCap == nil {
+		writeJSON(ResponseMessage{Message: "the cinema doesn't allow ticket resales"}, http.StatusConflict, w)
+		return
+	}
+	t, err := app.storage.Tickets.GetByID(p.TicketID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	s, err := app.storage.Schedules.GetByID(t.ScheduleID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if time.Now().After(s.StartsAt) {
+		writeJSON(ResponseMessage{Message: "can't resell ticket because movie already started"}, http.StatusConflict, w)
+		return
+	}
+	maxPrice := paid.Mul(*settings.ResalePriceCap).Div(decimal.NewFromInt(100)).RoundDown(2)
+	if req.Price.GreaterThan(maxPrice) {
+		writeJSON(ResponseMessage{Message: fmt.Sprintf("price can't exceed %v", maxPrice.StringFixed(2))}, http.StatusUnprocessableEntity, w)
+		return
+	}
+	listing, err := app.storage.Resales.GetActiveForTicket(p.TicketID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if listing != nil {
+		writeJSON(ResponseMessage{Message: "ticket is already listed for resale"}, http.StatusConflict, w)
+		return
+	}
+	fee := req.Price.Mul(settings.ResaleFeeRate).Div(decimal.NewFromInt(100)).Round(2)
+	listing, err = app.storage.Resales.Create(p.TicketID, u.ID, *p.OrderID, *req.Price, fee, req.PayoutToWallet)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ResaleListingResponse{Listing: listing}, http.StatusCreated, w)
+}
+
+type GetResaleListingsResponse struct {
+	Listings []internal.ResaleListing `json:"listings"`
+	MetaData *internal.MetaData       `json:"meta_data"`
+}
+
+// getResaleListingsHandler godoc
+//
+//	@Summary		Gets resale listings
+//	@Description	gets the available resale listings of a given schedule ordered by price
+//	@Tags			resales
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int	true	"schedule id"
+//	@Param			page		query		int	false	"page number"
+//	@Param			page_size	query		int	false	"page size"
+//	@Success		200			{object}	GetResaleListingsResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/schedules/{id}/resale-listings [get]
+func (app *Application) getResaleListingsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	page := getQueryIntOr(r, "page", 1, v)
+	pageSize := getQueryIntOr(r, "page_size", 20, v)
+	v.Check(page > 0 && page <= 10_000_000, "page", "must be between 1 and 10_000_000")
+	v.Check(pageSize > 0 && pageSize <= 100, "page_size", "must be between 1 and 100")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	listings, metaData, err := app.storage.Resales.GetAllForSchedule(int64(id), page, pageSize)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetResaleListingsResponse{Listings: listings, MetaData: metaData}, http.StatusOK, w)
+}
+
+// getResaleListingHandler godoc
+//
+//	@Summary		Gets a resale listing
+//	@Description	gets a resale listing by id
+//	@Tags			resales
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"listing id"
+//	@Success		200	{object}	ResaleListingResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/resale-listings/{id} [get]
+func (app *Application) getResaleListingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	listing, err := app.storage.Resales.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if listing == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(ResaleListingResponse{Listing: listing}, http.StatusOK, w)
+}
+
+// cancelResaleListingHandler godoc
+//
+//	@Summary		Cancels a resale listing
+//	@Description	takes a ticket off the resale market, it can't be cancelled while someone is buying it
+//	@Tags			resales
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"listing id"
+//	@Success		200	{object}	ResaleListingResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/resale-listings/{id} [delete]
+func (app *Application) cancelResaleListingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	listing, err := app.storage.Resales.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if listing == nil {
+		writeNotFound(w)
+		return
+	}
+	if listing.SellerID != u.ID {
+		writeForbidden(w)
+		return
+	}
+	ok, err := app.storage.Resales.Cancel(listing)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if !ok {
+		writeJSON(ResponseMessage{Message: "listing isn't active or someone is buying it"}, http.StatusConflict, w)
+		return
+	}
+	writeJSON(ResaleListingResponse{Listing: listing}, http.StatusOK, w)
+}
+
+// lockResaleListingHandler godoc
+//
+//	@Summary		Locks a resale listing
+//	@Description	holds a resold ticket for the user so it's bought with the user's next checkout, it counts against the same limits
+//	@Description	as the other locked tickets and needs an admission token if the schedule has a waiting room
+//	@Tags			resales
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"listing id"
+//	@Success		200	{object}	LockTicketResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		429	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/resale-listings/{id}/lock [post]
+func (app *Application) lockResaleListingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	listing, err := app.storage.Resales.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if listing == nil {
+		writeNotFound(w)
+		return
+	}
+	if listing.SellerID == u.ID {
+		writeJSON(ResponseMessage{Message: "you can't buy your own listing"}, http.StatusConflict, w)
+		return
+	}
+	checkoutSession, err := app.storage.Checkouts.GetByUserID(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if checkoutSession != nil {
+		writeJSON(ResponseMessage{Message: fmt.Sprintf("you can't lock a ticket during checkout: %v", checkoutSession.SessionID)}, http.StatusConflict, w)
+		return
+	}
+	t, err := app.storage.Tickets.GetByID(listing.TicketID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	lock, err := app.storage.Resales.Lock(listing, u, app.config.checkout.ticketLockDuration, app.config.checkout.lockLimits)
+	if err != nil {
+		var cooldownErr *internal.LockCooldownError
+		switch {
+		case errors.As(err, &cooldownErr):
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(cooldownErr.Until).Seconds())+1))
+			writeJSON(ResponseMessage{Message: cooldownErr.Error()}, http.StatusTooManyRequests, w)
+		case errors.Is(err, internal.ErrLockLimitPerUser), errors.Is(err, internal.ErrLockLimitPerSchedule), errors.Is(err, internal.ErrLockLimitPerOrder):
+			writeJSON(ResponseMessage{Message: err.Error()}, http.StatusConflict, w)
+		default:
+			writeServerErr(err, w)
+		}
+		return
+	}
+	if lock == nil {
+		writeJSON(ResponseMessage{Message: "listing isn't available"}, http.StatusConflict, w)
+		return
+	}
+	t.Price = listing.Price
+	writeJSON(LockTicketResponse{Ticket: t, Lock: lock}, http.StatusOK, w)
+}
+
+// unlockResaleListingHandler godoc
+//
+//	@Summary		Unlocks a resale listing
+//	@Description	releases the user's hold on a resold ticket
+//	@Tags			resales
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"listing id"
+//	@Success		200	{object}	ResponseMessage
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/resale-listings/{id}/unlock [post]
+func (app *Application) unlockResaleListingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	listing, err := app.storage.Resales.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if listing == nil {
+		writeNotFound(w)
+		return
+	}
+	checkoutSession, err := app.storage.Checkouts.GetByUserID(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if checkoutSession != nil {
+		writeJSON(ResponseMessage{Message: fmt.Sprintf("you can't unlock a ticket during checkout: %v", checkoutSession.SessionID)}, http.StatusConflict, w)
+		return
+	}
+	ok, err := app.storage.Resales.Unlock(listing, u)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if !ok {
+		writeJSON(ResponseMessage{Message: "you aren't holding this listing"}, http.StatusConflict, w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "listing was unlocked successfully"}, http.StatusOK, w)
+}
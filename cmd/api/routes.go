@@ -26,20 +26,45 @@ func composeRoutes(app *Application) http.Handler {
 	mux.HandleFunc("POST /v1/tokens/activation", app.createUserActivationTokenHandler)
 	mux.HandleFunc("PUT /v1/tokens/activation", app.activateUserHandler)
 	mux.HandleFunc("POST /v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	mux.HandleFunc("POST /v1/tokens/refresh", app.refreshAuthenticationTokenHandler)
+	mux.HandleFunc("POST /v1/tokens/two-factor", app.createTwoFactorAuthenticationTokenHandler)
+	mux.HandleFunc("POST /v1/tokens/oidc", app.createOIDCAuthenticationTokenHandler)
 	mux.HandleFunc("POST /v1/tokens/password-reset", app.createPasswordResetTokenHandler)
 	mux.HandleFunc("PUT /v1/tokens/password-reset", app.resetPasswordHandler)
 
-	mux.HandleFunc("POST /v1/movies", app.authenticate(app.authorize([]internal.Permission{"movies:create"}, app.createMovieHandler)))
+	mux.HandleFunc("GET /v1/oidc/providers", app.getOIDCProvidersHandler)
+	mux.HandleFunc("POST /v1/oidc/{provider}/authorization", app.createOIDCAuthorizationHandler)
+	if app.jwt != nil {
+		mux.HandleFunc("GET /v1/.well-known/jwks.json", app.getJWKSHandler)
+	}
+
+	mux.HandleFunc("GET /v1/users/me/sessions", app.authenticate(app.getSessionsHandler))
+	mux.HandleFunc("DELETE /v1/users/me/sessions", app.authenticate(app.deleteSessionsHandler))
+	mux.HandleFunc("DELETE /v1/users/me/sessions/{id}", app.authenticate(app.deleteSessionHandler))
+
+	mux.HandleFunc("GET /v1/users/me/two-factor", app.authenticate(app.getTwoFactorHandler))
+	mux.HandleFunc("POST /v1/users/me/two-factor", app.authenticate(app.enrollTwoFactorHandler))
+	mux.HandleFunc("PUT /v1/users/me/two-factor", app.authenticate(app.confirmTwoFactorHandler))
+	mux.HandleFunc("DELETE /v1/users/me/two-factor", app.authenticate(app.disableTwoFactorHandler))
+	mux.HandleFunc("POST /v1/users/me/two-factor/recovery-codes", app.authenticate(app.regenerateRecoveryCodesHandler))
+
+	mux.HandleFunc("POST /v1/users/me/api-keys", app.authenticate(app.requireUserActivation(app.createAPIKeyHandler)))
+	mux.HandleFunc("GET /v1/users/me/api-keys", app.authenticate(app.requireUserActivation(app.getAPIKeysHandler)))
+	mux.HandleFunc("DELETE /v1/users/me/api-keys/{id}", app.authenticate(app.requireUserActivation(app.deleteAPIKeyHandler)))
+
+	mux.HandleFunc("POST /v1/movies", app.allowAPIKeys([]internal.Permission{"movies:create"}, app.authenticate(app.authorize([]internal.Permission{"movies:create"}, app.createMovieHandler))))
 	mux.HandleFunc("GET /v1/movies/{id}", app.getMovieHandler)
 	mux.HandleFunc("GET /v1/movies", app.getMoviesHandler)
-	mux.HandleFunc("PUT /v1/movies/{id}", app.authenticate(app.authorize([]internal.Permission{"movies:update"}, app.updateMovieHandler)))
-	mux.HandleFunc("DELETE /v1/movies/{id}", app.authenticate(app.authorize([]internal.Permission{"movies:delete"}, app.deleteMovieHandler)))
+	mux.HandleFunc("PUT /v1/movies/{id}", app.allowAPIKeys([]internal.Permission{"movies:update"}, app.authenticate(app.authorize([]internal.Permission{"movies:update"}, app.updateMovieHandler))))
+	mux.HandleFunc("DELETE /v1/movies/{id}", app.allowAPIKeys([]internal.Permission{"movies:delete"}, app.authenticate(app.authorize([]internal.Permission{"movies:delete"}, app.deleteMovieHandler))))
 
 	mux.HandleFunc("POST /v1/cinemas", app.authenticate(app.requireUserActivation(app.createCinemaHandler)))
 	mux.HandleFunc("GET /v1/cinemas/{id}", app.getCinemaHandler)
 	mux.HandleFunc("GET /v1/cinemas", app.getCinemasHandler)
 	mux.HandleFunc("PUT /v1/cinemas/{id}", app.authenticate(app.requireUserActivation(app.updateCinemaHandler)))
 	mux.HandleFunc("DELETE /v1/cinemas/{id}", app.authenticate(app.requireUserActivation(app.deleteCinemaHandler)))
+	mux.HandleFunc("GET /v1/cinemas/{id}/settings", app.authenticate(app.requireUserActivation(app.getCinemaSettingsHandler)))
+	mux.HandleFunc("PUT /v1/cinemas/{id}/settings", app.authenticate(app.requireUserActivation(app.updateCinemaSettingsHandler)))
 
 	mux.HandleFunc("POST /v1/cinemas/{id}/halls", app.authenticate(app.requireUserActivation(app.createHallHandler)))
 	mux.HandleFunc("GET /v1/cinemas/{id}/halls", app.getHallsHandler)
@@ -51,19 +76,98 @@ func composeRoutes(app *Application) http.Handler {
 	mux.HandleFunc("PUT /v1/seats/{id}", app.authenticate(app.requireUserActivation(app.updateSeatHandler)))
 	mux.HandleFunc("DELETE /v1/seats/{id}", app.authenticate(app.requireUserActivation(app.deleteSeatHandler)))
 
-	mux.HandleFunc("POST /v1/schedules", app.authenticate(app.requireUserActivation(app.createScheduleHandler)))
+	mux.HandleFunc("POST /v1/cinemas/{id}/fees", app.authenticate(app.requireUserActivation(app.createFeeHandler)))
+	mux.HandleFunc("GET /v1/cinemas/{id}/fees", app.getFeesHandler)
+	mux.HandleFunc("PUT /v1/fees/{id}", app.authenticate(app.requireUserActivation(app.updateFeeHandler)))
+	mux.HandleFunc("DELETE /v1/fees/{id}", app.authenticate(app.requireUserActivation(app.deleteFeeHandler)))
+
+	mux.HandleFunc("POST /v1/cinemas/{id}/products", app.authenticate(app.requireUserActivation(app.createProductHandler)))
+	mux.HandleFunc("GET /v1/cinemas/{id}/products", app.getProductsHandler)
+	mux.HandleFunc("PUT /v1/products/{id}", app.authenticate(app.requireUserActivation(app.updateProductHandler)))
+	mux.HandleFunc("DELETE /v1/products/{id}", app.authenticate(app.requireUserActivation(app.deleteProductHandler)))
+
+	mux.HandleFunc("POST /v1/cinemas/{id}/members", app.authenticate(app.requireUserActivation(app.setCinemaMemberHandler)))
+	mux.HandleFunc("GET /v1/cinemas/{id}/members", app.authenticate(app.requireUserActivation(app.getCinemaMembersHandler)))
+	mux.HandleFunc("DELETE /v1/cinemas/{id}/members/{user_id}", app.authenticate(app.requireUserActivation(app.removeCinemaMemberHandler)))
+	mux.HandleFunc("GET /v1/users/me/cinemas", app.authenticate(app.requireUserActivation(app.getUserCinemasHandler)))
+
+	mux.HandleFunc("POST /v1/cinemas/{id}/drawers", app.allowAPIKeys([]internal.Permission{"box_office:sell"}, app.authenticate(app.requireUserActivation(app.openCashDrawerHandler))))
+	mux.HandleFunc("POST /v1/drawers/{id}/sales", app.allowAPIKeys([]internal.Permission{"box_office:sell"}, app.authenticate(app.requireUserActivation(app.createBoxOfficeSaleHandler))))
+	mux.HandleFunc("POST /v1/drawers/{id}/close", app.allowAPIKeys([]internal.Permission{"box_office:sell"}, app.authenticate(app.requireUserActivation(app.closeCashDrawerHandler))))
+	mux.HandleFunc("GET /v1/drawers/{id}/report", app.authenticate(app.requireUserActivation(app.getShiftReportHandler)))
+
+	mux.HandleFunc("POST /v1/cinemas/{id}/membership-plans", app.authenticate(app.requireUserActivation(app.createMembershipPlanHandler)))
+	mux.HandleFunc("GET /v1/cinemas/{id}/membership-plans", app.getMembershipPlansHandler)
+	mux.HandleFunc("DELETE /v1/membership-plans/{id}", app.authenticate(app.requireUserActivation(app.deleteMembershipPlanHandler)))
+	mux.HandleFunc("POST /v1/membership-plans/{id}/subscribe", app.authenticate(app.requireUserActivation(app.subscribeHandler)))
+	mux.HandleFunc("GET /v1/users/me/memberships", app.authenticate(app.requireUserActivation(app.getMembershipsHandler)))
+	mux.HandleFunc("DELETE /v1/memberships/{id}", app.authenticate(app.requireUserActivation(app.cancelMembershipHandler)))
+
+	mux.HandleFunc("POST /v1/schedules", app.allowAPIKeys([]internal.Permission{"schedules:create"}, app.authenticate(app.requireUserActivation(app.createScheduleHandler))))
 	mux.HandleFunc("GET /v1/schedules", app.getSchedulesHandler)
-	mux.HandleFunc("PUT /v1/schedules/{id}", app.authenticate(app.requireUserActivation(app.updateScheduleHandler)))
-	mux.HandleFunc("DELETE /v1/schedules/{id}", app.authenticate(app.requireUserActivation(app.deleteScheduleHandler)))
+	mux.HandleFunc("PUT /v1/schedules/{id}", app.allowAPIKeys([]internal.Permission{"schedules:update"}, app.authenticate(app.requireUserActivation(app.updateScheduleHandler))))
+	mux.HandleFunc("DELETE /v1/schedules/{id}", app.allowAPIKeys([]internal.Permission{"schedules:delete"}, app.authenticate(app.requireUserActivation(app.deleteScheduleHandler))))
+
+	mux.HandleFunc("PUT /v1/schedules/{id}/waiting-room", app.authenticate(app.requireUserActivation(app.setWaitingRoomHandler)))
+	mux.HandleFunc("GET /v1/schedules/{id}/waiting-room", app.getWaitingRoomHandler)
+	mux.HandleFunc("DELETE /v1/schedules/{id}/waiting-room", app.authenticate(app.requireUserActivation(app.deleteWaitingRoomHandler)))
+	mux.HandleFunc("POST /v1/schedules/{id}/waiting-room/join", app.authenticate(app.requireUserActivation(app.joinWaitingRoomHandler)))
+	mux.HandleFunc("GET /v1/schedules/{id}/waiting-room/position", app.authenticate(app.requireUserActivation(app.getWaitingRoomPositionHandler)))
+
+	mux.HandleFunc("POST /v1/schedules/{id}/waitlist", app.authenticate(app.requireUserActivation(app.joinWaitlistHandler)))
+	mux.HandleFunc("GET /v1/schedules/{id}/waitlist", app.authenticate(app.requireUserActivation(app.getWaitlistEntryHandler)))
+	mux.HandleFunc("DELETE /v1/schedules/{id}/waitlist", app.authenticate(app.requireUserActivation(app.leaveWaitlistHandler)))
 
-	mux.HandleFunc("POST /v1/schedules/{id}/tickets", app.authenticate(app.requireUserActivation(app.createTicketsForScheduleHandler)))
+	mux.HandleFunc("POST /v1/schedules/{id}/tickets", app.allowAPIKeys([]internal.Permission{"tickets:create"}, app.authenticate(app.requireUserActivation(app.createTicketsForScheduleHandler))))
 	mux.HandleFunc("GET /v1/schedules/{id}/tickets", app.getTicketsForScheduleHandler)
 
-	mux.HandleFunc("POST /v1/tickets/{id}/lock", app.authenticate(app.requireUserActivation(app.lockTicketHandler)))
+	mux.HandleFunc("POST /v1/tickets/{id}/lock", app.authenticate(app.requireUserActivation(app.requireAdmission(app.lockTicketHandler))))
 	mux.HandleFunc("POST /v1/tickets/{id}/unlock", app.authenticate(app.requireUserActivation(app.unlockTicketHandler)))
+	mux.HandleFunc("POST /v1/tickets/{id}/extend", app.authenticate(app.requireUserActivation(app.extendTicketLockHandler)))
+	mux.HandleFunc("GET /v1/tickets/{id}/pass", app.authenticate(app.requireUserActivation(app.getTicketPassHandler)))
+	mux.HandleFunc("POST /v1/tickets/{id}/transfer", app.authenticate(app.requireUserActivation(app.transferTicketHandler)))
+	mux.HandleFunc("GET /v1/tickets/{id}/transfers", app.authenticate(app.requireUserActivation(app.getTicketTransfersHandler)))
+	mux.HandleFunc("POST /v1/ticket-transfers/claim", app.authenticate(app.requireUserActivation(app.claimTicketTransferHandler)))
+	mux.HandleFunc("POST /v1/ticket-transfers/{id}/cancel", app.authenticate(app.requireUserActivation(app.cancelTicketTransferHandler)))
+
+	mux.HandleFunc("POST /v1/tickets/{id}/resale", app.authenticate(app.requireUserActivation(app.createResaleListingHandler)))
+	mux.HandleFunc("GET /v1/schedules/{id}/resale-listings", app.getResaleListingsHandler)
+	mux.HandleFunc("GET /v1/resale-listings/{id}", app.getResaleListingHandler)
+	mux.HandleFunc("DELETE /v1/resale-listings/{id}", app.authenticate(app.requireUserActivation(app.cancelResaleListingHandler)))
+	mux.HandleFunc("POST /v1/resale-listings/{id}/lock", app.authenticate(app.requireUserActivation(app.requireResaleAdmission(app.lockResaleListingHandler))))
+	mux.HandleFunc("POST /v1/resale-listings/{id}/unlock", app.authenticate(app.requireUserActivation(app.unlockResaleListingHandler)))
+
+	mux.HandleFunc("POST /v1/gift-cards", app.authenticate(app.requireUserActivation(app.purchaseGiftCardHandler)))
+	mux.HandleFunc("GET /v1/gift-cards", app.authenticate(app.requireUserActivation(app.getGiftCardsHandler)))
+	mux.HandleFunc("POST /v1/gift-cards/redeem", app.authenticate(app.requireUserActivation(app.redeemGiftCardHandler)))
+	mux.HandleFunc("GET /v1/users/me/wallet", app.authenticate(app.requireUserActivation(app.getWalletHandler)))
+	mux.HandleFunc("GET /v1/users/me/loyalty", app.authenticate(app.requireUserActivation(app.getLoyaltyHandler)))
 
 	mux.HandleFunc("GET /v1/checkout", app.authenticate(app.requireUserActivation(app.getCheckoutHandler)))
 	mux.HandleFunc("POST /v1/checkout", app.authenticate(app.requireUserActivation(app.checkoutHandler)))
+	mux.HandleFunc("PUT /v1/checkout/products", app.authenticate(app.requireUserActivation(app.setCheckoutProductHandler)))
+
+	mux.HandleFunc("GET /v1/orders/{id}/receipt.pdf", app.authenticate(app.requireUserActivation(app.getOrderReceiptHandler)))
+	mux.HandleFunc("GET /v1/orders/{id}/tickets.pdf", app.authenticate(app.requireUserActivation(app.getOrderTicketsHandler)))
+
+	mux.HandleFunc("POST /v1/admin/gift-cards", app.authenticate(app.authorize([]internal.Permission{"gift_cards:create"}, app.issueGiftCardHandler)))
+	mux.HandleFunc("GET /v1/admin/payment-events", app.authenticate(app.authorize([]internal.Permission{"payment_events:read"}, app.getPaymentEventsHandler)))
+	mux.HandleFunc("POST /v1/admin/payment-events/{id}/replay", app.authenticate(app.authorize([]internal.Permission{"payment_events:replay"}, app.replayPaymentEventHandler)))
+	mux.HandleFunc("GET /v1/admin/payment-discrepancies", app.authenticate(app.authorize([]internal.Permission{"payment_discrepancies:read"}, app.getPaymentDiscrepanciesHandler)))
+	mux.HandleFunc("POST /v1/admin/payment-discrepancies/{id}/resolve", app.authenticate(app.authorize([]internal.Permission{"payment_discrepancies:update"}, app.resolvePaymentDiscrepancyHandler)))
+	mux.HandleFunc("GET /v1/admin/users", app.authenticate(app.authorize([]internal.Permission{"users:read"}, app.getAdminUsersHandler)))
+	mux.HandleFunc("GET /v1/admin/users/{id}", app.authenticate(app.authorize([]internal.Permission{"users:read"}, app.getAdminUserHandler)))
+	mux.HandleFunc("POST /v1/admin/users/{id}/suspension", app.authenticate(app.authorize([]internal.Permission{"users:update"}, app.suspendUserHandler)))
+	mux.HandleFunc("DELETE /v1/admin/users/{id}/suspension", app.authenticate(app.authorize([]internal.Permission{"users:update"}, app.unsuspendUserHandler)))
+	mux.HandleFunc("POST /v1/admin/users/{id}/password-reset", app.authenticate(app.authorize([]internal.Permission{"users:update"}, app.forcePasswordResetHandler)))
+	mux.HandleFunc("GET /v1/admin/users/{id}/audit-entries", app.authenticate(app.authorize([]internal.Permission{"users:read"}, app.getUserAuditEntriesHandler)))
+	mux.HandleFunc("POST /v1/admin/token-revocations", app.authenticate(app.authorize([]internal.Permission{"tokens:revoke"}, app.revokeTokensHandler)))
+	mux.HandleFunc("GET /v1/admin/roles", app.authenticate(app.authorize([]internal.Permission{"roles:read"}, app.getRolesHandler)))
+	mux.HandleFunc("GET /v1/admin/users/{id}/permissions", app.authenticate(app.authorize([]internal.Permission{"roles:read"}, app.getUserPermissionsHandler)))
+	mux.HandleFunc("POST /v1/admin/users/{id}/permissions", app.authenticate(app.authorize([]internal.Permission{"roles:grant"}, app.grantPermissionsHandler)))
+	mux.HandleFunc("DELETE /v1/admin/users/{id}/permissions/{permission}", app.authenticate(app.authorize([]internal.Permission{"roles:grant"}, app.revokePermissionHandler)))
+	mux.HandleFunc("POST /v1/admin/users/{id}/roles", app.authenticate(app.authorize([]internal.Permission{"roles:grant"}, app.grantRolesHandler)))
+	mux.HandleFunc("DELETE /v1/admin/users/{id}/roles/{role}", app.authenticate(app.authorize([]internal.Permission{"roles:grant"}, app.revokeRoleHandler)))
 
 	mux.HandleFunc("/v1/webhook", app.handleWebhook)
 	mux.HandleFunc("/v1/checkout_sessions/cancel", app.handleCheckoutSessionCancel)
@@ -104,8 +104,7 @@ func (app *Application) createScheduleHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if c.OwnerID != u.ID {
-		writeForbidden(w)
+	if !app.checkCinemaScope(c, u, internal.CinemaScopeSchedules, w) {
 		return
 	}
 
@@ -242,6 +241,16 @@ func (app *Application) updateScheduleHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+
+	if !app.checkScheduleScope(s, u, internal.CinemaScopeSchedules, w) {
+		return
+	}
+
 	if req.Price != nil {
 		s.Price = *req.Price
 	}
@@ -308,6 +317,9 @@ func (app *Application) deleteScheduleHandler(w http.ResponseWriter, r *http.Req
 		writeNotFound(w)
 		return
 	}
+	if !app.checkScheduleScope(s, u, internal.CinemaScopeSchedules, w) {
+		return
+	}
 	err = app.storage.Schedules.Delete(s)
 	if err != nil {
 		writeServerErr(err, w)
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"html/template"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
+
 	"github.com/stripe/stripe-go/v81"
 	"github.com/stripe/stripe-go/v81/checkout/session"
+	"github.com/stripe/stripe-go/v81/refund"
+	"github.com/stripe/stripe-go/v81/subscription"
 )
 
 func (app *Application) Go(fn func()) {
@@ -62,6 +70,24 @@ func (app *Application) TokensService(tickRate time.Duration) ServiceFunc {
 				} else if n != 0 {
 					log.Printf("Deleted %d tokens\n", n)
 				}
+				n, err = app.storage.Identities.DeleteAllExpiredStates()
+				if err != nil {
+					log.Println(err)
+				} else if n != 0 {
+					log.Printf("Deleted %d oidc states\n", n)
+				}
+				n, err = app.storage.APIKeys.DeleteAllExpired()
+				if err != nil {
+					log.Println(err)
+				} else if n != 0 {
+					log.Printf("Deleted %d api keys\n", n)
+				}
+				n, err = app.storage.LoginThrottles.DeleteAllStale(app.config.login.accountLimits.FailureWindow)
+				if err != nil {
+					log.Println(err)
+				} else if n != 0 {
+					log.Printf("Deleted %d login throttles\n", n)
+				}
 			case _, open := <-app.quit:
 				if !open {
 					break loop
@@ -86,9 +112,26 @@ func (app *Application) CheckoutSessionsService(checkoutSessionsPullCount int, t
 					break
 				}
 				for _, cs := range checkoutSessions {
+					if strings.HasPrefix(cs.SessionID, prepaidSessionPrefix) || strings.HasPrefix(cs.SessionID, pendingSessionPrefix) {
+						// prepaid but never fulfilled or never made it to the payment gateway, there is nothing on it to expire
+						err := app.storage.Checkouts.DeleteBySessionID(cs.SessionID)
+						if err != nil {
+							log.Println(err)
+						}
+						continue
+					}
 					s, err := session.Get(cs.SessionID, nil)
 					if err != nil {
 						log.Println(err)
+						continue
+					}
+					if s.Status == stripe.CheckoutSessionStatusComplete {
+						// the webhook was missed, fulfill the session instead of letting the tickets go
+						_, err := app.reconcileCheckoutSession(s)
+						if err != nil {
+							log.Println(err)
+						}
+						continue
 					}
 					if s.Status == stripe.CheckoutSessionStatusOpen {
 						_, err := session.Expire(cs.SessionID, nil)
@@ -129,6 +172,23 @@ func (app *Application) TicketsService(tickRate time.Duration) ServiceFunc {
 					break
 				}
 				log.Printf("Unlocked %d tickets\n", n)
+				offers, err := app.storage.Waitlists.OfferAll(app.config.checkout.waitlistOffer)
+				if err != nil {
+					log.Println(err)
+					break
+				}
+				for _, o := range offers {
+					data := map[string]any{
+						"name":       o.UserName,
+						"scheduleID": o.ScheduleID,
+						"ticketID":   o.TicketID,
+						"expiresAt":  o.ExpiresAt.Format(time.RFC1123),
+					}
+					app.Go(app.SendMail(o.UserEmail, WaitlistOfferTmpl, data))
+				}
+				if len(offers) != 0 {
+					log.Printf("Offered %d tickets to waitlisted users\n", len(offers))
+				}
 			case _, open := <-app.quit:
 				if !open {
 					break loop
@@ -138,3 +198,287 @@ func (app *Application) TicketsService(tickRate time.Duration) ServiceFunc {
 		log.Println("Tickets service was shut down gracefully")
 	}
 }
+
+// reconcileCheckoutSession compares a completed session of the payment gateway with the local records,
+// it fulfills paid sessions that weren't fulfilled and records a discrepancy for everything that doesn't match.
+func (app *Application) reconcileCheckoutSession(s *stripe.CheckoutSession) (*internal.PaymentDiscrepancy, error) {
+	if s.Status != stripe.CheckoutSessionStatusComplete || s.PaymentStatus == stripe.CheckoutSessionPaymentStatusUnpaid {
+		return nil, nil
+	}
+	cs, err := app.storage.Checkouts.GetBySessionID(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	o, err := app.storage.Orders.GetBySessionID(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	g, err := app.storage.GiftCards.GetBySessionID(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := app.storage.Memberships.GetBySessionID(s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &internal.PaymentDiscrepancy{SessionID: s.ID}
+	switch {
+	case o != nil && o.StatusID == internal.OrderStatusCompleted:
+		paid := decimal.New(s.AmountTotal, -2)
+		due := o.Total.Sub(o.WalletAmount).Sub(o.LoyaltyDiscount)
+		if paid.Equal(due) {
+			return nil, nil
+		}
+		d.Kind = internal.PaymentDiscrepancyKindAmountMismatch
+		d.UserID = &o.UserID
+		d.Details = fmt.Sprintf("order %d is due %v but %v was paid", o.ID, due.StringFixed(2), paid.StringFixed(2))
+	case cs != nil:
+		paymentReference := s.ID
+		if s.PaymentIntent != nil {
+			paymentReference = s.PaymentIntent.ID
+		}
+		err = app.storage.Checkouts.Fulfill(s.ID, cs.UserID, paymentReference)
+		if err != nil {
+			return nil, err
+		}
+		d.Kind = internal.PaymentDiscrepancyKindMissedFulfillment
+		d.UserID = &cs.UserID
+		d.Details = "paid session wasn't fulfilled, it was fulfilled by reconciliation"
+	case g != nil && g.StatusID != internal.GiftCardStatusPending:
+		return nil, nil
+	case g != nil:
+		paymentReference := s.ID
+		if s.PaymentIntent != nil {
+			paymentReference = s.PaymentIntent.ID
+		}
+		_, err = app.storage.GiftCards.Activate(s.ID, paymentReference)
+		if err != nil {
+			return nil, err
+		}
+		d.Kind = internal.PaymentDiscrepancyKindMissedFulfillment
+		d.UserID = g.PurchasedBy
+		d.Details = fmt.Sprintf("paid gift card %d wasn't activated, it was activated by reconciliation", g.ID)
+	case m != nil && m.StatusID != internal.MembershipStatusIncomplete:
+		return nil, nil
+	case m != nil:
+		if s.Subscription == nil {
+			return nil, fmt.Errorf("subscription session %s has no subscription", s.ID)
+		}
+		sub, err := subscription.Get(s.Subscription.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		_, err = app.storage.Memberships.Activate(s.ID, toMembershipSubscription(sub))
+		if err != nil {
+			return nil, err
+		}
+		d.Kind = internal.PaymentDiscrepancyKindMissedFulfillment
+		d.UserID = &m.UserID
+		d.Details = fmt.Sprintf("paid membership %d wasn't activated, it was activated by reconciliation", m.ID)
+	case o != nil:
+		d.Kind = internal.PaymentDiscrepancyKindReleasedTickets
+		d.UserID = &o.UserID
+		d.Details = fmt.Sprintf("order %d was paid after its tickets were released", o.ID)
+	default:
+		d.Kind = internal.PaymentDiscrepancyKindUnknownSession
+		d.Details = "paid session has no checkout session or order"
+	}
+
+	created, err := app.storage.Discrepancies.Create(d.Kind, d.SessionID, d.UserID, d.Details)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, nil
+	}
+	log.Printf("Payment discrepancy %v for session %s: %s\n", d.Kind, d.SessionID, d.Details)
+	return d, nil
+}
+
+// ReconciliationService periodically checks the sessions that were completed on the payment gateway
+// between window and grace ago against the local records, the grace period gives the webhook time to arrive.
+func (app *Application) ReconciliationService(window time.Duration, grace time.Duration, tickRate time.Duration) ServiceFunc {
+	return func() {
+		log.Println("Started reconciliation service")
+		ticker := time.NewTicker(tickRate)
+	loop:
+		for {
+			select {
+			case <-ticker.C:
+				now := time.Now()
+				params := &stripe.CheckoutSessionListParams{
+					CreatedRange: &stripe.RangeQueryParams{
+						GreaterThanOrEqual: now.Add(-window).Unix(),
+						LesserThanOrEqual:  now.Add(-grace).Unix(),
+					},
+					Status: stripe.String(string(stripe.CheckoutSessionStatusComplete)),
+				}
+				checked := 0
+				discrepancies := make(map[internal.PaymentDiscrepancyKind]int)
+				i := session.List(params)
+				for i.Next() {
+					checked++
+					d, err := app.reconcileCheckoutSession(i.CheckoutSession())
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					if d != nil {
+						discrepancies[d.Kind]++
+					}
+				}
+				if err := i.Err(); err != nil {
+					log.Println(err)
+				}
+				if len(discrepancies) != 0 {
+					log.Printf("Reconciled %d sessions, new discrepancies: %v\n", checked, discrepancies)
+				}
+			case _, open := <-app.quit:
+				if !open {
+					break loop
+				}
+			}
+		}
+		log.Println("Reconciliation service was shut down gracefully")
+	}
+}
+
+func (app *Application) WaitingRoomsService(tickRate time.Duration) ServiceFunc {
+	return func() {
+		log.Println("Started waiting rooms background service")
+		ticker := time.NewTicker(tickRate)
+	loop:
+		for {
+			select {
+			case <-ticker.C:
+				n, err := app.storage.WaitingRooms.AdmitAll()
+				if err != nil {
+					log.Println(err)
+				} else if n != 0 {
+					log.Printf("Admitted %d users from waiting rooms\n", n)
+				}
+			case _, open := <-app.quit:
+				if !open {
+					break loop
+				}
+			}
+		}
+		log.Println("Waiting rooms service was shut down gracefully")
+	}
+}
+
+// ResalePayoutsService refunds the proceeds of sold resale listings to the payments the sellers bought the tickets with
+// or credits them to the sellers' wallets. A failed payout is recorded on the listing and retried after retryDelay,
+// which doubles with every failure, once it failed maxAttempts times it's reported as a payment discrepancy.
+func (app *Application) ResalePayoutsService(payoutsPullCount int, maxAttempts int, retryDelay time.Duration, tickRate time.Duration) ServiceFunc {
+	return func() {
+		log.Println("Started resale payouts service")
+		ticker := time.NewTicker(tickRate)
+	loop:
+		for {
+			select {
+			case <-ticker.C:
+				payouts, err := app.storage.Resales.GetAllUnpaid(payoutsPullCount, maxAttempts)
+				if err != nil {
+					log.Println(err)
+					break
+				}
+				for _, p := range payouts {
+					payoutErr := app.payoutResale(p)
+					if payoutErr == nil {
+						continue
+					}
+					log.Println(payoutErr)
+					attempts, err := app.storage.Resales.MarkPayoutFailed(p.ListingID, payoutErr.Error(), retryDelay<<p.Attempts)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					if attempts < maxAttempts {
+						continue
+					}
+					details := fmt.Sprintf("the payout of %v for resale listing %d failed %d times: %v", p.Amount.StringFixed(2), p.ListingID, attempts, payoutErr)
+					_, err = app.storage.Discrepancies.Create(internal.PaymentDiscrepancyKindFailedPayout, fmt.Sprintf("resale-payout-%d", p.ListingID), &p.SellerID, details)
+					if err != nil {
+						log.Println(err)
+					}
+				}
+				if len(payouts) != 0 {
+					log.Printf("Processed %d resale payouts\n", len(payouts))
+				}
+			case _, open := <-app.quit:
+				if !open {
+					break loop
+				}
+			}
+		}
+		log.Println("Resale payouts service was shut down gracefully")
+	}
+}
+
+func (app *Application) LoyaltyService(tickRate time.Duration) ServiceFunc {
+	return func() {
+		log.Println("Started loyalty service")
+		ticker := time.NewTicker(tickRate)
+	loop:
+		for {
+			select {
+			case <-ticker.C:
+				n, err := app.storage.Loyalty.EarnAll(app.config.loyalty.pointsPerDollar, app.config.loyalty.pointsLifetime)
+				if err != nil {
+					log.Println(err)
+				} else if n != 0 {
+					log.Printf("Awarded loyalty points for %d orders\n", n)
+				}
+				n, err = app.storage.Loyalty.ExpireAll()
+				if err != nil {
+					log.Println(err)
+				} else if n != 0 {
+					log.Printf("Expired loyalty points of %d users\n", n)
+				}
+			case _, open := <-app.quit:
+				if !open {
+					break loop
+				}
+			}
+		}
+		log.Println("Loyalty service was shut down gracefully")
+	}
+}
+
+func (app *Application) payoutResale(p internal.ResalePayout) error {
+	if p.ToWallet {
+		_, err := app.storage.Resales.PayOutToWallet(p.ListingID)
+		return err
+	}
+	if !p.Amount.IsPositive() {
+		return app.storage.Resales.MarkPaidOut(p.ListingID, "")
+	}
+	paymentIntent := p.PaymentReference
+	if strings.HasPrefix(paymentIntent, "cs_") {
+		// the order was fulfilled before its payment intent was known
+		s, err := session.Get(paymentIntent, nil)
+		if err != nil {
+			return err
+		}
+		if s.PaymentIntent == nil {
+			return fmt.Errorf("session %s has no payment intent", s.ID)
+		}
+		paymentIntent = s.PaymentIntent.ID
+	}
+	if paymentIntent == "" {
+		return errors.New("seller's order has no payment reference")
+	}
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentIntent),
+		Amount:        stripe.Int64(p.Amount.Shift(2).IntPart()),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+	}
+	params.SetIdempotencyKey(fmt.Sprintf("resale-payout-%d", p.ListingID))
+	rf, err := refund.New(params)
+	if err != nil {
+		return err
+	}
+	return app.storage.Resales.MarkPaidOut(p.ListingID, rf.ID)
+}
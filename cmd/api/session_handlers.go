@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+type GetSessionsResponse struct {
+	Sessions []internal.Session `json:"sessions"`
+}
+
+// getSessionsHandler godoc
+//
+//	@Summary		Gets the user's sessions
+//	@Description	gets where the user is logged in with when and from where each session was last used
+//	@Tags			sessions
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetSessionsResponse
+//	@Failure		500	{object}	ResponseError
+//	@Router			/users/me/sessions [get]
+func (app *Application) getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	sessions, err := app.storage.Tokens.GetSessions(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if current := getSessionFromRequestContext(r); current != nil {
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == current.ID
+		}
+	}
+	writeJSON(GetSessionsResponse{Sessions: sessions}, http.StatusOK, w)
+}
+
+// deleteSessionHandler godoc
+//
+//	@Summary		Revokes a session
+//	@Description	logs the user out of a session, its auth and refresh tokens stop working right away
+//	@Tags			sessions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"session id"
+//	@Success		200	{object}	ResponseMessage
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/users/me/sessions/{id} [delete]
+func (app *Application) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	deleted, err := app.storage.Tokens.DeleteSession(u.ID, int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if !deleted {
+		writeNotFound(w)
+		return
+	}
+	sessionID := int64(id)
+	err = app.revokeAccessTokens(u.ID, &sessionID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "session was revoked"}, http.StatusOK, w)
+}
+
+// deleteSessionsHandler godoc
+//
+//	@Summary		Logs out everywhere
+//	@Description	revokes all of the user's sessions including the current one
+//	@Tags			sessions
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/users/me/sessions [delete]
+func (app *Application) deleteSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	err := app.storage.Tokens.DeleteAll(u.ID, []internal.TokenScope{internal.TokenScopeAuthentication, internal.TokenScopeRefresh})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.revokeAccessTokens(u.ID, nil)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "all sessions were revoked"}, http.StatusOK, w)
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/AdventurerAmer/movie-reservation-system/internal"
@@ -49,6 +51,9 @@ func (app *Application) createTicketsForScheduleHandler(w http.ResponseWriter, r
 		writeNotFound(w)
 		return
 	}
+	if !app.checkScheduleScope(s, u, internal.CinemaScopeSchedules, w) {
+		return
+	}
 	n, err := app.storage.Tickets.CreateAll(s)
 	if err != nil {
 		writeServerErr(err, w)
@@ -95,13 +100,15 @@ func (app *Application) getTicketsForScheduleHandler(w http.ResponseWriter, r *h
 }
 
 type LockTicketResponse struct {
-	Ticket *internal.Ticket `json:"ticket"`
+	Ticket       *internal.Ticket     `json:"ticket"`
+	Lock         *internal.TicketLock `json:"lock,omitempty"`
+	MembershipID *int64               `json:"membership_id,omitempty"`
 }
 
 // lockTicketHandler godoc
 //
 //	@Summary		Locks a ticket
-//	@Description	locks a ticket to a given user for some time
+//	@Description	locks a ticket to a given user for some time, membership_id is set if one of the user's memberships covers the ticket at checkout
 //	@Tags			tickets
 //	@Accept			json
 //	@Produce		json
@@ -110,6 +117,7 @@ type LockTicketResponse struct {
 //	@Failure		400	{object}	ResponseError
 //	@Failure		404	{object}	ResponseMessage
 //	@Failure		409	{object}	ResponseMessage
+//	@Failure		429	{object}	ResponseMessage
 //	@Failure		500	{object}	ResponseError
 //	@Router			/tickets/{id}/lock [post]
 func (app *Application) lockTicketHandler(w http.ResponseWriter, r *http.Request) {
@@ -159,12 +167,82 @@ func (app *Application) lockTicketHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.storage.Tickets.Lock(t, u)
+	lock, err := app.storage.Tickets.Lock(t, u, app.config.checkout.ticketLockDuration, app.config.checkout.lockLimits)
+	if err != nil {
+		var cooldownErr *internal.LockCooldownError
+		switch {
+		case errors.As(err, &cooldownErr):
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(cooldownErr.Until).Seconds())+1))
+			writeJSON(ResponseMessage{Message: cooldownErr.Error()}, http.StatusTooManyRequests, w)
+		case errors.Is(err, internal.ErrTicketReserved), errors.Is(err, internal.ErrLockLimitPerUser), errors.Is(err, internal.ErrLockLimitPerSchedule), errors.Is(err, internal.ErrLockLimitPerOrder):
+			writeJSON(ResponseMessage{Message: err.Error()}, http.StatusConflict, w)
+		default:
+			writeServerErr(err, w)
+		}
+		return
+	}
+	res := LockTicketResponse{Ticket: t, Lock: lock}
+	items, _, _, err := app.getCheckoutItems(u.ID)
+	if err != nil {
+		// the ticket is locked already, its coverage is checked again at checkout
+		log.Println(err)
+	}
+	for _, item := range items {
+		if item.Ticket.ID == t.ID {
+			res.MembershipID = item.MembershipID
+		}
+	}
+	writeJSON(res, http.StatusOK, w)
+}
+
+// extendTicketLockHandler godoc
+//
+//	@Summary		Extends a ticket lock
+//	@Description	extends the hold of a locked ticket, a hold can only be extended once
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"ticket id"
+//	@Success		200	{object}	LockTicketResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/tickets/{id}/extend [post]
+func (app *Application) extendTicketLockHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	t, err := app.storage.Tickets.GetByID(int64(id))
 	if err != nil {
 		writeServerErr(err, w)
 		return
 	}
-	writeJSON(LockTicketResponse{Ticket: t}, http.StatusOK, w)
+	if t == nil {
+		writeNotFound(w)
+		return
+	}
+	if t.StateID != internal.TicketStateLocked {
+		writeJSON(ResponseMessage{Message: "ticket must be locked to extend its hold"}, http.StatusConflict, w)
+		return
+	}
+	lock, err := app.storage.Tickets.ExtendLock(t, u, app.config.checkout.ticketLockDuration)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if lock == nil {
+		writeJSON(ResponseMessage{Message: "hold can't be extended, it is not yours, expired, already extended or you are checking out"}, http.StatusConflict, w)
+		return
+	}
+	writeJSON(LockTicketResponse{Ticket: t, Lock: lock}, http.StatusOK, w)
 }
 
 // unlockTicketHandler godoc
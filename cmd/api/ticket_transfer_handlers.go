@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+type TicketPassResponse struct {
+	Pass *internal.TicketPass `json:"pass"`
+}
+
+// getTicketPassHandler godoc
+//
+//	@Summary		Gets a ticket pass
+//	@Description	gets the pass of a sold ticket, its code is encoded in the QR code presented at the entrance
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"ticket id"
+//	@Success		200	{object}	TicketPassResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/tickets/{id}/pass [get]
+func (app *Application) getTicketPassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	p, err := app.storage.Transfers.GetPass(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if p == nil {
+		writeNotFound(w)
+		return
+	}
+	if p.UserID != u.ID {
+		writeForbidden(w)
+		return
+	}
+	writeJSON(TicketPassResponse{Pass: p}, http.StatusOK, w)
+}
+
+type TicketTransferResponse struct {
+	Transfer *internal.TicketTransfer `json:"transfer"`
+}
+
+// transferTicketHandler godoc
+//
+//	@Summary		Transfers a ticket
+//	@Description	sends a claim token for a sold ticket to a given email, the ticket changes hands once it's claimed
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"ticket id"
+//	@Param			email	body		string	true	"recipient email"
+//	@Success		201		{object}	TicketTransferResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		403		{object}	ResponseError
+//	@Failure		404		{object}	ResponseMessage
+//	@Failure		409		{object}	ResponseMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/tickets/{id}/transfer [post]
+func (app *Application) transferTicketHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		Email *string `json:"email"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.CheckEmail(req.Email)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	if strings.EqualFold(*req.Email, u.Email) {
+		writeJSON(ResponseMessage{Message: "you can't transfer a ticket to yourself"}, http.StatusConflict, w)
+		return
+	}
+	p, err := app.storage.Transfers.GetPass(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if p == nil {
+		writeNotFound(w)
+		return
+	}
+	if p.UserID != u.ID {
+		writeForbidden(w)
+		return
+	}
+	settings, err := app.storage.Settings.GetForTicket(p.TicketID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if settings == nil {
+		writeNotFound(w)
+		return
+	}
+	if !settings.AllowTicketTransfers {
+		writeJSON(ResponseMessage{Message: "the cinema doesn't allow ticket transfers"}, http.StatusConflict, w)
+		return
+	}
+	t, err := app.storage.Tickets.GetByID(p.TicketID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	s, err := app.storage.Schedules.GetByID(t.ScheduleID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if time.Now().After(s.StartsAt) {
+		writeJSON(ResponseMessage{Message: "can't transfer ticket because movie already started"}, http.StatusConflict, w)
+		return
+	}
+	listing, err := app.storage.Resales.GetActiveForTicket(p.TicketID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if listing != nil {
+		writeJSON(ResponseMessage{Message: "can't transfer ticket while it's listed for resale"}, http.StatusConflict, w)
+		return
+	}
+	token := internal.GenerateToken()
+	transfer, err := app.storage.Transfers.Create(p.TicketID, u.ID, *req.Email, token, 3*24*time.Hour)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	data := map[string]any{
+		"sender":    u.Name,
+		"ticketID":  transfer.TicketID,
+		"token":     token,
+		"expiresAt": transfer.ExpiresAt.Format(time.RFC1123),
+	}
+	app.Go(app.SendMail(transfer.ToEmail, TicketTransferTmpl, data))
+	writeJSON(TicketTransferResponse{Transfer: transfer}, http.StatusCreated, w)
+}
+
+type GetTicketTransfersResponse struct {
+	Transfers []internal.TicketTransfer `json:"transfers"`
+}
+
+// getTicketTransfersHandler godoc
+//
+//	@Summary		Gets the transfer history
+//	@Description	gets the transfer history of a sold ticket
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"ticket id"
+//	@Success		200	{object}	GetTicketTransfersResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/tickets/{id}/transfers [get]
+func (app *Application) getTicketTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	p, err := app.storage.Transfers.GetPass(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if p == nil {
+		writeNotFound(w)
+		return
+	}
+	if p.UserID != u.ID {
+		writeForbidden(w)
+		return
+	}
+	transfers, err := app.storage.Transfers.GetAllForTicket(p.TicketID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetTicketTransfersResponse{Transfers: transfers}, http.StatusOK, w)
+}
+
+// claimTicketTransferHandler godoc
+//
+//	@Summary		Claims a ticket transfer
+//	@Description	claims a ticket sent to the user's email, the previous owner's pass is invalidated
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	body		string	true	"transfer token"
+//	@Success		200		{object}	TicketTransferResponse
+//	@Failure		400		{object}	ResponseError
+//	@Failure		404		{object}	ResponseMessage
+//	@Failure		500		{object}	ResponseError
+//	@Router			/ticket-transfers/claim [post]
+func (app *Application) claimTicketTransferHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.Token != "", "token", "must be provided")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	transfer, err := app.storage.Transfers.Accept(req.Token, u)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if transfer == nil {
+		writeJSON(ResponseMessage{Message: "invalid or expired token"}, http.StatusNotFound, w)
+		return
+	}
+	writeJSON(TicketTransferResponse{Transfer: transfer}, http.StatusOK, w)
+}
+
+// cancelTicketTransferHandler godoc
+//
+//	@Summary		Cancels a ticket transfer
+//	@Description	cancels a pending ticket transfer
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"transfer id"
+//	@Success		200	{object}	TicketTransferResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/ticket-transfers/{id}/cancel [post]
+func (app *Application) cancelTicketTransferHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	transfer, err := app.storage.Transfers.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if transfer == nil {
+		writeNotFound(w)
+		return
+	}
+	if transfer.FromUserID != u.ID {
+		writeForbidden(w)
+		return
+	}
+	if transfer.StatusID != internal.TicketTransferStatusPending {
+		writeJSON(ResponseMessage{Message: "transfer is not pending"}, http.StatusConflict, w)
+		return
+	}
+	err = app.storage.Transfers.Cancel(transfer)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(TicketTransferResponse{Transfer: transfer}, http.StatusOK, w)
+}
@@ -133,24 +133,40 @@ func (app *Application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 }
 
 type CreateAuthenticationTokenResponse struct {
-	Token string `json:"token"`
+	Token                 string    `json:"token"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+func newAuthenticationTokenResponse(t *internal.SessionTokens) CreateAuthenticationTokenResponse {
+	return CreateAuthenticationTokenResponse{
+		Token:                 t.AccessToken,
+		ExpiresAt:             t.AccessTokenExpiresAt,
+		RefreshToken:          t.RefreshToken,
+		RefreshTokenExpiresAt: t.RefreshTokenExpiresAt,
+	}
 }
 
 // createAuthenticationTokenHandler godoc
 //
 //	@Summary		Creates an auth token
-//	@Description	creates an auth token
+//	@Description	creates an auth token, the users with the two-factor authentication get a challenge instead
+//	@Description	to complete at /tokens/two-factor. The users who are required to have it get the enrollment with the challenge.
+//	@Description	Too many failed logins delay the next ones and then lock the account or the IP for a while
 //	@Tags			tokens
 //	@Accept			json
 //	@Produce		json
 //	@Param			email		body		string	true	"email"
 //	@Param			password	body		string	true	"password"
-//
-// @Success		201			{object}	CreateAuthenticationTokenResponse
-// @Failure		400			{object}	ViolationsMessage
-// @Failure		409			{object}	ResponseMessage
-// @Failure		500			{object}	ResponseError
-// @Router			/tokens/authentication [post]
+//	@Success		201			{object}	CreateAuthenticationTokenResponse
+//	@Success		202			{object}	TwoFactorChallengeResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		401			{object}	ResponseError
+//	@Failure		409			{object}	ResponseMessage
+//	@Failure		429			{object}	ResponseError
+//	@Failure		500			{object}	ResponseError
+//	@Router			/tokens/authentication [post]
 func (app *Application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Email    *string `json:"email"`
@@ -167,33 +183,139 @@ func (app *Application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		writeErrors(v, w)
 		return
 	}
+	a, ok := app.attemptLogin(*req.Email, getSessionClient(r).IP, w)
+	if !ok {
+		return
+	}
 	u, err := app.storage.Users.GetByEmail(*req.Email)
 	if err != nil {
 		writeServerErr(err, w)
 		return
 	}
-	if u == nil {
+	if u == nil || bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(*req.Password)) != nil {
+		app.failLogin(a, u)
 		writeError(errors.New("invalid credentials"), http.StatusUnauthorized, w)
 		return
 	}
-	if bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(*req.Password)) != nil {
-		writeError(errors.New("invalid credentials"), http.StatusUnauthorized, w)
+	err = app.passLogin(a)
+	if err != nil {
+		writeServerErr(err, w)
 		return
 	}
+	// the failures are kept until the tokens are issued so the two-factor codes can't be guessed with fresh challenges
+	if app.writeLogin(u, w, r) {
+		if err := app.unlockLogin(*req.Email); err != nil {
+			log.Println(err)
+		}
+	}
+}
 
-	err = app.storage.Tokens.DeleteAll(u.ID, []internal.TokenScope{internal.TokenScopeAuthentication})
+// writeLogin signs the user in once the user proved who they are, the users with the two-factor authentication
+// get a challenge instead of the tokens. It reports whether the tokens were issued.
+func (app *Application) writeLogin(u *internal.User, w http.ResponseWriter, r *http.Request) bool {
+	if u.SuspendedAt != nil {
+		writeError(errors.New("account is suspended"), http.StatusForbidden, w)
+		return false
+	}
+	if u.PasswordResetRequired {
+		writeError(errors.New("password must be reset, a password-reset token was sent to your email"), http.StatusForbidden, w)
+		return false
+	}
+	tf, err := app.storage.TwoFactor.Get(u.ID)
 	if err != nil {
 		writeServerErr(err, w)
-		return
+		return false
+	}
+	if tf != nil && tf.ConfirmedAt != nil {
+		app.writeTwoFactorChallenge(u, tf, w)
+		return false
+	}
+	required, err := app.isTwoFactorRequired(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return false
+	}
+	if required {
+		app.writeTwoFactorChallenge(u, tf, w)
+		return false
 	}
 
-	token := internal.GenerateToken()
-	_, err = app.storage.Tokens.Create(u.ID, internal.TokenScopeAuthentication, token, 24*time.Hour)
+	t, err := app.storage.Tokens.CreateSession(u.ID, getSessionClient(r), app.storedAccessTokenDuration(), app.config.tokens.refreshDuration)
+	if err != nil {
+		writeServerErr(err, w)
+		return false
+	}
+	err = app.signAccessToken(u, t)
 	if err != nil {
 		writeServerErr(err, w)
+		return false
+	}
+	writeJSON(newAuthenticationTokenResponse(t), http.StatusCreated, w)
+	return true
+}
+
+// refreshAuthenticationTokenHandler godoc
+//
+//	@Summary		Refreshes an auth token
+//	@Description	exchanges a refresh token for a new auth token and a new refresh token,
+//	@Description	a refresh token can only be used once and using it again logs the session out
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			refresh_token	body		string	true	"refresh token"
+//	@Success		201				{object}	CreateAuthenticationTokenResponse
+//	@Failure		400				{object}	ViolationsMessage
+//	@Failure		401				{object}	ResponseError
+//	@Failure		500				{object}	ResponseError
+//	@Router			/tokens/refresh [post]
+func (app *Application) refreshAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken *string `json:"refresh_token"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.RefreshToken != nil && *req.RefreshToken != "", "refresh_token", "must be provided")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	t, err := app.storage.Tokens.Refresh(*req.RefreshToken, getSessionClient(r), app.storedAccessTokenDuration(), app.config.tokens.refreshDuration)
+	if err != nil {
+		if errors.Is(err, internal.ErrRefreshTokenReused) {
+			if err := app.revokeAccessTokens(t.UserID, &t.SessionID); err != nil {
+				writeServerErr(err, w)
+				return
+			}
+			writeError(err, http.StatusUnauthorized, w)
+			return
+		}
+		writeServerErr(err, w)
+		return
+	}
+	if t == nil {
+		writeError(errors.New("invalid token"), http.StatusUnauthorized, w)
 		return
 	}
-	writeJSON(CreateAuthenticationTokenResponse{Token: token}, http.StatusCreated, w)
+	if app.jwt != nil {
+		u, err := app.storage.Users.GetByID(t.UserID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if u == nil {
+			writeError(errors.New("invalid token"), http.StatusUnauthorized, w)
+			return
+		}
+		err = app.signAccessToken(u, t)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+	}
+	writeJSON(newAuthenticationTokenResponse(t), http.StatusCreated, w)
 }
 
 // createPasswordResetTokenHandler godoc
@@ -301,7 +423,7 @@ func (app *Application) resetPasswordHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.storage.Tokens.DeleteAll(u.ID, []internal.TokenScope{internal.TokenScopePasswordReset, internal.TokenScopeAuthentication})
+	err = app.storage.Tokens.DeleteAll(u.ID, []internal.TokenScope{internal.TokenScopePasswordReset, internal.TokenScopeAuthentication, internal.TokenScopeRefresh})
 	if err != nil {
 		writeServerErr(err, w)
 		return
@@ -314,11 +436,25 @@ func (app *Application) resetPasswordHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	u.PasswordHash = passwordHash
+	u.PasswordResetRequired = false
 	err = app.storage.Users.Update(u)
 	if err != nil {
 		writeServerErr(err, w)
 		return
 	}
 
+	// resetting the password proves the user owns the email so the account is unlocked
+	err = app.unlockLogin(u.Email)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+
+	err = app.revokeAccessTokens(u.ID, nil)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+
 	writeJSON(ResponseMessage{Message: "password was reset"}, http.StatusOK, w)
 }
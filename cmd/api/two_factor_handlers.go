@@ -0,0 +1,547 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+const (
+	twoFactorChallengeDuration = 5 * time.Minute
+	recoveryCodesCount         = 10
+)
+
+// isTwoFactorRequired reports whether the user holds one of the roles that can't sign in with only a password,
+// the cinemas' owners hold the cinema-owner role and their staff the cinema-staff role.
+func (app *Application) isTwoFactorRequired(userID int64) (bool, error) {
+	if len(app.config.twoFactor.requiredRoles) == 0 {
+		return false, nil
+	}
+	roles, err := app.storage.Permissions.GetRoles(userID)
+	if err != nil {
+		return false, err
+	}
+	staffRoles, err := app.storage.CinemaMembers.GetStaffRoles(userID)
+	if err != nil {
+		return false, err
+	}
+	roles = append(roles, staffRoles...)
+	for _, role := range roles {
+		if slices.Contains(app.config.twoFactor.requiredRoles, role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+func (app *Application) newTwoFactorEnrollmentResponse(u *internal.User, tf *internal.TwoFactor) *TwoFactorEnrollmentResponse {
+	return &TwoFactorEnrollmentResponse{
+		Secret:          internal.EncodeTOTPSecret(tf.Secret),
+		ProvisioningURI: internal.TOTPProvisioningURI(app.config.twoFactor.issuer, u.Email, tf.Secret),
+	}
+}
+
+type TwoFactorChallengeResponse struct {
+	Challenge  string                       `json:"challenge"`
+	ExpiresAt  time.Time                    `json:"expires_at"`
+	Enrollment *TwoFactorEnrollmentResponse `json:"enrollment,omitempty"`
+}
+
+// writeTwoFactorChallenge answers a login with a challenge instead of the tokens, the user has to complete it with a code.
+// The users who are required to have the two-factor authentication and haven't enrolled get the enrollment with the challenge.
+func (app *Application) writeTwoFactorChallenge(u *internal.User, tf *internal.TwoFactor, w http.ResponseWriter) {
+	var enrollment *TwoFactorEnrollmentResponse
+	if tf == nil || tf.ConfirmedAt == nil {
+		var err error
+		tf, err = app.storage.TwoFactor.Enroll(u.ID, internal.GenerateTOTPSecret())
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if tf == nil {
+			writeError(errors.New("two-factor authentication was enabled at the same time, try again"), http.StatusConflict, w)
+			return
+		}
+		enrollment = app.newTwoFactorEnrollmentResponse(u, tf)
+	}
+	err := app.storage.Tokens.DeleteAll(u.ID, []internal.TokenScope{internal.TokenScopeTwoFactor})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	challenge := internal.GenerateToken()
+	t, err := app.storage.Tokens.Create(u.ID, internal.TokenScopeTwoFactor, challenge, twoFactorChallengeDuration)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	res := TwoFactorChallengeResponse{
+		Challenge:  challenge,
+		ExpiresAt:  t.ExpiresAt,
+		Enrollment: enrollment,
+	}
+	writeJSON(res, http.StatusAccepted, w)
+}
+
+// verifyTwoFactor checks a code from the authenticator or a recovery code against the confirmed enrollment,
+// both can only be used once.
+func (app *Application) verifyTwoFactor(tf *internal.TwoFactor, code *string, recoveryCode *string) (bool, error) {
+	if tf == nil || tf.ConfirmedAt == nil {
+		return false, nil
+	}
+	if recoveryCode != nil {
+		return app.storage.TwoFactor.UseRecoveryCode(tf.UserID, *recoveryCode)
+	}
+	step, ok := internal.ValidateTOTP(tf.Secret, *code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return app.storage.TwoFactor.UseStep(tf.UserID, step)
+}
+
+func checkTwoFactorCodes(v *Validator, code *string, recoveryCode *string) {
+	v.Check((code == nil) != (recoveryCode == nil), "code", "either code or recovery_code must be provided")
+	if code != nil {
+		v.Check(*code != "", "code", "must be provided")
+	}
+	if recoveryCode != nil {
+		v.Check(*recoveryCode != "", "recovery_code", "must be provided")
+	}
+}
+
+type TwoFactorAuthenticationResponse struct {
+	CreateAuthenticationTokenResponse
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// createTwoFactorAuthenticationTokenHandler godoc
+//
+//	@Summary		Completes a two-factor login
+//	@Description	exchanges the challenge of a login and a code from the authenticator or a recovery code for an auth token,
+//	@Description	a challenge can only be tried once. Completing an enrollment returns the recovery codes.
+//	@Description	Wrong codes count against the same failed logins as the wrong passwords
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			challenge		body		string	true	"challenge"
+//	@Param			code			body		string	false	"code from the authenticator"
+//	@Param			recovery_code	body		string	false	"recovery code"
+//	@Success		201				{object}	TwoFactorAuthenticationResponse
+//	@Failure		400				{object}	ViolationsMessage
+//	@Failure		401				{object}	ResponseError
+//	@Failure		429				{object}	ResponseError
+//	@Failure		500				{object}	ResponseError
+//	@Router			/tokens/two-factor [post]
+func (app *Application) createTwoFactorAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Challenge    *string `json:"challenge"`
+		Code         *string `json:"code"`
+		RecoveryCode *string `json:"recovery_code"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.Challenge != nil && *req.Challenge != "", "challenge", "must be provided")
+	checkTwoFactorCodes(v, req.Code, req.RecoveryCode)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u, err := app.storage.Tokens.GetUser(internal.TokenScopeTwoFactor, *req.Challenge)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeError(errors.New("invalid or expired challenge"), http.StatusUnauthorized, w)
+		return
+	}
+	// the codes count against the same failed logins as the passwords
+	a, ok := app.attemptLogin(u.Email, getSessionClient(r).IP, w)
+	if !ok {
+		return
+	}
+	// the challenge is used up even with a wrong code so the codes can't be guessed with it
+	err = app.storage.Tokens.DeleteAll(u.ID, []internal.TokenScope{internal.TokenScopeTwoFactor})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	tf, err := app.storage.TwoFactor.Get(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if tf == nil {
+		writeError(errors.New("invalid or expired challenge"), http.StatusUnauthorized, w)
+		return
+	}
+
+	var recoveryCodes []string
+	if tf.ConfirmedAt == nil && req.Code != nil {
+		step, ok := internal.ValidateTOTP(tf.Secret, *req.Code, time.Now())
+		if !ok {
+			app.failLogin(a, u)
+			writeError(errors.New("invalid code"), http.StatusUnauthorized, w)
+			return
+		}
+		recoveryCodes = internal.GenerateRecoveryCodes(recoveryCodesCount)
+		confirmed, err := app.storage.TwoFactor.Confirm(u.ID, step, recoveryCodes)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if !confirmed {
+			app.failLogin(a, u)
+			writeError(errors.New("invalid code"), http.StatusUnauthorized, w)
+			return
+		}
+	} else {
+		ok, err := app.verifyTwoFactor(tf, req.Code, req.RecoveryCode)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if !ok {
+			app.failLogin(a, u)
+			writeError(errors.New("invalid code"), http.StatusUnauthorized, w)
+			return
+		}
+	}
+	err = app.passLogin(a)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+
+	t, err := app.storage.Tokens.CreateSession(u.ID, getSessionClient(r), app.storedAccessTokenDuration(), app.config.tokens.refreshDuration)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.signAccessToken(u, t)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	res := TwoFactorAuthenticationResponse{
+		CreateAuthenticationTokenResponse: newAuthenticationTokenResponse(t),
+		RecoveryCodes:                     recoveryCodes,
+	}
+	writeJSON(res, http.StatusCreated, w)
+	if err := app.unlockLogin(u.Email); err != nil {
+		log.Println(err)
+	}
+}
+
+type GetTwoFactorResponse struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// getTwoFactorHandler godoc
+//
+//	@Summary		Gets the user's two-factor authentication
+//	@Description	gets whether the two-factor authentication is enabled, whether it's required and how many recovery codes are left
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	GetTwoFactorResponse
+//	@Failure		500	{object}	ResponseError
+//	@Router			/users/me/two-factor [get]
+func (app *Application) getTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	tf, err := app.storage.TwoFactor.Get(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	required, err := app.isTwoFactorRequired(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	res := GetTwoFactorResponse{
+		Enabled:  tf != nil && tf.ConfirmedAt != nil,
+		Required: required,
+	}
+	if res.Enabled {
+		res.RecoveryCodesLeft, err = app.storage.TwoFactor.CountRecoveryCodes(u.ID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+	}
+	writeJSON(res, http.StatusOK, w)
+}
+
+// enrollTwoFactorHandler godoc
+//
+//	@Summary		Starts a two-factor enrollment
+//	@Description	creates a TOTP secret with the URI to show as a QR code for the authenticator,
+//	@Description	the two-factor authentication is enabled once it's confirmed with a code
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	TwoFactorEnrollmentResponse
+//	@Failure		409	{object}	ResponseError
+//	@Failure		500	{object}	ResponseError
+//	@Router			/users/me/two-factor [post]
+func (app *Application) enrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	tf, err := app.storage.TwoFactor.Enroll(u.ID, internal.GenerateTOTPSecret())
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if tf == nil {
+		writeError(errors.New("two-factor authentication is already enabled"), http.StatusConflict, w)
+		return
+	}
+	writeJSON(app.newTwoFactorEnrollmentResponse(u, tf), http.StatusCreated, w)
+}
+
+type TwoFactorRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTwoFactorHandler godoc
+//
+//	@Summary		Enables the two-factor authentication
+//	@Description	confirms the enrollment with a code from the authenticator and returns the recovery codes,
+//	@Description	they're only shown once
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		string	true	"code from the authenticator"
+//	@Success		200		{object}	TwoFactorRecoveryCodesResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		401		{object}	ResponseError
+//	@Failure		409		{object}	ResponseError
+//	@Failure		500		{object}	ResponseError
+//	@Router			/users/me/two-factor [put]
+func (app *Application) confirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code *string `json:"code"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.Code != nil && *req.Code != "", "code", "must be provided")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	tf, err := app.storage.TwoFactor.Get(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if tf == nil {
+		writeError(errors.New("two-factor enrollment wasn't started"), http.StatusConflict, w)
+		return
+	}
+	if tf.ConfirmedAt != nil {
+		writeError(errors.New("two-factor authentication is already enabled"), http.StatusConflict, w)
+		return
+	}
+	step, ok := internal.ValidateTOTP(tf.Secret, *req.Code, time.Now())
+	if !ok {
+		writeError(errors.New("invalid code"), http.StatusUnauthorized, w)
+		return
+	}
+	recoveryCodes := internal.GenerateRecoveryCodes(recoveryCodesCount)
+	confirmed, err := app.storage.TwoFactor.Confirm(u.ID, step, recoveryCodes)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if !confirmed {
+		writeError(errors.New("two-factor authentication is already enabled"), http.StatusConflict, w)
+		return
+	}
+	writeJSON(TwoFactorRecoveryCodesResponse{RecoveryCodes: recoveryCodes}, http.StatusOK, w)
+}
+
+// disableTwoFactorHandler godoc
+//
+//	@Summary		Disables the two-factor authentication
+//	@Description	disables the two-factor authentication with a code from the authenticator or a recovery code,
+//	@Description	it can't be disabled by the users who are required to have it, the wrong codes count as failed logins
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			code			body		string	false	"code from the authenticator"
+//	@Param			recovery_code	body		string	false	"recovery code"
+//	@Success		200				{object}	ResponseMessage
+//	@Failure		400				{object}	ViolationsMessage
+//	@Failure		401				{object}	ResponseError
+//	@Failure		409				{object}	ResponseError
+//	@Failure		429				{object}	ResponseError
+//	@Failure		500				{object}	ResponseError
+//	@Router			/users/me/two-factor [delete]
+func (app *Application) disableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code         *string `json:"code"`
+		RecoveryCode *string `json:"recovery_code"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	checkTwoFactorCodes(v, req.Code, req.RecoveryCode)
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	tf, err := app.storage.TwoFactor.Get(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if tf == nil || tf.ConfirmedAt == nil {
+		writeError(errors.New("two-factor authentication isn't enabled"), http.StatusConflict, w)
+		return
+	}
+	required, err := app.isTwoFactorRequired(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if required {
+		writeError(errors.New("two-factor authentication is required for your roles"), http.StatusConflict, w)
+		return
+	}
+	// the codes count against the failed logins so they can't be guessed with a stolen session
+	a, ok := app.attemptLogin(u.Email, getSessionClient(r).IP, w)
+	if !ok {
+		return
+	}
+	ok, err = app.verifyTwoFactor(tf, req.Code, req.RecoveryCode)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if !ok {
+		app.failLogin(a, u)
+		writeError(errors.New("invalid code"), http.StatusUnauthorized, w)
+		return
+	}
+	err = app.passLogin(a)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	err = app.storage.TwoFactor.Delete(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "two-factor authentication was disabled"}, http.StatusOK, w)
+}
+
+// regenerateRecoveryCodesHandler godoc
+//
+//	@Summary		Regenerates the recovery codes
+//	@Description	replaces the recovery codes with new ones using a code from the authenticator, the old ones stop working,
+//	@Description	the wrong codes count as failed logins
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		string	true	"code from the authenticator"
+//	@Success		201		{object}	TwoFactorRecoveryCodesResponse
+//	@Failure		400		{object}	ViolationsMessage
+//	@Failure		401		{object}	ResponseError
+//	@Failure		409		{object}	ResponseError
+//	@Failure		429		{object}	ResponseError
+//	@Failure		500		{object}	ResponseError
+//	@Router			/users/me/two-factor/recovery-codes [post]
+func (app *Application) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code *string `json:"code"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.Code != nil && *req.Code != "", "code", "must be provided")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	tf, err := app.storage.TwoFactor.Get(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if tf == nil || tf.ConfirmedAt == nil {
+		writeError(errors.New("two-factor authentication isn't enabled"), http.StatusConflict, w)
+		return
+	}
+	// the codes count against the failed logins so they can't be guessed with a stolen session
+	a, ok := app.attemptLogin(u.Email, getSessionClient(r).IP, w)
+	if !ok {
+		return
+	}
+	ok, err = app.verifyTwoFactor(tf, req.Code, nil)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if !ok {
+		app.failLogin(a, u)
+		writeError(errors.New("invalid code"), http.StatusUnauthorized, w)
+		return
+	}
+	err = app.passLogin(a)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	recoveryCodes := internal.GenerateRecoveryCodes(recoveryCodesCount)
+	err = app.storage.TwoFactor.SetRecoveryCodes(u.ID, recoveryCodes)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(TwoFactorRecoveryCodesResponse{RecoveryCodes: recoveryCodes}, http.StatusCreated, w)
+}
@@ -75,6 +75,11 @@ func (app *Application) createUserHandler(w http.ResponseWriter, r *http.Request
 		writeServerErr(err, w)
 		return
 	}
+	err = app.storage.Permissions.GrantRoles(user.ID, []string{internal.RoleCustomer})
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
 
 	token := internal.GenerateToken()
 	_, err = app.storage.Tokens.Create(user.ID, internal.TokenScopeActivation, token, 10*time.Minute)
@@ -122,6 +127,16 @@ func (app *Application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		writeForbidden(w)
 		return
 	}
+	// the user of a signed access token only has the token's claims
+	u, err = app.storage.Users.GetByID(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
 	writeJSON(GetUserResponse{User: u}, http.StatusOK, w)
 }
 
@@ -176,6 +191,17 @@ func (app *Application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// the user of a signed access token only has the token's claims
+	u, err = app.storage.Users.GetByID(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if u == nil {
+		writeNotFound(w)
+		return
+	}
+
 	if req.Name != nil {
 		u.Name = *req.Name
 	}
@@ -224,5 +250,10 @@ func (app *Application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		writeServerErr(err, w)
 		return
 	}
+	err = app.revokeAccessTokens(u.ID, nil)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
 	writeJSON(ResponseMessage{Message: "user delete successfully"}, http.StatusOK, w)
 }
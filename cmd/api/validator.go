@@ -2,6 +2,9 @@ package main
 
 import (
 	"regexp"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
 )
 
 var EmailRegexp = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
@@ -49,6 +52,68 @@ func (v *Validator) CheckPassword(password *string) {
 	}
 }
 
+func (v *Validator) CheckFee(f *internal.Fee) {
+	v.Check(f.Name != "", "name", "must be provided")
+	v.Check(f.Kind == internal.FeeKindFee || f.Kind == internal.FeeKindTax, "kind", "must be 0 (fee) or 1 (tax)")
+	v.Check(f.Basis == internal.FeeBasisTicket || f.Basis == internal.FeeBasisOrder, "basis", "must be 0 (ticket) or 1 (order)")
+	v.Check(f.Rate.GreaterThanOrEqual(decimal.Zero) && f.Rate.LessThanOrEqual(decimal.NewFromInt(100)), "rate", "must be between 0 and 100")
+	v.Check(f.Amount.GreaterThanOrEqual(decimal.Zero), "amount", "must be greater than or equal to zero")
+	if f.Kind == internal.FeeKindTax {
+		v.Check(f.Amount.IsZero(), "amount", "taxes must only have a rate")
+	} else {
+		v.Check(!f.IsInclusive, "is_inclusive", "only taxes can be inclusive")
+	}
+	v.Check(!f.Rate.IsZero() || !f.Amount.IsZero(), "rate or amount", "must be provided")
+}
+
+func (v *Validator) CheckCinemaSettings(s *internal.CinemaSettings) {
+	if s.TicketLockSeconds != nil {
+		v.Check(*s.TicketLockSeconds >= 60 && *s.TicketLockSeconds <= 3600, "ticket_lock_seconds", "must be between 60 and 3600")
+	}
+	if s.CheckoutSessionSeconds != nil {
+		d := s.CheckoutSessionDuration(0)
+		v.Check(d >= minCheckoutSessionDuration && d <= maxCheckoutSessionDuration, "checkout_session_seconds", "must be between 1800 and 86400")
+	}
+	// the proceeds are refunded to the seller's payment which can't be refunded more than it was charged
+	if s.ResalePriceCap != nil {
+		v.Check(s.ResalePriceCap.GreaterThan(decimal.Zero) && s.ResalePriceCap.LessThanOrEqual(decimal.NewFromInt(100)), "resale_price_cap", "must be greater than 0 and at most 100")
+	}
+	v.Check(s.ResaleFeeRate.GreaterThanOrEqual(decimal.Zero) && s.ResaleFeeRate.LessThan(decimal.NewFromInt(100)), "resale_fee_rate", "must be between 0 and 100")
+}
+
+func (v *Validator) CheckGiftCardAmount(amount *decimal.Decimal) {
+	v.Check(amount != nil, "amount", "must be provided")
+	if amount == nil {
+		return
+	}
+	v.Check(amount.GreaterThanOrEqual(minGiftCardAmount) && amount.LessThanOrEqual(maxGiftCardAmount), "amount", "must be between 5 and 500")
+	v.Check(amount.Exponent() >= -2, "amount", "must have at most 2 decimal places")
+}
+
+func (v *Validator) CheckMembershipPlan(p *internal.MembershipPlan) {
+	v.Check(p.Name != "", "name", "must be provided")
+	v.Check(len(p.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(p.Price.GreaterThanOrEqual(minCardAmount) && p.Price.LessThanOrEqual(decimal.NewFromInt(1000)), "price", "must be between 0.50 and 1000")
+	v.Check(p.Price.Exponent() >= -2, "price", "must have at most 2 decimal places")
+	if p.TicketsPerPeriod != nil {
+		v.Check(*p.TicketsPerPeriod > 0 && *p.TicketsPerPeriod <= 100, "tickets_per_period", "must be between 1 and 100")
+	}
+}
+
+func (v *Validator) CheckProduct(p *internal.Product) {
+	v.Check(p.Name != "", "name", "must be provided")
+	v.Check(len(p.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(p.Category >= internal.ProductCategorySnack && p.Category <= internal.ProductCategoryMerchandise, "category", "must be 0 (snack), 1 (drink), 2 (combo) or 3 (merchandise)")
+	v.Check(p.Price.IsPositive() && p.Price.LessThanOrEqual(decimal.NewFromInt(1000)), "price", "must be greater than 0 and at most 1000")
+	v.Check(p.Price.Exponent() >= -2, "price", "must have at most 2 decimal places")
+	v.Check(p.Stock >= 0 && p.Stock <= 1_000_000, "stock", "must be between 0 and 1_000_000")
+}
+
+func (v *Validator) CheckCashAmount(key string, amount decimal.Decimal) {
+	v.Check(amount.GreaterThanOrEqual(decimal.Zero) && amount.LessThanOrEqual(decimal.NewFromInt(100_000)), key, "must be between 0 and 100_000")
+	v.Check(amount.Exponent() >= -2, key, "must have at most 2 decimal places")
+}
+
 func (v *Validator) HasErrors() bool {
 	return len(v.violations) != 0
 }
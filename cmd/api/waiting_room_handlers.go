@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+type WaitingRoomResponse struct {
+	WaitingRoom *internal.WaitingRoom `json:"waiting_room"`
+}
+
+// setWaitingRoomHandler godoc
+//
+//	@Summary		Opens a waiting room
+//	@Description	opens or updates the waiting room of a given schedule
+//	@Tags			waiting-rooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		int	true	"schedule id"
+//	@Param			batch_size			body		int	true	"number of users admitted at the same time"
+//	@Param			admission_seconds	body		int	true	"how long an admission lasts in seconds"
+//	@Success		200					{object}	WaitingRoomResponse
+//	@Failure		400					{object}	ViolationsMessage
+//	@Failure		403					{object}	ResponseError
+//	@Failure		404					{object}	ResponseMessage
+//	@Failure		500					{object}	ResponseError
+//	@Router			/schedules/{id}/waiting-room [put]
+func (app *Application) setWaitingRoomHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	var req struct {
+		BatchSize        int32 `json:"batch_size"`
+		AdmissionSeconds int32 `json:"admission_seconds"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	v := NewValidator()
+	v.Check(req.BatchSize > 0 && req.BatchSize <= 10_000, "batch_size", "must be between 1 and 10_000")
+	v.Check(req.AdmissionSeconds >= 60 && req.AdmissionSeconds <= 3600, "admission_seconds", "must be between 60 and 3600")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	s, err := app.storage.Schedules.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if s == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkScheduleScope(s, u, internal.CinemaScopeSchedules, w) {
+		return
+	}
+	room := &internal.WaitingRoom{
+		ScheduleID:       s.ID,
+		BatchSize:        req.BatchSize,
+		AdmissionSeconds: req.AdmissionSeconds,
+	}
+	err = app.storage.WaitingRooms.Set(room)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(WaitingRoomResponse{WaitingRoom: room}, http.StatusOK, w)
+}
+
+// getWaitingRoomHandler godoc
+//
+//	@Summary		Gets a waiting room
+//	@Description	gets the waiting room of a given schedule
+//	@Tags			waiting-rooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"schedule id"
+//	@Success		200	{object}	WaitingRoomResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/schedules/{id}/waiting-room [get]
+func (app *Application) getWaitingRoomHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	room, err := app.storage.WaitingRooms.Get(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if room == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(WaitingRoomResponse{WaitingRoom: room}, http.StatusOK, w)
+}
+
+// deleteWaitingRoomHandler godoc
+//
+//	@Summary		Closes a waiting room
+//	@Description	closes the waiting room of a given schedule and drops its queue
+//	@Tags			waiting-rooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"schedule id"
+//	@Success		200	{object}	ResponseMessage
+//	@Failure		400	{object}	ResponseError
+//	@Failure		403	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/schedules/{id}/waiting-room [delete]
+func (app *Application) deleteWaitingRoomHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	s, err := app.storage.Schedules.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if s == nil {
+		writeNotFound(w)
+		return
+	}
+	if !app.checkScheduleScope(s, u, internal.CinemaScopeSchedules, w) {
+		return
+	}
+	err = app.storage.WaitingRooms.Delete(s.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "resource deleted successfully"}, http.StatusOK, w)
+}
+
+type JoinWaitingRoomResponse struct {
+	Entry          *internal.WaitingRoomEntry `json:"entry"`
+	AdmissionToken string                     `json:"admission_token"`
+}
+
+// joinWaitingRoomHandler godoc
+//
+//	@Summary		Joins a waiting room
+//	@Description	queues the user in the waiting room of a given schedule, the admission token must be sent in the X-Admission-Token header to lock tickets once admitted
+//	@Tags			waiting-rooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"schedule id"
+//	@Success		200	{object}	JoinWaitingRoomResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/schedules/{id}/waiting-room/join [post]
+func (app *Application) joinWaitingRoomHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	s, err := app.storage.Schedules.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if s == nil {
+		writeNotFound(w)
+		return
+	}
+	if time.Now().After(s.StartsAt) {
+		writeJSON(ResponseMessage{Message: "can't join the waiting room because movie already started"}, http.StatusConflict, w)
+		return
+	}
+	room, err := app.storage.WaitingRooms.Get(s.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if room == nil {
+		writeNotFound(w)
+		return
+	}
+	token := internal.GenerateToken()
+	entry, err := app.storage.WaitingRooms.Join(room.ScheduleID, u.ID, token)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(JoinWaitingRoomResponse{Entry: entry, AdmissionToken: token}, http.StatusOK, w)
+}
+
+type WaitingRoomPositionResponse struct {
+	Entry *internal.WaitingRoomEntry `json:"entry"`
+}
+
+// getWaitingRoomPositionHandler godoc
+//
+//	@Summary		Gets the queue position
+//	@Description	gets the position of the user in the waiting room of a given schedule, with "Accept: text/event-stream" the position is streamed until the user is admitted
+//	@Tags			waiting-rooms
+//	@Accept			json
+//	@Produce		json,text/event-stream
+//	@Param			id	path		int	true	"schedule id"
+//	@Success		200	{object}	WaitingRoomPositionResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/schedules/{id}/waiting-room/position [get]
+func (app *Application) getWaitingRoomPositionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	entry, err := app.storage.WaitingRooms.GetEntry(int64(id), u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if entry == nil || entry.IsExpired() {
+		writeNotFound(w)
+		return
+	}
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeJSON(WaitingRoomPositionResponse{Entry: entry}, http.StatusOK, w)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// the stream outlives the server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		data, err := json.Marshal(WaitingRoomPositionResponse{Entry: entry})
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: position\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		if entry.AdmittedAt != nil {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		case <-app.quit:
+			return
+		}
+		entry, err = app.storage.WaitingRooms.GetEntry(int64(id), u.ID)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if entry == nil {
+			fmt.Fprint(w, "event: removed\ndata: {}\n\n")
+			rc.Flush()
+			return
+		}
+	}
+}
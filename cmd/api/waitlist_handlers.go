@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+type WaitlistEntryResponse struct {
+	Entry *internal.WaitlistEntry `json:"entry"`
+}
+
+// joinWaitlistHandler godoc
+//
+//	@Summary		Joins a waitlist
+//	@Description	joins the waitlist of a sold out schedule, waitlisted users are notified by email when a seat is freed and it's reserved for them for a while
+//	@Tags			waitlists
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"schedule id"
+//	@Success		200	{object}	WaitlistEntryResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		409	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/schedules/{id}/waitlist [post]
+func (app *Application) joinWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	s, err := app.storage.Schedules.GetByID(int64(id))
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if s == nil {
+		writeNotFound(w)
+		return
+	}
+	if time.Now().After(s.StartsAt) {
+		writeJSON(ResponseMessage{Message: "can't join the waitlist because movie already started"}, http.StatusConflict, w)
+		return
+	}
+	entry, err := app.storage.Waitlists.GetEntry(s.ID, u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if entry == nil {
+		n, err := app.storage.Waitlists.CountAvailable(s.ID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+		if n != 0 {
+			writeJSON(ResponseMessage{Message: "schedule isn't sold out"}, http.StatusConflict, w)
+			return
+		}
+		entry, err = app.storage.Waitlists.Join(s.ID, u.ID)
+		if err != nil {
+			writeServerErr(err, w)
+			return
+		}
+	}
+	writeJSON(WaitlistEntryResponse{Entry: entry}, http.StatusOK, w)
+}
+
+// getWaitlistEntryHandler godoc
+//
+//	@Summary		Gets a waitlist entry
+//	@Description	gets the position of the user in the waitlist of a given schedule
+//	@Tags			waitlists
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"schedule id"
+//	@Success		200	{object}	WaitlistEntryResponse
+//	@Failure		400	{object}	ResponseError
+//	@Failure		404	{object}	ResponseMessage
+//	@Failure		500	{object}	ResponseError
+//	@Router			/schedules/{id}/waitlist [get]
+func (app *Application) getWaitlistEntryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	entry, err := app.storage.Waitlists.GetEntry(int64(id), u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	if entry == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(WaitlistEntryResponse{Entry: entry}, http.StatusOK, w)
+}
+
+// leaveWaitlistHandler godoc
+//
+//	@Summary		Leaves a waitlist
+//	@Description	removes the user from the waitlist of a given schedule
+//	@Tags			waitlists
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"schedule id"
+//	@Success		200	{object}	ResponseMessage
+//	@Failure		400	{object}	ResponseError
+//	@Failure		500	{object}	ResponseError
+//	@Router			/schedules/{id}/waitlist [delete]
+func (app *Application) leaveWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromPathValue(r)
+	if err != nil {
+		writeBadRequest(err, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	err = app.storage.Waitlists.Leave(int64(id), u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(ResponseMessage{Message: "left the waitlist successfully"}, http.StatusOK, w)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/shopspring/decimal"
+)
+
+type GetWalletResponse struct {
+	Balance  decimal.Decimal        `json:"balance"`
+	Entries  []internal.WalletEntry `json:"entries"`
+	MetaData *internal.MetaData     `json:"meta_data"`
+}
+
+// getWalletHandler godoc
+//
+//	@Summary		Gets the wallet
+//	@Description	gets the balance of the user's wallet and its ledger of credits and debits
+//	@Tags			wallets
+//	@Accept			json
+//	@Produce		json
+//	@Param			page		query		int	false	"page number"
+//	@Param			page_size	query		int	false	"page size"
+//	@Success		200			{object}	GetWalletResponse
+//	@Failure		400			{object}	ViolationsMessage
+//	@Failure		500			{object}	ResponseError
+//	@Router			/users/me/wallet [get]
+func (app *Application) getWalletHandler(w http.ResponseWriter, r *http.Request) {
+	v := NewValidator()
+	page := getQueryIntOr(r, "page", 1, v)
+	pageSize := getQueryIntOr(r, "page_size", 20, v)
+	v.Check(page > 0 && page <= 10_000_000, "page", "must be between 1 and 10_000_000")
+	v.Check(pageSize > 0 && pageSize <= 100, "page_size", "must be between 1 and 100")
+	if v.HasErrors() {
+		writeErrors(v, w)
+		return
+	}
+	u := getUserFromRequestContext(r)
+	if u == nil {
+		writeServerErr(errors.New("user is not authenticated"), w)
+		return
+	}
+	balance, err := app.storage.Wallets.GetBalance(u.ID)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	entries, metaData, err := app.storage.Wallets.GetEntries(u.ID, page, pageSize)
+	if err != nil {
+		writeServerErr(err, w)
+		return
+	}
+	writeJSON(GetWalletResponse{Balance: balance, Entries: entries, MetaData: metaData}, http.StatusOK, w)
+}
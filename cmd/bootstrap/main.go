@@ -0,0 +1,113 @@
+// bootstrap creates the first admin, it refuses to run once a user has the admin role
+// so the admins that follow are granted through the api.
+//
+//	BOOTSTRAP_ADMIN_PASSWORD='...' go run ./cmd/bootstrap -name admin -email admin@example.com
+//
+// an existing user with the email is made an admin and keeps the user's password.
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	name := flag.String("name", "admin", "name of the admin")
+	email := flag.String("email", "", "email of the admin")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("-email must be provided")
+	}
+
+	// the variables can also come from the environment like with `source .env`
+	_ = godotenv.Load()
+	dsn := os.Getenv("DB_DSN")
+	if dsn == "" {
+		log.Fatal("DB_DSN must be set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = db.PingContext(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	storage := internal.NewStorage(db, 5*time.Second)
+
+	exists, err := storage.Permissions.HasRoleHolder(internal.RoleAdmin)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if exists {
+		log.Fatal("an admin already exists, grant the admin role with the api instead")
+	}
+
+	u, err := storage.Users.GetByEmail(*email)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if u == nil {
+		password, err := readPassword()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(password) < 8 {
+			log.Fatal("password must be atleast 8 characters")
+		}
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Fatal(err)
+		}
+		u, err = storage.Users.Create(*name, *email, passwordHash)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if !u.IsActivated {
+		u.IsActivated = true
+		err = storage.Users.Update(u)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	err = storage.Permissions.GrantRoles(u.ID, []string{internal.RoleAdmin})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("user %d <%s> is an admin\n", u.ID, u.Email)
+}
+
+// readPassword reads the password of a new admin from BOOTSTRAP_ADMIN_PASSWORD
+// or from the first line of the standard input so it doesn't end up in the shell's history.
+func readPassword() (string, error) {
+	if password := os.Getenv("BOOTSTRAP_ADMIN_PASSWORD"); password != "" {
+		return password, nil
+	}
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read the password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
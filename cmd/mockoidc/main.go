@@ -0,0 +1,267 @@
+// mockoidc is a local OpenID Connect issuer to try the OIDC login without a real identity provider.
+// It signs everyone in without asking, the account is picked with the email, name, sub and email_verified
+// query parameters of the authorization URL.
+//
+//	go run ./cmd/mockoidc -addr :9000 -client-id mrs -client-secret mrs-secret
+//
+// with OIDC_PROVIDERS='mock' and OIDC_MOCK_ISSUER='http://localhost:9000'.
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const keyID = "mock"
+
+var encoding = base64.RawURLEncoding
+
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	subject       string
+	email         string
+	emailVerified bool
+	name          string
+	expiresAt     time.Time
+}
+
+type issuer struct {
+	url          string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+func main() {
+	log.SetFlags(0)
+
+	addr := flag.String("addr", ":9000", "address to listen on")
+	issuerURL := flag.String("issuer", "http://localhost:9000", "issuer url")
+	clientID := flag.String("client-id", "mrs", "client id")
+	clientSecret := flag.String("client-secret", "mrs-secret", "client secret, empty for a public client")
+	flag.Parse()
+
+	iss, err := newIssuer(*issuerURL, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Mock OIDC issuer %s is listening on %s\n", iss.url, *addr)
+	log.Fatal(http.ListenAndServe(*addr, iss.routes()))
+}
+
+func newIssuer(issuerURL string, clientID string, clientSecret string) (*issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	iss := &issuer{
+		url:          strings.TrimSuffix(issuerURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]authorization),
+	}
+	return iss, nil
+}
+
+func (iss *issuer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", iss.discoveryHandler)
+	mux.HandleFunc("GET /jwks", iss.jwksHandler)
+	mux.HandleFunc("GET /authorize", iss.authorizeHandler)
+	mux.HandleFunc("POST /token", iss.tokenHandler)
+	return mux
+}
+
+func writeJSON(src any, status int, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(src)
+}
+
+func writeError(code string, description string, w http.ResponseWriter) {
+	writeJSON(map[string]string{"error": code, "error_description": description}, http.StatusBadRequest, w)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return encoding.EncodeToString(b)
+}
+
+func (iss *issuer) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(map[string]any{
+		"issuer":                                iss.url,
+		"authorization_endpoint":                iss.url + "/authorize",
+		"token_endpoint":                        iss.url + "/token",
+		"jwks_uri":                              iss.url + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	}, http.StatusOK, w)
+}
+
+func (iss *issuer) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	pub := iss.key.PublicKey
+	writeJSON(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   encoding.EncodeToString(pub.N.Bytes()),
+			"e":   encoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	}, http.StatusOK, w)
+}
+
+func (iss *issuer) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" {
+		http.Error(w, "response_type must be code", http.StatusBadRequest)
+		return
+	}
+	if q.Get("client_id") != iss.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "a S256 code_challenge must be provided", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	email := q.Get("email")
+	if email == "" {
+		email = "user@example.com"
+	}
+	subject := q.Get("sub")
+	if subject == "" {
+		sum := sha256.Sum256([]byte(email))
+		subject = encoding.EncodeToString(sum[:12])
+	}
+	code := randomString()
+	iss.mu.Lock()
+	iss.codes[code] = authorization{
+		clientID:      q.Get("client_id"),
+		redirectURI:   q.Get("redirect_uri"),
+		codeChallenge: q.Get("code_challenge"),
+		nonce:         q.Get("nonce"),
+		subject:       subject,
+		email:         email,
+		emailVerified: q.Get("email_verified") != "false",
+		name:          q.Get("name"),
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	iss.mu.Unlock()
+
+	v := redirectURI.Query()
+	v.Set("code", code)
+	v.Set("state", q.Get("state"))
+	redirectURI.RawQuery = v.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (iss *issuer) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError("invalid_request", err.Error(), w)
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+	if clientID != iss.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(iss.clientSecret)) != 1 {
+		writeJSON(map[string]string{"error": "invalid_client"}, http.StatusUnauthorized, w)
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeError("unsupported_grant_type", "only authorization_code is supported", w)
+		return
+	}
+
+	iss.mu.Lock()
+	a, ok := iss.codes[r.PostForm.Get("code")]
+	delete(iss.codes, r.PostForm.Get("code"))
+	iss.mu.Unlock()
+	if !ok || time.Now().After(a.expiresAt) || a.clientID != clientID || a.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeError("invalid_grant", "invalid or expired code", w)
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if encoding.EncodeToString(sum[:]) != a.codeChallenge {
+		writeError("invalid_grant", "code_verifier doesn't match the code_challenge", w)
+		return
+	}
+
+	now := time.Now()
+	idToken, err := iss.sign(map[string]any{
+		"iss":            iss.url,
+		"sub":            a.subject,
+		"aud":            clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          a.nonce,
+		"email":          a.email,
+		"email_verified": a.emailVerified,
+		"name":           a.name,
+	})
+	if err != nil {
+		log.Println(err)
+		writeJSON(map[string]string{"error": "server_error"}, http.StatusInternalServerError, w)
+		return
+	}
+	writeJSON(map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	}, http.StatusOK, w)
+}
+
+func (iss *issuer) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encoding.EncodeToString(header) + "." + encoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, iss.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encoding.EncodeToString(signature), nil
+}
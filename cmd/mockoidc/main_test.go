@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/AdventurerAmer/movie-reservation-system/internal"
+)
+
+const testRedirectURL = "http://localhost:8080/oidc/callback"
+
+// startIssuer serves a mock issuer and returns a provider of the api configured for it.
+func startIssuer(t *testing.T, issuerSecret string, providerSecret string) *internal.OIDCProvider {
+	t.Helper()
+	iss, err := newIssuer("", "mrs", issuerSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(iss.routes())
+	t.Cleanup(srv.Close)
+	iss.url = srv.URL
+	return internal.NewOIDCProvider(internal.OIDCProviderConfig{
+		Name:         "mock",
+		Issuer:       srv.URL,
+		ClientID:     "mrs",
+		ClientSecret: providerSecret,
+		RedirectURL:  testRedirectURL,
+	})
+}
+
+// authorize sends the user to the authorization URL like a browser and returns the code the issuer redirected back with.
+func authorize(t *testing.T, p *internal.OIDCProvider, state string, nonce string, codeVerifier string, account url.Values) string {
+	t.Helper()
+	authorizationURL, err := p.AuthorizationURL(context.Background(), state, nonce, codeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Get(authorizationURL + "&" + account.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want %d", res.StatusCode, http.StatusFound)
+	}
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := location.Scheme + "://" + location.Host + location.Path; got != testRedirectURL {
+		t.Fatalf("redirected to %q, want %q", got, testRedirectURL)
+	}
+	if got := location.Query().Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+	return location.Query().Get("code")
+}
+
+func TestLoginFlow(t *testing.T) {
+	tests := []struct {
+		name              string
+		issuerSecret      string
+		providerSecret    string
+		account           url.Values
+		codeVerifier      string
+		nonce             string
+		wantErr           bool
+		wantInvalid       bool
+		wantEmail         string
+		wantEmailVerified bool
+		wantName          string
+		wantSubject       string
+	}{
+		{
+			name:              "verified email",
+			issuerSecret:      "mrs-secret",
+			providerSecret:    "mrs-secret",
+			account:           url.Values{"email": {"jane@example.com"}, "name": {"Jane"}, "sub": {"jane"}},
+			wantEmail:         "jane@example.com",
+			wantEmailVerified: true,
+			wantName:          "Jane",
+			wantSubject:       "jane",
+		},
+		{
+			name:              "unverified email",
+			issuerSecret:      "mrs-secret",
+			providerSecret:    "mrs-secret",
+			account:           url.Values{"email": {"jane@example.com"}, "sub": {"jane"}, "email_verified": {"false"}},
+			wantEmail:         "jane@example.com",
+			wantEmailVerified: false,
+			wantSubject:       "jane",
+		},
+		{
+			name:              "public client",
+			account:           url.Values{"email": {"jane@example.com"}, "sub": {"jane"}},
+			wantEmail:         "jane@example.com",
+			wantEmailVerified: true,
+			wantSubject:       "jane",
+		},
+		{
+			name:           "wrong client secret",
+			issuerSecret:   "mrs-secret",
+			providerSecret: "other-secret",
+			account:        url.Values{"email": {"jane@example.com"}},
+			wantErr:        true,
+		},
+		{
+			name:           "code verifier of another login",
+			issuerSecret:   "mrs-secret",
+			providerSecret: "mrs-secret",
+			account:        url.Values{"email": {"jane@example.com"}},
+			codeVerifier:   internal.GeneratePKCEVerifier(),
+			wantErr:        true,
+		},
+		{
+			name:           "nonce of another login",
+			issuerSecret:   "mrs-secret",
+			providerSecret: "mrs-secret",
+			account:        url.Values{"email": {"jane@example.com"}},
+			nonce:          internal.GenerateToken(),
+			wantErr:        true,
+			wantInvalid:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := startIssuer(t, tt.issuerSecret, tt.providerSecret)
+			state, nonce, codeVerifier := internal.GenerateToken(), internal.GenerateToken(), internal.GeneratePKCEVerifier()
+			code := authorize(t, p, state, nonce, codeVerifier, tt.account)
+			if tt.codeVerifier != "" {
+				codeVerifier = tt.codeVerifier
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			claims, err := p.Exchange(context.Background(), code, codeVerifier, nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Exchange() error = nil, want an error")
+				}
+				if errors.Is(err, internal.ErrInvalidIDToken) != tt.wantInvalid {
+					t.Errorf("Exchange() error = %v, want invalid id token %v", err, tt.wantInvalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if claims.Email != tt.wantEmail || bool(claims.EmailVerified) != tt.wantEmailVerified ||
+				claims.Name != tt.wantName || claims.Subject != tt.wantSubject {
+				t.Errorf("Exchange() = %+v, want email %q verified %v name %q subject %q",
+					claims, tt.wantEmail, tt.wantEmailVerified, tt.wantName, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestLoginFlowSameAccount(t *testing.T) {
+	p := startIssuer(t, "mrs-secret", "mrs-secret")
+	account := url.Values{"email": {"jane@example.com"}}
+
+	// the account is found again by its subject, the issuer picks the same one for the same email
+	var subjects []string
+	for range 2 {
+		state, nonce, codeVerifier := internal.GenerateToken(), internal.GenerateToken(), internal.GeneratePKCEVerifier()
+		code := authorize(t, p, state, nonce, codeVerifier, account)
+		claims, err := p.Exchange(context.Background(), code, codeVerifier, nonce)
+		if err != nil {
+			t.Fatalf("Exchange() error = %v", err)
+		}
+		subjects = append(subjects, claims.Subject)
+
+		// a code can only be exchanged once
+		if _, err := p.Exchange(context.Background(), code, codeVerifier, nonce); err == nil {
+			t.Error("Exchange() of a used code error = nil, want an error")
+		}
+	}
+	if subjects[0] == "" || subjects[0] != subjects[1] {
+		t.Errorf("subjects = %q, want the same subject twice", subjects)
+	}
+}
@@ -18,9 +18,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/checkout": {
+        "/.well-known/jwks.json": {
             "get": {
-                "description": "checks out a user",
+                "description": "gets the public keys the signed access tokens can be verified with",
                 "consumes": [
                     "application/json"
                 ],
@@ -28,32 +28,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "checkouts"
+                    "tokens"
                 ],
-                "summary": "Checks out a user",
+                "summary": "Gets the JSON web key set",
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.GetCheckoutResponse"
+                            "$ref": "#/definitions/internal.JWKS"
                         }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    }
+                }
+            }
+        },
+        "/admin/gift-cards": {
+            "post": {
+                "description": "issues a gift card that can be redeemed right away, the code is only returned once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gift-cards"
+                ],
+                "summary": "Issues a gift card",
+                "parameters": [
+                    {
+                        "description": "gift card amount",
+                        "name": "amount",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "type": "number"
                         }
-                    },
-                    "409": {
-                        "description": "Conflict",
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.GiftCardResponse"
                         }
                     },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
                     "500": {
@@ -65,9 +87,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/cinemas": {
+        "/admin/payment-discrepancies": {
             "get": {
-                "description": "gets a list of cinemas by search parameters",
+                "description": "gets a list of the discrepancies found between the payment gateway and the local records",
                 "consumes": [
                     "application/json"
                 ],
@@ -75,20 +97,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cinemas"
+                    "payment-events"
                 ],
-                "summary": "Get a list of cinemas",
+                "summary": "Gets the discrepancy report",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "name",
-                        "name": "name",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "location",
-                        "name": "location",
+                        "type": "boolean",
+                        "description": "resolved discrepancies, defaults to false",
+                        "name": "resolved",
                         "in": "query"
                     },
                     {
@@ -102,25 +118,25 @@ const docTemplate = `{
                         "description": "page size",
                         "name": "page_size",
                         "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "sort params are (name, location) prefix with - to sort descending",
-                        "name": "sort",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.CreateCinemaResponse"
+                            "$ref": "#/definitions/main.GetPaymentDiscrepanciesResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "500": {
@@ -130,9 +146,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/payment-discrepancies/{id}/resolve": {
             "post": {
-                "description": "creates a cinema",
+                "description": "marks a discrepancy as resolved",
                 "consumes": [
                     "application/json"
                 ],
@@ -140,40 +158,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cinemas"
+                    "payment-events"
                 ],
-                "summary": "Creates a cinema",
+                "summary": "Resolves a discrepancy",
                 "parameters": [
                     {
-                        "description": "name",
-                        "name": "name",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    {
-                        "description": "location",
-                        "name": "location",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
+                        "type": "integer",
+                        "description": "discrepancy id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.CreateCinemaResponse"
+                            "$ref": "#/definitions/main.ResolvePaymentDiscrepancyResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
                     "500": {
@@ -185,9 +204,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/cinemas/{id}": {
+        "/admin/payment-events": {
             "get": {
-                "description": "gets a cinema by id",
+                "description": "gets a list of the payment gateway webhook events by status",
                 "consumes": [
                     "application/json"
                 ],
@@ -195,29 +214,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cinemas"
+                    "payment-events"
                 ],
-                "summary": "Gets a cinema",
+                "summary": "Gets a list of payment events",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "comma separated statuses (pending, processing, processed, failed) defaults to failed",
+                        "name": "status",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size",
+                        "name": "page_size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.GetCinemaResponse"
+                            "$ref": "#/definitions/main.GetPaymentEventsResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "500": {
@@ -227,9 +263,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "deletes a cinema",
+            }
+        },
+        "/admin/payment-events/{id}/replay": {
+            "post": {
+                "description": "processes a failed or pending payment event again, or one that has been stuck processing for too long",
                 "consumes": [
                     "application/json"
                 ],
@@ -237,32 +275,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cinemas"
+                    "payment-events"
                 ],
-                "summary": "Deletes a cinema",
+                "summary": "Replays a payment event",
                 "parameters": [
                     {
-                        "description": "name",
-                        "name": "name",
-                        "in": "body",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    {
-                        "description": "location",
-                        "name": "location",
-                        "in": "body",
-                        "schema": {
-                            "type": "string"
-                        }
+                        "type": "string",
+                        "description": "event id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ReplayPaymentEventResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "404": {
@@ -277,6 +312,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ReplayPaymentEventResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -286,9 +327,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/cinemas/{id}/halls": {
+        "/admin/roles": {
             "get": {
-                "description": "gets a list of halls for a given cinema",
+                "description": "gets the roles with the permissions they bundle and all the permissions that can be granted",
                 "consumes": [
                     "application/json"
                 ],
@@ -296,29 +337,20 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "halls"
-                ],
-                "summary": "Gets a list of halls",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "cinema id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "permissions"
                 ],
+                "summary": "Gets the roles",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.GetHallsResponse"
+                            "$ref": "#/definitions/main.GetRolesResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "500": {
@@ -328,9 +360,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/token-revocations": {
             "post": {
-                "description": "creates a hall for a given cinema",
+                "description": "revokes the signed access tokens of a user or of a session right away for emergencies,\nthe sessions can still be refreshed unless they're revoked too",
                 "consumes": [
                     "application/json"
                 ],
@@ -338,39 +372,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "halls"
+                    "tokens"
                 ],
-                "summary": "Creates a hall",
+                "summary": "Revokes access tokens",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "cinema id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "name",
-                        "name": "name",
-                        "in": "body",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    {
-                        "description": "seat arrangement",
-                        "name": "seat_arrangement",
+                        "description": "user id",
+                        "name": "user_id",
                         "in": "body",
                         "schema": {
-                            "type": "string"
+                            "type": "integer"
                         }
                     },
                     {
-                        "description": "seat price",
-                        "name": "seat_price",
+                        "description": "session id",
+                        "name": "session_id",
                         "in": "body",
                         "schema": {
-                            "type": "string"
+                            "type": "integer"
                         }
                     }
                 ],
@@ -378,7 +397,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.CreateHallResponse"
+                            "$ref": "#/definitions/main.TokenRevocationResponse"
                         }
                     },
                     "400": {
@@ -387,6 +406,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
                     "409": {
                         "description": "Conflict",
                         "schema": {
@@ -402,9 +427,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/halls/{id}": {
-            "put": {
-                "description": "Updates a hall by id",
+        "/admin/users": {
+            "get": {
+                "description": "gets a list of users, searching by email",
                 "consumes": [
                     "application/json"
                 ],
@@ -412,23 +437,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "halls"
+                    "admin-users"
                 ],
-                "summary": "Updates a hall",
+                "summary": "Gets a list of users",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "part of the email",
+                        "name": "email",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "hall id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size",
+                        "name": "page_size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.UpdateHallResponse"
+                            "$ref": "#/definitions/main.GetAdminUsersResponse"
                         }
                     },
                     "400": {
@@ -437,10 +473,10 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "500": {
@@ -450,9 +486,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes a hall by id",
+            }
+        },
+        "/admin/users/{id}": {
+            "get": {
+                "description": "gets a user with the user's roles and permissions",
                 "consumes": [
                     "application/json"
                 ],
@@ -460,13 +498,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "halls"
+                    "admin-users"
                 ],
-                "summary": "Deletes a hall",
+                "summary": "Gets a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "hall id",
+                        "description": "user id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -476,23 +514,23 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.GetAdminUserResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
@@ -506,12 +544,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/halls/{id}/seats": {
+        "/admin/users/{id}/audit-entries": {
             "get": {
-                "responses": {}
-            },
-            "post": {
-                "description": "Creates a seat for a given hall",
+                "description": "gets the actions the admins took on a user, the latest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -519,23 +554,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "seats"
+                    "admin-users"
                 ],
-                "summary": "Creates a seat",
+                "summary": "Gets the audit entries of a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "hall id",
+                        "description": "user id",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size",
+                        "name": "page_size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.CreateSeatReponse"
+                            "$ref": "#/definitions/main.GetAuditEntriesResponse"
                         }
                     },
                     "400": {
@@ -544,16 +591,10 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "500": {
@@ -565,9 +606,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/healthcheck": {
-            "get": {
-                "description": "gets a health check status",
+        "/admin/users/{id}/password-reset": {
+            "post": {
+                "description": "signs the user out and sends a password-reset token to the user's email,\nthe user can't sign in until the password is reset",
                 "consumes": [
                     "application/json"
                 ],
@@ -575,32 +616,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "checkouts"
+                    "admin-users"
                 ],
-                "summary": "Gets Health Check status",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/main.HealthCheckResponse"
-                        }
+                "summary": "Forces a password reset",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "user id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
-                }
-            }
-        },
-        "/movies": {
-            "get": {
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.GetMoviesResponse"
+                            "$ref": "#/definitions/main.GetAdminUserResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
                     "500": {
@@ -610,9 +660,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "sreates a movie",
+            }
+        },
+        "/admin/users/{id}/permissions": {
+            "get": {
+                "description": "gets the roles of a user, the permissions granted directly and the permissions the user ends up with",
                 "consumes": [
                     "application/json"
                 ],
@@ -620,58 +672,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "movies"
+                    "permissions"
                 ],
-                "summary": "Creates a movie",
+                "summary": "Gets the permissions of a user",
                 "parameters": [
                     {
-                        "description": "title",
-                        "name": "title",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    {
-                        "description": "runtime",
-                        "name": "runtime",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "user id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "integer"
+                            "$ref": "#/definitions/main.UserPermissionsResponse"
                         }
                     },
-                    {
-                        "description": "year",
-                        "name": "year",
-                        "in": "body",
-                        "required": true,
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "integer"
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
-                    {
-                        "description": "genres",
-                        "name": "genres",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "array"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/main.CreateMovieResponse"
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
+                            "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
                     "500": {
@@ -681,11 +716,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/movies/{id}": {
-            "get": {
-                "description": "gets a movie by id",
+            },
+            "post": {
+                "description": "grants permissions to a user directly, on top of the ones of the user's roles",
                 "consumes": [
                     "application/json"
                 ],
@@ -693,29 +726,47 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "movies"
+                    "permissions"
                 ],
-                "summary": "Gets a movie",
+                "summary": "Grants permissions",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id",
+                        "description": "user id",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "permission codes",
+                        "name": "permissions",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.GetMovieResponse"
+                            "$ref": "#/definitions/main.UserPermissionsResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "404": {
@@ -731,19 +782,54 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/admin/users/{id}/permissions/{permission}": {
+            "delete": {
+                "description": "revokes a permission granted to a user directly, the permissions of the user's roles stay",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "permissions"
+                ],
+                "summary": "Revokes a permission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "user id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "permission code",
+                        "name": "permission",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.UpdateMovieResponse"
+                            "$ref": "#/definitions/main.UserPermissionsResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "404": {
@@ -759,33 +845,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/main.ResponseError"
-                        }
-                    }
-                }
             }
         },
-        "/schedules": {
-            "get": {
-                "description": "Gets a list of schedules by search paramaters",
+        "/admin/users/{id}/roles": {
+            "post": {
+                "description": "grants roles to a user",
                 "consumes": [
                     "application/json"
                 ],
@@ -793,51 +857,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "schedules"
+                    "permissions"
                 ],
-                "summary": "Gets a list of schedules",
+                "summary": "Grants roles",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "movie_id",
-                        "name": "movie_id",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "hall_id",
-                        "name": "hall_id",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "page number",
-                        "name": "page",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "page size",
-                        "name": "page_size",
-                        "in": "query",
+                        "description": "user id",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "sort paramterers (id, price, starts_at, ends_at) prefix with - to sort descending",
-                        "name": "sort",
-                        "in": "query",
-                        "required": true
+                        "description": "role codes",
+                        "name": "roles",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.CreateScheduleResponse"
+                            "$ref": "#/definitions/main.UserPermissionsResponse"
                         }
                     },
                     "400": {
@@ -846,6 +894,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -853,9 +913,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "creates a schedule for a given movie and hall",
+            }
+        },
+        "/admin/users/{id}/roles/{role}": {
+            "delete": {
+                "description": "revokes a role from a user, admins can't revoke their own admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -863,61 +925,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "schedules"
+                    "permissions"
                 ],
-                "summary": "Creates a schedule",
+                "summary": "Revokes a role",
                 "parameters": [
                     {
-                        "description": "movie_id",
-                        "name": "movie_id",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    {
-                        "description": "hall_id",
-                        "name": "hall_id",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    {
-                        "description": "price",
-                        "name": "price",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    {
-                        "description": "starts at",
-                        "name": "starts_at",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
+                        "type": "integer",
+                        "description": "user id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "description": "ends at",
-                        "name": "ends_at",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
+                        "type": "string",
+                        "description": "role code",
+                        "name": "role",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.CreateScheduleResponse"
+                            "$ref": "#/definitions/main.UserPermissionsResponse"
                         }
                     },
                     "400": {
@@ -926,6 +957,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ResponseError"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
                     "409": {
                         "description": "Conflict",
                         "schema": {
@@ -941,9 +984,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/schedules/{id}": {
-            "put": {
-                "description": "updates a schedule by id",
+        "/admin/users/{id}/suspension": {
+            "post": {
+                "description": "suspends a user, the user is signed out right away and can't sign in until unsuspended",
                 "consumes": [
                     "application/json"
                 ],
@@ -951,38 +994,20 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "schedules"
+                    "admin-users"
                 ],
-                "summary": "Updates a schedule",
+                "summary": "Suspends a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id",
+                        "description": "user id",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "price",
-                        "name": "price",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    {
-                        "description": "starts at",
-                        "name": "starts_at",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    {
-                        "description": "ends at",
-                        "name": "ends_at",
+                        "description": "reason of the suspension",
+                        "name": "reason",
                         "in": "body",
                         "required": true,
                         "schema": {
@@ -994,7 +1019,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.UpdateScheduleResponse"
+                            "$ref": "#/definitions/main.GetAdminUserResponse"
                         }
                     },
                     "400": {
@@ -1003,6 +1028,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -1024,7 +1055,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "deletes a schedule by id",
+                "description": "lifts the suspension of a user, the user has to sign in again",
                 "consumes": [
                     "application/json"
                 ],
@@ -1032,13 +1063,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "schedules"
+                    "admin-users"
                 ],
-                "summary": "Deletes a schedule",
+                "summary": "Unsuspends a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id",
+                        "description": "user id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1048,13 +1079,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.UpdateScheduleResponse"
+                            "$ref": "#/definitions/main.GetAdminUserResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "404": {
@@ -1063,6 +1100,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1072,9 +1115,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/schedules/{id}/tickets": {
+        "/checkout": {
             "get": {
-                "description": "gets a list of tickets for a given schedule",
+                "description": "checks out a user with the locked tickets and the products added to the checkout, tickets covered by the user's memberships are free, part or all of the total can be paid from the wallet and with loyalty points and an order that isn't paid by card is fulfilled right away",
                 "consumes": [
                     "application/json"
                 ],
@@ -1082,75 +1125,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "checkouts"
                 ],
-                "summary": "Gets a list of tickets",
+                "summary": "Checks out a user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "schedule id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "amount to pay from the wallet",
+                        "name": "wallet_amount",
+                        "in": "body",
                         "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
+                            "type": "number"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    {
+                        "description": "loyalty points to redeem, enough points make the tickets free",
+                        "name": "loyalty_points",
+                        "in": "body",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseError"
+                            "type": "integer"
                         }
                     }
-                }
-            },
-            "post": {
-                "description": "creates the tickets for a given schedule",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "tickets"
-                ],
-                "summary": "Creates the tickets",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "schedule id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.GetCheckoutResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
+                            "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
                         "schema": {
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
@@ -1164,9 +1180,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/seats/{id}": {
+        "/checkout/products": {
             "put": {
-                "description": "updates a seat by id",
+                "description": "sets the quantity of a product in the checkout, a quantity of 0 removes it. products are ordered with the tickets for their cinema",
                 "consumes": [
                     "application/json"
                 ],
@@ -1174,23 +1190,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "seats"
+                    "checkouts"
                 ],
-                "summary": "Updates a seat",
+                "summary": "Sets a checkout product",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "seat id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "product id",
+                        "name": "product_id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "quantity",
+                        "name": "quantity",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.CreateSeatReponse"
+                            "$ref": "#/definitions/main.GetCheckoutResponse"
                         }
                     },
                     "400": {
@@ -1211,6 +1238,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1218,9 +1251,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "deletes a seat by id",
+            }
+        },
+        "/cinemas": {
+            "get": {
+                "description": "gets a list of cinemas by search parameters",
                 "consumes": [
                     "application/json"
                 ],
@@ -1228,29 +1263,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "seats"
+                    "cinemas"
                 ],
-                "summary": "Deletes a seat",
+                "summary": "Get a list of cinemas",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "location",
+                        "name": "location",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "seat id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort params are (name, location) prefix with - to sort descending",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
+                            "$ref": "#/definitions/main.CreateCinemaResponse"
                         }
                     },
                     "404": {
@@ -1259,12 +1311,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
-                        "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1272,11 +1318,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tickets/{id}/lock": {
+            },
             "post": {
-                "description": "locks a ticket to a given user for some time",
+                "description": "creates a cinema",
                 "consumes": [
                     "application/json"
                 ],
@@ -1284,41 +1328,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "cinemas"
                 ],
-                "summary": "Locks a ticket",
+                "summary": "Creates a cinema",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ticket id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                        "description": "name",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.LockTicketResponse"
+                            "type": "string"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    {
+                        "description": "location",
+                        "name": "location",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseError"
+                            "type": "string"
                         }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.CreateCinemaResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
                     "500": {
@@ -1330,9 +1373,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tickets/{id}/unlock": {
-            "post": {
-                "description": "unlocks a ticket",
+        "/cinemas/{id}": {
+            "get": {
+                "description": "gets a cinema by id",
                 "consumes": [
                     "application/json"
                 ],
@@ -1340,13 +1383,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "cinemas"
                 ],
-                "summary": "Unlocks a ticket",
+                "summary": "Gets a cinema",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ticket id",
+                        "description": "id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1356,13 +1399,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.LockTicketResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/main.ResponseError"
+                            "$ref": "#/definitions/main.GetCinemaResponse"
                         }
                     },
                     "404": {
@@ -1371,12 +1408,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
-                        "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1384,11 +1415,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tokens/activation": {
+            },
             "put": {
-                "description": "activates a user",
+                "description": "deletes a cinema",
                 "consumes": [
                     "application/json"
                 ],
@@ -1396,15 +1425,22 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tokens"
+                    "cinemas"
                 ],
-                "summary": "Activates a user",
+                "summary": "Deletes a cinema",
                 "parameters": [
                     {
-                        "description": "token",
-                        "name": "token",
+                        "description": "name",
+                        "name": "name",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "location",
+                        "name": "location",
                         "in": "body",
-                        "required": true,
                         "schema": {
                             "type": "string"
                         }
@@ -1414,7 +1450,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal.User"
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
                     "409": {
@@ -1430,9 +1472,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/cinemas/{id}/drawers": {
             "post": {
-                "description": "creates an activation token and sends it to the email",
+                "description": "starts the user's box office shift at a given cinema, a cashier can have one open drawer per cinema",
                 "consumes": [
                     "application/json"
                 ],
@@ -1440,15 +1484,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tokens"
+                    "box office"
                 ],
-                "summary": "Creates an activation token",
+                "summary": "Opens a cash drawer",
                 "parameters": [
                     {
-                        "description": "email of the user",
-                        "name": "email",
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "cash in the drawer at the start of the shift",
+                        "name": "opening_float",
                         "in": "body",
-                        "required": true,
                         "schema": {
                             "type": "string"
                         }
@@ -1458,7 +1508,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.CashDrawerResponse"
                         }
                     },
                     "400": {
@@ -1467,6 +1517,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
                     "409": {
                         "description": "Conflict",
                         "schema": {
@@ -1482,9 +1544,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tokens/authentication": {
-            "post": {
-                "description": "creates an auth token",
+        "/cinemas/{id}/fees": {
+            "get": {
+                "description": "gets a list of fees and taxes for a given cinema",
                 "consumes": [
                     "application/json"
                 ],
@@ -1492,46 +1554,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tokens"
+                    "fees"
                 ],
-                "summary": "Creates an auth token",
+                "summary": "Gets a list of fees",
                 "parameters": [
                     {
-                        "description": "email",
-                        "name": "email",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.GetFeesResponse"
                         }
                     },
-                    {
-                        "description": "password",
-                        "name": "password",
-                        "in": "body",
-                        "required": true,
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/main.CreateAuthenticationTokenResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict",
-                        "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.ResponseError"
                         }
                     },
                     "500": {
@@ -1541,11 +1586,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tokens/password-reset": {
-            "put": {
-                "description": "creates a password-reset token",
+            },
+            "post": {
+                "description": "creates a fee or a tax for a given cinema",
                 "consumes": [
                     "application/json"
                 ],
@@ -1553,25 +1596,82 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tokens"
+                    "fees"
                 ],
-                "summary": "Creates a password-reset token",
+                "summary": "Creates a fee",
                 "parameters": [
                     {
-                        "description": "email",
-                        "name": "email",
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "name",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "kind (0 fee, 1 tax)",
+                        "name": "kind",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "basis (0 ticket, 1 order)",
+                        "name": "basis",
                         "in": "body",
                         "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "percentage",
+                        "name": "rate",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "fixed amount",
+                        "name": "amount",
+                        "in": "body",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    {
+                        "description": "tax is included in the price",
+                        "name": "is_inclusive",
+                        "in": "body",
+                        "schema": {
+                            "type": "boolean"
+                        }
+                    },
+                    {
+                        "description": "hall id",
+                        "name": "hall_id",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.CreateFeeResponse"
                         }
                     },
                     "400": {
@@ -1580,8 +1680,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
@@ -1593,9 +1699,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "creates a password-reset token and sends the token to the given email",
+            }
+        },
+        "/cinemas/{id}/halls": {
+            "get": {
+                "description": "gets a list of halls for a given cinema",
                 "consumes": [
                     "application/json"
                 ],
@@ -1603,35 +1711,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tokens"
+                    "halls"
                 ],
-                "summary": "Creates a password-reset token",
+                "summary": "Gets a list of halls",
                 "parameters": [
                     {
-                        "description": "email",
-                        "name": "email",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseMessage"
+                            "$ref": "#/definitions/main.GetHallsResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/main.ViolationsMessage"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
@@ -1643,11 +1743,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/users": {
+            },
             "post": {
-                "description": "creates a new user by name, email, password",
+                "description": "creates a hall for a given cinema",
                 "consumes": [
                     "application/json"
                 ],
@@ -1655,33 +1753,37 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "halls"
                 ],
-                "summary": "Creates a new user",
+                "summary": "Creates a hall",
                 "parameters": [
                     {
-                        "description": "name of the user",
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "name",
                         "name": "name",
                         "in": "body",
-                        "required": true,
                         "schema": {
                             "type": "string"
                         }
                     },
                     {
-                        "description": "email of the user",
-                        "name": "email",
+                        "description": "seat arrangement",
+                        "name": "seat_arrangement",
                         "in": "body",
-                        "required": true,
                         "schema": {
                             "type": "string"
                         }
                     },
                     {
-                        "description": "password of the user",
-                        "name": "password",
+                        "description": "seat price",
+                        "name": "seat_price",
                         "in": "body",
-                        "required": true,
                         "schema": {
                             "type": "string"
                         }
@@ -1691,7 +1793,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.CreatedUserResponse"
+                            "$ref": "#/definitions/main.CreateHallResponse"
                         }
                     },
                     "400": {
@@ -1715,9 +1817,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{id}": {
+        "/cinemas/{id}/members": {
             "get": {
-                "description": "gets The user Info by ID",
+                "description": "gets the staff of a given cinema with their roles and scopes",
                 "consumes": [
                     "application/json"
                 ],
@@ -1725,13 +1827,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "cinemas"
                 ],
-                "summary": "Get User Info",
+                "summary": "Gets the cinema members",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id of the user",
+                        "description": "cinema id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1741,7 +1843,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.GetUserResponse"
+                            "$ref": "#/definitions/main.GetCinemaMembersResponse"
                         }
                     },
                     "400": {
@@ -1755,11 +1857,23 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ResponseError"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
                     }
                 }
             },
-            "put": {
-                "description": "updates the user Info by ID",
+            "post": {
+                "description": "adds a user to the staff of a given cinema with a role or changes the role of a member.\nmanagers run the cinema, schedulers manage the schedules and box office staff sell at the counter",
                 "consumes": [
                     "application/json"
                 ],
@@ -1767,37 +1881,47 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "cinemas"
                 ],
-                "summary": "Updates User Info",
+                "summary": "Sets a cinema member",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id of the user",
+                        "description": "cinema id",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "new name of the user",
-                        "name": "name",
+                        "description": "email of the user",
+                        "name": "email",
                         "in": "body",
+                        "required": true,
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    {
+                        "description": "role (0 manager, 1 scheduler, 2 box office)",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal.User"
+                            "$ref": "#/definitions/main.GetCinemaMembersResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.ResponseError"
+                            "$ref": "#/definitions/main.ViolationsMessage"
                         }
                     },
                     "403": {
@@ -1806,6 +1930,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ResponseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1813,9 +1949,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/cinemas/{id}/members/{user_id}": {
             "delete": {
-                "description": "deletes the user",
+                "description": "removes a user from the staff of a given cinema, the user's open drawers can still be closed by the managers",
                 "consumes": [
                     "application/json"
                 ],
@@ -1823,16 +1961,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "cinemas"
                 ],
-                "summary": "Delete User",
+                "summary": "Removes a cinema member",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id of the user",
+                        "description": "cinema id",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "user id",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -1842,12 +1987,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ResponseMessage"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
                     "403": {
                         "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.ResponseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1856,396 +2013,6976 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "internal.CheckoutItem": {
-            "type": "object",
-            "properties": {
-                "cinema": {
-                    "$ref": "#/definitions/internal.Cinema"
-                },
-                "hall": {
-                    "$ref": "#/definitions/internal.Hall"
-                },
-                "movie": {
+        },
+        "/cinemas/{id}/membership-plans": {
+            "get": {
+                "description": "gets the membership plans that can be subscribed to for a given cinema",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "memberships"
+                ],
+                "summary": "Gets a list of membership plans",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetMembershipPlansResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "creates a monthly membership plan for a given cinema, members get tickets_per_period free tickets every month or unlimited ones if it's not provided",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "memberships"
+                ],
+                "summary": "Creates a membership plan",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "name",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "monthly price",
+                        "name": "price",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "free tickets every month",
+                        "name": "tickets_per_period",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateMembershipPlanResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/cinemas/{id}/products": {
+            "get": {
+                "description": "gets the concessions and merchandise catalog of a given cinema",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Gets a list of products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetProductsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "adds a concession or merchandise product to the catalog of a given cinema",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Creates a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "name",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "category (0 snack, 1 drink, 2 combo, 3 merchandise)",
+                        "name": "category",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "price",
+                        "name": "price",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "stock",
+                        "name": "stock",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateProductResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/cinemas/{id}/settings": {
+            "get": {
+                "description": "gets the settings of a given cinema, null values fall back to the global ones",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cinemas"
+                ],
+                "summary": "Gets cinema settings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.CinemaSettingsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "updates the settings of a given cinema, null values fall back to the global ones",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cinemas"
+                ],
+                "summary": "Updates cinema settings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "cinema id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "ticket hold duration in seconds",
+                        "name": "ticket_lock_seconds",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "checkout session duration in seconds",
+                        "name": "checkout_session_seconds",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "buyers can transfer their tickets",
+                        "name": "allow_ticket_transfers",
+                        "in": "body",
+                        "schema": {
+                            "type": "boolean"
+                        }
+                    },
+                    {
+                        "description": "percentage of the face value tickets can be resold for, resale is disabled if it's null",
+                        "name": "resale_price_cap",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "percentage of the resale price kept from the seller",
+                        "name": "resale_fee_rate",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.CinemaSettingsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/drawers/{id}/close": {
+            "post": {
+                "description": "ends a box office shift with the cash counted in the drawer and returns the end of shift report,\nthe cinema owner and managers can close the drawers of the cashiers",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "box office"
+                ],
+                "summary": "Closes a cash drawer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "drawer id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "cash counted in the drawer",
+                        "name": "counted_cash",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ShiftReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/drawers/{id}/report": {
+            "get": {
+                "description": "gets the sales of a cash drawer so far, the difference is only set once the drawer is closed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "box office"
+                ],
+                "summary": "Gets a shift report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "drawer id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ShiftReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/drawers/{id}/sales": {
+            "post": {
+                "description": "sells tickets and products at the counter paid by cash or card present, the tickets are sold immediately\nand the order is recorded in the cashier's drawer. taxes apply but fees are for online checkouts only.\nthe tickets are printed from /orders/{id}/tickets.pdf",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "box office"
+                ],
+                "summary": "Sells at the box office",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "drawer id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "ticket ids",
+                        "name": "ticket_ids",
+                        "in": "body",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    {
+                        "description": "products as product_id and quantity",
+                        "name": "products",
+                        "in": "body",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    {
+                        "description": "payment method (1 cash, 2 card present)",
+                        "name": "payment_method",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "reference of the card terminal payment",
+                        "name": "payment_reference",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "cash handed over by the customer",
+                        "name": "cash_tendered",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.BoxOfficeSaleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/fees/{id}": {
+            "put": {
+                "description": "updates a fee or a tax by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fees"
+                ],
+                "summary": "Updates a fee",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "fee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateFeeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "deletes a fee or a tax by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fees"
+                ],
+                "summary": "Deletes a fee",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "fee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift-cards": {
+            "get": {
+                "description": "gets the gift cards purchased by the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gift-cards"
+                ],
+                "summary": "Gets purchased gift cards",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetGiftCardsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "creates a checkout session for a gift card, the code is only returned once and it can be redeemed after the session is paid",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gift-cards"
+                ],
+                "summary": "Purchases a gift card",
+                "parameters": [
+                    {
+                        "description": "gift card amount",
+                        "name": "amount",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "number"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.GiftCardResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift-cards/redeem": {
+            "post": {
+                "description": "credits the amount of a gift card to the user's wallet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gift-cards"
+                ],
+                "summary": "Redeems a gift card",
+                "parameters": [
+                    {
+                        "description": "gift card code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.RedeemGiftCardResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/halls/{id}": {
+            "put": {
+                "description": "Updates a hall by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "halls"
+                ],
+                "summary": "Updates a hall",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "hall id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateHallResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a hall by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "halls"
+                ],
+                "summary": "Deletes a hall",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "hall id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/halls/{id}/seats": {
+            "get": {
+                "responses": {}
+            },
+            "post": {
+                "description": "Creates a seat for a given hall",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seats"
+                ],
+                "summary": "Creates a seat",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "hall id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateSeatReponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthcheck": {
+            "get": {
+                "description": "gets a health check status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "checkouts"
+                ],
+                "summary": "Gets Health Check status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.HealthCheckResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/membership-plans/{id}": {
+            "delete": {
+                "description": "stops new subscriptions to a membership plan, the existing memberships keep renewing until they are canceled",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "memberships"
+                ],
+                "summary": "Deletes a membership plan",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "membership plan id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/membership-plans/{id}/subscribe": {
+            "post": {
+                "description": "creates a subscription checkout session for a membership plan, the membership is active once the session is paid",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "memberships"
+                ],
+                "summary": "Subscribes to a membership plan",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "membership plan id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.SubscribeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/memberships/{id}": {
+            "delete": {
+                "description": "cancels a membership at the end of its current billing period, its free tickets can be used until then",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "memberships"
+                ],
+                "summary": "Cancels a membership",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "membership id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.CancelMembershipResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/movies": {
+            "get": {
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetMoviesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "sreates a movie",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "movies"
+                ],
+                "summary": "Creates a movie",
+                "parameters": [
+                    {
+                        "description": "title",
+                        "name": "title",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "runtime",
+                        "name": "runtime",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "year",
+                        "name": "year",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "genres",
+                        "name": "genres",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateMovieResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/movies/{id}": {
+            "get": {
+                "description": "gets a movie by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "movies"
+                ],
+                "summary": "Gets a movie",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetMovieResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateMovieResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/providers": {
+            "get": {
+                "description": "gets the identity providers the users can sign in with",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Gets the OpenID Connect providers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetOIDCProvidersResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/{provider}/authorization": {
+            "post": {
+                "description": "returns the provider's URL to send the user to, the provider redirects the user back to the\nredirect URL with the code and the state to complete the login at /tokens/oidc",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Starts an OpenID Connect login",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "provider",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateOIDCAuthorizationResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/receipt.pdf": {
+            "get": {
+                "description": "gets the receipt of a completed order as a PDF with an invoice for every cinema",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Gets an order receipt",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/tickets.pdf": {
+            "get": {
+                "description": "gets the tickets of a completed order the user still holds as a PDF with a page for every ticket to print at the box office",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Gets printable order tickets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}": {
+            "put": {
+                "description": "updates a product by id, stock sets the count left to sell",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Updates a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "product id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "name",
+                        "name": "name",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "category (0 snack, 1 drink, 2 combo, 3 merchandise)",
+                        "name": "category",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "price",
+                        "name": "price",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "stock",
+                        "name": "stock",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateProductResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "removes a product from the catalog and from the checkouts it was added to",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Deletes a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "product id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/resale-listings/{id}": {
+            "get": {
+                "description": "gets a resale listing by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resales"
+                ],
+                "summary": "Gets a resale listing",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "listing id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResaleListingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "takes a ticket off the resale market, it can't be cancelled while someone is buying it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resales"
+                ],
+                "summary": "Cancels a resale listing",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "listing id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResaleListingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/resale-listings/{id}/lock": {
+            "post": {
+                "description": "holds a resold ticket for the user so it's bought with the user's next checkout, it counts against the same limits\nas the other locked tickets and needs an admission token if the schedule has a waiting room",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resales"
+                ],
+                "summary": "Locks a resale listing",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "listing id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.LockTicketResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/resale-listings/{id}/unlock": {
+            "post": {
+                "description": "releases the user's hold on a resold ticket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resales"
+                ],
+                "summary": "Unlocks a resale listing",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "listing id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules": {
+            "get": {
+                "description": "Gets a list of schedules by search paramaters",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Gets a list of schedules",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "movie_id",
+                        "name": "movie_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "hall_id",
+                        "name": "hall_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort paramterers (id, price, starts_at, ends_at) prefix with - to sort descending",
+                        "name": "sort",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "creates a schedule for a given movie and hall",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Creates a schedule",
+                "parameters": [
+                    {
+                        "description": "movie_id",
+                        "name": "movie_id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "hall_id",
+                        "name": "hall_id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "price",
+                        "name": "price",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "starts at",
+                        "name": "starts_at",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "ends at",
+                        "name": "ends_at",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}": {
+            "put": {
+                "description": "updates a schedule by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Updates a schedule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "price",
+                        "name": "price",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "starts at",
+                        "name": "starts_at",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "ends at",
+                        "name": "ends_at",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "deletes a schedule by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Deletes a schedule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}/resale-listings": {
+            "get": {
+                "description": "gets the available resale listings of a given schedule ordered by price",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resales"
+                ],
+                "summary": "Gets resale listings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetResaleListingsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}/tickets": {
+            "get": {
+                "description": "gets a list of tickets for a given schedule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Gets a list of tickets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "creates the tickets for a given schedule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Creates the tickets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}/waiting-room": {
+            "get": {
+                "description": "gets the waiting room of a given schedule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "waiting-rooms"
+                ],
+                "summary": "Gets a waiting room",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.WaitingRoomResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "opens or updates the waiting room of a given schedule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "waiting-rooms"
+                ],
+                "summary": "Opens a waiting room",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "number of users admitted at the same time",
+                        "name": "batch_size",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "how long an admission lasts in seconds",
+                        "name": "admission_seconds",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.WaitingRoomResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "closes the waiting room of a given schedule and drops its queue",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "waiting-rooms"
+                ],
+                "summary": "Closes a waiting room",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}/waiting-room/join": {
+            "post": {
+                "description": "queues the user in the waiting room of a given schedule, the admission token must be sent in the X-Admission-Token header to lock tickets once admitted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "waiting-rooms"
+                ],
+                "summary": "Joins a waiting room",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.JoinWaitingRoomResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}/waiting-room/position": {
+            "get": {
+                "description": "gets the position of the user in the waiting room of a given schedule, with \"Accept: text/event-stream\" the position is streamed until the user is admitted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "waiting-rooms"
+                ],
+                "summary": "Gets the queue position",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.WaitingRoomPositionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}/waitlist": {
+            "get": {
+                "description": "gets the position of the user in the waitlist of a given schedule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "waitlists"
+                ],
+                "summary": "Gets a waitlist entry",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.WaitlistEntryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "joins the waitlist of a sold out schedule, waitlisted users are notified by email when a seat is freed and it's reserved for them for a while",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "waitlists"
+                ],
+                "summary": "Joins a waitlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.WaitlistEntryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "removes the user from the waitlist of a given schedule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "waitlists"
+                ],
+                "summary": "Leaves a waitlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "schedule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/seats/{id}": {
+            "put": {
+                "description": "updates a seat by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seats"
+                ],
+                "summary": "Updates a seat",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "seat id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateSeatReponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "deletes a seat by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seats"
+                ],
+                "summary": "Deletes a seat",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "seat id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/ticket-transfers/claim": {
+            "post": {
+                "description": "claims a ticket sent to the user's email, the previous owner's pass is invalidated",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Claims a ticket transfer",
+                "parameters": [
+                    {
+                        "description": "transfer token",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.TicketTransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/ticket-transfers/{id}/cancel": {
+            "post": {
+                "description": "cancels a pending ticket transfer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Cancels a ticket transfer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "transfer id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.TicketTransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/extend": {
+            "post": {
+                "description": "extends the hold of a locked ticket, a hold can only be extended once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Extends a ticket lock",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ticket id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.LockTicketResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/lock": {
+            "post": {
+                "description": "locks a ticket to a given user for some time, membership_id is set if one of the user's memberships covers the ticket at checkout",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Locks a ticket",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ticket id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.LockTicketResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/pass": {
+            "get": {
+                "description": "gets the pass of a sold ticket, its code is encoded in the QR code presented at the entrance",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Gets a ticket pass",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ticket id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.TicketPassResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/resale": {
+            "post": {
+                "description": "lists a sold ticket for resale at a price capped by the cinema as a percentage of what the seller paid for it, once it's sold the price minus the cinema's fee is refunded to the seller's card or wallet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resales"
+                ],
+                "summary": "Lists a ticket for resale",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ticket id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "resale price",
+                        "name": "price",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "number"
+                        }
+                    },
+                    {
+                        "description": "credit the proceeds to the wallet instead of refunding the card",
+                        "name": "payout_to_wallet",
+                        "in": "body",
+                        "schema": {
+                            "type": "boolean"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResaleListingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/transfer": {
+            "post": {
+                "description": "sends a claim token for a sold ticket to a given email, the ticket changes hands once it's claimed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Transfers a ticket",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ticket id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "recipient email",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.TicketTransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/transfers": {
+            "get": {
+                "description": "gets the transfer history of a sold ticket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Gets the transfer history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ticket id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetTicketTransfersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/unlock": {
+            "post": {
+                "description": "unlocks a ticket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Unlocks a ticket",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ticket id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.LockTicketResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/activation": {
+            "put": {
+                "description": "activates a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Activates a user",
+                "parameters": [
+                    {
+                        "description": "token",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal.User"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "creates an activation token and sends it to the email",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Creates an activation token",
+                "parameters": [
+                    {
+                        "description": "email of the user",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/authentication": {
+            "post": {
+                "description": "creates an auth token, the users with the two-factor authentication get a challenge instead\nto complete at /tokens/two-factor. The users who are required to have it get the enrollment with the challenge.\nToo many failed logins delay the next ones and then lock the account or the IP for a while",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Creates an auth token",
+                "parameters": [
+                    {
+                        "description": "email",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "password",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateAuthenticationTokenResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/main.TwoFactorChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/oidc": {
+            "post": {
+                "description": "exchanges the code the provider redirected the user back with for an auth token,\nthe account is linked to the activated user with the same email if the provider verified it or a new user is created.\nA linked user is signed out everywhere and can only set a password again with the password reset.\nThe users with the two-factor authentication get a challenge instead",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Completes an OpenID Connect login",
+                "parameters": [
+                    {
+                        "description": "state",
+                        "name": "state",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateAuthenticationTokenResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/main.TwoFactorChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/password-reset": {
+            "put": {
+                "description": "creates a password-reset token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Creates a password-reset token",
+                "parameters": [
+                    {
+                        "description": "email",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "creates a password-reset token and sends the token to the given email",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Creates a password-reset token",
+                "parameters": [
+                    {
+                        "description": "email",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/refresh": {
+            "post": {
+                "description": "exchanges a refresh token for a new auth token and a new refresh token,\na refresh token can only be used once and using it again logs the session out",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Refreshes an auth token",
+                "parameters": [
+                    {
+                        "description": "refresh token",
+                        "name": "refresh_token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateAuthenticationTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/two-factor": {
+            "post": {
+                "description": "exchanges the challenge of a login and a code from the authenticator or a recovery code for an auth token,\na challenge can only be tried once. Completing an enrollment returns the recovery codes.\nWrong codes count against the same failed logins as the wrong passwords",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Completes a two-factor login",
+                "parameters": [
+                    {
+                        "description": "challenge",
+                        "name": "challenge",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "code from the authenticator",
+                        "name": "code",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "recovery code",
+                        "name": "recovery_code",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.TwoFactorAuthenticationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "post": {
+                "description": "creates a new user by name, email, password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Creates a new user",
+                "parameters": [
+                    {
+                        "description": "name of the user",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "email of the user",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "password of the user",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreatedUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/api-keys": {
+            "get": {
+                "description": "gets the user's API keys with their permissions and when they were last used",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Gets the user's API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetAPIKeysResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "creates a key for the integrations like kiosks and partner systems limited to some of the user's permissions,\nit's sent as \"Authorization: ApiKey \u003ckey\u003e\" and is only shown once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Creates an API key",
+                "parameters": [
+                    {
+                        "description": "name",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "permission codes",
+                        "name": "permissions",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    {
+                        "description": "expiration time",
+                        "name": "expires_at",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateAPIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/api-keys/{id}": {
+            "delete": {
+                "description": "revokes an API key, it stops working right away",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Revokes an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "api key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/cinemas": {
+            "get": {
+                "description": "gets the cinemas the user is a member of with the user's role and scopes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Gets the user's cinemas",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetCinemaMembersResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/loyalty": {
+            "get": {
+                "description": "gets the user's loyalty points balance, tier and the history of earned, redeemed and expired points",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "loyalty"
+                ],
+                "summary": "Gets the loyalty account",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetLoyaltyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/memberships": {
+            "get": {
+                "description": "gets the user's memberships with the free tickets used during their current billing period",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "memberships"
+                ],
+                "summary": "Gets the memberships",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetMembershipsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/sessions": {
+            "get": {
+                "description": "gets where the user is logged in with when and from where each session was last used",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Gets the user's sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetSessionsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "revokes all of the user's sessions including the current one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Logs out everywhere",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/sessions/{id}": {
+            "delete": {
+                "description": "logs the user out of a session, its auth and refresh tokens stop working right away",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Revokes a session",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "session id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/two-factor": {
+            "get": {
+                "description": "gets whether the two-factor authentication is enabled, whether it's required and how many recovery codes are left",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Gets the user's two-factor authentication",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetTwoFactorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "confirms the enrollment with a code from the authenticator and returns the recovery codes,\nthey're only shown once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Enables the two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "code from the authenticator",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.TwoFactorRecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "creates a TOTP secret with the URI to show as a QR code for the authenticator,\nthe two-factor authentication is enabled once it's confirmed with a code",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Starts a two-factor enrollment",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.TwoFactorEnrollmentResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "disables the two-factor authentication with a code from the authenticator or a recovery code,\nit can't be disabled by the users who are required to have it, the wrong codes count as failed logins",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Disables the two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "code from the authenticator",
+                        "name": "code",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "recovery code",
+                        "name": "recovery_code",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/two-factor/recovery-codes": {
+            "post": {
+                "description": "replaces the recovery codes with new ones using a code from the authenticator, the old ones stop working,\nthe wrong codes count as failed logins",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Regenerates the recovery codes",
+                "parameters": [
+                    {
+                        "description": "code from the authenticator",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.TwoFactorRecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/wallet": {
+            "get": {
+                "description": "gets the balance of the user's wallet and its ledger of credits and debits",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Gets the wallet",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetWalletResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ViolationsMessage"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "description": "gets The user Info by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get User Info",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.GetUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "updates the user Info by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Updates User Info",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new name of the user",
+                        "name": "name",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "deletes the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete User",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseMessage"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ResponseError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "internal.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "prefix": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal.AuditAction": {
+            "type": "string",
+            "enum": [
+                "user:suspend",
+                "user:unsuspend",
+                "user:force_password_reset",
+                "user:grant_roles",
+                "user:revoke_role",
+                "user:grant_permissions",
+                "user:revoke_permission",
+                "user:revoke_tokens"
+            ],
+            "x-enum-varnames": [
+                "AuditActionSuspend",
+                "AuditActionUnsuspend",
+                "AuditActionForcePasswordReset",
+                "AuditActionGrantRoles",
+                "AuditActionRevokeRole",
+                "AuditActionGrantPermissions",
+                "AuditActionRevokePermission",
+                "AuditActionRevokeTokens"
+            ]
+        },
+        "internal.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/internal.AuditAction"
+                },
+                "actor_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.Breakdown": {
+            "type": "object",
+            "properties": {
+                "fees": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.Charge"
+                    }
+                },
+                "fees_total": {
+                    "type": "number"
+                },
+                "subtotal": {
+                    "type": "number"
+                },
+                "taxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.Charge"
+                    }
+                },
+                "taxes_total": {
+                    "type": "number"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "internal.CashDrawer": {
+            "type": "object",
+            "properties": {
+                "cashier_id": {
+                    "type": "integer"
+                },
+                "cinema_id": {
+                    "type": "integer"
+                },
+                "closed_at": {
+                    "type": "string"
+                },
+                "counted_cash": {
+                    "type": "number"
+                },
+                "expected_cash": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "opened_at": {
+                    "type": "string"
+                },
+                "opening_float": {
+                    "type": "number"
+                }
+            }
+        },
+        "internal.Charge": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "cinema_id": {
+                    "type": "integer"
+                },
+                "is_inclusive": {
+                    "type": "boolean"
+                },
+                "kind": {
+                    "$ref": "#/definitions/internal.FeeKind"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.CheckoutItem": {
+            "type": "object",
+            "properties": {
+                "cinema": {
+                    "$ref": "#/definitions/internal.Cinema"
+                },
+                "hall": {
+                    "$ref": "#/definitions/internal.Hall"
+                },
+                "listing_id": {
+                    "type": "integer"
+                },
+                "membership_id": {
+                    "type": "integer"
+                },
+                "movie": {
+                    "$ref": "#/definitions/internal.Movie"
+                },
+                "schedule": {
+                    "$ref": "#/definitions/internal.Schedule"
+                },
+                "seat": {
+                    "$ref": "#/definitions/internal.Seat"
+                },
+                "ticket": {
+                    "$ref": "#/definitions/internal.Ticket"
+                }
+            }
+        },
+        "internal.CheckoutProduct": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "product": {
+                    "$ref": "#/definitions/internal.Product"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.Cinema": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "location": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ower_id": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.CinemaMember": {
+            "type": "object",
+            "properties": {
+                "cinema_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "invited_by": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/internal.CinemaRole"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.CinemaScope"
+                    }
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.CinemaRole": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "CinemaRoleManager",
+                "CinemaRoleScheduler",
+                "CinemaRoleBoxOffice"
+            ]
+        },
+        "internal.CinemaScope": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3,
+                4,
+                5
+            ],
+            "x-enum-varnames": [
+                "CinemaScopeCinema",
+                "CinemaScopeHalls",
+                "CinemaScopeSchedules",
+                "CinemaScopePricing",
+                "CinemaScopeBoxOffice",
+                "CinemaScopeOwner"
+            ]
+        },
+        "internal.CinemaSettings": {
+            "type": "object",
+            "properties": {
+                "allow_ticket_transfers": {
+                    "type": "boolean"
+                },
+                "checkout_session_seconds": {
+                    "type": "integer"
+                },
+                "cinema_id": {
+                    "type": "integer"
+                },
+                "resale_fee_rate": {
+                    "type": "number"
+                },
+                "resale_price_cap": {
+                    "type": "number"
+                },
+                "ticket_lock_seconds": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.Fee": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "basis": {
+                    "$ref": "#/definitions/internal.FeeBasis"
+                },
+                "cinema_id": {
+                    "type": "integer"
+                },
+                "hall_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_inclusive": {
+                    "type": "boolean"
+                },
+                "kind": {
+                    "$ref": "#/definitions/internal.FeeKind"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.FeeBasis": {
+            "type": "integer",
+            "enum": [
+                0,
+                1
+            ],
+            "x-enum-varnames": [
+                "FeeBasisTicket",
+                "FeeBasisOrder"
+            ]
+        },
+        "internal.FeeKind": {
+            "type": "integer",
+            "enum": [
+                0,
+                1
+            ],
+            "x-enum-varnames": [
+                "FeeKindFee",
+                "FeeKindTax"
+            ]
+        },
+        "internal.GiftCard": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "issued_by": {
+                    "type": "integer"
+                },
+                "purchased_by": {
+                    "type": "integer"
+                },
+                "redeemed_at": {
+                    "type": "string"
+                },
+                "redeemed_by": {
+                    "type": "integer"
+                },
+                "status_id": {
+                    "$ref": "#/definitions/internal.GiftCardStatus"
+                }
+            }
+        },
+        "internal.GiftCardStatus": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "GiftCardStatusPending",
+                "GiftCardStatusActive",
+                "GiftCardStatusRedeemed"
+            ]
+        },
+        "internal.Hall": {
+            "type": "object",
+            "properties": {
+                "cinema_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "seat_arrangement": {
+                    "type": "string"
+                },
+                "seat_price": {
+                    "type": "number"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.JWK"
+                    }
+                }
+            }
+        },
+        "internal.LoyaltyAccount": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "next_tier": {
+                    "$ref": "#/definitions/internal.LoyaltyTier"
+                },
+                "tier": {
+                    "$ref": "#/definitions/internal.LoyaltyTier"
+                },
+                "tier_points": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.LoyaltyEntry": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "$ref": "#/definitions/internal.LoyaltyEntryKind"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.LoyaltyEntryKind": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "LoyaltyEntryKindEarning",
+                "LoyaltyEntryKindRedemption",
+                "LoyaltyEntryKindRedemptionRelease",
+                "LoyaltyEntryKindExpiration"
+            ]
+        },
+        "internal.LoyaltyTier": {
+            "type": "object",
+            "properties": {
+                "min_points": {
+                    "type": "integer"
+                },
+                "multiplier": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal.Membership": {
+            "type": "object",
+            "properties": {
+                "cancel_at_period_end": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "plan": {
+                    "$ref": "#/definitions/internal.MembershipPlan"
+                },
+                "plan_id": {
+                    "type": "integer"
+                },
+                "status_id": {
+                    "$ref": "#/definitions/internal.MembershipStatus"
+                },
+                "tickets_used": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.MembershipPlan": {
+            "type": "object",
+            "properties": {
+                "cinema_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "tickets_per_period": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.MembershipStatus": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "MembershipStatusIncomplete",
+                "MembershipStatusActive",
+                "MembershipStatusPastDue",
+                "MembershipStatusCanceled"
+            ]
+        },
+        "internal.MetaData": {
+            "type": "object",
+            "properties": {
+                "current_page": {
+                    "type": "integer"
+                },
+                "first_page": {
+                    "type": "integer"
+                },
+                "last_page": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "total_records": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.Movie": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "genres": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "runtime": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.Order": {
+            "type": "object",
+            "properties": {
+                "cashier_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "drawer_id": {
+                    "type": "integer"
+                },
+                "fees_total": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.OrderLine"
+                    }
+                },
+                "loyalty_discount": {
+                    "type": "number"
+                },
+                "loyalty_points": {
+                    "type": "integer"
+                },
+                "payment_method": {
+                    "$ref": "#/definitions/internal.PaymentMethod"
+                },
+                "payment_reference": {
+                    "type": "string"
+                },
+                "pickup_code": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                },
+                "status_id": {
+                    "$ref": "#/definitions/internal.OrderStatus"
+                },
+                "subtotal": {
+                    "type": "number"
+                },
+                "taxes_total": {
+                    "type": "number"
+                },
+                "total": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wallet_amount": {
+                    "type": "number"
+                }
+            }
+        },
+        "internal.OrderLine": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "cinema_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_inclusive": {
+                    "type": "boolean"
+                },
+                "kind": {
+                    "$ref": "#/definitions/internal.OrderLineKind"
+                },
+                "membership_id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.OrderLineKind": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "OrderLineKindTicket",
+                "OrderLineKindFee",
+                "OrderLineKindTax",
+                "OrderLineKindProduct"
+            ]
+        },
+        "internal.OrderStatus": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "OrderStatusPending",
+                "OrderStatusCompleted",
+                "OrderStatusCancelled"
+            ]
+        },
+        "internal.PaymentDiscrepancy": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "$ref": "#/definitions/internal.PaymentDiscrepancyKind"
+                },
+                "resolved_at": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.PaymentDiscrepancyKind": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3,
+                4
+            ],
+            "x-enum-varnames": [
+                "PaymentDiscrepancyKindMissedFulfillment",
+                "PaymentDiscrepancyKindReleasedTickets",
+                "PaymentDiscrepancyKindAmountMismatch",
+                "PaymentDiscrepancyKindUnknownSession",
+                "PaymentDiscrepancyKindFailedPayout"
+            ]
+        },
+        "internal.PaymentEvent": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "claimed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "processed_at": {
+                    "type": "string"
+                },
+                "status_id": {
+                    "$ref": "#/definitions/internal.PaymentEventStatus"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal.PaymentEventStatus": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "PaymentEventStatusPending",
+                "PaymentEventStatusProcessing",
+                "PaymentEventStatusProcessed",
+                "PaymentEventStatusFailed"
+            ]
+        },
+        "internal.PaymentMethod": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "PaymentMethodOnline",
+                "PaymentMethodCash",
+                "PaymentMethodCardPresent"
+            ]
+        },
+        "internal.Product": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "$ref": "#/definitions/internal.ProductCategory"
+                },
+                "cinema_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.ProductCategory": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "ProductCategorySnack",
+                "ProductCategoryDrink",
+                "ProductCategoryCombo",
+                "ProductCategoryMerchandise"
+            ]
+        },
+        "internal.ResaleListing": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "fee": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "paid_out_at": {
+                    "type": "string"
+                },
+                "payout_error": {
+                    "type": "string"
+                },
+                "payout_to_wallet": {
+                    "type": "boolean"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "seller_id": {
+                    "type": "integer"
+                },
+                "sold_at": {
+                    "type": "string"
+                },
+                "status_id": {
+                    "$ref": "#/definitions/internal.ResaleListingStatus"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.ResaleListingStatus": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "ResaleListingStatusActive",
+                "ResaleListingStatusSold",
+                "ResaleListingStatusCancelled"
+            ]
+        },
+        "internal.Role": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal.Schedule": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "hall_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "movie_id": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.Seat": {
+            "type": "object",
+            "properties": {
+                "coordinates": {
+                    "type": "string"
+                },
+                "hall_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.Session": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal.ShiftReport": {
+            "type": "object",
+            "properties": {
+                "card_sales": {
+                    "type": "number"
+                },
+                "cash_sales": {
+                    "type": "number"
+                },
+                "difference": {
+                    "type": "number"
+                },
+                "drawer": {
+                    "$ref": "#/definitions/internal.CashDrawer"
+                },
+                "expected_cash": {
+                    "type": "number"
+                },
+                "orders": {
+                    "type": "integer"
+                },
+                "products": {
+                    "type": "integer"
+                },
+                "tickets": {
+                    "type": "integer"
+                },
+                "total_sales": {
+                    "type": "number"
+                }
+            }
+        },
+        "internal.Ticket": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "seat_id": {
+                    "type": "integer"
+                },
+                "state_changed_at": {
+                    "type": "string"
+                },
+                "state_id": {
+                    "$ref": "#/definitions/internal.TicketState"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.TicketLock": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "extended": {
+                    "type": "boolean"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.TicketPass": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.TicketState": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "TicketStateUnsold",
+                "TicketStateLocked",
+                "TicketStateSold"
+            ]
+        },
+        "internal.TicketTransfer": {
+            "type": "object",
+            "properties": {
+                "accepted_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "from_user_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "status_id": {
+                    "$ref": "#/definitions/internal.TicketTransferStatus"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                },
+                "to_email": {
+                    "type": "string"
+                },
+                "to_user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.TicketTransferStatus": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "TicketTransferStatusPending",
+                "TicketTransferStatusAccepted",
+                "TicketTransferStatusCancelled"
+            ]
+        },
+        "internal.TokenRevocation": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.User": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt",
+                    "type": "string"
+                },
+                "email": {
+                    "description": "Email",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID",
+                    "type": "integer"
+                },
+                "is_activated": {
+                    "description": "IsActivated",
+                    "type": "boolean"
+                },
+                "name": {
+                    "description": "Name",
+                    "type": "string"
+                },
+                "password_reset_required": {
+                    "description": "PasswordResetRequired",
+                    "type": "boolean"
+                },
+                "suspended_at": {
+                    "description": "SuspendedAt",
+                    "type": "string"
+                },
+                "suspension_reason": {
+                    "description": "SuspensionReason",
+                    "type": "string"
+                }
+            }
+        },
+        "internal.WaitingRoom": {
+            "type": "object",
+            "properties": {
+                "admission_seconds": {
+                    "type": "integer"
+                },
+                "batch_size": {
+                    "type": "integer"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.WaitingRoomEntry": {
+            "type": "object",
+            "properties": {
+                "admitted_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "position": {
+                    "description": "number of users ahead in the queue plus one, it's zero once admitted",
+                    "type": "integer"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.WaitlistEntry": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "notified_at": {
+                    "type": "string"
+                },
+                "position": {
+                    "description": "number of users ahead in the waitlist plus one, it's zero once notified",
+                    "type": "integer"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.WalletEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "gift_card_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "$ref": "#/definitions/internal.WalletEntryKind"
+                },
+                "listing_id": {
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal.WalletEntryKind": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "WalletEntryKindGiftCard",
+                "WalletEntryKindPayment",
+                "WalletEntryKindPaymentRelease",
+                "WalletEntryKindRefund"
+            ]
+        },
+        "main.BoxOfficeSaleResponse": {
+            "type": "object",
+            "properties": {
+                "change": {
+                    "type": "number"
+                },
+                "order": {
+                    "$ref": "#/definitions/internal.Order"
+                }
+            }
+        },
+        "main.CancelMembershipResponse": {
+            "type": "object",
+            "properties": {
+                "membership": {
+                    "$ref": "#/definitions/internal.Membership"
+                }
+            }
+        },
+        "main.CashDrawerResponse": {
+            "type": "object",
+            "properties": {
+                "drawer": {
+                    "$ref": "#/definitions/internal.CashDrawer"
+                }
+            }
+        },
+        "main.CinemaSettingsResponse": {
+            "type": "object",
+            "properties": {
+                "settings": {
+                    "$ref": "#/definitions/internal.CinemaSettings"
+                }
+            }
+        },
+        "main.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "$ref": "#/definitions/internal.APIKey"
+                },
+                "key": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateAuthenticationTokenResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "refresh_token_expires_at": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateCinemaResponse": {
+            "type": "object",
+            "properties": {
+                "cinema": {
+                    "$ref": "#/definitions/internal.Cinema"
+                }
+            }
+        },
+        "main.CreateFeeResponse": {
+            "type": "object",
+            "properties": {
+                "fee": {
+                    "$ref": "#/definitions/internal.Fee"
+                }
+            }
+        },
+        "main.CreateHallResponse": {
+            "type": "object",
+            "properties": {
+                "hall": {
+                    "$ref": "#/definitions/internal.Hall"
+                }
+            }
+        },
+        "main.CreateMembershipPlanResponse": {
+            "type": "object",
+            "properties": {
+                "plan": {
+                    "$ref": "#/definitions/internal.MembershipPlan"
+                }
+            }
+        },
+        "main.CreateMovieResponse": {
+            "type": "object",
+            "properties": {
+                "movie": {
                     "$ref": "#/definitions/internal.Movie"
+                }
+            }
+        },
+        "main.CreateOIDCAuthorizationResponse": {
+            "type": "object",
+            "properties": {
+                "authorization_url": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateProductResponse": {
+            "type": "object",
+            "properties": {
+                "product": {
+                    "$ref": "#/definitions/internal.Product"
+                }
+            }
+        },
+        "main.CreateScheduleResponse": {
+            "type": "object",
+            "properties": {
+                "schedule": {
+                    "$ref": "#/definitions/internal.Schedule"
+                }
+            }
+        },
+        "main.CreateSeatReponse": {
+            "type": "object",
+            "properties": {
+                "seat": {
+                    "$ref": "#/definitions/internal.Seat"
+                }
+            }
+        },
+        "main.CreatedUserResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/internal.User"
+                }
+            }
+        },
+        "main.GetAPIKeysResponse": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.APIKey"
+                    }
+                }
+            }
+        },
+        "main.GetAdminUserResponse": {
+            "type": "object",
+            "properties": {
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "schedule": {
-                    "$ref": "#/definitions/internal.Schedule"
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "seat": {
-                    "$ref": "#/definitions/internal.Seat"
+                "user": {
+                    "$ref": "#/definitions/internal.User"
+                }
+            }
+        },
+        "main.GetAdminUsersResponse": {
+            "type": "object",
+            "properties": {
+                "meta_data": {
+                    "$ref": "#/definitions/internal.MetaData"
                 },
-                "ticket": {
-                    "$ref": "#/definitions/internal.Ticket"
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.User"
+                    }
                 }
             }
         },
-        "internal.Cinema": {
+        "main.GetAuditEntriesResponse": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer"
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.AuditEntry"
+                    }
                 },
-                "location": {
-                    "type": "string"
+                "meta_data": {
+                    "$ref": "#/definitions/internal.MetaData"
+                }
+            }
+        },
+        "main.GetCheckoutResponse": {
+            "type": "object",
+            "properties": {
+                "breakdown": {
+                    "$ref": "#/definitions/internal.Breakdown"
                 },
-                "name": {
-                    "type": "string"
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.CheckoutItem"
+                    }
                 },
-                "ower_id": {
-                    "type": "integer"
+                "price": {
+                    "type": "number"
                 },
-                "version": {
-                    "type": "integer"
+                "products": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.CheckoutProduct"
+                    }
                 }
             }
         },
-        "internal.Hall": {
+        "main.GetCinemaMembersResponse": {
             "type": "object",
             "properties": {
-                "cinema_id": {
-                    "type": "integer"
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.CinemaMember"
+                    }
+                }
+            }
+        },
+        "main.GetCinemaResponse": {
+            "type": "object",
+            "properties": {
+                "cinema": {
+                    "$ref": "#/definitions/internal.Cinema"
+                }
+            }
+        },
+        "main.GetFeesResponse": {
+            "type": "object",
+            "properties": {
+                "fees": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.Fee"
+                    }
+                }
+            }
+        },
+        "main.GetGiftCardsResponse": {
+            "type": "object",
+            "properties": {
+                "gift_cards": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.GiftCard"
+                    }
+                }
+            }
+        },
+        "main.GetHallsResponse": {
+            "type": "object",
+            "properties": {
+                "halls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.Hall"
+                    }
+                }
+            }
+        },
+        "main.GetLoyaltyResponse": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/internal.LoyaltyAccount"
                 },
-                "id": {
-                    "type": "integer"
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.LoyaltyEntry"
+                    }
                 },
-                "name": {
-                    "type": "string"
+                "meta_data": {
+                    "$ref": "#/definitions/internal.MetaData"
+                }
+            }
+        },
+        "main.GetMembershipPlansResponse": {
+            "type": "object",
+            "properties": {
+                "plans": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.MembershipPlan"
+                    }
+                }
+            }
+        },
+        "main.GetMembershipsResponse": {
+            "type": "object",
+            "properties": {
+                "memberships": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.Membership"
+                    }
+                }
+            }
+        },
+        "main.GetMovieResponse": {
+            "type": "object",
+            "properties": {
+                "movie": {
+                    "$ref": "#/definitions/internal.Movie"
+                }
+            }
+        },
+        "main.GetMoviesResponse": {
+            "type": "object",
+            "properties": {
+                "meta_data": {
+                    "$ref": "#/definitions/internal.MetaData"
                 },
-                "seat_arrangement": {
-                    "type": "string"
+                "movies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.Movie"
+                    }
+                }
+            }
+        },
+        "main.GetOIDCProvidersResponse": {
+            "type": "object",
+            "properties": {
+                "providers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.GetPaymentDiscrepanciesResponse": {
+            "type": "object",
+            "properties": {
+                "discrepancies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.PaymentDiscrepancy"
+                    }
                 },
-                "seat_price": {
-                    "type": "number"
+                "meta_data": {
+                    "$ref": "#/definitions/internal.MetaData"
+                }
+            }
+        },
+        "main.GetPaymentEventsResponse": {
+            "type": "object",
+            "properties": {
+                "meta_data": {
+                    "$ref": "#/definitions/internal.MetaData"
                 },
-                "version": {
-                    "type": "integer"
+                "payment_events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.PaymentEvent"
+                    }
                 }
             }
         },
-        "internal.MetaData": {
+        "main.GetProductsResponse": {
             "type": "object",
             "properties": {
-                "current_page": {
-                    "type": "integer"
+                "products": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.Product"
+                    }
+                }
+            }
+        },
+        "main.GetResaleListingsResponse": {
+            "type": "object",
+            "properties": {
+                "listings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.ResaleListing"
+                    }
                 },
-                "first_page": {
-                    "type": "integer"
+                "meta_data": {
+                    "$ref": "#/definitions/internal.MetaData"
+                }
+            }
+        },
+        "main.GetRolesResponse": {
+            "type": "object",
+            "properties": {
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "last_page": {
-                    "type": "integer"
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.Role"
+                    }
+                }
+            }
+        },
+        "main.GetSessionsResponse": {
+            "type": "object",
+            "properties": {
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.Session"
+                    }
+                }
+            }
+        },
+        "main.GetTicketTransfersResponse": {
+            "type": "object",
+            "properties": {
+                "transfers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal.TicketTransfer"
+                    }
+                }
+            }
+        },
+        "main.GetTwoFactorResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
                 },
-                "page_size": {
+                "recovery_codes_left": {
                     "type": "integer"
                 },
-                "total_records": {
-                    "type": "integer"
+                "required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.GetUserResponse": {
+            "type": "object",
+            "properties": {
+                "user": {
+                    "$ref": "#/definitions/internal.User"
                 }
             }
         },
-        "internal.Movie": {
+        "main.GetWalletResponse": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "balance": {
+                    "type": "number"
                 },
-                "genres": {
+                "entries": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/internal.WalletEntry"
                     }
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "runtime": {
-                    "type": "integer"
-                },
-                "title": {
-                    "type": "string"
-                },
-                "version": {
-                    "type": "integer"
-                },
-                "year": {
-                    "type": "integer"
+                "meta_data": {
+                    "$ref": "#/definitions/internal.MetaData"
                 }
             }
         },
-        "internal.Schedule": {
+        "main.GiftCardResponse": {
             "type": "object",
             "properties": {
-                "created_at": {
+                "code": {
                     "type": "string"
                 },
-                "ends_at": {
-                    "type": "string"
-                },
-                "hall_id": {
-                    "type": "integer"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "movie_id": {
-                    "type": "integer"
-                },
-                "price": {
-                    "type": "number"
+                "gift_card": {
+                    "$ref": "#/definitions/internal.GiftCard"
                 },
-                "starts_at": {
+                "url": {
                     "type": "string"
-                },
-                "version": {
-                    "type": "integer"
                 }
             }
         },
-        "internal.Seat": {
+        "main.HealthCheckResponse": {
             "type": "object",
             "properties": {
-                "coordinates": {
+                "enviroment": {
                     "type": "string"
                 },
-                "hall_id": {
-                    "type": "integer"
-                },
-                "id": {
-                    "type": "integer"
+                "status": {
+                    "type": "string"
                 },
                 "version": {
-                    "type": "integer"
+                    "type": "string"
                 }
             }
         },
-        "internal.Ticket": {
+        "main.JoinWaitingRoomResponse": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "price": {
-                    "type": "number"
-                },
-                "schedule_id": {
-                    "type": "integer"
-                },
-                "seat_id": {
-                    "type": "integer"
-                },
-                "state_changed_at": {
+                "admission_token": {
                     "type": "string"
                 },
-                "state_id": {
-                    "$ref": "#/definitions/internal.TicketState"
-                },
-                "version": {
-                    "type": "integer"
+                "entry": {
+                    "$ref": "#/definitions/internal.WaitingRoomEntry"
                 }
             }
         },
-        "internal.TicketState": {
-            "type": "integer",
-            "enum": [
-                0,
-                1,
-                2
-            ],
-            "x-enum-varnames": [
-                "TicketStateUnsold",
-                "TicketStateLocked",
-                "TicketStateSold"
-            ]
-        },
-        "internal.User": {
+        "main.LockTicketResponse": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "description": "CreatedAt",
-                    "type": "string"
-                },
-                "email": {
-                    "description": "Email",
-                    "type": "string"
+                "lock": {
+                    "$ref": "#/definitions/internal.TicketLock"
                 },
-                "id": {
-                    "description": "ID",
+                "membership_id": {
                     "type": "integer"
                 },
-                "is_activated": {
-                    "description": "IsActivated",
-                    "type": "boolean"
-                },
-                "name": {
-                    "description": "Name",
-                    "type": "string"
+                "ticket": {
+                    "$ref": "#/definitions/internal.Ticket"
                 }
             }
         },
-        "main.CreateAuthenticationTokenResponse": {
+        "main.RedeemGiftCardResponse": {
             "type": "object",
             "properties": {
-                "token": {
-                    "type": "string"
+                "entry": {
+                    "$ref": "#/definitions/internal.WalletEntry"
+                },
+                "gift_card": {
+                    "$ref": "#/definitions/internal.GiftCard"
                 }
             }
         },
-        "main.CreateCinemaResponse": {
+        "main.ReplayPaymentEventResponse": {
             "type": "object",
             "properties": {
-                "cinema": {
-                    "$ref": "#/definitions/internal.Cinema"
+                "payment_event": {
+                    "$ref": "#/definitions/internal.PaymentEvent"
                 }
             }
         },
-        "main.CreateHallResponse": {
+        "main.ResaleListingResponse": {
             "type": "object",
             "properties": {
-                "hall": {
-                    "$ref": "#/definitions/internal.Hall"
+                "listing": {
+                    "$ref": "#/definitions/internal.ResaleListing"
                 }
             }
         },
-        "main.CreateMovieResponse": {
+        "main.ResolvePaymentDiscrepancyResponse": {
             "type": "object",
             "properties": {
-                "movie": {
-                    "$ref": "#/definitions/internal.Movie"
+                "discrepancy": {
+                    "$ref": "#/definitions/internal.PaymentDiscrepancy"
                 }
             }
         },
-        "main.CreateScheduleResponse": {
+        "main.ResponseError": {
             "type": "object",
             "properties": {
-                "schedule": {
-                    "$ref": "#/definitions/internal.Schedule"
+                "error": {
+                    "description": "Error",
+                    "type": "string"
                 }
             }
         },
-        "main.CreateSeatReponse": {
+        "main.ResponseMessage": {
             "type": "object",
             "properties": {
-                "seat": {
-                    "$ref": "#/definitions/internal.Seat"
+                "message": {
+                    "description": "Message",
+                    "type": "string"
                 }
             }
         },
-        "main.CreatedUserResponse": {
+        "main.ShiftReportResponse": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "user": {
-                    "$ref": "#/definitions/internal.User"
+                "report": {
+                    "$ref": "#/definitions/internal.ShiftReport"
                 }
             }
         },
-        "main.GetCheckoutResponse": {
+        "main.SubscribeResponse": {
             "type": "object",
             "properties": {
-                "items": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/internal.CheckoutItem"
-                    }
+                "membership": {
+                    "$ref": "#/definitions/internal.Membership"
                 },
-                "price": {
-                    "type": "number"
+                "url": {
+                    "type": "string"
                 }
             }
         },
-        "main.GetCinemaResponse": {
+        "main.TicketPassResponse": {
             "type": "object",
             "properties": {
-                "cinema": {
-                    "$ref": "#/definitions/internal.Cinema"
+                "pass": {
+                    "$ref": "#/definitions/internal.TicketPass"
                 }
             }
         },
-        "main.GetHallsResponse": {
+        "main.TicketTransferResponse": {
             "type": "object",
             "properties": {
-                "halls": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/internal.Hall"
-                    }
+                "transfer": {
+                    "$ref": "#/definitions/internal.TicketTransfer"
                 }
             }
         },
-        "main.GetMovieResponse": {
+        "main.TokenRevocationResponse": {
             "type": "object",
             "properties": {
-                "movie": {
-                    "$ref": "#/definitions/internal.Movie"
+                "revocation": {
+                    "$ref": "#/definitions/internal.TokenRevocation"
                 }
             }
         },
-        "main.GetMoviesResponse": {
+        "main.TwoFactorAuthenticationResponse": {
             "type": "object",
             "properties": {
-                "meta_data": {
-                    "$ref": "#/definitions/internal.MetaData"
+                "expires_at": {
+                    "type": "string"
                 },
-                "movies": {
+                "recovery_codes": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/internal.Movie"
+                        "type": "string"
                     }
-                }
-            }
-        },
-        "main.GetUserResponse": {
-            "type": "object",
-            "properties": {
-                "user": {
-                    "$ref": "#/definitions/internal.User"
-                }
-            }
-        },
-        "main.HealthCheckResponse": {
-            "type": "object",
-            "properties": {
-                "enviroment": {
+                },
+                "refresh_token": {
                     "type": "string"
                 },
-                "status": {
+                "refresh_token_expires_at": {
                     "type": "string"
                 },
-                "version": {
+                "token": {
                     "type": "string"
                 }
             }
         },
-        "main.LockTicketResponse": {
+        "main.TwoFactorChallengeResponse": {
             "type": "object",
             "properties": {
-                "ticket": {
-                    "$ref": "#/definitions/internal.Ticket"
+                "challenge": {
+                    "type": "string"
+                },
+                "enrollment": {
+                    "$ref": "#/definitions/main.TwoFactorEnrollmentResponse"
+                },
+                "expires_at": {
+                    "type": "string"
                 }
             }
         },
-        "main.ResponseError": {
+        "main.TwoFactorEnrollmentResponse": {
             "type": "object",
             "properties": {
-                "error": {
-                    "description": "Error",
+                "provisioning_uri": {
+                    "type": "string"
+                },
+                "secret": {
                     "type": "string"
                 }
             }
         },
-        "main.ResponseMessage": {
+        "main.TwoFactorRecoveryCodesResponse": {
             "type": "object",
             "properties": {
-                "message": {
-                    "description": "Message",
-                    "type": "string"
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
@@ -2257,6 +8994,14 @@ const docTemplate = `{
                 }
             }
         },
+        "main.UpdateFeeResponse": {
+            "type": "object",
+            "properties": {
+                "fee": {
+                    "$ref": "#/definitions/internal.Fee"
+                }
+            }
+        },
         "main.UpdateHallResponse": {
             "type": "object",
             "properties": {
@@ -2273,6 +9018,14 @@ const docTemplate = `{
                 }
             }
         },
+        "main.UpdateProductResponse": {
+            "type": "object",
+            "properties": {
+                "product": {
+                    "$ref": "#/definitions/internal.Product"
+                }
+            }
+        },
         "main.UpdateScheduleResponse": {
             "type": "object",
             "properties": {
@@ -2281,6 +9034,32 @@ const docTemplate = `{
                 }
             }
         },
+        "main.UserPermissionsResponse": {
+            "type": "object",
+            "properties": {
+                "granted": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "main.ViolationsMessage": {
             "type": "object",
             "properties": {
@@ -2292,6 +9071,30 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "main.WaitingRoomPositionResponse": {
+            "type": "object",
+            "properties": {
+                "entry": {
+                    "$ref": "#/definitions/internal.WaitingRoomEntry"
+                }
+            }
+        },
+        "main.WaitingRoomResponse": {
+            "type": "object",
+            "properties": {
+                "waiting_room": {
+                    "$ref": "#/definitions/internal.WaitingRoom"
+                }
+            }
+        },
+        "main.WaitlistEntryResponse": {
+            "type": "object",
+            "properties": {
+                "entry": {
+                    "$ref": "#/definitions/internal.WaitlistEntry"
+                }
+            }
         }
     }
 }`
@@ -3,6 +3,7 @@ module github.com/AdventurerAmer/movie-reservation-system
 go 1.23.2
 
 require (
+	github.com/go-pdf/fpdf v0.9.0
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
 	github.com/shopspring/decimal v1.4.0
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// apiKeyPrefixLength is how much of a key is kept to tell the keys apart, it's shown in the list of keys.
+const apiKeyPrefixLength = 12
+
+// APIKey is a long-lived credential of a user for the integrations like kiosks and partner systems,
+// it's limited to its permissions even if the user has more.
+type APIKey struct {
+	ID          int64        `json:"id"`
+	UserID      int64        `json:"-"`
+	Name        string       `json:"name"`
+	Prefix      string       `json:"prefix"`
+	Permissions []Permission `json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ExpiresAt   *time.Time   `json:"expires_at"`
+	LastUsedAt  *time.Time   `json:"last_used_at"`
+}
+
+// GenerateAPIKey returns a key that starts with "mrs_" so it's recognized when it leaks.
+func GenerateAPIKey() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return "mrs_" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+}
+
+type APIKeyStorer interface {
+	Create(userID int64, name string, key string, permissions []Permission, expiresAt *time.Time) (*APIKey, error)
+	GetAll(userID int64) ([]APIKey, error)
+	Authenticate(key string) (*User, *APIKey, error)
+	Touch(id int64) error
+	Delete(userID int64, id int64) (bool, error)
+	DeleteAllExpired() (int, error)
+}
+
+type apiKeyStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func permissionCodes(permissions []Permission) []string {
+	codes := make([]string, len(permissions))
+	for i, p := range permissions {
+		codes[i] = string(p)
+	}
+	return codes
+}
+
+func toPermissions(codes []string) []Permission {
+	permissions := make([]Permission, len(codes))
+	for i, code := range codes {
+		permissions[i] = Permission(code)
+	}
+	return permissions
+}
+
+func (s apiKeyStorage) Create(userID int64, name string, key string, permissions []Permission, expiresAt *time.Time) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	k := APIKey{
+		UserID:      userID,
+		Name:        name,
+		Prefix:      key[:apiKeyPrefixLength],
+		Permissions: permissions,
+		ExpiresAt:   expiresAt,
+	}
+	query := `INSERT INTO api_keys(user_id, name, prefix, hash, permissions, expires_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, created_at`
+	args := []any{userID, name, k.Prefix, HashToken(key), pq.Array(permissionCodes(permissions)), expiresAt}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// GetAll returns the user's keys that didn't expire.
+func (s apiKeyStorage) GetAll(userID int64) ([]APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT id, name, prefix, permissions, created_at, expires_at, last_used_at
+	          FROM api_keys
+			  WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+			  ORDER BY id`
+	args := []any{userID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []APIKey{}
+	for rows.Next() {
+		k := APIKey{
+			UserID: userID,
+		}
+		var codes []string
+		err := rows.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&codes), &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		k.Permissions = toPermissions(codes)
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Authenticate returns the user of the key and the key, suspended users' keys don't work.
+func (s apiKeyStorage) Authenticate(key string) (*User, *APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	var (
+		u     User
+		k     APIKey
+		codes []string
+	)
+	query := `SELECT u.id, u.created_at, u.name, u.email, u.password_hash, u.is_activated, u.version, u.password_reset_required,
+	                 k.id, k.name, k.prefix, k.permissions, k.created_at, k.expires_at, k.last_used_at
+	          FROM api_keys AS k
+			  INNER JOIN users AS u
+			  ON k.user_id = u.id
+			  WHERE k.hash = $1 AND (k.expires_at IS NULL OR k.expires_at > NOW()) AND u.suspended_at IS NULL`
+	args := []any{HashToken(key)}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
+		&u.ID, &u.CreatedAt, &u.Name, &u.Email, &u.PasswordHash, &u.IsActivated, &u.Version, &u.PasswordResetRequired,
+		&k.ID, &k.Name, &k.Prefix, pq.Array(&codes), &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	k.UserID = u.ID
+	k.Permissions = toPermissions(codes)
+	return &u, &k, nil
+}
+
+// Touch records that the key was just used.
+func (s apiKeyStorage) Touch(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE api_keys
+	          SET last_used_at = NOW()
+			  WHERE id = $1`
+	args := []any{id}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Delete revokes the user's key and reports whether the user had it.
+func (s apiKeyStorage) Delete(userID int64, id int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM api_keys
+	          WHERE id = $1 AND user_id = $2`
+	args := []any{id, userID}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s apiKeyStorage) DeleteAllExpired() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM api_keys
+	          WHERE NOW() > expires_at`
+	result, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
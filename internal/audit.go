@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"time"
+)
+
+// AuditAction is an action taken by an admin on a user.
+type AuditAction string
+
+const (
+	AuditActionSuspend            AuditAction = "user:suspend"
+	AuditActionUnsuspend          AuditAction = "user:unsuspend"
+	AuditActionForcePasswordReset AuditAction = "user:force_password_reset"
+	AuditActionGrantRoles         AuditAction = "user:grant_roles"
+	AuditActionRevokeRole         AuditAction = "user:revoke_role"
+	AuditActionGrantPermissions   AuditAction = "user:grant_permissions"
+	AuditActionRevokePermission   AuditAction = "user:revoke_permission"
+	AuditActionRevokeTokens       AuditAction = "user:revoke_tokens"
+)
+
+type AuditEntry struct {
+	ID        int64       `json:"id"`
+	CreatedAt time.Time   `json:"created_at"`
+	ActorID   *int64      `json:"actor_id"`
+	UserID    *int64      `json:"user_id"`
+	Action    AuditAction `json:"action"`
+	Details   string      `json:"details,omitempty"`
+}
+
+type AuditStorer interface {
+	Create(actorID int64, userID int64, action AuditAction, details string) (*AuditEntry, error)
+	GetAll(userID int64, page int, pageSize int) ([]AuditEntry, *MetaData, error)
+}
+
+type auditStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s auditStorage) Create(actorID int64, userID int64, action AuditAction, details string) (*AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	e := AuditEntry{
+		ActorID: &actorID,
+		UserID:  &userID,
+		Action:  action,
+		Details: details,
+	}
+	query := `INSERT INTO audit_entries(actor_id, user_id, action, details)
+	          VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at`
+	args := []any{actorID, userID, action, details}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// GetAll returns the entries of the actions taken on the user, the latest first.
+func (s auditStorage) GetAll(userID int64, page int, pageSize int) ([]AuditEntry, *MetaData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT count(*) OVER(), id, created_at, actor_id, user_id, action, details
+	          FROM audit_entries
+			  WHERE user_id = $1
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $2 OFFSET $3`
+	limit := pageSize
+	offset := (page - 1) * pageSize
+	args := []any{userID, limit, offset}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	totalRecords := 0
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		err := rows.Scan(&totalRecords, &e.ID, &e.CreatedAt, &e.ActorID, &e.UserID, &e.Action, &e.Details)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metaData := &MetaData{}
+	if totalRecords != 0 {
+		metaData = &MetaData{
+			CurrentPage:  page,
+			PageSize:     pageSize,
+			FirstPage:    1,
+			LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+			TotalRecords: totalRecords,
+		}
+	}
+	return entries, metaData, nil
+}
@@ -0,0 +1,326 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+type PaymentMethod int16
+
+const (
+	PaymentMethodOnline PaymentMethod = iota
+	PaymentMethodCash
+	PaymentMethodCardPresent
+)
+
+func (m PaymentMethod) String() string {
+	switch m {
+	case PaymentMethodOnline:
+		return "Online"
+	case PaymentMethodCash:
+		return "Cash"
+	case PaymentMethodCardPresent:
+		return "Card Present"
+	}
+	return fmt.Sprintf("PaymentMethod %d", m)
+}
+
+var (
+	ErrTicketUnavailable = errors.New("ticket is not available")
+	ErrDrawerClosed      = errors.New("cash drawer is closed")
+)
+
+// CashDrawer is a cashier's shift at a cinema, every box office sale is recorded in the open drawer of its cashier.
+type CashDrawer struct {
+	ID           int64            `json:"id"`
+	CinemaID     int32            `json:"cinema_id"`
+	CashierID    int64            `json:"cashier_id"`
+	OpenedAt     time.Time        `json:"opened_at"`
+	ClosedAt     *time.Time       `json:"closed_at,omitempty"`
+	OpeningFloat decimal.Decimal  `json:"opening_float"`
+	ExpectedCash *decimal.Decimal `json:"expected_cash,omitempty"`
+	CountedCash  *decimal.Decimal `json:"counted_cash,omitempty"`
+}
+
+// ShiftReport sums the sales of a cash drawer, Difference is the counted cash minus the expected cash once it's closed.
+type ShiftReport struct {
+	Drawer       CashDrawer       `json:"drawer"`
+	Orders       int64            `json:"orders"`
+	Tickets      int64            `json:"tickets"`
+	Products     int64            `json:"products"`
+	CashSales    decimal.Decimal  `json:"cash_sales"`
+	CardSales    decimal.Decimal  `json:"card_sales"`
+	TotalSales   decimal.Decimal  `json:"total_sales"`
+	ExpectedCash decimal.Decimal  `json:"expected_cash"`
+	Difference   *decimal.Decimal `json:"difference,omitempty"`
+}
+
+type BoxOfficeStorer interface {
+	OpenDrawer(cinemaID int32, cashierID int64, openingFloat decimal.Decimal) (*CashDrawer, error)
+	GetDrawer(id int64) (*CashDrawer, error)
+	GetItems(cinemaID int32, ticketIDs []int64) ([]CheckoutItem, error)
+	Sell(d *CashDrawer, items []CheckoutItem, products []CheckoutProduct, b *Breakdown, method PaymentMethod, paymentReference string) (*Order, error)
+	CloseDrawer(d *CashDrawer, countedCash decimal.Decimal) error
+	GetReport(d *CashDrawer) (*ShiftReport, error)
+}
+
+type boxOfficeStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// OpenDrawer opens a cash drawer for the cashier, it returns nil if the cashier already has an open drawer at the cinema.
+func (s boxOfficeStorage) OpenDrawer(cinemaID int32, cashierID int64, openingFloat decimal.Decimal) (*CashDrawer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	d := CashDrawer{
+		CinemaID:     cinemaID,
+		CashierID:    cashierID,
+		OpeningFloat: openingFloat,
+	}
+	query := `INSERT INTO cash_drawers(cinema_id, cashier_id, opening_float)
+	          VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING
+			  RETURNING id, opened_at`
+	args := []any{cinemaID, cashierID, openingFloat}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&d.ID, &d.OpenedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s boxOfficeStorage) GetDrawer(id int64) (*CashDrawer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	d := CashDrawer{
+		ID: id,
+	}
+	query := `SELECT cinema_id, cashier_id, opened_at, closed_at, opening_float, expected_cash, counted_cash
+	          FROM cash_drawers
+			  WHERE id = $1`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&d.CinemaID, &d.CashierID, &d.OpenedAt, &d.ClosedAt, &d.OpeningFloat, &d.ExpectedCash, &d.CountedCash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetItems returns the tickets of the cinema with the given ids, the box office sells them at their own price.
+func (s boxOfficeStorage) GetItems(cinemaID int32, ticketIDs []int64) ([]CheckoutItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT t.id, t.created_at, t.schedule_id, t.seat_id, t.price, t.state_id, t.state_changed_at, t.version,
+			  sc.id, sc.created_at, sc.movie_id, sc.hall_id, sc.price, sc.starts_at, sc.ends_at, sc.version,
+	          m.id, m.created_at, m.title, m.runtime, m.year, m.genres, m.version,
+			  s.id, s.hall_id, s.coordinates, s.version,
+			  h.id, h.name, h.cinema_id, h.seat_arrangement, h.seat_price, h.version,
+			  c.id, c.name, c.location, c.owner_id, c.version
+			  FROM tickets as t
+			  INNER JOIN schedules as sc
+			  ON t.schedule_id = sc.id
+			  INNER JOIN movies as m
+			  ON sc.movie_id = m.id
+			  INNER JOIN seats as s
+			  ON s.id = t.seat_id
+			  INNER JOIN halls as h
+			  ON h.id = s.hall_id
+			  INNER JOIN cinemas as c
+			  ON c.id = h.cinema_id
+			  WHERE t.id = ANY($1) AND c.id = $2
+			  ORDER BY t.id ASC`
+	args := []any{pq.Array(ticketIDs), cinemaID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var items []CheckoutItem
+	for rows.Next() {
+		item := CheckoutItem{}
+		t := &item.Ticket
+		sc := &item.Schedule
+		m := &item.Movie
+		s := &item.Seat
+		h := &item.Hall
+		c := &item.Cinema
+		err = rows.Scan(&t.ID, &t.CreatedAt, &t.ScheduleID, &t.SeatID, &t.Price, &t.StateID, &t.StateChangedAt, &t.Version,
+			&sc.ID, &sc.CreatedAt, &sc.MovieID, &sc.HallID, &sc.Price, &sc.StartsAt, &sc.EndsAt, &sc.Version,
+			&m.ID, &m.CreatedAt, &m.Title, &m.Runtime, &m.Year, pq.Array(&m.Genres), &m.Version,
+			&s.ID, &s.HallID, &s.Coordinates, &s.Version,
+			&h.ID, &h.Name, &h.CinemaID, &h.SeatArrangement, &h.SeatPrice, &h.Version,
+			&c.ID, &c.Name, &c.Location, &c.OwnerID, &c.Version)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Sell sells the tickets and products at the counter, the tickets are sold immediately and the order is completed
+// with the cashier as its user. It returns ErrTicketUnavailable if a ticket isn't unsold, is reserved for a waitlisted user
+// or its schedule ended and ErrDrawerClosed if the drawer was closed.
+func (s boxOfficeStorage) Sell(d *CashDrawer, items []CheckoutItem, products []CheckoutProduct, b *Breakdown, method PaymentMethod, paymentReference string) (*Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	o := newOrder(d.CashierID, "box_office_"+GenerateToken(), items, products, b)
+	o.StatusID = OrderStatusCompleted
+	o.PaymentMethod = method
+	o.PaymentReference = paymentReference
+	o.CashierID = &d.CashierID
+	o.DrawerID = &d.ID
+	ticketIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		ticketIDs = append(ticketIDs, item.Ticket.ID)
+	}
+
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	// closing the drawer waits for the sales in progress.
+	query0 := `SELECT id FROM cash_drawers
+	           WHERE id = $1 AND closed_at IS NULL
+			   FOR UPDATE`
+	args0 := []any{d.ID}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&d.ID)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDrawerClosed
+		}
+		return nil, err
+	}
+	query1 := `UPDATE tickets AS t
+			   SET state_id = 2, state_changed_at = NOW(), version = t.version + 1
+			   FROM schedules AS sc
+			   JOIN halls AS h
+			   ON h.id = sc.hall_id
+			   WHERE t.schedule_id = sc.id AND t.id = ANY($1) AND h.cinema_id = $2 AND t.state_id = 0 AND NOW() < sc.ends_at
+			   AND NOT EXISTS(SELECT 1 FROM ticket_reservations AS r WHERE r.ticket_id = t.id AND NOW() < r.expires_at)`
+	args1 := []any{pq.Array(ticketIDs), d.CinemaID}
+	result, err := tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if n != int64(len(ticketIDs)) {
+		tx.Rollback()
+		return nil, ErrTicketUnavailable
+	}
+	err = insertOrder(ctx, tx, &o, products)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	query2 := `INSERT INTO transactions(ticket_id, user_id, order_id)
+			   SELECT unnest($1::bigint[]), $2, $3`
+	args2 := []any{pq.Array(ticketIDs), d.CashierID, o.ID}
+	_, err = tx.ExecContext(ctx, query2, args2...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	query3 := `WITH numbers AS (
+				   UPDATE cinemas AS c
+				   SET last_invoice_number = c.last_invoice_number + 1
+				   WHERE c.id = $2
+				   RETURNING c.id, c.last_invoice_number
+			   )
+			   INSERT INTO invoices(order_id, cinema_id, number)
+			   SELECT $1, id, last_invoice_number FROM numbers`
+	args3 := []any{o.ID, d.CinemaID}
+	_, err = tx.ExecContext(ctx, query3, args3...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// CloseDrawer closes the drawer with the cash counted by the cashier, the expected cash is the opening float
+// plus the cash sales. It returns ErrDrawerClosed if the drawer was already closed.
+func (s boxOfficeStorage) CloseDrawer(d *CashDrawer, countedCash decimal.Decimal) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE cash_drawers AS d
+	          SET closed_at = NOW(), counted_cash = $2,
+			  expected_cash = d.opening_float + (SELECT COALESCE(SUM(o.total), 0) FROM orders AS o WHERE o.drawer_id = d.id AND o.status_id = 1 AND o.payment_method_id = 1)
+			  WHERE d.id = $1 AND d.closed_at IS NULL
+			  RETURNING closed_at, expected_cash, counted_cash`
+	args := []any{d.ID, countedCash}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&d.ClosedAt, &d.ExpectedCash, &d.CountedCash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDrawerClosed
+		}
+		return err
+	}
+	return nil
+}
+
+func (s boxOfficeStorage) GetReport(d *CashDrawer) (*ShiftReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	r := ShiftReport{
+		Drawer: *d,
+	}
+	query := `SELECT
+	              (SELECT count(*) FROM orders WHERE drawer_id = $1 AND status_id = 1),
+	              (SELECT COALESCE(SUM(l.quantity) FILTER (WHERE l.kind_id = 0), 0) FROM order_lines AS l JOIN orders AS o ON o.id = l.order_id WHERE o.drawer_id = $1 AND o.status_id = 1),
+	              (SELECT COALESCE(SUM(l.quantity) FILTER (WHERE l.kind_id = 3), 0) FROM order_lines AS l JOIN orders AS o ON o.id = l.order_id WHERE o.drawer_id = $1 AND o.status_id = 1),
+	              (SELECT COALESCE(SUM(total) FILTER (WHERE payment_method_id = 1), 0) FROM orders WHERE drawer_id = $1 AND status_id = 1),
+	              (SELECT COALESCE(SUM(total) FILTER (WHERE payment_method_id = 2), 0) FROM orders WHERE drawer_id = $1 AND status_id = 1)`
+	args := []any{d.ID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&r.Orders, &r.Tickets, &r.Products, &r.CashSales, &r.CardSales)
+	if err != nil {
+		return nil, err
+	}
+	r.TotalSales = r.CashSales.Add(r.CardSales)
+	r.ExpectedCash = d.OpeningFloat.Add(r.CashSales)
+	if d.ExpectedCash != nil && d.CountedCash != nil {
+		r.ExpectedCash = *d.ExpectedCash
+		difference := d.CountedCash.Sub(*d.ExpectedCash)
+		r.Difference = &difference
+	}
+	return &r, nil
+}
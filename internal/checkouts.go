@@ -12,12 +12,14 @@ import (
 )
 
 type CheckoutItem struct {
-	Ticket   Ticket   `json:"ticket"`
-	Schedule Schedule `json:"schedule"`
-	Movie    Movie    `json:"movie"`
-	Seat     Seat     `json:"seat"`
-	Hall     Hall     `json:"hall"`
-	Cinema   Cinema   `json:"cinema"`
+	Ticket       Ticket   `json:"ticket"`
+	Schedule     Schedule `json:"schedule"`
+	Movie        Movie    `json:"movie"`
+	Seat         Seat     `json:"seat"`
+	Hall         Hall     `json:"hall"`
+	Cinema       Cinema   `json:"cinema"`
+	ListingID    *int64   `json:"listing_id,omitempty"`
+	MembershipID *int64   `json:"membership_id,omitempty"`
 }
 
 type CheckoutSession struct {
@@ -28,13 +30,14 @@ type CheckoutSession struct {
 
 type CheckoutStorer interface {
 	GetItems(userID int64) ([]CheckoutItem, decimal.Decimal, error)
-	Create(userID int64, sessionID string) (*CheckoutSession, error)
+	Create(userID int64, sessionID string, expiresAt time.Time) (*CheckoutSession, error)
+	SetSessionID(sessionID string, newSessionID string) error
 	GetByUserID(userID int64) (*CheckoutSession, error)
 	GetBySessionID(sessionID string) (*CheckoutSession, error)
 	DeleteByUserID(UserID int64) error
 	DeleteBySessionID(sessionID string) error
 	GetAllExpired(limit int64) ([]CheckoutSession, error)
-	Fulfill(sessionID string, userID int64) error
+	Fulfill(sessionID string, userID int64, paymentReference string) error
 }
 
 type checkoutStorage struct {
@@ -42,15 +45,17 @@ type checkoutStorage struct {
 	db           *sql.DB
 }
 
+// GetItems returns the tickets locked by the user, resold tickets are priced at their listing's price.
 func (s checkoutStorage) GetItems(userID int64) ([]CheckoutItem, decimal.Decimal, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
-	query := `SELECT t.id, t.created_at, t.schedule_id, t.seat_id, t.price, t.state_id, t.state_changed_at, t.version,
+	query := `SELECT t.id, t.created_at, t.schedule_id, t.seat_id, COALESCE(l.price, t.price), t.state_id, t.state_changed_at, t.version,
 			  sc.id, sc.created_at, sc.movie_id, sc.hall_id, sc.price, sc.starts_at, sc.ends_at, sc.version,
 	          m.id, m.created_at, m.title, m.runtime, m.year, m.genres, m.version,
 			  s.id, s.hall_id, s.coordinates, s.version,
 			  h.id, h.name, h.cinema_id, h.seat_arrangement, h.seat_price, h.version,
-			  c.id, c.name, c.location, c.owner_id, c.version
+			  c.id, c.name, c.location, c.owner_id, c.version,
+			  tu.listing_id
 			  FROM tickets_users as tu
 			  INNER JOIN tickets as t
 			  ON t.id = tu.ticket_id
@@ -64,6 +69,8 @@ func (s checkoutStorage) GetItems(userID int64) ([]CheckoutItem, decimal.Decimal
 			  ON h.id = s.hall_id
 			  INNER JOIN cinemas as c
 			  ON c.id = h.cinema_id
+			  LEFT JOIN resale_listings as l
+			  ON l.id = tu.listing_id
 			  WHERE tu.user_id = $1 AND NOW() < sc.starts_at`
 	args := []any{userID}
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -94,7 +101,8 @@ func (s checkoutStorage) GetItems(userID int64) ([]CheckoutItem, decimal.Decimal
 			&m.ID, &m.CreatedAt, &m.Title, &m.Runtime, &m.Year, pq.Array(&m.Genres), &m.Version,
 			&s.ID, &s.HallID, &s.Coordinates, &s.Version,
 			&h.ID, &h.Name, &h.CinemaID, &h.SeatArrangement, &h.SeatPrice, &h.Version,
-			&c.ID, &c.Name, &c.Location, &c.OwnerID, &c.Version)
+			&c.ID, &c.Name, &c.Location, &c.OwnerID, &c.Version,
+			&item.ListingID)
 		if err != nil {
 			return nil, decimal.Zero, err
 		}
@@ -107,24 +115,76 @@ func (s checkoutStorage) GetItems(userID int64) ([]CheckoutItem, decimal.Decimal
 	return items, total, nil
 }
 
-func (s checkoutStorage) Create(userID int64, sessionID string) (*CheckoutSession, error) {
+// Create creates the checkout session and holds the tickets of the user until it expires.
+func (s checkoutStorage) Create(userID int64, sessionID string, expiresAt time.Time) (*CheckoutSession, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
 	session := CheckoutSession{
 		UserID:    userID,
 		SessionID: sessionID,
 	}
-	query := `INSERT INTO checkout_sessions(user_id, session_id)
-	          VALUES ($1, $2)
-			  RETURNING expires_at`
-	args := []any{userID, sessionID}
-	err := s.db.QueryRowContext(ctx, query, args...).Scan(&session.ExpiresAt)
+	query0 := `INSERT INTO checkout_sessions(user_id, session_id, expires_at)
+	           VALUES ($1, $2, $3)
+			   RETURNING expires_at`
+	args0 := []any{userID, sessionID, expiresAt}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&session.ExpiresAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	query1 := `UPDATE tickets_users
+	           SET expires_at = GREATEST(expires_at, $2)
+			   WHERE user_id = $1`
+	args1 := []any{userID, session.ExpiresAt}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
 	return &session, nil
 }
 
+// SetSessionID moves the checkout session and its order to the session created on the payment gateway.
+func (s checkoutStorage) SetSessionID(sessionID string, newSessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	query0 := `UPDATE checkout_sessions
+	           SET session_id = $2
+			   WHERE session_id = $1`
+	args0 := []any{sessionID, newSessionID}
+	_, err = tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query1 := `UPDATE orders
+	           SET session_id = $2
+			   WHERE session_id = $1`
+	args1 := []any{sessionID, newSessionID}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
 func (s checkoutStorage) GetByUserID(userID int64) (*CheckoutSession, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
@@ -163,23 +223,159 @@ func (s checkoutStorage) GetBySessionID(sessionID string) (*CheckoutSession, err
 	return &session, nil
 }
 
+// DeleteByUserID cancels the pending order of the user's checkout session, gives back the stock of its products
+// and releases what was paid from the wallet and the loyalty points redeemed for it.
 func (s checkoutStorage) DeleteByUserID(UserID int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
-	query := `DELETE FROM checkout_sessions
-	          WHERE user_id = $1`
-	args := []any{UserID}
-	_, err := s.db.ExecContext(ctx, query, args...)
+	// the releases are computed from the ledger so concurrent deletes must not both see the same debits.
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	query0 := `WITH cancelled AS (
+	               UPDATE orders AS o
+	               SET status_id = 2
+			       FROM checkout_sessions AS cs
+			       WHERE o.session_id = cs.session_id AND cs.user_id = $1 AND o.status_id = 0
+				   RETURNING o.id
+			   )
+			   UPDATE products AS p
+			   SET stock = p.stock + l.quantity
+			   FROM (SELECT ol.product_id, SUM(ol.quantity) AS quantity
+			         FROM order_lines AS ol
+					 JOIN cancelled AS c
+					 ON c.id = ol.order_id
+					 WHERE ol.product_id IS NOT NULL
+					 GROUP BY ol.product_id) AS l
+			   WHERE p.id = l.product_id`
+	args0 := []any{UserID}
+	_, err = tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query1 := `INSERT INTO wallet_entries(user_id, kind_id, amount, order_id)
+	           SELECT we.user_id, 2, -SUM(we.amount), we.order_id
+			   FROM wallet_entries AS we
+			   JOIN orders AS o
+			   ON o.id = we.order_id
+			   JOIN checkout_sessions AS cs
+			   ON cs.session_id = o.session_id
+			   WHERE cs.user_id = $1 AND we.kind_id IN (1, 2)
+			   GROUP BY we.user_id, we.order_id
+			   HAVING SUM(we.amount) < 0`
+	args1 := []any{UserID}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query2 := `INSERT INTO loyalty_entries(user_id, kind_id, points, order_id)
+	           SELECT le.user_id, 2, -SUM(le.points), le.order_id
+			   FROM loyalty_entries AS le
+			   JOIN orders AS o
+			   ON o.id = le.order_id
+			   JOIN checkout_sessions AS cs
+			   ON cs.session_id = o.session_id
+			   WHERE cs.user_id = $1 AND le.kind_id IN (1, 2)
+			   GROUP BY le.user_id, le.order_id
+			   HAVING SUM(le.points) < 0`
+	args2 := []any{UserID}
+	_, err = tx.ExecContext(ctx, query2, args2...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query3 := `DELETE FROM checkout_sessions
+	           WHERE user_id = $1`
+	args3 := []any{UserID}
+	_, err = tx.ExecContext(ctx, query3, args3...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
 	return err
 }
 
+// DeleteBySessionID cancels the pending order of the checkout session, gives back the stock of its products
+// and releases what was paid from the wallet and the loyalty points redeemed for it.
 func (s checkoutStorage) DeleteBySessionID(sessionID string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
-	query := `DELETE FROM checkout_sessions
-	          WHERE session_id = $1`
-	args := []any{sessionID}
-	_, err := s.db.ExecContext(ctx, query, args...)
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	query0 := `WITH cancelled AS (
+	               UPDATE orders
+	               SET status_id = 2
+			       WHERE session_id = $1 AND status_id = 0
+				   RETURNING id
+			   )
+			   UPDATE products AS p
+			   SET stock = p.stock + l.quantity
+			   FROM (SELECT ol.product_id, SUM(ol.quantity) AS quantity
+			         FROM order_lines AS ol
+					 JOIN cancelled AS c
+					 ON c.id = ol.order_id
+					 WHERE ol.product_id IS NOT NULL
+					 GROUP BY ol.product_id) AS l
+			   WHERE p.id = l.product_id`
+	args0 := []any{sessionID}
+	_, err = tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query1 := `INSERT INTO wallet_entries(user_id, kind_id, amount, order_id)
+	           SELECT we.user_id, 2, -SUM(we.amount), we.order_id
+			   FROM wallet_entries AS we
+			   JOIN orders AS o
+			   ON o.id = we.order_id
+			   JOIN checkout_sessions AS cs
+			   ON cs.session_id = o.session_id
+			   WHERE cs.session_id = $1 AND we.kind_id IN (1, 2)
+			   GROUP BY we.user_id, we.order_id
+			   HAVING SUM(we.amount) < 0`
+	args1 := []any{sessionID}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query2 := `INSERT INTO loyalty_entries(user_id, kind_id, points, order_id)
+	           SELECT le.user_id, 2, -SUM(le.points), le.order_id
+			   FROM loyalty_entries AS le
+			   JOIN orders AS o
+			   ON o.id = le.order_id
+			   JOIN checkout_sessions AS cs
+			   ON cs.session_id = o.session_id
+			   WHERE cs.session_id = $1 AND le.kind_id IN (1, 2)
+			   GROUP BY le.user_id, le.order_id
+			   HAVING SUM(le.points) < 0`
+	args2 := []any{sessionID}
+	_, err = tx.ExecContext(ctx, query2, args2...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query3 := `DELETE FROM checkout_sessions
+	           WHERE session_id = $1`
+	args3 := []any{sessionID}
+	_, err = tx.ExecContext(ctx, query3, args3...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
 	return err
 }
 
@@ -212,7 +408,9 @@ func (s checkoutStorage) GetAllExpired(limit int64) ([]CheckoutSession, error) {
 	return sessions, nil
 }
 
-func (s checkoutStorage) Fulfill(sessionID string, userID int64) error {
+// Fulfill sells the tickets locked by the user and empties the user's products, it does nothing if the checkout session
+// was already fulfilled or deleted so it's safe to call it again for the same session.
+func (s checkoutStorage) Fulfill(sessionID string, userID int64, paymentReference string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
 
@@ -223,41 +421,121 @@ func (s checkoutStorage) Fulfill(sessionID string, userID int64) error {
 	if err != nil {
 		return err
 	}
-	query0 := `UPDATE tickets AS t
-			   SET state_id = 2, state_changed_at = NOW(), version = t.version + 1
-			   FROM tickets_users AS tu
-			   WHERE t.id = tu.ticket_id AND tu.user_id = $1 AND t.state_id = 1`
-	args0 := []any{userID}
-	_, err = tx.ExecContext(ctx, query0, args0...)
+	query0 := `DELETE FROM checkout_sessions
+	           WHERE user_id = $1 AND session_id = $2`
+	args0 := []any{userID, sessionID}
+	result, err := tx.ExecContext(ctx, query0, args0...)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	query1 := `INSERT INTO transactions(ticket_id, user_id)
-			   SELECT ticket_id, user_id FROM tickets_users
-			   WHERE user_id = $1`
+	n, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n == 0 {
+		return tx.Rollback()
+	}
+	query1 := `UPDATE tickets AS t
+			   SET state_id = 2, state_changed_at = NOW(), version = t.version + 1
+			   FROM tickets_users AS tu
+			   WHERE t.id = tu.ticket_id AND tu.user_id = $1 AND t.state_id = 1`
 	args1 := []any{userID}
 	_, err = tx.ExecContext(ctx, query1, args1...)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	query2 := `DELETE FROM tickets_users
-			   WHERE user_id = $1`
-	args2 := []any{userID}
+	query2 := `INSERT INTO transactions(ticket_id, user_id, order_id)
+			   SELECT tu.ticket_id, tu.user_id, o.id FROM tickets_users AS tu
+			   LEFT JOIN orders AS o
+			   ON o.session_id = $2
+			   WHERE tu.user_id = $1 AND tu.listing_id IS NULL`
+	args2 := []any{userID, sessionID}
 	_, err = tx.ExecContext(ctx, query2, args2...)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	query3 := `DELETE FROM checkout_sessions
-	           WHERE user_id = $1 AND session_id = $2`
+	// resold tickets change hands in place so the seller's pass stops working and the seller's order
+	// stays the one refunded with the proceeds.
+	query3 := `UPDATE transactions AS tr
+			   SET user_id = tu.user_id, code = gen_random_uuid(), order_id = (SELECT id FROM orders WHERE session_id = $2)
+			   FROM tickets_users AS tu
+			   JOIN resale_listings AS l
+			   ON l.id = tu.listing_id
+			   WHERE tu.user_id = $1 AND tr.ticket_id = l.ticket_id AND tr.user_id = l.seller_id`
 	args3 := []any{userID, sessionID}
 	_, err = tx.ExecContext(ctx, query3, args3...)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
+	query4 := `UPDATE resale_listings AS l
+			   SET status_id = 1, buyer_id = tu.user_id, buyer_order_id = (SELECT id FROM orders WHERE session_id = $2), sold_at = NOW()
+			   FROM tickets_users AS tu
+			   WHERE tu.user_id = $1 AND l.id = tu.listing_id AND l.status_id = 0`
+	args4 := []any{userID, sessionID}
+	_, err = tx.ExecContext(ctx, query4, args4...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query5 := `UPDATE ticket_transfers AS tt
+			   SET status_id = 2
+			   FROM tickets_users AS tu
+			   WHERE tu.user_id = $1 AND tu.listing_id IS NOT NULL AND tt.ticket_id = tu.ticket_id AND tt.status_id = 0`
+	args5 := []any{userID}
+	_, err = tx.ExecContext(ctx, query5, args5...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query6 := `UPDATE orders
+	           SET status_id = 1, payment_reference = $2
+			   WHERE session_id = $1 AND status_id = 0
+			   RETURNING id`
+	args6 := []any{sessionID, paymentReference}
+	var orderID int64
+	err = tx.QueryRowContext(ctx, query6, args6...).Scan(&orderID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		return err
+	}
+	if err == nil {
+		// issuing the invoice numbers locks the cinemas' counters until the commit so the numbers have no gaps.
+		query7 := `WITH numbers AS (
+					   UPDATE cinemas AS c
+					   SET last_invoice_number = c.last_invoice_number + 1
+					   WHERE c.id IN (SELECT DISTINCT cinema_id FROM order_lines WHERE order_id = $1)
+					   RETURNING c.id, c.last_invoice_number
+				   )
+				   INSERT INTO invoices(order_id, cinema_id, number)
+				   SELECT $1, id, last_invoice_number FROM numbers`
+		args7 := []any{orderID}
+		_, err = tx.ExecContext(ctx, query7, args7...)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	query8 := `DELETE FROM tickets_users
+			   WHERE user_id = $1`
+	args8 := []any{userID}
+	_, err = tx.ExecContext(ctx, query8, args8...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	query9 := `DELETE FROM cart_products
+			   WHERE user_id = $1`
+	args9 := []any{userID}
+	_, err = tx.ExecContext(ctx, query9, args9...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 	err = tx.Commit()
 	return err
 }
@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// CinemaRole is the role of a member of a cinema's staff, the owner isn't a member and has every scope.
+type CinemaRole int16
+
+const (
+	CinemaRoleManager CinemaRole = iota
+	CinemaRoleScheduler
+	CinemaRoleBoxOffice
+)
+
+func (r CinemaRole) String() string {
+	switch r {
+	case CinemaRoleManager:
+		return "Manager"
+	case CinemaRoleScheduler:
+		return "Scheduler"
+	case CinemaRoleBoxOffice:
+		return "Box Office"
+	}
+	return fmt.Sprintf("CinemaRole %d", r)
+}
+
+// CinemaScope is what a user can do at a cinema.
+type CinemaScope int16
+
+const (
+	// CinemaScopeCinema covers the cinema's details and settings and overseeing the box office drawers.
+	CinemaScopeCinema CinemaScope = iota
+	// CinemaScopeHalls covers the halls and their seats.
+	CinemaScopeHalls
+	// CinemaScopeSchedules covers the schedules, their tickets and waiting rooms.
+	CinemaScopeSchedules
+	// CinemaScopePricing covers the fees, products and membership plans.
+	CinemaScopePricing
+	// CinemaScopeBoxOffice covers selling at the box office.
+	CinemaScopeBoxOffice
+	// CinemaScopeOwner covers the staff and deleting the cinema, only the owner has it.
+	CinemaScopeOwner
+)
+
+func (s CinemaScope) String() string {
+	switch s {
+	case CinemaScopeCinema:
+		return "Cinema"
+	case CinemaScopeHalls:
+		return "Halls"
+	case CinemaScopeSchedules:
+		return "Schedules"
+	case CinemaScopePricing:
+		return "Pricing"
+	case CinemaScopeBoxOffice:
+		return "Box Office"
+	case CinemaScopeOwner:
+		return "Owner"
+	}
+	return fmt.Sprintf("CinemaScope %d", s)
+}
+
+var cinemaRoleScopes = map[CinemaRole][]CinemaScope{
+	CinemaRoleManager:   {CinemaScopeCinema, CinemaScopeHalls, CinemaScopeSchedules, CinemaScopePricing, CinemaScopeBoxOffice},
+	CinemaRoleScheduler: {CinemaScopeSchedules},
+	CinemaRoleBoxOffice: {CinemaScopeBoxOffice},
+}
+
+// Scopes returns the scopes of the role.
+func (r CinemaRole) Scopes() []CinemaScope {
+	return cinemaRoleScopes[r]
+}
+
+// HasScope reports whether the role has the scope.
+func (r CinemaRole) HasScope(scope CinemaScope) bool {
+	return slices.Contains(cinemaRoleScopes[r], scope)
+}
+
+type CinemaMember struct {
+	CinemaID  int32         `json:"cinema_id"`
+	UserID    int64         `json:"user_id"`
+	Name      string        `json:"name"`
+	Email     string        `json:"email"`
+	Role      CinemaRole    `json:"role"`
+	Scopes    []CinemaScope `json:"scopes"`
+	InvitedBy *int64        `json:"invited_by,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type CinemaMemberStorer interface {
+	Set(cinemaID int32, userID int64, role CinemaRole, invitedBy int64) error
+	Remove(cinemaID int32, userID int64) error
+	GetAll(cinemaID int32) ([]CinemaMember, error)
+	GetAllForUser(userID int64) ([]CinemaMember, error)
+	GetRole(cinemaID int32, userID int64) (*CinemaRole, error)
+	GetStaffRoles(userID int64) ([]string, error)
+}
+
+type cinemaMemberStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// Set adds the user to the cinema's staff or changes the role of a member.
+func (s cinemaMemberStorage) Set(cinemaID int32, userID int64, role CinemaRole, invitedBy int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `INSERT INTO cinema_members(cinema_id, user_id, role_id, invited_by)
+	          VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (cinema_id, user_id) DO UPDATE
+			  SET role_id = EXCLUDED.role_id`
+	args := []any{cinemaID, userID, role, invitedBy}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (s cinemaMemberStorage) Remove(cinemaID int32, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM cinema_members
+	          WHERE cinema_id = $1 AND user_id = $2`
+	args := []any{cinemaID, userID}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (s cinemaMemberStorage) GetAll(cinemaID int32) ([]CinemaMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT cm.cinema_id, cm.user_id, u.name, u.email, cm.role_id, cm.invited_by, cm.created_at
+	          FROM cinema_members AS cm
+			  INNER JOIN users AS u
+			  ON u.id = cm.user_id
+			  WHERE cm.cinema_id = $1
+			  ORDER BY cm.role_id ASC, cm.created_at ASC`
+	args := []any{cinemaID}
+	return s.getMembers(ctx, query, args)
+}
+
+// GetAllForUser returns the cinemas the user is a member of.
+func (s cinemaMemberStorage) GetAllForUser(userID int64) ([]CinemaMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT cm.cinema_id, cm.user_id, u.name, u.email, cm.role_id, cm.invited_by, cm.created_at
+	          FROM cinema_members AS cm
+			  INNER JOIN users AS u
+			  ON u.id = cm.user_id
+			  WHERE cm.user_id = $1
+			  ORDER BY cm.cinema_id ASC`
+	args := []any{userID}
+	return s.getMembers(ctx, query, args)
+}
+
+func (s cinemaMemberStorage) getMembers(ctx context.Context, query string, args []any) ([]CinemaMember, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var members []CinemaMember
+	for rows.Next() {
+		var m CinemaMember
+		err := rows.Scan(&m.CinemaID, &m.UserID, &m.Name, &m.Email, &m.Role, &m.InvitedBy, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		m.Scopes = m.Role.Scopes()
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetRole returns the role of the user at the cinema or nil if the user isn't a member.
+func (s cinemaMemberStorage) GetRole(cinemaID int32, userID int64) (*CinemaRole, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT role_id
+	          FROM cinema_members
+			  WHERE cinema_id = $1 AND user_id = $2`
+	args := []any{cinemaID, userID}
+	var role CinemaRole
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetStaffRoles returns RoleCinemaOwner if the user owns a cinema and RoleCinemaStaff if the user is a member
+// of a cinema's staff, they're the roles the user has to hold through the cinemas.
+func (s cinemaMemberStorage) GetStaffRoles(userID int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT EXISTS(SELECT 1 FROM cinemas WHERE owner_id = $1),
+	                 EXISTS(SELECT 1 FROM cinema_members WHERE user_id = $1)`
+	args := []any{userID}
+	var isOwner, isMember bool
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&isOwner, &isMember)
+	if err != nil {
+		return nil, err
+	}
+	var roles []string
+	if isOwner {
+		roles = append(roles, RoleCinemaOwner)
+	}
+	if isMember {
+		roles = append(roles, RoleCinemaStaff)
+	}
+	return roles, nil
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// CinemaSettings overrides the global configuration for a cinema, nil values fall back to the global ones.
+// ResalePriceCap is the percentage of the face value a ticket can be resold for, resale is disabled if it's nil,
+// and ResaleFeeRate is the percentage of the resale price kept from the seller's proceeds.
+type CinemaSettings struct {
+	CinemaID               int32            `json:"cinema_id"`
+	TicketLockSeconds      *int32           `json:"ticket_lock_seconds"`
+	CheckoutSessionSeconds *int32           `json:"checkout_session_seconds"`
+	AllowTicketTransfers   bool             `json:"allow_ticket_transfers"`
+	ResalePriceCap         *decimal.Decimal `json:"resale_price_cap"`
+	ResaleFeeRate          decimal.Decimal  `json:"resale_fee_rate"`
+	Version                int32            `json:"version"`
+}
+
+// CheckoutSessionDuration returns the checkout session duration of the cinema or def if it's not overridden.
+func (s *CinemaSettings) CheckoutSessionDuration(def time.Duration) time.Duration {
+	if s.CheckoutSessionSeconds == nil {
+		return def
+	}
+	return time.Duration(*s.CheckoutSessionSeconds) * time.Second
+}
+
+type CinemaSettingsStorer interface {
+	Get(cinemaID int32) (*CinemaSettings, error)
+	GetForTicket(ticketID int64) (*CinemaSettings, error)
+	GetAllForCinemas(cinemaIDs []int32) ([]CinemaSettings, error)
+	Update(s *CinemaSettings) error
+}
+
+type cinemaSettingsStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// Get returns the settings of the cinema, a cinema that has no settings gets the defaults.
+func (s cinemaSettingsStorage) Get(cinemaID int32) (*CinemaSettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	settings := CinemaSettings{
+		CinemaID:             cinemaID,
+		AllowTicketTransfers: true,
+	}
+	query := `SELECT ticket_lock_seconds, checkout_session_seconds, allow_ticket_transfers, resale_price_cap, resale_fee_rate, version
+	          FROM cinema_settings
+			  WHERE cinema_id = $1`
+	args := []any{cinemaID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&settings.TicketLockSeconds, &settings.CheckoutSessionSeconds, &settings.AllowTicketTransfers, &settings.ResalePriceCap, &settings.ResaleFeeRate, &settings.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &settings, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// GetForTicket returns the settings of the cinema the ticket belongs to or nil if the ticket doesn't exist.
+func (s cinemaSettingsStorage) GetForTicket(ticketID int64) (*CinemaSettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	var settings CinemaSettings
+	query := `SELECT h.cinema_id, cs.ticket_lock_seconds, cs.checkout_session_seconds, COALESCE(cs.allow_ticket_transfers, true), cs.resale_price_cap, COALESCE(cs.resale_fee_rate, 0), COALESCE(cs.version, 0)
+	          FROM tickets as t
+			  JOIN schedules as sc
+			  ON sc.id = t.schedule_id
+			  JOIN halls as h
+			  ON h.id = sc.hall_id
+			  LEFT JOIN cinema_settings as cs
+			  ON cs.cinema_id = h.cinema_id
+			  WHERE t.id = $1`
+	args := []any{ticketID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&settings.CinemaID, &settings.TicketLockSeconds, &settings.CheckoutSessionSeconds, &settings.AllowTicketTransfers, &settings.ResalePriceCap, &settings.ResaleFeeRate, &settings.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (s cinemaSettingsStorage) GetAllForCinemas(cinemaIDs []int32) ([]CinemaSettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT c.id, cs.ticket_lock_seconds, cs.checkout_session_seconds, COALESCE(cs.allow_ticket_transfers, true), cs.resale_price_cap, COALESCE(cs.resale_fee_rate, 0), COALESCE(cs.version, 0)
+	          FROM cinemas as c
+			  LEFT JOIN cinema_settings as cs
+			  ON cs.cinema_id = c.id
+			  WHERE c.id = ANY($1)`
+	args := []any{pq.Array(cinemaIDs)}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var settings []CinemaSettings
+	for rows.Next() {
+		var cs CinemaSettings
+		err := rows.Scan(&cs.CinemaID, &cs.TicketLockSeconds, &cs.CheckoutSessionSeconds, &cs.AllowTicketTransfers, &cs.ResalePriceCap, &cs.ResaleFeeRate, &cs.Version)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, cs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Update creates the settings of the cinema if it has none.
+func (s cinemaSettingsStorage) Update(settings *CinemaSettings) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `INSERT INTO cinema_settings AS cs (cinema_id, ticket_lock_seconds, checkout_session_seconds, allow_ticket_transfers, resale_price_cap, resale_fee_rate)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT (cinema_id) DO UPDATE
+			  SET ticket_lock_seconds = $2, checkout_session_seconds = $3, allow_ticket_transfers = $4, resale_price_cap = $5, resale_fee_rate = $6, version = cs.version + 1
+			  WHERE cs.version = $7
+			  RETURNING version`
+	args := []any{settings.CinemaID, settings.TicketLockSeconds, settings.CheckoutSessionSeconds, settings.AllowTicketTransfers, settings.ResalePriceCap, settings.ResaleFeeRate, settings.Version}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&settings.Version)
+	return err
+}
@@ -0,0 +1,368 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+type FeeKind int16
+
+const (
+	FeeKindFee FeeKind = iota
+	FeeKindTax
+)
+
+func (k FeeKind) String() string {
+	switch k {
+	case FeeKindFee:
+		return "Fee"
+	case FeeKindTax:
+		return "Tax"
+	}
+	return fmt.Sprintf("FeeKind %d", k)
+}
+
+type FeeBasis int16
+
+const (
+	FeeBasisTicket FeeBasis = iota
+	FeeBasisOrder
+)
+
+func (b FeeBasis) String() string {
+	switch b {
+	case FeeBasisTicket:
+		return "Ticket"
+	case FeeBasisOrder:
+		return "Order"
+	}
+	return fmt.Sprintf("FeeBasis %d", b)
+}
+
+// Fee is a tax or a fee configured by a cinema.
+//
+// Fees are charged on top of the ticket price either per ticket or once per order,
+// as a fixed amount plus a percentage (rate) of the ticket prices.
+// Taxes are percentages of the ticket prices (basis ticket) or of the ticket prices
+// and the fees (basis order), and are either inclusive (already part of the price) or exclusive.
+// A fee restricted to a hall (e.g. a 3D surcharge) only applies to tickets in that hall.
+type Fee struct {
+	ID          int32           `json:"id"`
+	CinemaID    int32           `json:"cinema_id"`
+	HallID      *int32          `json:"hall_id,omitempty"`
+	Name        string          `json:"name"`
+	Kind        FeeKind         `json:"kind"`
+	Basis       FeeBasis        `json:"basis"`
+	Rate        decimal.Decimal `json:"rate"`
+	Amount      decimal.Decimal `json:"amount"`
+	IsInclusive bool            `json:"is_inclusive"`
+	Version     int32           `json:"version"`
+}
+
+type FeeStorer interface {
+	Create(cinemaID int32, hallID *int32, name string, kind FeeKind, basis FeeBasis, rate decimal.Decimal, amount decimal.Decimal, isInclusive bool) (*Fee, error)
+	GetAndCinema(id int32) (*Fee, *Cinema, error)
+	GetAllForCinema(cinemaID int32) ([]Fee, error)
+	GetAllForCinemas(cinemaIDs []int32) ([]Fee, error)
+	Update(f *Fee) error
+	Delete(f *Fee) error
+}
+
+type feeStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s feeStorage) Create(cinemaID int32, hallID *int32, name string, kind FeeKind, basis FeeBasis, rate decimal.Decimal, amount decimal.Decimal, isInclusive bool) (*Fee, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	f := Fee{
+		CinemaID:    cinemaID,
+		HallID:      hallID,
+		Name:        name,
+		Kind:        kind,
+		Basis:       basis,
+		Rate:        rate,
+		Amount:      amount,
+		IsInclusive: isInclusive,
+	}
+	query := `INSERT INTO fees(cinema_id, hall_id, name, kind_id, basis_id, rate, amount, is_inclusive)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING id, version`
+	args := []any{cinemaID, hallID, name, kind, basis, rate, amount, isInclusive}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&f.ID, &f.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (s feeStorage) GetAndCinema(id int32) (*Fee, *Cinema, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	f := Fee{
+		ID: id,
+	}
+	var c Cinema
+	query := `SELECT f.cinema_id, f.hall_id, f.name, f.kind_id, f.basis_id, f.rate, f.amount, f.is_inclusive, f.version,
+	          c.id, c.name, c.location, c.owner_id, c.version
+	          FROM fees as f
+			  INNER JOIN cinemas as c
+			  ON c.id = f.cinema_id
+			  WHERE f.id = $1`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&f.CinemaID, &f.HallID, &f.Name, &f.Kind, &f.Basis, &f.Rate, &f.Amount, &f.IsInclusive, &f.Version,
+		&c.ID, &c.Name, &c.Location, &c.OwnerID, &c.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	return &f, &c, nil
+}
+
+func (s feeStorage) GetAllForCinema(cinemaID int32) ([]Fee, error) {
+	return s.GetAllForCinemas([]int32{cinemaID})
+}
+
+func (s feeStorage) GetAllForCinemas(cinemaIDs []int32) ([]Fee, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT id, cinema_id, hall_id, name, kind_id, basis_id, rate, amount, is_inclusive, version
+	          FROM fees
+			  WHERE cinema_id = ANY($1)
+			  ORDER BY cinema_id ASC, kind_id ASC, id ASC`
+	args := []any{pq.Array(cinemaIDs)}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var fees []Fee
+	for rows.Next() {
+		var f Fee
+		err := rows.Scan(&f.ID, &f.CinemaID, &f.HallID, &f.Name, &f.Kind, &f.Basis, &f.Rate, &f.Amount, &f.IsInclusive, &f.Version)
+		if err != nil {
+			return nil, err
+		}
+		fees = append(fees, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return fees, nil
+}
+
+func (s feeStorage) Update(f *Fee) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE fees
+	          SET hall_id = $1, name = $2, kind_id = $3, basis_id = $4, rate = $5, amount = $6, is_inclusive = $7, version = version + 1
+			  WHERE id = $8 AND version = $9
+			  RETURNING version`
+	args := []any{f.HallID, f.Name, f.Kind, f.Basis, f.Rate, f.Amount, f.IsInclusive, f.ID, f.Version}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&f.Version)
+	return err
+}
+
+func (s feeStorage) Delete(f *Fee) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM fees
+			  WHERE id = $1`
+	args := []any{f.ID}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Charge is a single fee or tax applied to a checkout.
+type Charge struct {
+	CinemaID    int32           `json:"cinema_id"`
+	TicketID    *int64          `json:"ticket_id,omitempty"`
+	Name        string          `json:"name"`
+	Kind        FeeKind         `json:"kind"`
+	Amount      decimal.Decimal `json:"amount"`
+	IsInclusive bool            `json:"is_inclusive"`
+}
+
+// Breakdown is the result of applying the cinemas' fees and taxes to checkout items.
+// Inclusive taxes are reported but not added to the total.
+type Breakdown struct {
+	Subtotal   decimal.Decimal `json:"subtotal"`
+	Fees       []Charge        `json:"fees"`
+	Taxes      []Charge        `json:"taxes"`
+	FeesTotal  decimal.Decimal `json:"fees_total"`
+	TaxesTotal decimal.Decimal `json:"taxes_total"`
+	Total      decimal.Decimal `json:"total"`
+}
+
+// Lines merges the per-ticket charges of the same fee into a single charge per cinema.
+func (b *Breakdown) Lines() []Charge {
+	type key struct {
+		cinemaID    int32
+		name        string
+		kind        FeeKind
+		isInclusive bool
+	}
+	var lines []Charge
+	index := make(map[key]int)
+	for _, charges := range [][]Charge{b.Fees, b.Taxes} {
+		for _, c := range charges {
+			k := key{cinemaID: c.CinemaID, name: c.Name, kind: c.Kind, isInclusive: c.IsInclusive}
+			if i, ok := index[k]; ok {
+				lines[i].Amount = lines[i].Amount.Add(c.Amount)
+				continue
+			}
+			index[k] = len(lines)
+			c.TicketID = nil
+			lines = append(lines, c)
+		}
+	}
+	return lines
+}
+
+var hundred = decimal.NewFromInt(100)
+
+func percentOf(base decimal.Decimal, rate decimal.Decimal) decimal.Decimal {
+	return base.Mul(rate).Div(hundred)
+}
+
+func (f *Fee) appliesTo(item *CheckoutItem) bool {
+	return f.CinemaID == item.Cinema.ID && (f.HallID == nil || *f.HallID == item.Hall.ID)
+}
+
+// CalculateBreakdown applies the fees and taxes to the checkout items and products, amounts are rounded to cents.
+// Fees are only charged on tickets and products are only taxed by the taxes of the whole cinema.
+func CalculateBreakdown(items []CheckoutItem, products []CheckoutProduct, fees []Fee) *Breakdown {
+	b := &Breakdown{
+		Subtotal:   decimal.Zero,
+		FeesTotal:  decimal.Zero,
+		TaxesTotal: decimal.Zero,
+	}
+
+	var cinemaIDs []int32
+	seen := make(map[int32]bool)
+	for i := range items {
+		b.Subtotal = b.Subtotal.Add(items[i].Ticket.Price)
+		if !seen[items[i].Cinema.ID] {
+			seen[items[i].Cinema.ID] = true
+			cinemaIDs = append(cinemaIDs, items[i].Cinema.ID)
+		}
+	}
+	for i := range products {
+		b.Subtotal = b.Subtotal.Add(products[i].Amount)
+		if !seen[products[i].Product.CinemaID] {
+			seen[products[i].Product.CinemaID] = true
+			cinemaIDs = append(cinemaIDs, products[i].Product.CinemaID)
+		}
+	}
+
+	for _, cinemaID := range cinemaIDs {
+		// fees charged on the tickets in the same cinema, taxes with basis order are applied to them.
+		cinemaFees := decimal.Zero
+
+		for i := range fees {
+			f := &fees[i]
+			if f.CinemaID != cinemaID || f.Kind != FeeKindFee {
+				continue
+			}
+			switch f.Basis {
+			case FeeBasisTicket:
+				for j := range items {
+					item := &items[j]
+					if !f.appliesTo(item) {
+						continue
+					}
+					amount := f.Amount.Add(percentOf(item.Ticket.Price, f.Rate)).Round(2)
+					if amount.IsZero() {
+						continue
+					}
+					ticketID := item.Ticket.ID
+					b.Fees = append(b.Fees, Charge{CinemaID: cinemaID, TicketID: &ticketID, Name: f.Name, Kind: f.Kind, Amount: amount})
+					cinemaFees = cinemaFees.Add(amount)
+				}
+			case FeeBasisOrder:
+				applies := false
+				base := decimal.Zero
+				for j := range items {
+					if f.appliesTo(&items[j]) {
+						applies = true
+						base = base.Add(items[j].Ticket.Price)
+					}
+				}
+				if !applies {
+					continue
+				}
+				amount := f.Amount.Add(percentOf(base, f.Rate)).Round(2)
+				if amount.IsZero() {
+					continue
+				}
+				b.Fees = append(b.Fees, Charge{CinemaID: cinemaID, Name: f.Name, Kind: f.Kind, Amount: amount})
+				cinemaFees = cinemaFees.Add(amount)
+			}
+		}
+		b.FeesTotal = b.FeesTotal.Add(cinemaFees)
+
+		for i := range fees {
+			f := &fees[i]
+			if f.CinemaID != cinemaID || f.Kind != FeeKindTax {
+				continue
+			}
+			applies := false
+			base := decimal.Zero
+			for j := range items {
+				if f.appliesTo(&items[j]) {
+					applies = true
+					base = base.Add(items[j].Ticket.Price)
+				}
+			}
+			if f.HallID == nil {
+				for j := range products {
+					if products[j].Product.CinemaID == cinemaID {
+						applies = true
+						base = base.Add(products[j].Amount)
+					}
+				}
+			}
+			if !applies {
+				continue
+			}
+			if f.Basis == FeeBasisOrder {
+				base = base.Add(cinemaFees)
+			}
+			var amount decimal.Decimal
+			if f.IsInclusive {
+				// the base already contains the tax: base = net * (100 + rate) / 100
+				amount = base.Mul(f.Rate).Div(hundred.Add(f.Rate)).Round(2)
+			} else {
+				amount = percentOf(base, f.Rate).Round(2)
+			}
+			if amount.IsZero() {
+				continue
+			}
+			b.Taxes = append(b.Taxes, Charge{CinemaID: cinemaID, Name: f.Name, Kind: f.Kind, Amount: amount, IsInclusive: f.IsInclusive})
+			if !f.IsInclusive {
+				b.TaxesTotal = b.TaxesTotal.Add(amount)
+			}
+		}
+	}
+
+	b.Total = b.Subtotal.Add(b.FeesTotal).Add(b.TaxesTotal)
+	return b
+}
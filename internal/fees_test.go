@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func ticketItem(id int64, cinemaID int32, hallID int32, price string) CheckoutItem {
+	return CheckoutItem{
+		Ticket: Ticket{ID: id, Price: dec(price)},
+		Hall:   Hall{ID: hallID},
+		Cinema: Cinema{ID: cinemaID},
+	}
+}
+
+func productItem(cinemaID int32, amount string) CheckoutProduct {
+	return CheckoutProduct{
+		Product:  Product{CinemaID: cinemaID},
+		Quantity: 1,
+		Amount:   dec(amount),
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	tests := []struct {
+		base string
+		rate string
+		want string
+	}{
+		{"100", "10", "10"},
+		{"19.99", "0", "0"},
+		{"0", "15", "0"},
+		{"12.50", "8", "1"},
+		{"3.33", "7.5", "0.24975"},
+	}
+	for _, tt := range tests {
+		got := percentOf(dec(tt.base), dec(tt.rate))
+		if !got.Equal(dec(tt.want)) {
+			t.Errorf("percentOf(%s, %s) = %s, want %s", tt.base, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateBreakdown(t *testing.T) {
+	hall2 := int32(2)
+	tests := []struct {
+		name       string
+		items      []CheckoutItem
+		products   []CheckoutProduct
+		fees       []Fee
+		subtotal   string
+		feesTotal  string
+		taxesTotal string
+		total      string
+		numFees    int
+		numTaxes   int
+	}{
+		{
+			name:       "no fees",
+			items:      []CheckoutItem{ticketItem(1, 1, 1, "10.00"), ticketItem(2, 1, 1, "12.50")},
+			subtotal:   "22.50",
+			feesTotal:  "0",
+			taxesTotal: "0",
+			total:      "22.50",
+		},
+		{
+			name:       "ticket fee with amount and rate",
+			items:      []CheckoutItem{ticketItem(1, 1, 1, "10.00"), ticketItem(2, 1, 1, "10.00")},
+			fees:       []Fee{{CinemaID: 1, Kind: FeeKindFee, Basis: FeeBasisTicket, Amount: dec("1.00"), Rate: dec("5")}},
+			subtotal:   "20.00",
+			feesTotal:  "3.00",
+			taxesTotal: "0",
+			total:      "23.00",
+			numFees:    2,
+		},
+		{
+			name:       "fee restricted to a hall",
+			items:      []CheckoutItem{ticketItem(1, 1, 1, "10.00"), ticketItem(2, 1, 2, "10.00")},
+			fees:       []Fee{{CinemaID: 1, HallID: &hall2, Kind: FeeKindFee, Basis: FeeBasisTicket, Amount: dec("2.00")}},
+			subtotal:   "20.00",
+			feesTotal:  "2.00",
+			taxesTotal: "0",
+			total:      "22.00",
+			numFees:    1,
+		},
+		{
+			name:       "order fee is charged once",
+			items:      []CheckoutItem{ticketItem(1, 1, 1, "10.00"), ticketItem(2, 1, 1, "10.00")},
+			fees:       []Fee{{CinemaID: 1, Kind: FeeKindFee, Basis: FeeBasisOrder, Amount: dec("2.00"), Rate: dec("10")}},
+			subtotal:   "20.00",
+			feesTotal:  "4.00",
+			taxesTotal: "0",
+			total:      "24.00",
+			numFees:    1,
+		},
+		{
+			name:  "ticket tax excludes the fees",
+			items: []CheckoutItem{ticketItem(1, 1, 1, "20.00")},
+			fees: []Fee{
+				{CinemaID: 1, Kind: FeeKindFee, Basis: FeeBasisOrder, Amount: dec("2.00")},
+				{CinemaID: 1, Kind: FeeKindTax, Basis: FeeBasisTicket, Rate: dec("10")},
+			},
+			subtotal:   "20.00",
+			feesTotal:  "2.00",
+			taxesTotal: "2.00",
+			total:      "24.00",
+			numFees:    1,
+			numTaxes:   1,
+		},
+		{
+			name:  "order tax includes the fees",
+			items: []CheckoutItem{ticketItem(1, 1, 1, "20.00")},
+			fees: []Fee{
+				{CinemaID: 1, Kind: FeeKindFee, Basis: FeeBasisOrder, Amount: dec("2.00")},
+				{CinemaID: 1, Kind: FeeKindTax, Basis: FeeBasisOrder, Rate: dec("10")},
+			},
+			subtotal:   "20.00",
+			feesTotal:  "2.00",
+			taxesTotal: "2.20",
+			total:      "24.20",
+			numFees:    1,
+			numTaxes:   1,
+		},
+		{
+			name:       "inclusive tax isn't added to the total",
+			items:      []CheckoutItem{ticketItem(1, 1, 1, "12.00")},
+			fees:       []Fee{{CinemaID: 1, Kind: FeeKindTax, Basis: FeeBasisTicket, Rate: dec("20"), IsInclusive: true}},
+			subtotal:   "12.00",
+			feesTotal:  "0",
+			taxesTotal: "0",
+			total:      "12.00",
+			numTaxes:   1,
+		},
+		{
+			name:     "products are only taxed by the cinema's taxes",
+			items:    []CheckoutItem{ticketItem(1, 1, 2, "10.00")},
+			products: []CheckoutProduct{productItem(1, "5.00")},
+			fees: []Fee{
+				{CinemaID: 1, Kind: FeeKindFee, Basis: FeeBasisOrder, Amount: dec("1.00")},
+				{CinemaID: 1, Kind: FeeKindTax, Basis: FeeBasisTicket, Rate: dec("10")},
+				{CinemaID: 1, HallID: &hall2, Kind: FeeKindTax, Basis: FeeBasisTicket, Rate: dec("5")},
+			},
+			subtotal:   "15.00",
+			feesTotal:  "1.00",
+			taxesTotal: "2.00",
+			total:      "18.00",
+			numFees:    1,
+			numTaxes:   2,
+		},
+		{
+			name:  "fees only apply to their cinema",
+			items: []CheckoutItem{ticketItem(1, 1, 1, "10.00"), ticketItem(2, 2, 3, "8.00")},
+			fees: []Fee{
+				{CinemaID: 2, Kind: FeeKindFee, Basis: FeeBasisTicket, Amount: dec("1.50")},
+				{CinemaID: 1, Kind: FeeKindTax, Basis: FeeBasisOrder, Rate: dec("10")},
+			},
+			subtotal:   "18.00",
+			feesTotal:  "1.50",
+			taxesTotal: "1.00",
+			total:      "20.50",
+			numFees:    1,
+			numTaxes:   1,
+		},
+		{
+			name:       "amounts are rounded to cents",
+			items:      []CheckoutItem{ticketItem(1, 1, 1, "3.33")},
+			fees:       []Fee{{CinemaID: 1, Kind: FeeKindTax, Basis: FeeBasisTicket, Rate: dec("7.5")}},
+			subtotal:   "3.33",
+			feesTotal:  "0",
+			taxesTotal: "0.25",
+			total:      "3.58",
+			numTaxes:   1,
+		},
+		{
+			name:       "zero charges are left out",
+			items:      []CheckoutItem{ticketItem(1, 1, 1, "0.01")},
+			fees:       []Fee{{CinemaID: 1, Kind: FeeKindTax, Basis: FeeBasisTicket, Rate: dec("1")}},
+			subtotal:   "0.01",
+			feesTotal:  "0",
+			taxesTotal: "0",
+			total:      "0.01",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := CalculateBreakdown(tt.items, tt.products, tt.fees)
+			if !b.Subtotal.Equal(dec(tt.subtotal)) {
+				t.Errorf("subtotal = %s, want %s", b.Subtotal, tt.subtotal)
+			}
+			if !b.FeesTotal.Equal(dec(tt.feesTotal)) {
+				t.Errorf("fees total = %s, want %s", b.FeesTotal, tt.feesTotal)
+			}
+			if !b.TaxesTotal.Equal(dec(tt.taxesTotal)) {
+				t.Errorf("taxes total = %s, want %s", b.TaxesTotal, tt.taxesTotal)
+			}
+			if !b.Total.Equal(dec(tt.total)) {
+				t.Errorf("total = %s, want %s", b.Total, tt.total)
+			}
+			if len(b.Fees) != tt.numFees {
+				t.Errorf("got %d fees, want %d", len(b.Fees), tt.numFees)
+			}
+			if len(b.Taxes) != tt.numTaxes {
+				t.Errorf("got %d taxes, want %d", len(b.Taxes), tt.numTaxes)
+			}
+		})
+	}
+}
+
+func TestBreakdownLines(t *testing.T) {
+	items := []CheckoutItem{ticketItem(1, 1, 1, "10.00"), ticketItem(2, 1, 1, "10.00"), ticketItem(3, 2, 2, "10.00")}
+	fees := []Fee{
+		{CinemaID: 1, Name: "Service", Kind: FeeKindFee, Basis: FeeBasisTicket, Amount: dec("1.00")},
+		{CinemaID: 2, Name: "Service", Kind: FeeKindFee, Basis: FeeBasisTicket, Amount: dec("1.25")},
+	}
+	lines := CalculateBreakdown(items, nil, fees).Lines()
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if lines[0].CinemaID != 1 || !lines[0].Amount.Equal(dec("2.00")) || lines[0].TicketID != nil {
+		t.Errorf("line 0 = %+v, want cinema 1 with 2.00", lines[0])
+	}
+	if lines[1].CinemaID != 2 || !lines[1].Amount.Equal(dec("1.25")) {
+		t.Errorf("line 1 = %+v, want cinema 2 with 1.25", lines[1])
+	}
+}
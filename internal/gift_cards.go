@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type GiftCardStatus int16
+
+const (
+	GiftCardStatusPending GiftCardStatus = iota
+	GiftCardStatusActive
+	GiftCardStatusRedeemed
+)
+
+func (s GiftCardStatus) String() string {
+	switch s {
+	case GiftCardStatusPending:
+		return "Pending"
+	case GiftCardStatusActive:
+		return "Active"
+	case GiftCardStatusRedeemed:
+		return "Redeemed"
+	}
+	return fmt.Sprintf("GiftCardStatus %d", s)
+}
+
+// GiftCard is either issued by an admin or purchased by a user, a purchased gift card
+// is pending until its checkout session is paid. only the hash of its code is stored.
+type GiftCard struct {
+	ID          int64           `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Amount      decimal.Decimal `json:"amount"`
+	StatusID    GiftCardStatus  `json:"status_id"`
+	IssuedBy    *int64          `json:"issued_by,omitempty"`
+	PurchasedBy *int64          `json:"purchased_by,omitempty"`
+	SessionID   *string         `json:"-"`
+	RedeemedBy  *int64          `json:"redeemed_by,omitempty"`
+	RedeemedAt  *time.Time      `json:"redeemed_at,omitempty"`
+}
+
+type GiftCardStorer interface {
+	Issue(code string, amount decimal.Decimal, issuedBy int64) (*GiftCard, error)
+	CreatePurchase(code string, amount decimal.Decimal, purchasedBy int64, sessionID string) (*GiftCard, error)
+	GetBySessionID(sessionID string) (*GiftCard, error)
+	GetAllPurchased(userID int64) ([]GiftCard, error)
+	Activate(sessionID string, paymentReference string) (bool, error)
+	Redeem(code string, userID int64) (*GiftCard, *WalletEntry, error)
+}
+
+type giftCardStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s giftCardStorage) Issue(code string, amount decimal.Decimal, issuedBy int64) (*GiftCard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	g := GiftCard{
+		Amount:   amount,
+		StatusID: GiftCardStatusActive,
+		IssuedBy: &issuedBy,
+	}
+	query := `INSERT INTO gift_cards(hash, amount, status_id, issued_by)
+	          VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at`
+	args := []any{HashToken(code), amount, g.StatusID, issuedBy}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&g.ID, &g.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (s giftCardStorage) CreatePurchase(code string, amount decimal.Decimal, purchasedBy int64, sessionID string) (*GiftCard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	g := GiftCard{
+		Amount:      amount,
+		StatusID:    GiftCardStatusPending,
+		PurchasedBy: &purchasedBy,
+		SessionID:   &sessionID,
+	}
+	query := `INSERT INTO gift_cards(hash, amount, purchased_by, session_id)
+	          VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at`
+	args := []any{HashToken(code), amount, purchasedBy, sessionID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&g.ID, &g.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (s giftCardStorage) GetBySessionID(sessionID string) (*GiftCard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	var g GiftCard
+	query := `SELECT id, created_at, amount, status_id, issued_by, purchased_by, session_id, redeemed_by, redeemed_at
+	          FROM gift_cards
+			  WHERE session_id = $1`
+	args := []any{sessionID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&g.ID, &g.CreatedAt, &g.Amount, &g.StatusID, &g.IssuedBy, &g.PurchasedBy, &g.SessionID, &g.RedeemedBy, &g.RedeemedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (s giftCardStorage) GetAllPurchased(userID int64) ([]GiftCard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT id, created_at, amount, status_id, issued_by, purchased_by, session_id, redeemed_by, redeemed_at
+	          FROM gift_cards
+			  WHERE purchased_by = $1
+			  ORDER BY created_at DESC, id DESC`
+	args := []any{userID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var cards []GiftCard
+	for rows.Next() {
+		var g GiftCard
+		err := rows.Scan(&g.ID, &g.CreatedAt, &g.Amount, &g.StatusID, &g.IssuedBy, &g.PurchasedBy, &g.SessionID, &g.RedeemedBy, &g.RedeemedAt)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// Activate makes the gift card purchased with the checkout session redeemable,
+// it reports false if there is no pending gift card for the session so it's safe to call it again.
+func (s giftCardStorage) Activate(sessionID string, paymentReference string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE gift_cards
+	          SET status_id = 1, payment_reference = $2
+			  WHERE session_id = $1 AND status_id = 0`
+	args := []any{sessionID, paymentReference}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n != 0, nil
+}
+
+// Redeem credits the amount of the gift card to the user's wallet,
+// it returns nil if the code is invalid, the gift card isn't paid or it was already redeemed.
+func (s giftCardStorage) Redeem(code string, userID int64) (*GiftCard, *WalletEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var g GiftCard
+	query0 := `UPDATE gift_cards
+	           SET status_id = 2, redeemed_by = $2, redeemed_at = NOW()
+			   WHERE hash = $1 AND status_id = 1
+			   RETURNING id, created_at, amount, status_id, issued_by, purchased_by, session_id, redeemed_by, redeemed_at`
+	args0 := []any{HashToken(code), userID}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&g.ID, &g.CreatedAt, &g.Amount, &g.StatusID, &g.IssuedBy, &g.PurchasedBy, &g.SessionID, &g.RedeemedBy, &g.RedeemedAt)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	e := WalletEntry{
+		UserID:     userID,
+		Kind:       WalletEntryKindGiftCard,
+		Amount:     g.Amount,
+		GiftCardID: &g.ID,
+	}
+	query1 := `INSERT INTO wallet_entries(user_id, kind_id, amount, gift_card_id)
+	           VALUES ($1, $2, $3, $4)
+			   RETURNING id, created_at`
+	args1 := []any{e.UserID, e.Kind, e.Amount, g.ID}
+	err = tx.QueryRowContext(ctx, query1, args1...).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &g, &e, nil
+}
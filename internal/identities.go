@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// OIDCState is what a login started with an OpenID Connect issuer needs to be completed, it's looked up by the state
+// the issuer sends back.
+type OIDCState struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type IdentityStorer interface {
+	CreateState(state string, s OIDCState, duration time.Duration) error
+	ConsumeState(state string) (*OIDCState, error)
+	GetUser(provider string, subject string) (*User, error)
+	Link(userID int64, provider string, subject string, email string) error
+	CreateUser(name string, email string, passwordHash []byte, provider string, subject string) (*User, error)
+	DeleteAllExpiredStates() (int, error)
+}
+
+type identityStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s identityStorage) CreateState(state string, st OIDCState, duration time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `INSERT INTO oidc_states(hash, provider, nonce, code_verifier, expires_at)
+	          VALUES ($1, $2, $3, $4, $5)`
+	args := []any{HashToken(state), st.Provider, st.Nonce, st.CodeVerifier, time.Now().Add(duration)}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// ConsumeState deletes the state and returns it if it didn't expire, a state can only be used once.
+func (s identityStorage) ConsumeState(state string) (*OIDCState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	var st OIDCState
+	query := `DELETE FROM oidc_states
+	          WHERE hash = $1
+			  RETURNING provider, nonce, code_verifier, expires_at`
+	args := []any{HashToken(state)}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&st.Provider, &st.Nonce, &st.CodeVerifier, &st.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if time.Now().After(st.ExpiresAt) {
+		return nil, nil
+	}
+	return &st, nil
+}
+
+// GetUser returns the user linked to the issuer's account.
+func (s identityStorage) GetUser(provider string, subject string) (*User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	var u User
+	query := `SELECT u.id, u.created_at, u.name, u.email, u.password_hash, u.is_activated, u.version, u.suspended_at, u.suspension_reason, u.password_reset_required
+	          FROM identities AS i
+			  INNER JOIN users AS u
+			  ON i.user_id = u.id
+			  WHERE i.provider = $1 AND i.subject = $2`
+	args := []any{provider, subject}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.ID, &u.CreatedAt, &u.Name, &u.Email, &u.PasswordHash, &u.IsActivated, &u.Version, &u.SuspendedAt, &u.SuspensionReason, &u.PasswordResetRequired)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s identityStorage) Link(userID int64, provider string, subject string, email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `INSERT INTO identities(user_id, provider, subject, email)
+	          VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (provider, subject) DO NOTHING`
+	args := []any{userID, provider, subject, email}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// CreateUser creates an activated user linked to the issuer's account, the issuer already verified the email.
+func (s identityStorage) CreateUser(name string, email string, passwordHash []byte, provider string, subject string) (*User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	u := User{
+		Name:         name,
+		Email:        email,
+		PasswordHash: passwordHash,
+		IsActivated:  true,
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	query0 := `INSERT INTO users(name, email, password_hash, is_activated)
+	           VALUES ($1, $2, $3, true)
+			   RETURNING id, created_at, version`
+	args0 := []any{name, email, passwordHash}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&u.ID, &u.CreatedAt, &u.Version)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	query1 := `INSERT INTO identities(user_id, provider, subject, email)
+	           VALUES ($1, $2, $3, $4)`
+	args1 := []any{u.ID, provider, subject, email}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s identityStorage) DeleteAllExpiredStates() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM oidc_states
+	          WHERE NOW() > expires_at`
+	result, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
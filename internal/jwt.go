@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidJWT = errors.New("invalid token")
+
+// AccessClaims are the claims of a signed access token, they carry what the authenticate
+// and authorize middlewares need so they don't query the database.
+type AccessClaims struct {
+	Issuer      string       `json:"iss"`
+	Subject     string       `json:"sub"`
+	IssuedAt    int64        `json:"iat"`
+	ExpiresAt   int64        `json:"exp"`
+	ID          string       `json:"jti"`
+	SessionID   int64        `json:"sid"`
+	Name        string       `json:"name"`
+	Email       string       `json:"email"`
+	IsActivated bool         `json:"activated"`
+	Permissions []Permission `json:"permissions"`
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// JWK is the public part of a signing key as published in the JWKS.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWTSigner signs access tokens with EdDSA (Ed25519) using the active key and verifies them with any of the keys,
+// rotating means adding a new key, making it the active one and removing the old one once its tokens expired.
+type JWTSigner struct {
+	issuer    string
+	activeID  string
+	active    ed25519.PrivateKey
+	verifiers map[string]ed25519.PublicKey
+}
+
+// LoadJWTSigner loads the PKCS #8 Ed25519 private keys in the directory, the file name without the .pem extension is the key id.
+func LoadJWTSigner(dir string, activeID string, issuer string) (*JWTSigner, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	s := &JWTSigner{
+		issuer:    issuer,
+		activeID:  activeID,
+		verifiers: make(map[string]ed25519.PublicKey),
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s is not a pem file", path)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		private, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+		}
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		s.verifiers[id] = private.Public().(ed25519.PublicKey)
+		if id == activeID {
+			s.active = private
+		}
+	}
+	if s.active == nil {
+		return nil, fmt.Errorf("couldn't find the signing key %q in %s", activeID, dir)
+	}
+	return s, nil
+}
+
+var jwtEncoding = base64.RawURLEncoding
+
+func (s *JWTSigner) Sign(claims AccessClaims) (string, error) {
+	claims.Issuer = s.issuer
+	header, err := json.Marshal(jwtHeader{Algorithm: "EdDSA", Type: "JWT", KeyID: s.activeID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := jwtEncoding.EncodeToString(header) + "." + jwtEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(s.active, []byte(signingInput))
+	return signingInput + "." + jwtEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature, the issuer and the expiration of the token, it returns ErrInvalidJWT if any of them is wrong.
+func (s *JWTSigner) Verify(token string) (*AccessClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidJWT
+	}
+	headerJSON, err := jwtEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrInvalidJWT
+	}
+	// only EdDSA is accepted so a token can't pick a weaker algorithm
+	if header.Algorithm != "EdDSA" {
+		return nil, ErrInvalidJWT
+	}
+	key, ok := s.verifiers[header.KeyID]
+	if !ok {
+		return nil, ErrInvalidJWT
+	}
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+	if !ed25519.Verify(key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidJWT
+	}
+	payload, err := jwtEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+	var claims AccessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidJWT
+	}
+	if claims.Issuer != s.issuer || time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidJWT
+	}
+	return &claims, nil
+}
+
+// JWKS returns the public keys the tokens can be verified with.
+func (s *JWTSigner) JWKS() JWKS {
+	ids := make([]string, 0, len(s.verifiers))
+	for id := range s.verifiers {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	set := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         jwtEncoding.EncodeToString(s.verifiers[id]),
+			KeyID:     id,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+		})
+	}
+	return set
+}
@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testIssuer = "https://api.example.com"
+
+func writeJWTKey(t *testing.T, dir string, id string) ed25519.PrivateKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, id+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return private
+}
+
+func loadJWTSigner(t *testing.T, dir string, activeID string) *JWTSigner {
+	t.Helper()
+	s, err := LoadJWTSigner(dir, activeID, testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func testClaims(expiresAt time.Time) AccessClaims {
+	return AccessClaims{
+		Subject:     "42",
+		IssuedAt:    time.Now().Unix(),
+		ExpiresAt:   expiresAt.Unix(),
+		ID:          "jti",
+		SessionID:   7,
+		Name:        "Jane",
+		Email:       "jane@example.com",
+		IsActivated: true,
+		Permissions: []Permission{"movies:read"},
+	}
+}
+
+func tokenKeyID(t *testing.T, token string) string {
+	t.Helper()
+	headerJSON, err := jwtEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		t.Fatal(err)
+	}
+	return header.KeyID
+}
+
+// signPart signs the header and the payload like Sign but lets the tests pick them.
+func signPart(key ed25519.PrivateKey, header jwtHeader, payload any) string {
+	h, _ := json.Marshal(header)
+	p, _ := json.Marshal(payload)
+	input := jwtEncoding.EncodeToString(h) + "." + jwtEncoding.EncodeToString(p)
+	return input + "." + jwtEncoding.EncodeToString(ed25519.Sign(key, []byte(input)))
+}
+
+func TestJWTSignVerify(t *testing.T) {
+	dir := t.TempDir()
+	key := writeJWTKey(t, dir, "k1")
+	s := loadJWTSigner(t, dir, "k1")
+
+	claims := testClaims(time.Now().Add(time.Minute))
+	token, err := s.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	claims.Issuer = testIssuer
+	if got.Subject != claims.Subject || got.SessionID != claims.SessionID || got.Email != claims.Email ||
+		got.Issuer != claims.Issuer || len(got.Permissions) != 1 || got.Permissions[0] != claims.Permissions[0] {
+		t.Errorf("Verify() = %+v, want %+v", got, claims)
+	}
+
+	parts := strings.Split(token, ".")
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	valid := jwtHeader{Algorithm: "EdDSA", Type: "JWT", KeyID: "k1"}
+	expired := claims
+	expired.ExpiresAt = time.Now().Add(-time.Second).Unix()
+	otherIssuer := claims
+	otherIssuer.Issuer = "https://evil.example.com"
+	tampered := claims
+	tampered.Subject = "1"
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"two parts", parts[0] + "." + parts[1]},
+		{"four parts", token + "." + parts[2]},
+		{"bad header encoding", "!!." + parts[1] + "." + parts[2]},
+		{"bad signature encoding", parts[0] + "." + parts[1] + ".!!"},
+		{"payload changed", parts[0] + "." + jwtEncoding.EncodeToString(mustJSON(t, tampered)) + "." + parts[2]},
+		{"signature of another token", parts[0] + "." + parts[1] + "." + strings.Split(signPart(key, valid, tampered), ".")[2]},
+		{"signed by an unknown key", signPart(otherKey, valid, claims)},
+		{"unknown key id", signPart(key, jwtHeader{Algorithm: "EdDSA", Type: "JWT", KeyID: "k2"}, claims)},
+		{"none algorithm", signPart(key, jwtHeader{Algorithm: "none", Type: "JWT", KeyID: "k1"}, claims)},
+		{"other algorithm", signPart(key, jwtHeader{Algorithm: "HS256", Type: "JWT", KeyID: "k1"}, claims)},
+		{"expired", signPart(key, valid, expired)},
+		{"other issuer", signPart(key, valid, otherIssuer)},
+		{"payload isn't json", signPart(key, valid, "claims")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Verify(tt.token); !errors.Is(err, ErrInvalidJWT) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrInvalidJWT)
+			}
+		})
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	writeJWTKey(t, dir, "2026-01")
+	old := loadJWTSigner(t, dir, "2026-01")
+	oldToken, err := old.Sign(testClaims(time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key is added and made the active one, the old one is kept for its tokens
+	writeJWTKey(t, dir, "2026-02")
+	rotated := loadJWTSigner(t, dir, "2026-02")
+	newToken, err := rotated.Sign(testClaims(time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tokenKeyID(t, newToken); got != "2026-02" {
+		t.Errorf("new token key id = %q, want %q", got, "2026-02")
+	}
+	jwks := rotated.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "2026-01" || jwks.Keys[1].KeyID != "2026-02" {
+		t.Errorf("JWKS() = %+v, want the keys 2026-01 and 2026-02", jwks.Keys)
+	}
+
+	// the old key is removed once its tokens expired
+	if err := os.Remove(filepath.Join(dir, "2026-01.pem")); err != nil {
+		t.Fatal(err)
+	}
+	retired := loadJWTSigner(t, dir, "2026-02")
+
+	tests := []struct {
+		name   string
+		signer *JWTSigner
+		token  string
+		valid  bool
+	}{
+		{"old key verifies its token", old, oldToken, true},
+		{"old key doesn't know the new key", old, newToken, false},
+		{"rotated verifies the old token", rotated, oldToken, true},
+		{"rotated verifies the new token", rotated, newToken, true},
+		{"retired old key rejects its token", retired, oldToken, false},
+		{"retired old key keeps the new token", retired, newToken, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.signer.Verify(tt.token)
+			if tt.valid && err != nil {
+				t.Errorf("Verify() error = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidJWT) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrInvalidJWT)
+			}
+		})
+	}
+}
+
+func TestLoadJWTSigner(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		activeID string
+		wantErr  bool
+	}{
+		{"active key", nil, "k1", false},
+		{"missing active key", nil, "k2", true},
+		{"not pem", map[string]string{"bad.pem": "not a key"}, "k1", true},
+		{"not pkcs8", map[string]string{"bad.pem": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("junk")}))}, "k1", true},
+		{"other files are ignored", map[string]string{"README": "keys"}, "k1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeJWTKey(t, dir, "k1")
+			for name, data := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := LoadJWTSigner(dir, tt.activeID, testIssuer)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadJWTSigner() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type LoginThrottleKind int16
+
+const (
+	LoginThrottleAccount LoginThrottleKind = iota
+	LoginThrottleIP
+)
+
+func (k LoginThrottleKind) String() string {
+	switch k {
+	case LoginThrottleAccount:
+		return "Account"
+	case LoginThrottleIP:
+		return "IP"
+	}
+	return fmt.Sprintf("LoginThrottleKind %d", k)
+}
+
+// LoginLimits bounds the failed logins of an account or of an IP, a zero limit means there is no limit.
+type LoginLimits struct {
+	// the failures are counted until there's none for FailureWindow.
+	FailureWindow time.Duration
+	// after DelayAfter failures the next attempt has to wait a second doubling with every failure up to MaxDelay.
+	DelayAfter int
+	MaxDelay   time.Duration
+	// LockAfter failures lock the logins for Lockout.
+	LockAfter int
+	Lockout   time.Duration
+}
+
+// LoginThrottle counts the failed logins of an account or of an IP.
+type LoginThrottle struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// IsLocked reports whether the logins are locked at the time.
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t != nil && t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
+
+// RetryAfter returns how long the next login has to wait, it's zero when it can be tried right away.
+func (l LoginLimits) RetryAfter(t *LoginThrottle, now time.Time) time.Duration {
+	if t == nil {
+		return 0
+	}
+	if t.IsLocked(now) {
+		return t.LockedUntil.Sub(now)
+	}
+	if now.Sub(t.LastFailureAt) > l.FailureWindow {
+		return 0
+	}
+	if l.DelayAfter == 0 || t.Failures < l.DelayAfter {
+		return 0
+	}
+	delay := l.MaxDelay
+	if n := t.Failures - l.DelayAfter; n < 32 {
+		delay = min(time.Second<<n, l.MaxDelay)
+	}
+	return max(t.LastFailureAt.Add(delay).Sub(now), 0)
+}
+
+type LoginThrottleStorer interface {
+	Attempt(kind LoginThrottleKind, key string, limits LoginLimits) (*LoginThrottle, time.Duration, error)
+	Forgive(kind LoginThrottleKind, key string) error
+	Delete(kind LoginThrottleKind, key string) error
+	DeleteAllStale(window time.Duration) (int, error)
+}
+
+type loginThrottleStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// Attempt counts a login attempt as a failure before the password or the code is checked, unless the logins
+// have to wait, and returns how long they have to wait. The attempts are checked and counted under the row's lock
+// so the parallel ones are counted one after another and can't skip the delays.
+// The returned throttle is locked if this attempt locked the logins.
+func (s loginThrottleStorage) Attempt(kind LoginThrottleKind, key string, limits LoginLimits) (*LoginThrottle, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	query0 := `INSERT INTO login_throttles(kind, key, failures, last_failure_at)
+	           VALUES ($1, $2, 0, $3)
+			   ON CONFLICT (kind, key) DO NOTHING`
+	args0 := []any{kind, key, now}
+	_, err = tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	var t LoginThrottle
+	query1 := `SELECT failures, last_failure_at, locked_until
+	           FROM login_throttles
+			   WHERE kind = $1 AND key = $2
+			   FOR UPDATE`
+	args1 := []any{kind, key}
+	err = tx.QueryRowContext(ctx, query1, args1...).Scan(&t.Failures, &t.LastFailureAt, &t.LockedUntil)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+	if retryAfter := limits.RetryAfter(&t, now); retryAfter > 0 {
+		tx.Rollback()
+		return &t, retryAfter, nil
+	}
+
+	if now.Sub(t.LastFailureAt) > limits.FailureWindow {
+		t.Failures = 0
+	}
+	t.Failures++
+	t.LastFailureAt = now
+	if limits.LockAfter != 0 && t.Failures >= limits.LockAfter {
+		// the failures start over so the logins aren't locked again right after the lockout
+		lockedUntil := now.Add(limits.Lockout)
+		t.Failures = 0
+		t.LockedUntil = &lockedUntil
+	}
+	query2 := `UPDATE login_throttles
+	           SET failures = $3, last_failure_at = $4, locked_until = $5
+			   WHERE kind = $1 AND key = $2`
+	args2 := []any{kind, key, t.Failures, t.LastFailureAt, t.LockedUntil}
+	_, err = tx.ExecContext(ctx, query2, args2...)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, 0, err
+	}
+	return &t, 0, nil
+}
+
+// Forgive takes back an attempt that succeeded.
+func (s loginThrottleStorage) Forgive(kind LoginThrottleKind, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE login_throttles
+	          SET failures = GREATEST(failures - 1, 0)
+			  WHERE kind = $1 AND key = $2`
+	args := []any{kind, key}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Delete forgets the failed logins and unlocks the logins.
+func (s loginThrottleStorage) Delete(kind LoginThrottleKind, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM login_throttles
+	          WHERE kind = $1 AND key = $2`
+	args := []any{kind, key}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeleteAllStale deletes the counters that aren't locked and had no failures within the window.
+func (s loginThrottleStorage) DeleteAllStale(window time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM login_throttles
+	          WHERE (locked_until IS NULL OR locked_until < NOW()) AND last_failure_at < NOW() - $1 * interval '1 second'`
+	args := []any{window.Seconds()}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
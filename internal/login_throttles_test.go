@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginLimitsRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limits := LoginLimits{
+		FailureWindow: 15 * time.Minute,
+		DelayAfter:    3,
+		MaxDelay:      30 * time.Second,
+		LockAfter:     10,
+		Lockout:       15 * time.Minute,
+	}
+	lockedUntil := now.Add(time.Minute)
+	expiredLock := now.Add(-time.Second)
+	tests := []struct {
+		name     string
+		limits   LoginLimits
+		throttle *LoginThrottle
+		want     time.Duration
+	}{
+		{"no failures", limits, nil, 0},
+		{"under the delay", limits, &LoginThrottle{Failures: 2, LastFailureAt: now}, 0},
+		{"first delay", limits, &LoginThrottle{Failures: 3, LastFailureAt: now}, time.Second},
+		{"delay doubles", limits, &LoginThrottle{Failures: 5, LastFailureAt: now}, 4 * time.Second},
+		{"delay is capped", limits, &LoginThrottle{Failures: 9, LastFailureAt: now}, 30 * time.Second},
+		{"huge failures don't overflow", limits, &LoginThrottle{Failures: 100, LastFailureAt: now}, 30 * time.Second},
+		{"delay already waited", limits, &LoginThrottle{Failures: 4, LastFailureAt: now.Add(-5 * time.Second)}, 0},
+		{"delay partly waited", limits, &LoginThrottle{Failures: 4, LastFailureAt: now.Add(-500 * time.Millisecond)}, 1500 * time.Millisecond},
+		{"failures out of the window", limits, &LoginThrottle{Failures: 9, LastFailureAt: now.Add(-16 * time.Minute)}, 0},
+		{"locked", limits, &LoginThrottle{LastFailureAt: now, LockedUntil: &lockedUntil}, time.Minute},
+		{"lock expired", limits, &LoginThrottle{LastFailureAt: now.Add(-time.Hour), LockedUntil: &expiredLock}, 0},
+		{"no delay", LoginLimits{FailureWindow: time.Minute}, &LoginThrottle{Failures: 50, LastFailureAt: now}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.limits.RetryAfter(tt.throttle, now)
+			if got != tt.want {
+				t.Errorf("RetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginThrottleIsLocked(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	lockedUntil := now.Add(time.Minute)
+	tests := []struct {
+		name     string
+		throttle *LoginThrottle
+		want     bool
+	}{
+		{"nil", nil, false},
+		{"never locked", &LoginThrottle{Failures: 5}, false},
+		{"locked", &LoginThrottle{LockedUntil: &lockedUntil}, true},
+		{"lock ends now", &LoginThrottle{LockedUntil: &now}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.throttle.IsLocked(now); got != tt.want {
+			t.Errorf("%s: IsLocked() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type LoyaltyEntryKind int16
+
+const (
+	LoyaltyEntryKindEarning LoyaltyEntryKind = iota
+	LoyaltyEntryKindRedemption
+	LoyaltyEntryKindRedemptionRelease
+	LoyaltyEntryKindExpiration
+)
+
+func (k LoyaltyEntryKind) String() string {
+	switch k {
+	case LoyaltyEntryKindEarning:
+		return "Earning"
+	case LoyaltyEntryKindRedemption:
+		return "Redemption"
+	case LoyaltyEntryKindRedemptionRelease:
+		return "RedemptionRelease"
+	case LoyaltyEntryKindExpiration:
+		return "Expiration"
+	}
+	return fmt.Sprintf("LoyaltyEntryKind %d", k)
+}
+
+var (
+	ErrInsufficientPoints = errors.New("loyalty points are insufficient")
+)
+
+// LoyaltyTier multiplies the points earned by a user who earned at least MinPoints during the last LoyaltyTierWindow.
+type LoyaltyTier struct {
+	Name       string  `json:"name"`
+	MinPoints  int64   `json:"min_points"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// LoyaltyTiers are ordered by MinPoints, the first tier is the one every user starts with.
+var LoyaltyTiers = []LoyaltyTier{
+	{Name: "Bronze", MinPoints: 0, Multiplier: 1},
+	{Name: "Silver", MinPoints: 1_000, Multiplier: 1.25},
+	{Name: "Gold", MinPoints: 5_000, Multiplier: 1.5},
+}
+
+const LoyaltyTierWindow = 365 * 24 * time.Hour
+
+// GetLoyaltyTier returns the tier of the user who earned points during the last LoyaltyTierWindow
+// and the next tier or nil if it's the highest one.
+func GetLoyaltyTier(points int64) (LoyaltyTier, *LoyaltyTier) {
+	i := 0
+	for i+1 < len(LoyaltyTiers) && LoyaltyTiers[i+1].MinPoints <= points {
+		i++
+	}
+	if i+1 < len(LoyaltyTiers) {
+		return LoyaltyTiers[i], &LoyaltyTiers[i+1]
+	}
+	return LoyaltyTiers[i], nil
+}
+
+// LoyaltyEntry is a line of the user's points ledger, the points of an earning expire at ExpiresAt
+// and the oldest points are the first to be redeemed.
+type LoyaltyEntry struct {
+	ID        int64            `json:"id"`
+	CreatedAt time.Time        `json:"created_at"`
+	UserID    int64            `json:"user_id"`
+	Kind      LoyaltyEntryKind `json:"kind"`
+	Points    int64            `json:"points"`
+	OrderID   *int64           `json:"order_id,omitempty"`
+	ExpiresAt *time.Time       `json:"expires_at,omitempty"`
+}
+
+type LoyaltyAccount struct {
+	Balance    int64        `json:"balance"`
+	TierPoints int64        `json:"tier_points"`
+	Tier       LoyaltyTier  `json:"tier"`
+	NextTier   *LoyaltyTier `json:"next_tier,omitempty"`
+}
+
+type LoyaltyStorer interface {
+	GetAccount(userID int64) (*LoyaltyAccount, error)
+	GetEntries(userID int64, page int, pageSize int) ([]LoyaltyEntry, *MetaData, error)
+	Redeem(userID int64, orderID int64, points int64) (*LoyaltyEntry, error)
+	EarnAll(pointsPerDollar int, lifetime time.Duration) (int64, error)
+	ExpireAll() (int64, error)
+}
+
+type loyaltyStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s loyaltyStorage) GetAccount(userID int64) (*LoyaltyAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	var a LoyaltyAccount
+	query := `SELECT COALESCE(SUM(points), 0),
+	          COALESCE(SUM(points) FILTER (WHERE kind_id = 0 AND created_at > NOW() - make_interval(secs => $2)), 0)
+	          FROM loyalty_entries
+			  WHERE user_id = $1`
+	args := []any{userID, LoyaltyTierWindow.Seconds()}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&a.Balance, &a.TierPoints)
+	if err != nil {
+		return nil, err
+	}
+	a.Tier, a.NextTier = GetLoyaltyTier(a.TierPoints)
+	return &a, nil
+}
+
+func (s loyaltyStorage) GetEntries(userID int64, page int, pageSize int) ([]LoyaltyEntry, *MetaData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT count(*) OVER(), id, created_at, user_id, kind_id, points, order_id, expires_at
+	          FROM loyalty_entries
+			  WHERE user_id = $1
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $2 OFFSET $3`
+	limit := pageSize
+	offset := (page - 1) * pageSize
+	args := []any{userID, limit, offset}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	totalRecords := 0
+	var entries []LoyaltyEntry
+	for rows.Next() {
+		var e LoyaltyEntry
+		err := rows.Scan(&totalRecords, &e.ID, &e.CreatedAt, &e.UserID, &e.Kind, &e.Points, &e.OrderID, &e.ExpiresAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metaData := &MetaData{}
+	if totalRecords != 0 {
+		metaData = &MetaData{
+			CurrentPage:  page,
+			PageSize:     pageSize,
+			FirstPage:    1,
+			LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+			TotalRecords: totalRecords,
+		}
+	}
+	return entries, metaData, nil
+}
+
+// Redeem spends the points for the order, they are released if the order's checkout session
+// is deleted before it's fulfilled.
+func (s loyaltyStorage) Redeem(userID int64, orderID int64, points int64) (*LoyaltyEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	var balance int64
+	query0 := `SELECT COALESCE(SUM(points), 0)
+	           FROM loyalty_entries
+			   WHERE user_id = $1`
+	args0 := []any{userID}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&balance)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if balance < points {
+		tx.Rollback()
+		return nil, ErrInsufficientPoints
+	}
+	e := LoyaltyEntry{
+		UserID:  userID,
+		Kind:    LoyaltyEntryKindRedemption,
+		Points:  -points,
+		OrderID: &orderID,
+	}
+	query1 := `INSERT INTO loyalty_entries(user_id, kind_id, points, order_id)
+	           VALUES ($1, $2, $3, $4)
+			   RETURNING id, created_at`
+	args1 := []any{e.UserID, e.Kind, e.Points, orderID}
+	err = tx.QueryRowContext(ctx, query1, args1...).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// EarnAll awards the points of the orders completed during the last lifetime that didn't earn points yet,
+// an order earns pointsPerDollar for every dollar paid multiplied by the user's tier.
+// box office orders are recorded for the cashier so they don't earn points.
+func (s loyaltyStorage) EarnAll(pointsPerDollar int, lifetime time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	minPoints := make([]int64, 0, len(LoyaltyTiers))
+	multipliers := make([]float64, 0, len(LoyaltyTiers))
+	for _, t := range LoyaltyTiers {
+		minPoints = append(minPoints, t.MinPoints)
+		multipliers = append(multipliers, t.Multiplier)
+	}
+	query := `INSERT INTO loyalty_entries(user_id, kind_id, points, order_id, expires_at)
+	          SELECT o.user_id, 0, FLOOR((o.total - o.loyalty_discount) * $1 * tier.multiplier), o.id, NOW() + make_interval(secs => $4)
+			  FROM orders AS o
+			  CROSS JOIN LATERAL (
+			      SELECT COALESCE(SUM(le.points), 0) AS points
+				  FROM loyalty_entries AS le
+				  WHERE le.user_id = o.user_id AND le.kind_id = 0 AND le.created_at > NOW() - make_interval(secs => $5)
+			  ) AS earned
+			  CROSS JOIN LATERAL (
+			      SELECT t.multiplier
+				  FROM unnest($2::bigint[], $3::float8[]) AS t(min_points, multiplier)
+				  WHERE t.min_points <= earned.points
+				  ORDER BY t.min_points DESC
+				  LIMIT 1
+			  ) AS tier
+			  WHERE o.status_id = 1 AND o.cashier_id IS NULL AND o.created_at > NOW() - make_interval(secs => $4)
+			  AND NOT EXISTS(SELECT 1 FROM loyalty_entries AS le WHERE le.order_id = o.id AND le.kind_id = 0)
+			  ON CONFLICT DO NOTHING`
+	args := []any{pointsPerDollar, pq.Array(minPoints), pq.Array(multipliers), lifetime.Seconds(), LoyaltyTierWindow.Seconds()}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ExpireAll takes away the expired points that weren't redeemed, redemptions use the oldest points first
+// so a user's expired points are the expired earnings minus everything spent or expired before.
+func (s loyaltyStorage) ExpireAll() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `INSERT INTO loyalty_entries(user_id, kind_id, points)
+	          SELECT user_id, 3, used - expired
+			  FROM (
+			      SELECT user_id,
+				  COALESCE(SUM(points) FILTER (WHERE kind_id = 0 AND expires_at <= NOW()), 0) AS expired,
+				  COALESCE(-SUM(points) FILTER (WHERE kind_id <> 0), 0) AS used
+				  FROM loyalty_entries
+				  GROUP BY user_id
+			  ) AS balances
+			  WHERE expired > used`
+	// redemptions check the balance in serializable transactions, expiring in one keeps them from spending expired points.
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	err = tx.Commit()
+	return n, err
+}
@@ -0,0 +1,441 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type MembershipStatus int16
+
+const (
+	MembershipStatusIncomplete MembershipStatus = iota
+	MembershipStatusActive
+	MembershipStatusPastDue
+	MembershipStatusCanceled
+)
+
+func (s MembershipStatus) String() string {
+	switch s {
+	case MembershipStatusIncomplete:
+		return "Incomplete"
+	case MembershipStatusActive:
+		return "Active"
+	case MembershipStatusPastDue:
+		return "PastDue"
+	case MembershipStatusCanceled:
+		return "Canceled"
+	}
+	return fmt.Sprintf("MembershipStatus %d", s)
+}
+
+// MembershipPlan is a monthly subscription sold by a cinema, its members get TicketsPerPeriod free tickets
+// in the cinema every billing period or unlimited ones if it's nil. the plan is billed with the payment gateway
+// price PriceReference.
+type MembershipPlan struct {
+	ID               int32           `json:"id"`
+	CreatedAt        time.Time       `json:"created_at"`
+	CinemaID         int32           `json:"cinema_id"`
+	Name             string          `json:"name"`
+	Price            decimal.Decimal `json:"price"`
+	TicketsPerPeriod *int32          `json:"tickets_per_period,omitempty"`
+	PriceReference   string          `json:"-"`
+	IsActive         bool            `json:"is_active"`
+	Version          int32           `json:"version"`
+}
+
+// Membership is a user's subscription to a plan, it's incomplete until its checkout session is paid
+// and its status and billing period follow the payment gateway's subscription.
+type Membership struct {
+	ID                    int64            `json:"id"`
+	CreatedAt             time.Time        `json:"created_at"`
+	UpdatedAt             time.Time        `json:"updated_at"`
+	UserID                int64            `json:"user_id"`
+	PlanID                int32            `json:"plan_id"`
+	StatusID              MembershipStatus `json:"status_id"`
+	SessionID             string           `json:"-"`
+	SubscriptionReference *string          `json:"-"`
+	PeriodStart           *time.Time       `json:"period_start,omitempty"`
+	PeriodEnd             *time.Time       `json:"period_end,omitempty"`
+	CancelAtPeriodEnd     bool             `json:"cancel_at_period_end"`
+	TicketsUsed           int              `json:"tickets_used"`
+	Plan                  *MembershipPlan  `json:"plan,omitempty"`
+}
+
+// MembershipEntitlement is how many more free tickets an active membership has in the cinema
+// during the current billing period, Remaining is nil for unlimited plans.
+type MembershipEntitlement struct {
+	MembershipID int64
+	CinemaID     int32
+	Remaining    *int64
+}
+
+// MembershipSubscription is the state of a membership's subscription on the payment gateway.
+type MembershipSubscription struct {
+	Reference         string
+	StatusID          MembershipStatus
+	PeriodStart       time.Time
+	PeriodEnd         time.Time
+	CancelAtPeriodEnd bool
+}
+
+type MembershipStorer interface {
+	CreatePlan(cinemaID int32, name string, price decimal.Decimal, ticketsPerPeriod *int32, priceReference string) (*MembershipPlan, error)
+	GetPlanAndCinema(id int32) (*MembershipPlan, *Cinema, error)
+	GetAllPlansForCinema(cinemaID int32) ([]MembershipPlan, error)
+	DeactivatePlan(p *MembershipPlan) error
+	Create(userID int64, planID int32, sessionID string) (*Membership, error)
+	GetByID(id int64) (*Membership, error)
+	GetBySessionID(sessionID string) (*Membership, error)
+	GetAllForUser(userID int64) ([]Membership, error)
+	Activate(sessionID string, sub MembershipSubscription) (bool, error)
+	Sync(sub MembershipSubscription) (bool, error)
+	DeleteIncomplete(sessionID string) error
+	GetEntitlements(userID int64) ([]MembershipEntitlement, error)
+}
+
+type membershipStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s membershipStorage) CreatePlan(cinemaID int32, name string, price decimal.Decimal, ticketsPerPeriod *int32, priceReference string) (*MembershipPlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	p := MembershipPlan{
+		CinemaID:         cinemaID,
+		Name:             name,
+		Price:            price,
+		TicketsPerPeriod: ticketsPerPeriod,
+		PriceReference:   priceReference,
+		IsActive:         true,
+	}
+	query := `INSERT INTO membership_plans(cinema_id, name, price, tickets_per_period, price_reference)
+	          VALUES ($1, $2, $3, $4, $5)
+			  RETURNING id, created_at, version`
+	args := []any{cinemaID, name, price, ticketsPerPeriod, priceReference}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&p.ID, &p.CreatedAt, &p.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s membershipStorage) GetPlanAndCinema(id int32) (*MembershipPlan, *Cinema, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	p := MembershipPlan{
+		ID: id,
+	}
+	var c Cinema
+	query := `SELECT p.created_at, p.cinema_id, p.name, p.price, p.tickets_per_period, p.price_reference, p.is_active, p.version,
+	          c.id, c.name, c.location, c.owner_id, c.version
+	          FROM membership_plans as p
+			  INNER JOIN cinemas as c
+			  ON c.id = p.cinema_id
+			  WHERE p.id = $1`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&p.CreatedAt, &p.CinemaID, &p.Name, &p.Price, &p.TicketsPerPeriod, &p.PriceReference, &p.IsActive, &p.Version,
+		&c.ID, &c.Name, &c.Location, &c.OwnerID, &c.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	return &p, &c, nil
+}
+
+// GetAllPlansForCinema returns the plans of the cinema that can still be subscribed to.
+func (s membershipStorage) GetAllPlansForCinema(cinemaID int32) ([]MembershipPlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT id, created_at, cinema_id, name, price, tickets_per_period, price_reference, is_active, version
+	          FROM membership_plans
+			  WHERE cinema_id = $1 AND is_active = true
+			  ORDER BY price ASC, id ASC`
+	args := []any{cinemaID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var plans []MembershipPlan
+	for rows.Next() {
+		var p MembershipPlan
+		err := rows.Scan(&p.ID, &p.CreatedAt, &p.CinemaID, &p.Name, &p.Price, &p.TicketsPerPeriod, &p.PriceReference, &p.IsActive, &p.Version)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// DeactivatePlan stops new subscriptions to the plan, the memberships already subscribed to it keep renewing.
+func (s membershipStorage) DeactivatePlan(p *MembershipPlan) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE membership_plans
+	          SET is_active = false, version = version + 1
+			  WHERE id = $1 AND version = $2
+			  RETURNING version`
+	args := []any{p.ID, p.Version}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&p.Version)
+	if err != nil {
+		return err
+	}
+	p.IsActive = false
+	return nil
+}
+
+// Create creates an incomplete membership for the subscription checkout session,
+// it returns nil if the user already has a membership of the plan that isn't canceled.
+func (s membershipStorage) Create(userID int64, planID int32, sessionID string) (*Membership, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	m := Membership{
+		UserID:    userID,
+		PlanID:    planID,
+		StatusID:  MembershipStatusIncomplete,
+		SessionID: sessionID,
+	}
+	query := `INSERT INTO memberships(user_id, plan_id, session_id)
+	          VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING
+			  RETURNING id, created_at, updated_at`
+	args := []any{userID, planID, sessionID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s membershipStorage) GetByID(id int64) (*Membership, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	m := Membership{
+		ID: id,
+	}
+	query := `SELECT created_at, updated_at, user_id, plan_id, status_id, session_id, subscription_reference, period_start, period_end, cancel_at_period_end
+	          FROM memberships
+			  WHERE id = $1`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&m.CreatedAt, &m.UpdatedAt, &m.UserID, &m.PlanID, &m.StatusID, &m.SessionID, &m.SubscriptionReference, &m.PeriodStart, &m.PeriodEnd, &m.CancelAtPeriodEnd)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s membershipStorage) GetBySessionID(sessionID string) (*Membership, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	m := Membership{
+		SessionID: sessionID,
+	}
+	query := `SELECT id, created_at, updated_at, user_id, plan_id, status_id, subscription_reference, period_start, period_end, cancel_at_period_end
+	          FROM memberships
+			  WHERE session_id = $1`
+	args := []any{sessionID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt, &m.UserID, &m.PlanID, &m.StatusID, &m.SubscriptionReference, &m.PeriodStart, &m.PeriodEnd, &m.CancelAtPeriodEnd)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetAllForUser returns the user's memberships with their plans and the tickets used during the current billing period.
+func (s membershipStorage) GetAllForUser(userID int64) ([]Membership, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT m.id, m.created_at, m.updated_at, m.user_id, m.plan_id, m.status_id, m.session_id, m.subscription_reference, m.period_start, m.period_end, m.cancel_at_period_end,
+	          (SELECT count(*)
+			   FROM order_lines AS ol
+			   INNER JOIN orders AS o
+			   ON o.id = ol.order_id
+			   WHERE ol.membership_id = m.id AND ol.kind_id = 0 AND o.status_id <> 2 AND o.created_at >= m.period_start),
+	          p.id, p.created_at, p.cinema_id, p.name, p.price, p.tickets_per_period, p.price_reference, p.is_active, p.version
+	          FROM memberships AS m
+			  INNER JOIN membership_plans AS p
+			  ON p.id = m.plan_id
+			  WHERE m.user_id = $1 AND m.status_id <> 0
+			  ORDER BY m.created_at DESC, m.id DESC`
+	args := []any{userID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var memberships []Membership
+	for rows.Next() {
+		var m Membership
+		var p MembershipPlan
+		err := rows.Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt, &m.UserID, &m.PlanID, &m.StatusID, &m.SessionID, &m.SubscriptionReference, &m.PeriodStart, &m.PeriodEnd, &m.CancelAtPeriodEnd,
+			&m.TicketsUsed,
+			&p.ID, &p.CreatedAt, &p.CinemaID, &p.Name, &p.Price, &p.TicketsPerPeriod, &p.PriceReference, &p.IsActive, &p.Version)
+		if err != nil {
+			return nil, err
+		}
+		m.Plan = &p
+		memberships = append(memberships, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// Activate links the incomplete membership of the checkout session to its subscription,
+// it reports false if there is no incomplete membership for the session so it's safe to call it again.
+func (s membershipStorage) Activate(sessionID string, sub MembershipSubscription) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE memberships
+	          SET subscription_reference = $2, status_id = $3, period_start = $4, period_end = $5, cancel_at_period_end = $6, updated_at = NOW()
+			  WHERE session_id = $1 AND status_id = 0`
+	args := []any{sessionID, sub.Reference, sub.StatusID, sub.PeriodStart, sub.PeriodEnd, sub.CancelAtPeriodEnd}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n != 0, nil
+}
+
+// Sync updates the membership of the subscription to its latest state, subscription events can arrive
+// out of order so the state is always fetched from the payment gateway. a canceled membership stays canceled.
+func (s membershipStorage) Sync(sub MembershipSubscription) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE memberships
+	          SET status_id = $2, period_start = $3, period_end = $4, cancel_at_period_end = $5, updated_at = NOW()
+			  WHERE subscription_reference = $1 AND status_id <> 3`
+	args := []any{sub.Reference, sub.StatusID, sub.PeriodStart, sub.PeriodEnd, sub.CancelAtPeriodEnd}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n != 0, nil
+}
+
+// DeleteIncomplete deletes the membership of a subscription checkout session that was never paid.
+func (s membershipStorage) DeleteIncomplete(sessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM memberships
+	          WHERE session_id = $1 AND status_id = 0`
+	args := []any{sessionID}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetEntitlements returns the free tickets left in the current billing period of the user's active memberships,
+// tickets of pending orders are counted as used so they can't be spent twice.
+func (s membershipStorage) GetEntitlements(userID int64) ([]MembershipEntitlement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT m.id, p.cinema_id,
+	          p.tickets_per_period - (SELECT count(*)
+			                          FROM order_lines AS ol
+			                          INNER JOIN orders AS o
+			                          ON o.id = ol.order_id
+			                          WHERE ol.membership_id = m.id AND ol.kind_id = 0 AND o.status_id <> 2 AND o.created_at >= m.period_start)
+	          FROM memberships AS m
+			  INNER JOIN membership_plans AS p
+			  ON p.id = m.plan_id
+			  WHERE m.user_id = $1 AND m.status_id = 1 AND m.period_end > NOW()
+			  ORDER BY m.id ASC`
+	args := []any{userID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var entitlements []MembershipEntitlement
+	for rows.Next() {
+		var e MembershipEntitlement
+		err := rows.Scan(&e.MembershipID, &e.CinemaID, &e.Remaining)
+		if err != nil {
+			return nil, err
+		}
+		entitlements = append(entitlements, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entitlements, nil
+}
+
+// ApplyMemberships zero-prices the checkout items covered by the entitlements, resold tickets are paid to their sellers
+// so they are never covered.
+func ApplyMemberships(items []CheckoutItem, entitlements []MembershipEntitlement) {
+	for i := range items {
+		item := &items[i]
+		if item.ListingID != nil {
+			continue
+		}
+		for j := range entitlements {
+			e := &entitlements[j]
+			if e.CinemaID != item.Cinema.ID || (e.Remaining != nil && *e.Remaining <= 0) {
+				continue
+			}
+			if e.Remaining != nil {
+				*e.Remaining--
+			}
+			membershipID := e.MembershipID
+			item.MembershipID = &membershipID
+			item.Ticket.Price = decimal.Zero
+			break
+		}
+	}
+}
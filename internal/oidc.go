@@ -0,0 +1,386 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// OIDCProviderConfig is an OpenID Connect issuer the users can sign in with, its client must be registered
+// with the redirect URL and be allowed to use the authorization code flow.
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// IDTokenClaims are the claims of an ID token that are used to find or create the user.
+type IDTokenClaims struct {
+	Issuer        string       `json:"iss"`
+	Subject       string       `json:"sub"`
+	Audience      oidcAudience `json:"aud"`
+	ExpiresAt     int64        `json:"exp"`
+	IssuedAt      int64        `json:"iat"`
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified oidcBool     `json:"email_verified"`
+	Name          string       `json:"name"`
+}
+
+// oidcAudience is either a string or an array of strings.
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = oidcAudience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+// oidcBool is a boolean some issuers send as a string.
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	var v bool
+	if err := json.Unmarshal(data, &v); err == nil {
+		*b = oidcBool(v)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*b = s == "true"
+	return nil
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcJWK struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// the keys are fetched again for an unknown key id at most once in this duration so tokens with made up key ids
+// don't make the issuer get flooded.
+const oidcKeysRefetchInterval = time.Minute
+
+// OIDCProvider runs the authorization code flow with PKCE against an issuer,
+// the issuer's metadata and keys are discovered on first use and cached.
+type OIDCProvider struct {
+	OIDCProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	metadata      *oidcMetadata
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+func NewOIDCProvider(cfg OIDCProviderConfig) *OIDCProvider {
+	return &OIDCProvider{
+		OIDCProviderConfig: cfg,
+		client:             &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GeneratePKCEVerifier returns a code verifier of RFC 7636.
+func GeneratePKCEVerifier() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return jwtEncoding.EncodeToString(b)
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return jwtEncoding.EncodeToString(sum[:])
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with %s", url, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(dst)
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	var m oidcMetadata
+	err := p.getJSON(ctx, strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", &m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %w", p.Name, err)
+	}
+	if m.Issuer != p.Issuer {
+		return nil, fmt.Errorf("%s's issuer is %q instead of %q", p.Name, m.Issuer, p.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("%s's metadata is missing endpoints", p.Name)
+	}
+	p.metadata = &m
+	return p.metadata, nil
+}
+
+// AuthorizationURL returns where the user is sent to sign in, the issuer redirects back to the redirect URL with the code and the state.
+func (p *OIDCProvider) AuthorizationURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.ClientID)
+	v.Set("redirect_uri", p.RedirectURL)
+	v.Set("scope", "openid email profile")
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", pkceChallenge(codeVerifier))
+	v.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange exchanges the code for the tokens and returns the claims of the verified ID token.
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*IDTokenClaims, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// public clients don't have a secret, the code verifier proves the code is theirs
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to read %s's token response: %w", p.Name, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s rejected the code: %s %s", p.Name, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("%s didn't return an id token", p.Name)
+	}
+	return p.verifyIDToken(ctx, body.IDToken, nonce)
+}
+
+func (p *OIDCProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcKeysRefetchInterval {
+		return nil, ErrInvalidIDToken
+	}
+	p.keysFetchedAt = time.Now()
+	var set struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	err := p.getJSON(ctx, p.metadata.JWKSURI, &set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s's keys: %w", p.Name, err)
+	}
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+	p.keys = keys
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, ErrInvalidIDToken
+	}
+	return key, nil
+}
+
+func (k oidcJWK) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := jwtEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := jwtEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Curve)
+		}
+		x, err := jwtEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := jwtEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Curve)
+		}
+		x, err := jwtEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.KeyType)
+}
+
+// verifySignature only accepts the algorithm that matches the key so a token can't pick a weaker one.
+func verifySignature(key crypto.PublicKey, alg string, signingInput []byte, signature []byte) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			return false
+		}
+		sum := sha256.Sum256(signingInput)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || len(signature) != 64 {
+			return false
+		}
+		sum := sha256.Sum256(signingInput)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, sum[:], r, s)
+	case ed25519.PublicKey:
+		if alg != "EdDSA" {
+			return false
+		}
+		return ed25519.Verify(key, signingInput, signature)
+	}
+	return false
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, token string, nonce string) (*IDTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+	headerJSON, err := jwtEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	key, err := p.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	if !verifySignature(key, header.Algorithm, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidIDToken
+	}
+	payload, err := jwtEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	var claims IDTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	if claims.Issuer != p.Issuer || !slices.Contains(claims.Audience, p.ClientID) || claims.Subject == "" {
+		return nil, ErrInvalidIDToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidIDToken
+	}
+	// the nonce ties the token to the login that was started here so it can't be replayed
+	if claims.Nonce != nonce {
+		return nil, ErrInvalidIDToken
+	}
+	return &claims, nil
+}
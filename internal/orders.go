@@ -0,0 +1,335 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type OrderStatus int16
+
+const (
+	OrderStatusPending OrderStatus = iota
+	OrderStatusCompleted
+	OrderStatusCancelled
+)
+
+func (s OrderStatus) String() string {
+	switch s {
+	case OrderStatusPending:
+		return "Pending"
+	case OrderStatusCompleted:
+		return "Completed"
+	case OrderStatusCancelled:
+		return "Cancelled"
+	}
+	return fmt.Sprintf("OrderStatus %d", s)
+}
+
+type OrderLineKind int16
+
+const (
+	OrderLineKindTicket OrderLineKind = iota
+	OrderLineKindFee
+	OrderLineKindTax
+	OrderLineKindProduct
+)
+
+func (k OrderLineKind) String() string {
+	switch k {
+	case OrderLineKindTicket:
+		return "Ticket"
+	case OrderLineKindFee:
+		return "Fee"
+	case OrderLineKindTax:
+		return "Tax"
+	case OrderLineKindProduct:
+		return "Product"
+	}
+	return fmt.Sprintf("OrderLineKind %d", k)
+}
+
+type OrderLine struct {
+	ID           int64           `json:"id"`
+	CinemaID     int32           `json:"cinema_id"`
+	TicketID     *int64          `json:"ticket_id,omitempty"`
+	Kind         OrderLineKind   `json:"kind"`
+	Name         string          `json:"name"`
+	Amount       decimal.Decimal `json:"amount"`
+	IsInclusive  bool            `json:"is_inclusive"`
+	MembershipID *int64          `json:"membership_id,omitempty"`
+	ProductID    *int32          `json:"product_id,omitempty"`
+	Quantity     int32           `json:"quantity"`
+}
+
+// Order is the record of what the user was charged for a checkout session.
+type Order struct {
+	ID               int64           `json:"id"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UserID           int64           `json:"user_id"`
+	SessionID        string          `json:"session_id"`
+	StatusID         OrderStatus     `json:"status_id"`
+	Subtotal         decimal.Decimal `json:"subtotal"`
+	FeesTotal        decimal.Decimal `json:"fees_total"`
+	TaxesTotal       decimal.Decimal `json:"taxes_total"`
+	Total            decimal.Decimal `json:"total"`
+	WalletAmount     decimal.Decimal `json:"wallet_amount"`
+	LoyaltyPoints    int64           `json:"loyalty_points"`
+	LoyaltyDiscount  decimal.Decimal `json:"loyalty_discount"`
+	PaymentReference string          `json:"payment_reference,omitempty"`
+	PickupCode       string          `json:"pickup_code,omitempty"`
+	PaymentMethod    PaymentMethod   `json:"payment_method"`
+	CashierID        *int64          `json:"cashier_id,omitempty"`
+	DrawerID         *int64          `json:"drawer_id,omitempty"`
+	Lines            []OrderLine     `json:"lines"`
+}
+
+// Invoice numbers are sequential per cinema, an order gets an invoice for every cinema it has tickets for.
+type Invoice struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	OrderID   int64     `json:"order_id"`
+	CinemaID  int32     `json:"cinema_id"`
+	Number    int64     `json:"number"`
+}
+
+func (i *Invoice) String() string {
+	return fmt.Sprintf("INV-%d-%06d", i.CinemaID, i.Number)
+}
+
+type OrderStorer interface {
+	Create(userID int64, sessionID string, items []CheckoutItem, products []CheckoutProduct, b *Breakdown, walletAmount decimal.Decimal, loyaltyPoints int64, loyaltyDiscount decimal.Decimal) (*Order, error)
+	GetByID(id int64) (*Order, error)
+	GetBySessionID(sessionID string) (*Order, error)
+	GetInvoices(orderID int64) ([]Invoice, error)
+}
+
+type orderStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s orderStorage) Create(userID int64, sessionID string, items []CheckoutItem, products []CheckoutProduct, b *Breakdown, walletAmount decimal.Decimal, loyaltyPoints int64, loyaltyDiscount decimal.Decimal) (*Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	o := newOrder(userID, sessionID, items, products, b)
+	o.WalletAmount = walletAmount
+	o.LoyaltyPoints = loyaltyPoints
+	o.LoyaltyDiscount = loyaltyDiscount
+	if len(products) != 0 {
+		o.PickupCode = GeneratePickupCode()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = insertOrder(ctx, tx, &o, products)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// newOrder returns a pending order with a line for every ticket, product and charge of the breakdown.
+func newOrder(userID int64, sessionID string, items []CheckoutItem, products []CheckoutProduct, b *Breakdown) Order {
+	o := Order{
+		UserID:     userID,
+		SessionID:  sessionID,
+		StatusID:   OrderStatusPending,
+		Subtotal:   b.Subtotal,
+		FeesTotal:  b.FeesTotal,
+		TaxesTotal: b.TaxesTotal,
+		Total:      b.Total,
+	}
+	for _, item := range items {
+		ticketID := item.Ticket.ID
+		name := fmt.Sprintf("%s - %s - %s - %s", item.Movie.Title, item.Hall.Name, item.Seat.Coordinates, item.Schedule.StartsAt.Format(time.RFC3339))
+		o.Lines = append(o.Lines, OrderLine{CinemaID: item.Cinema.ID, TicketID: &ticketID, Kind: OrderLineKindTicket, Name: name, Amount: item.Ticket.Price, MembershipID: item.MembershipID, Quantity: 1})
+	}
+	for _, p := range products {
+		productID := p.Product.ID
+		name := fmt.Sprintf("%s x%d", p.Product.Name, p.Quantity)
+		o.Lines = append(o.Lines, OrderLine{CinemaID: p.Product.CinemaID, Kind: OrderLineKindProduct, Name: name, Amount: p.Amount, ProductID: &productID, Quantity: p.Quantity})
+	}
+	for _, c := range b.Fees {
+		o.Lines = append(o.Lines, OrderLine{CinemaID: c.CinemaID, TicketID: c.TicketID, Kind: OrderLineKindFee, Name: c.Name, Amount: c.Amount, Quantity: 1})
+	}
+	for _, c := range b.Taxes {
+		o.Lines = append(o.Lines, OrderLine{CinemaID: c.CinemaID, Kind: OrderLineKindTax, Name: c.Name, Amount: c.Amount, IsInclusive: c.IsInclusive, Quantity: 1})
+	}
+	return o
+}
+
+// insertOrder inserts the order with its lines and takes the stock of its products,
+// it returns ErrProductOutOfStock if a product doesn't have enough stock left.
+func insertOrder(ctx context.Context, tx *sql.Tx, o *Order, products []CheckoutProduct) error {
+	query0 := `INSERT INTO orders(user_id, session_id, status_id, subtotal, fees_total, taxes_total, total, wallet_amount, loyalty_points, loyalty_discount, pickup_code,
+	           payment_reference, payment_method_id, cashier_id, drawer_id)
+	           VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, $13, $14, $15)
+			   RETURNING id, created_at`
+	args0 := []any{o.UserID, o.SessionID, o.StatusID, o.Subtotal, o.FeesTotal, o.TaxesTotal, o.Total, o.WalletAmount, o.LoyaltyPoints, o.LoyaltyDiscount, o.PickupCode,
+		o.PaymentReference, o.PaymentMethod, o.CashierID, o.DrawerID}
+	err := tx.QueryRowContext(ctx, query0, args0...).Scan(&o.ID, &o.CreatedAt)
+	if err != nil {
+		return err
+	}
+	query1 := `INSERT INTO order_lines(order_id, cinema_id, ticket_id, kind_id, name, amount, is_inclusive, membership_id, product_id, quantity)
+	           VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			   RETURNING id`
+	for i := range o.Lines {
+		l := &o.Lines[i]
+		args1 := []any{o.ID, l.CinemaID, l.TicketID, l.Kind, l.Name, l.Amount, l.IsInclusive, l.MembershipID, l.ProductID, l.Quantity}
+		err = tx.QueryRowContext(ctx, query1, args1...).Scan(&l.ID)
+		if err != nil {
+			return err
+		}
+	}
+	// the stock is taken until the order is cancelled.
+	query2 := `UPDATE products
+	           SET stock = stock - $2
+			   WHERE id = $1 AND stock >= $2`
+	for _, p := range products {
+		args2 := []any{p.Product.ID, p.Quantity}
+		result, err := tx.ExecContext(ctx, query2, args2...)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: %s", ErrProductOutOfStock, p.Product.Name)
+		}
+	}
+	return nil
+}
+
+func (s orderStorage) GetByID(id int64) (*Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	o := Order{
+		ID: id,
+	}
+	query := `SELECT created_at, user_id, session_id, status_id, subtotal, fees_total, taxes_total, total, wallet_amount, loyalty_points, loyalty_discount, payment_reference, COALESCE(pickup_code, ''), payment_method_id, cashier_id, drawer_id
+	          FROM orders
+			  WHERE id = $1`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&o.CreatedAt, &o.UserID, &o.SessionID, &o.StatusID, &o.Subtotal, &o.FeesTotal, &o.TaxesTotal, &o.Total, &o.WalletAmount, &o.LoyaltyPoints, &o.LoyaltyDiscount, &o.PaymentReference, &o.PickupCode, &o.PaymentMethod, &o.CashierID, &o.DrawerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	o.Lines, err = s.getLines(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (s orderStorage) GetBySessionID(sessionID string) (*Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	o := Order{
+		SessionID: sessionID,
+	}
+	query := `SELECT id, created_at, user_id, status_id, subtotal, fees_total, taxes_total, total, wallet_amount, loyalty_points, loyalty_discount, payment_reference, COALESCE(pickup_code, ''), payment_method_id, cashier_id, drawer_id
+	          FROM orders
+			  WHERE session_id = $1`
+	args := []any{sessionID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&o.ID, &o.CreatedAt, &o.UserID, &o.StatusID, &o.Subtotal, &o.FeesTotal, &o.TaxesTotal, &o.Total, &o.WalletAmount, &o.LoyaltyPoints, &o.LoyaltyDiscount, &o.PaymentReference, &o.PickupCode, &o.PaymentMethod, &o.CashierID, &o.DrawerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	o.Lines, err = s.getLines(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (s orderStorage) getLines(ctx context.Context, orderID int64) ([]OrderLine, error) {
+	query := `SELECT id, cinema_id, ticket_id, kind_id, name, amount, is_inclusive, membership_id, product_id, quantity
+	          FROM order_lines
+			  WHERE order_id = $1
+			  ORDER BY kind_id ASC, id ASC`
+	args := []any{orderID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var lines []OrderLine
+	for rows.Next() {
+		var l OrderLine
+		err := rows.Scan(&l.ID, &l.CinemaID, &l.TicketID, &l.Kind, &l.Name, &l.Amount, &l.IsInclusive, &l.MembershipID, &l.ProductID, &l.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func (s orderStorage) GetInvoices(orderID int64) ([]Invoice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT id, created_at, cinema_id, number
+	          FROM invoices
+			  WHERE order_id = $1
+			  ORDER BY cinema_id ASC`
+	args := []any{orderID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var invoices []Invoice
+	for rows.Next() {
+		inv := Invoice{
+			OrderID: orderID,
+		}
+		err := rows.Scan(&inv.ID, &inv.CreatedAt, &inv.CinemaID, &inv.Number)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+type PaymentDiscrepancyKind int16
+
+const (
+	// the session was paid but not fulfilled, reconciliation fulfilled it
+	PaymentDiscrepancyKindMissedFulfillment PaymentDiscrepancyKind = iota
+	// the session was paid after its tickets were released, it needs a refund or manual fulfillment
+	PaymentDiscrepancyKindReleasedTickets
+	// the paid amount doesn't match the order total
+	PaymentDiscrepancyKindAmountMismatch
+	// the session was paid but there is no record of it
+	PaymentDiscrepancyKindUnknownSession
+	// the proceeds of a sold resale listing couldn't be paid out to the seller, they need a manual payout
+	PaymentDiscrepancyKindFailedPayout
+)
+
+func (k PaymentDiscrepancyKind) String() string {
+	switch k {
+	case PaymentDiscrepancyKindMissedFulfillment:
+		return "MissedFulfillment"
+	case PaymentDiscrepancyKindReleasedTickets:
+		return "ReleasedTickets"
+	case PaymentDiscrepancyKindAmountMismatch:
+		return "AmountMismatch"
+	case PaymentDiscrepancyKindUnknownSession:
+		return "UnknownSession"
+	case PaymentDiscrepancyKindFailedPayout:
+		return "FailedPayout"
+	}
+	return fmt.Sprintf("PaymentDiscrepancyKind %d", k)
+}
+
+type PaymentDiscrepancy struct {
+	ID         int64                  `json:"id"`
+	CreatedAt  time.Time              `json:"created_at"`
+	Kind       PaymentDiscrepancyKind `json:"kind"`
+	SessionID  string                 `json:"session_id"`
+	UserID     *int64                 `json:"user_id,omitempty"`
+	Details    string                 `json:"details"`
+	ResolvedAt *time.Time             `json:"resolved_at,omitempty"`
+}
+
+type PaymentDiscrepancyStorer interface {
+	Create(kind PaymentDiscrepancyKind, sessionID string, userID *int64, details string) (bool, error)
+	GetAll(resolved bool, page int, pageSize int) ([]PaymentDiscrepancy, *MetaData, error)
+	Resolve(id int64) (*PaymentDiscrepancy, error)
+}
+
+type paymentDiscrepancyStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// Create records the discrepancy and reports whether it wasn't recorded before for the session.
+func (s paymentDiscrepancyStorage) Create(kind PaymentDiscrepancyKind, sessionID string, userID *int64, details string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `INSERT INTO payment_discrepancies(kind_id, session_id, user_id, details)
+	          VALUES ($1, $2, $3, $4)
+			  ON CONFLICT DO NOTHING`
+	args := []any{kind, sessionID, userID, details}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s paymentDiscrepancyStorage) GetAll(resolved bool, page int, pageSize int) ([]PaymentDiscrepancy, *MetaData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT count(*) OVER(), id, created_at, kind_id, session_id, user_id, details, resolved_at
+	          FROM payment_discrepancies
+			  WHERE (resolved_at IS NOT NULL) = $1
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $2 OFFSET $3`
+	limit := pageSize
+	offset := (page - 1) * pageSize
+	args := []any{resolved, limit, offset}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	totalRecords := 0
+	var discrepancies []PaymentDiscrepancy
+	for rows.Next() {
+		var d PaymentDiscrepancy
+		err := rows.Scan(&totalRecords, &d.ID, &d.CreatedAt, &d.Kind, &d.SessionID, &d.UserID, &d.Details, &d.ResolvedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		discrepancies = append(discrepancies, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metaData := &MetaData{}
+	if totalRecords != 0 {
+		metaData = &MetaData{
+			CurrentPage:  page,
+			PageSize:     pageSize,
+			FirstPage:    1,
+			LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+			TotalRecords: totalRecords,
+		}
+	}
+	return discrepancies, metaData, nil
+}
+
+func (s paymentDiscrepancyStorage) Resolve(id int64) (*PaymentDiscrepancy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	d := PaymentDiscrepancy{
+		ID: id,
+	}
+	query := `UPDATE payment_discrepancies
+	          SET resolved_at = COALESCE(resolved_at, NOW())
+			  WHERE id = $1
+			  RETURNING created_at, kind_id, session_id, user_id, details, resolved_at`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&d.CreatedAt, &d.Kind, &d.SessionID, &d.UserID, &d.Details, &d.ResolvedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
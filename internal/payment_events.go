@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PaymentEventStatus int16
+
+const (
+	PaymentEventStatusPending PaymentEventStatus = iota
+	PaymentEventStatusProcessing
+	PaymentEventStatusProcessed
+	PaymentEventStatusFailed
+)
+
+func (s PaymentEventStatus) String() string {
+	switch s {
+	case PaymentEventStatusPending:
+		return "Pending"
+	case PaymentEventStatusProcessing:
+		return "Processing"
+	case PaymentEventStatusProcessed:
+		return "Processed"
+	case PaymentEventStatusFailed:
+		return "Failed"
+	}
+	return fmt.Sprintf("PaymentEventStatus %d", s)
+}
+
+// PaymentEvent is a webhook event received from the payment gateway.
+type PaymentEvent struct {
+	ID          string             `json:"id"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Type        string             `json:"type"`
+	Data        []byte             `json:"-"`
+	StatusID    PaymentEventStatus `json:"status_id"`
+	Attempts    int32              `json:"attempts"`
+	Error       string             `json:"error,omitempty"`
+	ClaimedAt   *time.Time         `json:"claimed_at,omitempty"`
+	ProcessedAt *time.Time         `json:"processed_at,omitempty"`
+}
+
+type PaymentEventStorer interface {
+	Create(id string, eventType string, data []byte) (bool, error)
+	GetByID(id string) (*PaymentEvent, error)
+	GetAll(statuses []PaymentEventStatus, page int, pageSize int) ([]PaymentEvent, *MetaData, error)
+	Claim(id string, from []PaymentEventStatus, timeout time.Duration) (*PaymentEvent, error)
+	MarkProcessed(id string) error
+	MarkFailed(id string, reason string) error
+}
+
+type paymentEventStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// Create records the event and reports whether it wasn't recorded before.
+func (s paymentEventStorage) Create(id string, eventType string, data []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `INSERT INTO payment_events(id, type, data)
+	          VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING`
+	args := []any{id, eventType, data}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s paymentEventStorage) GetByID(id string) (*PaymentEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	e := PaymentEvent{
+		ID: id,
+	}
+	query := `SELECT created_at, type, data, status_id, attempts, error, claimed_at, processed_at
+	          FROM payment_events
+			  WHERE id = $1`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&e.CreatedAt, &e.Type, &e.Data, &e.StatusID, &e.Attempts, &e.Error, &e.ClaimedAt, &e.ProcessedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s paymentEventStorage) GetAll(statuses []PaymentEventStatus, page int, pageSize int) ([]PaymentEvent, *MetaData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT count(*) OVER(), id, created_at, type, status_id, attempts, error, claimed_at, processed_at
+	          FROM payment_events
+			  WHERE status_id = ANY($1)
+			  ORDER BY created_at DESC, id ASC
+			  LIMIT $2 OFFSET $3`
+	limit := pageSize
+	offset := (page - 1) * pageSize
+	args := []any{pq.Array(statuses), limit, offset}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	totalRecords := 0
+	var events []PaymentEvent
+	for rows.Next() {
+		var e PaymentEvent
+		err := rows.Scan(&totalRecords, &e.ID, &e.CreatedAt, &e.Type, &e.StatusID, &e.Attempts, &e.Error, &e.ClaimedAt, &e.ProcessedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metaData := &MetaData{}
+	if totalRecords != 0 {
+		metaData = &MetaData{
+			CurrentPage:  page,
+			PageSize:     pageSize,
+			FirstPage:    1,
+			LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+			TotalRecords: totalRecords,
+		}
+	}
+	return events, metaData, nil
+}
+
+// Claim marks the event as being processed if its status is one of from or if it was claimed more than timeout ago
+// and is still being processed, the processing must have crashed then.
+// It returns nil if the event doesn't exist or someone else already processed it or is processing it.
+func (s paymentEventStorage) Claim(id string, from []PaymentEventStatus, timeout time.Duration) (*PaymentEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	e := PaymentEvent{
+		ID: id,
+	}
+	query := `UPDATE payment_events
+	          SET status_id = 1, attempts = attempts + 1, claimed_at = NOW()
+			  WHERE id = $1 AND (status_id = ANY($2) OR (status_id = 1 AND (claimed_at IS NULL OR claimed_at < NOW() - $3 * interval '1 second')))
+			  RETURNING created_at, type, data, status_id, attempts, error, claimed_at, processed_at`
+	args := []any{id, pq.Array(from), timeout.Seconds()}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&e.CreatedAt, &e.Type, &e.Data, &e.StatusID, &e.Attempts, &e.Error, &e.ClaimedAt, &e.ProcessedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s paymentEventStorage) MarkProcessed(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE payment_events
+	          SET status_id = 2, error = '', processed_at = NOW()
+			  WHERE id = $1`
+	args := []any{id}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (s paymentEventStorage) MarkFailed(id string, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE payment_events
+	          SET status_id = 3, error = $2
+			  WHERE id = $1`
+	args := []any{id, reason}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
@@ -12,9 +12,31 @@ import (
 
 type Permission string
 
+// Role bundles permissions, a user has the permissions granted directly and the ones of the user's roles.
+type Role struct {
+	ID          int32        `json:"id"`
+	Code        string       `json:"code"`
+	Permissions []Permission `json:"permissions"`
+}
+
+const (
+	RoleAdmin       = "admin"
+	RoleCinemaOwner = "cinema-owner"
+	RoleCinemaStaff = "cinema-staff"
+	RoleCustomer    = "customer"
+)
+
 type PermissionStorer interface {
 	Get(userID int64) ([]Permission, error)
+	GetGranted(userID int64) ([]Permission, error)
+	GetAll() ([]Permission, error)
 	Grant(userID int64, permissions []Permission) error
+	Revoke(userID int64, permissions []Permission) error
+	GetRoles(userID int64) ([]string, error)
+	GetAllRoles() ([]Role, error)
+	GrantRoles(userID int64, roles []string) error
+	RevokeRoles(userID int64, roles []string) error
+	HasRoleHolder(role string) (bool, error)
 }
 
 type permissionStorage struct {
@@ -22,6 +44,7 @@ type permissionStorage struct {
 	db           *sql.DB
 }
 
+// Get returns the permissions granted to the user directly or by the user's roles.
 func (s permissionStorage) Get(userID int64) ([]Permission, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
@@ -30,9 +53,48 @@ func (s permissionStorage) Get(userID int64) ([]Permission, error) {
 	          FROM permissions as p
 			  INNER JOIN users_permissions as up
 			  ON p.id = up.permission_id
-			  WHERE up.user_id = $1`
+			  WHERE up.user_id = $1
+			  UNION
+			  SELECT p.code
+			  FROM permissions as p
+			  INNER JOIN roles_permissions as rp
+			  ON p.id = rp.permission_id
+			  INNER JOIN users_roles as ur
+			  ON ur.role_id = rp.role_id
+			  WHERE ur.user_id = $1`
+
+	args := []any{userID}
+	return s.getPermissions(ctx, query, args)
+}
+
+// GetGranted returns the permissions granted to the user directly.
+func (s permissionStorage) GetGranted(userID int64) ([]Permission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `SELECT p.code
+	          FROM permissions as p
+			  INNER JOIN users_permissions as up
+			  ON p.id = up.permission_id
+			  WHERE up.user_id = $1
+			  ORDER BY p.code ASC`
 
 	args := []any{userID}
+	return s.getPermissions(ctx, query, args)
+}
+
+func (s permissionStorage) GetAll() ([]Permission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `SELECT code
+	          FROM permissions
+			  ORDER BY code ASC`
+
+	return s.getPermissions(ctx, query, nil)
+}
+
+func (s permissionStorage) getPermissions(ctx context.Context, query string, args []any) ([]Permission, error) {
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -65,11 +127,154 @@ func (s permissionStorage) Grant(userID int64, permissions []Permission) error {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
 
-	query := `INSERT INTO user_permissions
-			  SELECT $1, p.id FROM permissions WHERE p.code = ANY($2)
+	query := `INSERT INTO users_permissions(user_id, permission_id)
+			  SELECT $1, p.id FROM permissions AS p WHERE p.code = ANY($2)
 			  ON CONFLICT DO NOTHING`
 
 	args := []any{userID, pq.Array(permissions)}
 	_, err := s.db.ExecContext(ctx, query, args...)
 	return err
 }
+
+func (s permissionStorage) Revoke(userID int64, permissions []Permission) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `DELETE FROM users_permissions AS up
+			  USING permissions AS p
+			  WHERE p.id = up.permission_id AND up.user_id = $1 AND p.code = ANY($2)`
+
+	args := []any{userID, pq.Array(permissions)}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (s permissionStorage) GetRoles(userID int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `SELECT r.code
+	          FROM roles as r
+			  INNER JOIN users_roles as ur
+			  ON r.id = ur.role_id
+			  WHERE ur.user_id = $1
+			  ORDER BY r.id ASC`
+
+	args := []any{userID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var roles []string
+	for rows.Next() {
+		var r string
+		err := rows.Scan(&r)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (s permissionStorage) GetAllRoles() ([]Role, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `SELECT r.id, r.code, COALESCE(array_agg(p.code ORDER BY p.code) FILTER (WHERE p.code IS NOT NULL), '{}')
+	          FROM roles as r
+			  LEFT JOIN roles_permissions as rp
+			  ON rp.role_id = r.id
+			  LEFT JOIN permissions as p
+			  ON p.id = rp.permission_id
+			  GROUP BY r.id
+			  ORDER BY r.id ASC`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var roles []Role
+	for rows.Next() {
+		var r Role
+		var codes []string
+		err := rows.Scan(&r.ID, &r.Code, pq.Array(&codes))
+		if err != nil {
+			return nil, err
+		}
+		r.Permissions = make([]Permission, 0, len(codes))
+		for _, c := range codes {
+			r.Permissions = append(r.Permissions, Permission(c))
+		}
+		roles = append(roles, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (s permissionStorage) GrantRoles(userID int64, roles []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `INSERT INTO users_roles(user_id, role_id)
+			  SELECT $1, r.id FROM roles AS r WHERE r.code = ANY($2)
+			  ON CONFLICT DO NOTHING`
+
+	args := []any{userID, pq.Array(roles)}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (s permissionStorage) RevokeRoles(userID int64, roles []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `DELETE FROM users_roles AS ur
+			  USING roles AS r
+			  WHERE r.id = ur.role_id AND ur.user_id = $1 AND r.code = ANY($2)`
+
+	args := []any{userID, pq.Array(roles)}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// HasRoleHolder reports whether any user has the role.
+func (s permissionStorage) HasRoleHolder(role string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `SELECT EXISTS(
+	              SELECT 1 FROM users_roles AS ur
+				  INNER JOIN roles AS r
+				  ON r.id = ur.role_id
+				  WHERE r.code = $1
+			  )`
+
+	args := []any{role}
+	var ok bool
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&ok)
+	return ok, err
+}
@@ -0,0 +1,248 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type ProductCategory int16
+
+const (
+	ProductCategorySnack ProductCategory = iota
+	ProductCategoryDrink
+	ProductCategoryCombo
+	ProductCategoryMerchandise
+)
+
+func (c ProductCategory) String() string {
+	switch c {
+	case ProductCategorySnack:
+		return "Snack"
+	case ProductCategoryDrink:
+		return "Drink"
+	case ProductCategoryCombo:
+		return "Combo"
+	case ProductCategoryMerchandise:
+		return "Merchandise"
+	}
+	return fmt.Sprintf("ProductCategory %d", c)
+}
+
+var (
+	ErrProductOutOfStock = errors.New("product is out of stock")
+)
+
+// Product is a concession or merchandise item sold by a cinema, Stock is taken when an order is created
+// and given back if the order is cancelled.
+type Product struct {
+	ID        int32           `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	CinemaID  int32           `json:"cinema_id"`
+	Category  ProductCategory `json:"category"`
+	Name      string          `json:"name"`
+	Price     decimal.Decimal `json:"price"`
+	Stock     int32           `json:"stock"`
+	Version   int32           `json:"version"`
+}
+
+// CheckoutProduct is a product the user added to the checkout, it's picked up at the cinema.
+type CheckoutProduct struct {
+	Product  Product         `json:"product"`
+	Quantity int32           `json:"quantity"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+// GeneratePickupCode returns the short code the user shows at the cinema to pick up the products of an order.
+func GeneratePickupCode() string {
+	b := make([]byte, 5)
+	_, _ = rand.Read(b)
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+}
+
+type ProductStorer interface {
+	Create(cinemaID int32, category ProductCategory, name string, price decimal.Decimal, stock int32) (*Product, error)
+	GetAndCinema(id int32) (*Product, *Cinema, error)
+	GetAllForCinema(cinemaID int32) ([]Product, error)
+	Update(p *Product) error
+	Delete(p *Product) error
+	GetCart(userID int64) ([]CheckoutProduct, error)
+	SetCartQuantity(userID int64, productID int32, quantity int32) error
+}
+
+type productStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s productStorage) Create(cinemaID int32, category ProductCategory, name string, price decimal.Decimal, stock int32) (*Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	p := Product{
+		CinemaID: cinemaID,
+		Category: category,
+		Name:     name,
+		Price:    price,
+		Stock:    stock,
+	}
+	query := `INSERT INTO products(cinema_id, category_id, name, price, stock)
+	          VALUES ($1, $2, $3, $4, $5)
+			  RETURNING id, created_at, version`
+	args := []any{cinemaID, category, name, price, stock}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&p.ID, &p.CreatedAt, &p.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s productStorage) GetAndCinema(id int32) (*Product, *Cinema, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	p := Product{
+		ID: id,
+	}
+	var c Cinema
+	query := `SELECT p.created_at, p.cinema_id, p.category_id, p.name, p.price, p.stock, p.version,
+	          c.id, c.name, c.location, c.owner_id, c.version
+	          FROM products as p
+			  INNER JOIN cinemas as c
+			  ON c.id = p.cinema_id
+			  WHERE p.id = $1`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&p.CreatedAt, &p.CinemaID, &p.Category, &p.Name, &p.Price, &p.Stock, &p.Version,
+		&c.ID, &c.Name, &c.Location, &c.OwnerID, &c.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	return &p, &c, nil
+}
+
+func (s productStorage) GetAllForCinema(cinemaID int32) ([]Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT id, created_at, cinema_id, category_id, name, price, stock, version
+	          FROM products
+			  WHERE cinema_id = $1
+			  ORDER BY category_id ASC, name ASC, id ASC`
+	args := []any{cinemaID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var products []Product
+	for rows.Next() {
+		var p Product
+		err := rows.Scan(&p.ID, &p.CreatedAt, &p.CinemaID, &p.Category, &p.Name, &p.Price, &p.Stock, &p.Version)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (s productStorage) Update(p *Product) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE products
+	          SET category_id = $1, name = $2, price = $3, stock = $4, version = version + 1
+			  WHERE id = $5 AND version = $6
+			  RETURNING version`
+	args := []any{p.Category, p.Name, p.Price, p.Stock, p.ID, p.Version}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&p.Version)
+	return err
+}
+
+func (s productStorage) Delete(p *Product) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM products
+			  WHERE id = $1`
+	args := []any{p.ID}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetCart returns the products the user added to the checkout.
+func (s productStorage) GetCart(userID int64) ([]CheckoutProduct, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT p.id, p.created_at, p.cinema_id, p.category_id, p.name, p.price, p.stock, p.version, cp.quantity
+	          FROM cart_products AS cp
+			  INNER JOIN products AS p
+			  ON p.id = cp.product_id
+			  WHERE cp.user_id = $1
+			  ORDER BY cp.created_at ASC, p.id ASC`
+	args := []any{userID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var products []CheckoutProduct
+	for rows.Next() {
+		var cp CheckoutProduct
+		p := &cp.Product
+		err := rows.Scan(&p.ID, &p.CreatedAt, &p.CinemaID, &p.Category, &p.Name, &p.Price, &p.Stock, &p.Version, &cp.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		cp.Amount = p.Price.Mul(decimal.NewFromInt32(cp.Quantity))
+		products = append(products, cp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// SetCartQuantity sets the quantity of the product in the user's checkout, a quantity of zero removes it.
+func (s productStorage) SetCartQuantity(userID int64, productID int32, quantity int32) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	if quantity == 0 {
+		query := `DELETE FROM cart_products
+		          WHERE user_id = $1 AND product_id = $2`
+		args := []any{userID, productID}
+		_, err := s.db.ExecContext(ctx, query, args...)
+		return err
+	}
+	query := `INSERT INTO cart_products(user_id, product_id, quantity)
+	          VALUES ($1, $2, $3)
+			  ON CONFLICT (user_id, product_id) DO UPDATE
+			  SET quantity = EXCLUDED.quantity`
+	args := []any{userID, productID, quantity}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
@@ -0,0 +1,446 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type ResaleListingStatus int16
+
+const (
+	ResaleListingStatusActive ResaleListingStatus = iota
+	ResaleListingStatusSold
+	ResaleListingStatusCancelled
+)
+
+func (s ResaleListingStatus) String() string {
+	switch s {
+	case ResaleListingStatusActive:
+		return "Active"
+	case ResaleListingStatusSold:
+		return "Sold"
+	case ResaleListingStatusCancelled:
+		return "Cancelled"
+	}
+	return fmt.Sprintf("ResaleListingStatus %d", s)
+}
+
+// ResaleListing is a sold ticket put up for resale by its owner, once it's sold the price minus the fee
+// is refunded to the payment of the order the seller bought the ticket with or credited to the seller's wallet.
+type ResaleListing struct {
+	ID             int64               `json:"id"`
+	CreatedAt      time.Time           `json:"created_at"`
+	TicketID       int64               `json:"ticket_id"`
+	SellerID       int64               `json:"seller_id"`
+	OrderID        int64               `json:"-"`
+	Price          decimal.Decimal     `json:"price"`
+	Fee            decimal.Decimal     `json:"fee"`
+	PayoutToWallet bool                `json:"payout_to_wallet"`
+	StatusID       ResaleListingStatus `json:"status_id"`
+	BuyerID        *int64              `json:"-"`
+	BuyerOrderID   *int64              `json:"-"`
+	SoldAt         *time.Time          `json:"sold_at,omitempty"`
+	RefundID       *string             `json:"-"`
+	PaidOutAt      *time.Time          `json:"paid_out_at,omitempty"`
+	PayoutError    string              `json:"payout_error,omitempty"`
+}
+
+func (l *ResaleListing) Payout() decimal.Decimal {
+	return l.Price.Sub(l.Fee)
+}
+
+// ResalePayout is a sold listing whose proceeds weren't paid out to the seller yet,
+// orders that weren't paid entirely by card are always paid out to the wallet.
+type ResalePayout struct {
+	ListingID        int64           `json:"listing_id"`
+	SellerID         int64           `json:"seller_id"`
+	Amount           decimal.Decimal `json:"amount"`
+	PaymentReference string          `json:"payment_reference"`
+	ToWallet         bool            `json:"to_wallet"`
+	// Attempts is how many times the payout failed.
+	Attempts int `json:"attempts"`
+}
+
+type ResaleStorer interface {
+	Create(ticketID int64, sellerID int64, orderID int64, price decimal.Decimal, fee decimal.Decimal, payoutToWallet bool) (*ResaleListing, error)
+	GetByID(id int64) (*ResaleListing, error)
+	GetActiveForTicket(ticketID int64) (*ResaleListing, error)
+	GetAllForSchedule(scheduleID int64, page int, pageSize int) ([]ResaleListing, *MetaData, error)
+	Cancel(l *ResaleListing) (bool, error)
+	Lock(l *ResaleListing, u *User, lockDuration time.Duration, limits LockLimits) (*TicketLock, error)
+	Unlock(l *ResaleListing, u *User) (bool, error)
+	GetAllUnpaid(limit int, maxAttempts int) ([]ResalePayout, error)
+	MarkPaidOut(listingID int64, refundID string) error
+	PayOutToWallet(listingID int64) (*WalletEntry, error)
+	MarkPayoutFailed(listingID int64, reason string, retryIn time.Duration) (int, error)
+}
+
+type resaleStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// Create lists the ticket for resale and cancels its pending transfers.
+func (s resaleStorage) Create(ticketID int64, sellerID int64, orderID int64, price decimal.Decimal, fee decimal.Decimal, payoutToWallet bool) (*ResaleListing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	query0 := `UPDATE ticket_transfers
+	           SET status_id = 2
+			   WHERE ticket_id = $1 AND status_id = 0`
+	args0 := []any{ticketID}
+	_, err = tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	l := ResaleListing{
+		TicketID:       ticketID,
+		SellerID:       sellerID,
+		OrderID:        orderID,
+		Price:          price,
+		Fee:            fee,
+		PayoutToWallet: payoutToWallet,
+		StatusID:       ResaleListingStatusActive,
+	}
+	query1 := `INSERT INTO resale_listings(ticket_id, seller_id, order_id, price, fee, payout_to_wallet)
+	           VALUES ($1, $2, $3, $4, $5, $6)
+			   RETURNING id, created_at`
+	args1 := []any{ticketID, sellerID, orderID, price, fee, payoutToWallet}
+	err = tx.QueryRowContext(ctx, query1, args1...).Scan(&l.ID, &l.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (s resaleStorage) GetByID(id int64) (*ResaleListing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	l := ResaleListing{
+		ID: id,
+	}
+	query := `SELECT created_at, ticket_id, seller_id, order_id, price, fee, payout_to_wallet, status_id, buyer_id, buyer_order_id, sold_at, refund_id, paid_out_at, payout_error
+	          FROM resale_listings
+			  WHERE id = $1`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&l.CreatedAt, &l.TicketID, &l.SellerID, &l.OrderID, &l.Price, &l.Fee, &l.PayoutToWallet, &l.StatusID, &l.BuyerID, &l.BuyerOrderID, &l.SoldAt, &l.RefundID, &l.PaidOutAt, &l.PayoutError)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (s resaleStorage) GetActiveForTicket(ticketID int64) (*ResaleListing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	l := ResaleListing{
+		TicketID: ticketID,
+	}
+	query := `SELECT id, created_at, seller_id, order_id, price, fee, payout_to_wallet, status_id, buyer_id, buyer_order_id, sold_at, refund_id, paid_out_at, payout_error
+	          FROM resale_listings
+			  WHERE ticket_id = $1 AND status_id = 0`
+	args := []any{ticketID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&l.ID, &l.CreatedAt, &l.SellerID, &l.OrderID, &l.Price, &l.Fee, &l.PayoutToWallet, &l.StatusID, &l.BuyerID, &l.BuyerOrderID, &l.SoldAt, &l.RefundID, &l.PaidOutAt, &l.PayoutError)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// GetAllForSchedule returns the active listings of the schedule that no one is holding.
+func (s resaleStorage) GetAllForSchedule(scheduleID int64, page int, pageSize int) ([]ResaleListing, *MetaData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT count(*) OVER(), l.id, l.created_at, l.ticket_id, l.seller_id, l.order_id, l.price, l.fee, l.payout_to_wallet, l.status_id
+	          FROM resale_listings as l
+			  JOIN tickets as t
+			  ON t.id = l.ticket_id
+			  WHERE t.schedule_id = $1 AND l.status_id = 0
+			  AND NOT EXISTS(SELECT 1 FROM tickets_users as tu WHERE tu.ticket_id = l.ticket_id)
+			  ORDER BY l.price ASC, l.id ASC
+			  LIMIT $2 OFFSET $3`
+	limit := pageSize
+	offset := (page - 1) * pageSize
+	args := []any{scheduleID, limit, offset}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	totalRecords := 0
+	var listings []ResaleListing
+	for rows.Next() {
+		var l ResaleListing
+		err := rows.Scan(&totalRecords, &l.ID, &l.CreatedAt, &l.TicketID, &l.SellerID, &l.OrderID, &l.Price, &l.Fee, &l.PayoutToWallet, &l.StatusID)
+		if err != nil {
+			return nil, nil, err
+		}
+		listings = append(listings, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metaData := &MetaData{}
+	if totalRecords != 0 {
+		metaData = &MetaData{
+			CurrentPage:  page,
+			PageSize:     pageSize,
+			FirstPage:    1,
+			LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+			TotalRecords: totalRecords,
+		}
+	}
+	return listings, metaData, nil
+}
+
+// Cancel takes the listing off the market, it reports false if the listing isn't active or someone is holding it.
+func (s resaleStorage) Cancel(l *ResaleListing) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE resale_listings
+	          SET status_id = 2
+			  WHERE id = $1 AND status_id = 0
+			  AND NOT EXISTS(SELECT 1 FROM tickets_users WHERE listing_id = $1)
+			  RETURNING status_id`
+	args := []any{l.ID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&l.StatusID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Lock holds the listed ticket for the user so it's checked out with the user's other tickets,
+// lockDuration is used unless the cinema overrides it. it returns nil if the listing isn't active,
+// someone is already holding it or the movie already started.
+func (s resaleStorage) Lock(l *ResaleListing, u *User, lockDuration time.Duration, limits LockLimits) (*TicketLock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	var scheduleID int64
+	query0 := `SELECT schedule_id
+	           FROM tickets
+			   WHERE id = $1`
+	args0 := []any{l.TicketID}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&scheduleID)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// the resold tickets count against the same limits as the tickets locked at the box office price
+	err = checkLockLimits(ctx, tx, u.ID, scheduleID, limits)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	lock := TicketLock{
+		TicketID: l.TicketID,
+		UserID:   u.ID,
+	}
+	query1 := `INSERT INTO tickets_users(ticket_id, user_id, expires_at, listing_id)
+	           SELECT l.ticket_id, $2, NOW() + make_interval(secs => COALESCE(cs.ticket_lock_seconds, $3)), l.id
+			   FROM resale_listings as l
+			   JOIN tickets as t ON t.id = l.ticket_id
+			   JOIN schedules as sc ON sc.id = t.schedule_id
+			   JOIN halls as h ON h.id = sc.hall_id
+			   LEFT JOIN cinema_settings as cs ON cs.cinema_id = h.cinema_id
+			   WHERE l.id = $1 AND l.status_id = 0 AND NOW() < sc.starts_at
+			   AND NOT EXISTS(SELECT 1 FROM tickets_users as tu WHERE tu.ticket_id = l.ticket_id)
+			   RETURNING expires_at, extended`
+	args1 := []any{l.ID, u.ID, lockDuration.Seconds()}
+	err = tx.QueryRowContext(ctx, query1, args1...).Scan(&lock.ExpiresAt, &lock.Extended)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// Unlock releases the user's hold on the listed ticket, it reports false if the user isn't holding it.
+func (s resaleStorage) Unlock(l *ResaleListing, u *User) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM tickets_users
+	          WHERE listing_id = $1 AND user_id = $2`
+	args := []any{l.ID, u.ID}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n != 0, nil
+}
+
+// GetAllUnpaid returns the payouts that are due, the failed ones are due again at their next attempt
+// until they failed maxAttempts times.
+func (s resaleStorage) GetAllUnpaid(limit int, maxAttempts int) ([]ResalePayout, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT l.id, l.seller_id, l.price - l.fee, o.payment_reference, l.payout_to_wallet OR o.wallet_amount > 0 OR o.loyalty_discount > 0, l.payout_attempts
+	          FROM resale_listings as l
+			  JOIN orders as o
+			  ON o.id = l.order_id
+			  WHERE l.status_id = 1 AND l.paid_out_at IS NULL AND l.payout_attempts < $2 AND (l.next_payout_at IS NULL OR l.next_payout_at <= NOW())
+			  ORDER BY l.sold_at ASC
+			  LIMIT $1`
+	args := []any{limit, maxAttempts}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var payouts []ResalePayout
+	for rows.Next() {
+		var p ResalePayout
+		err := rows.Scan(&p.ListingID, &p.SellerID, &p.Amount, &p.PaymentReference, &p.ToWallet, &p.Attempts)
+		if err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return payouts, nil
+}
+
+func (s resaleStorage) MarkPaidOut(listingID int64, refundID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE resale_listings
+	          SET refund_id = $2, paid_out_at = NOW(), payout_error = ''
+			  WHERE id = $1`
+	args := []any{listingID, refundID}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// PayOutToWallet credits the proceeds of the sold listing to the seller's wallet,
+// it returns nil if the listing isn't sold or it was already paid out.
+func (s resaleStorage) PayOutToWallet(listingID int64) (*WalletEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	e := WalletEntry{
+		Kind:      WalletEntryKindRefund,
+		ListingID: &listingID,
+	}
+	query0 := `UPDATE resale_listings
+	           SET paid_out_at = NOW(), payout_error = ''
+			   WHERE id = $1 AND status_id = 1 AND paid_out_at IS NULL
+			   RETURNING seller_id, price - fee`
+	args0 := []any{listingID}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&e.UserID, &e.Amount)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !e.Amount.IsPositive() {
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	query1 := `INSERT INTO wallet_entries(user_id, kind_id, amount, listing_id)
+	           VALUES ($1, $2, $3, $4)
+			   RETURNING id, created_at`
+	args1 := []any{e.UserID, e.Kind, e.Amount, listingID}
+	err = tx.QueryRowContext(ctx, query1, args1...).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// MarkPayoutFailed records the failure and when the payout is attempted again, it returns how many times the payout failed.
+func (s resaleStorage) MarkPayoutFailed(listingID int64, reason string, retryIn time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE resale_listings
+	          SET payout_error = $2, payout_attempts = payout_attempts + 1, next_payout_at = NOW() + make_interval(secs => $3)
+			  WHERE id = $1
+			  RETURNING payout_attempts`
+	args := []any{listingID, reason, retryIn.Seconds()}
+	var attempts int
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&attempts)
+	return attempts, err
+}
@@ -6,30 +6,74 @@ import (
 )
 
 type Storage struct {
-	Users       UserStorer
-	Tokens      TokenStorer
-	Permissions PermissionStorer
-	Movies      MovieStorer
-	Cinemas     CinemaStorer
-	Halls       HallStorer
-	Seats       SeatStorer
-	Schedules   ScheduleStorer
-	Tickets     TicketStorer
-	Checkouts   CheckoutStorer
+	Users          UserStorer
+	Tokens         TokenStorer
+	Permissions    PermissionStorer
+	Movies         MovieStorer
+	Cinemas        CinemaStorer
+	Settings       CinemaSettingsStorer
+	Halls          HallStorer
+	Seats          SeatStorer
+	Schedules      ScheduleStorer
+	Tickets        TicketStorer
+	Checkouts      CheckoutStorer
+	Transfers      TicketTransferStorer
+	Fees           FeeStorer
+	Orders         OrderStorer
+	PaymentEvents  PaymentEventStorer
+	Discrepancies  PaymentDiscrepancyStorer
+	WaitingRooms   WaitingRoomStorer
+	Waitlists      WaitlistStorer
+	Resales        ResaleStorer
+	GiftCards      GiftCardStorer
+	Wallets        WalletStorer
+	Loyalty        LoyaltyStorer
+	Memberships    MembershipStorer
+	Products       ProductStorer
+	BoxOffice      BoxOfficeStorer
+	CinemaMembers  CinemaMemberStorer
+	Audit          AuditStorer
+	Revocations    TokenRevocationStorer
+	TwoFactor      TwoFactorStorer
+	Identities     IdentityStorer
+	APIKeys        APIKeyStorer
+	LoginThrottles LoginThrottleStorer
 }
 
 func NewStorage(db *sql.DB, queryTimeout time.Duration) *Storage {
 	s := &Storage{
-		Users:       userStorage{db: db, queryTimeout: queryTimeout},
-		Tokens:      tokenStorage{db: db, queryTimeout: queryTimeout},
-		Permissions: permissionStorage{db: db, queryTimeout: queryTimeout},
-		Movies:      movieStorage{db: db, queryTimeout: queryTimeout},
-		Cinemas:     cinemaStorage{db: db, queryTimeout: queryTimeout},
-		Halls:       hallStorage{db: db, queryTimeout: queryTimeout},
-		Seats:       seatStorage{db: db, queryTimeout: queryTimeout},
-		Schedules:   scheduleStorage{db: db, queryTimeout: queryTimeout},
-		Tickets:     ticketStorage{db: db, queryTimeout: queryTimeout},
-		Checkouts:   checkoutStorage{db: db, queryTimeout: queryTimeout},
+		Users:          userStorage{db: db, queryTimeout: queryTimeout},
+		Tokens:         tokenStorage{db: db, queryTimeout: queryTimeout},
+		Permissions:    permissionStorage{db: db, queryTimeout: queryTimeout},
+		Movies:         movieStorage{db: db, queryTimeout: queryTimeout},
+		Cinemas:        cinemaStorage{db: db, queryTimeout: queryTimeout},
+		Settings:       cinemaSettingsStorage{db: db, queryTimeout: queryTimeout},
+		Halls:          hallStorage{db: db, queryTimeout: queryTimeout},
+		Seats:          seatStorage{db: db, queryTimeout: queryTimeout},
+		Schedules:      scheduleStorage{db: db, queryTimeout: queryTimeout},
+		Tickets:        ticketStorage{db: db, queryTimeout: queryTimeout},
+		Checkouts:      checkoutStorage{db: db, queryTimeout: queryTimeout},
+		Transfers:      ticketTransferStorage{db: db, queryTimeout: queryTimeout},
+		Fees:           feeStorage{db: db, queryTimeout: queryTimeout},
+		Orders:         orderStorage{db: db, queryTimeout: queryTimeout},
+		PaymentEvents:  paymentEventStorage{db: db, queryTimeout: queryTimeout},
+		Discrepancies:  paymentDiscrepancyStorage{db: db, queryTimeout: queryTimeout},
+		WaitingRooms:   waitingRoomStorage{db: db, queryTimeout: queryTimeout},
+		Waitlists:      waitlistStorage{db: db, queryTimeout: queryTimeout},
+		Resales:        resaleStorage{db: db, queryTimeout: queryTimeout},
+		GiftCards:      giftCardStorage{db: db, queryTimeout: queryTimeout},
+		Wallets:        walletStorage{db: db, queryTimeout: queryTimeout},
+		Loyalty:        loyaltyStorage{db: db, queryTimeout: queryTimeout},
+		Memberships:    membershipStorage{db: db, queryTimeout: queryTimeout},
+		Products:       productStorage{db: db, queryTimeout: queryTimeout},
+		BoxOffice:      boxOfficeStorage{db: db, queryTimeout: queryTimeout},
+		CinemaMembers:  cinemaMemberStorage{db: db, queryTimeout: queryTimeout},
+		Audit:          auditStorage{db: db, queryTimeout: queryTimeout},
+		Revocations:    tokenRevocationStorage{db: db, queryTimeout: queryTimeout},
+		TwoFactor:      twoFactorStorage{db: db, queryTimeout: queryTimeout},
+		Identities:     identityStorage{db: db, queryTimeout: queryTimeout},
+		APIKeys:        apiKeyStorage{db: db, queryTimeout: queryTimeout},
+		LoginThrottles: loginThrottleStorage{db: db, queryTimeout: queryTimeout},
 	}
 	return s
 }
@@ -0,0 +1,252 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+type TicketTransferStatus int16
+
+const (
+	TicketTransferStatusPending TicketTransferStatus = iota
+	TicketTransferStatusAccepted
+	TicketTransferStatusCancelled
+)
+
+func (s TicketTransferStatus) String() string {
+	switch s {
+	case TicketTransferStatusPending:
+		return "Pending"
+	case TicketTransferStatusAccepted:
+		return "Accepted"
+	case TicketTransferStatusCancelled:
+		return "Cancelled"
+	}
+	return fmt.Sprintf("TicketTransferStatus %d", s)
+}
+
+// TicketPass is the proof of owning a sold ticket, its code is encoded in the QR code
+// presented at the entrance and it changes whenever the ticket changes hands.
+type TicketPass struct {
+	TicketID int64  `json:"ticket_id"`
+	UserID   int64  `json:"user_id"`
+	OrderID  *int64 `json:"-"`
+	Code     string `json:"code"`
+}
+
+type TicketTransfer struct {
+	ID         int64                `json:"id"`
+	CreatedAt  time.Time            `json:"created_at"`
+	TicketID   int64                `json:"ticket_id"`
+	FromUserID int64                `json:"from_user_id"`
+	ToEmail    string               `json:"to_email"`
+	ToUserID   *int64               `json:"to_user_id,omitempty"`
+	StatusID   TicketTransferStatus `json:"status_id"`
+	ExpiresAt  time.Time            `json:"expires_at"`
+	AcceptedAt *time.Time           `json:"accepted_at,omitempty"`
+}
+
+type TicketTransferStorer interface {
+	GetPass(ticketID int64) (*TicketPass, error)
+	Create(ticketID int64, fromUserID int64, toEmail string, token string, duration time.Duration) (*TicketTransfer, error)
+	GetByID(id int64) (*TicketTransfer, error)
+	GetAllForTicket(ticketID int64) ([]TicketTransfer, error)
+	Accept(token string, u *User) (*TicketTransfer, error)
+	Cancel(t *TicketTransfer) error
+}
+
+type ticketTransferStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// GetPass returns the pass of the ticket's current owner or nil if the ticket isn't sold.
+func (s ticketTransferStorage) GetPass(ticketID int64) (*TicketPass, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	p := TicketPass{
+		TicketID: ticketID,
+	}
+	query := `SELECT user_id, order_id, code
+	          FROM transactions
+			  WHERE ticket_id = $1
+			  ORDER BY id DESC
+			  LIMIT 1`
+	args := []any{ticketID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&p.UserID, &p.OrderID, &p.Code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create cancels the pending transfers of the ticket and creates a new one claimable with token.
+func (s ticketTransferStorage) Create(ticketID int64, fromUserID int64, toEmail string, token string, duration time.Duration) (*TicketTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	query0 := `UPDATE ticket_transfers
+	           SET status_id = 2
+			   WHERE ticket_id = $1 AND status_id = 0`
+	args0 := []any{ticketID}
+	_, err = tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	t := TicketTransfer{
+		TicketID:   ticketID,
+		FromUserID: fromUserID,
+		ToEmail:    toEmail,
+		StatusID:   TicketTransferStatusPending,
+		ExpiresAt:  time.Now().Add(duration),
+	}
+	query1 := `INSERT INTO ticket_transfers(ticket_id, from_user_id, to_email, hash, expires_at)
+	           VALUES ($1, $2, $3, $4, $5)
+			   RETURNING id, created_at`
+	args1 := []any{ticketID, fromUserID, toEmail, HashToken(token), t.ExpiresAt}
+	err = tx.QueryRowContext(ctx, query1, args1...).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s ticketTransferStorage) GetByID(id int64) (*TicketTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	t := TicketTransfer{
+		ID: id,
+	}
+	query := `SELECT created_at, ticket_id, from_user_id, to_email, to_user_id, status_id, expires_at, accepted_at
+	          FROM ticket_transfers
+			  WHERE id = $1`
+	args := []any{id}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&t.CreatedAt, &t.TicketID, &t.FromUserID, &t.ToEmail, &t.ToUserID, &t.StatusID, &t.ExpiresAt, &t.AcceptedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s ticketTransferStorage) GetAllForTicket(ticketID int64) ([]TicketTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT id, created_at, ticket_id, from_user_id, to_email, to_user_id, status_id, expires_at, accepted_at
+	          FROM ticket_transfers
+			  WHERE ticket_id = $1
+			  ORDER BY created_at DESC, id DESC`
+	args := []any{ticketID}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var transfers []TicketTransfer
+	for rows.Next() {
+		var t TicketTransfer
+		err := rows.Scan(&t.ID, &t.CreatedAt, &t.TicketID, &t.FromUserID, &t.ToEmail, &t.ToUserID, &t.StatusID, &t.ExpiresAt, &t.AcceptedAt)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// Accept hands the ticket over to the user the transfer was sent to and issues a new pass code,
+// it returns nil if the token is invalid, expired, sent to another email, the ticket is listed for resale
+// or the sender no longer owns the ticket.
+func (s ticketTransferStorage) Accept(token string, u *User) (*TicketTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	var t TicketTransfer
+	query0 := `UPDATE ticket_transfers
+	           SET status_id = 1, to_user_id = $2, accepted_at = NOW()
+			   WHERE hash = $1 AND to_email = $3 AND status_id = 0 AND NOW() < expires_at
+			   AND NOT EXISTS(SELECT 1 FROM resale_listings as l WHERE l.ticket_id = ticket_transfers.ticket_id AND l.status_id = 0)
+			   RETURNING id, created_at, ticket_id, from_user_id, to_email, to_user_id, status_id, expires_at, accepted_at`
+	args0 := []any{HashToken(token), u.ID, u.Email}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&t.ID, &t.CreatedAt, &t.TicketID, &t.FromUserID, &t.ToEmail, &t.ToUserID, &t.StatusID, &t.ExpiresAt, &t.AcceptedAt)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	query1 := `UPDATE transactions
+	           SET user_id = $2, code = gen_random_uuid()
+			   WHERE ticket_id = $1 AND user_id = $3`
+	args1 := []any{t.TicketID, u.ID, t.FromUserID}
+	result, err := tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if n == 0 {
+		tx.Rollback()
+		return nil, nil
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s ticketTransferStorage) Cancel(t *TicketTransfer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE ticket_transfers
+	          SET status_id = 2
+			  WHERE id = $1 AND status_id = 0
+			  RETURNING status_id`
+	args := []any{t.ID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&t.StatusID)
+	return err
+}
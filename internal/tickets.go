@@ -47,12 +47,51 @@ type TicketSeat struct {
 	Seat   Seat   `json:"seat"`
 }
 
+// LockLimits bounds how many tickets a user can hold at the same time,
+// a zero limit means there is no limit.
+type LockLimits struct {
+	MaxPerUser     int
+	MaxPerSchedule int
+	// MaxPerOrder bounds the holds on the schedules that haven't started, they are what the user's next order can have.
+	MaxPerOrder int
+	// users who let their holds expire CooldownExpirations times within CooldownWindow
+	// can't lock tickets for Cooldown after the last expiration.
+	CooldownExpirations int
+	CooldownWindow      time.Duration
+	Cooldown            time.Duration
+}
+
+var (
+	ErrLockLimitPerUser     = errors.New("maximum number of locked tickets reached")
+	ErrLockLimitPerSchedule = errors.New("maximum number of locked tickets for the schedule reached")
+	ErrLockLimitPerOrder    = errors.New("maximum number of tickets per order reached")
+	ErrTicketReserved       = errors.New("ticket is reserved for a waitlisted user")
+)
+
+// LockCooldownError is returned by Lock when the user is in a cooldown.
+type LockCooldownError struct {
+	Until time.Time
+}
+
+func (e *LockCooldownError) Error() string {
+	return fmt.Sprintf("too many expired holds, you can lock tickets again at %v", e.Until.Format(time.RFC3339))
+}
+
+// TicketLock is a hold of a ticket by a user until it expires, a hold can be extended once.
+type TicketLock struct {
+	TicketID  int64     `json:"ticket_id"`
+	UserID    int64     `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Extended  bool      `json:"extended"`
+}
+
 type TicketStorer interface {
 	CreateAll(schedule *Schedule) (int, error)
 	GetByID(id int64) (*Ticket, error)
 	GetAllForSchedule(schedule_id int64) ([]Ticket, error)
 	GetSeatsForSchedule(schedule_id int64) ([]TicketSeat, error)
-	Lock(t *Ticket, u *User) error
+	Lock(t *Ticket, u *User, lockDuration time.Duration, limits LockLimits) (*TicketLock, error)
+	ExtendLock(t *Ticket, u *User, lockDuration time.Duration) (*TicketLock, error)
 	Unlock(t *Ticket, u *User) error
 	Update(t *Ticket) error
 	Delete(t *Ticket) error
@@ -179,7 +218,46 @@ func (s ticketStorage) GetSeatsForSchedule(schedule_id int64) ([]TicketSeat, err
 	return ticketSeats, nil
 }
 
-func (s ticketStorage) Lock(t *Ticket, u *User) error {
+// checkLockLimits returns an error if the user can't hold another ticket of the schedule because of the limits,
+// it's checked in the transaction that locks the ticket.
+func checkLockLimits(ctx context.Context, tx *sql.Tx, userID int64, scheduleID int64, limits LockLimits) error {
+	var userLocks, scheduleLocks, orderLocks, expirations int
+	var lastExpiration *time.Time
+	query := `SELECT
+	              (SELECT count(*) FROM tickets_users WHERE user_id = $1),
+	              (SELECT count(*) FROM tickets_users as tu JOIN tickets as t ON t.id = tu.ticket_id WHERE tu.user_id = $1 AND t.schedule_id = $2),
+	              (SELECT count(*) FROM tickets_users as tu JOIN tickets as t ON t.id = tu.ticket_id JOIN schedules as sc ON sc.id = t.schedule_id WHERE tu.user_id = $1 AND NOW() < sc.starts_at),
+	              count(DISTINCT e.expired_at),
+	              max(e.expired_at)
+	              FROM ticket_lock_expirations as e
+	              WHERE e.user_id = $1 AND e.expired_at > NOW() - make_interval(secs => $3)`
+	args := []any{userID, scheduleID, limits.CooldownWindow.Seconds()}
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&userLocks, &scheduleLocks, &orderLocks, &expirations, &lastExpiration)
+	if err != nil {
+		return err
+	}
+	if limits.CooldownExpirations > 0 && expirations >= limits.CooldownExpirations && lastExpiration != nil {
+		until := lastExpiration.Add(limits.Cooldown)
+		if time.Now().Before(until) {
+			return &LockCooldownError{Until: until}
+		}
+	}
+	if limits.MaxPerUser > 0 && userLocks >= limits.MaxPerUser {
+		return ErrLockLimitPerUser
+	}
+	if limits.MaxPerSchedule > 0 && scheduleLocks >= limits.MaxPerSchedule {
+		return ErrLockLimitPerSchedule
+	}
+	if limits.MaxPerOrder > 0 && orderLocks >= limits.MaxPerOrder {
+		return ErrLockLimitPerOrder
+	}
+	return nil
+}
+
+// Lock holds the ticket for the user, lockDuration is used unless the cinema overrides it.
+// it returns ErrTicketReserved if the ticket is reserved for another user,
+// one of the ErrLockLimit errors or a *LockCooldownError if the user exceeded the limits.
+func (s ticketStorage) Lock(t *Ticket, u *User, lockDuration time.Duration, limits LockLimits) (*TicketLock, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
 	opts := &sql.TxOptions{
@@ -187,9 +265,26 @@ func (s ticketStorage) Lock(t *Ticket, u *User) error {
 	}
 	tx, err := s.db.BeginTx(ctx, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	query0 := `UPDATE tickets AS t
+	var reserved bool
+	query0 := `SELECT EXISTS(SELECT 1 FROM ticket_reservations WHERE ticket_id = $1 AND user_id <> $2 AND NOW() < expires_at)`
+	args0 := []any{t.ID, u.ID}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&reserved)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if reserved {
+		tx.Rollback()
+		return nil, ErrTicketReserved
+	}
+	err = checkLockLimits(ctx, tx, u.ID, t.ScheduleID, limits)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	query1 := `UPDATE tickets AS t
 			   SET state_id = 1, state_changed_at = NOW(), version = t.version + 1
 			   FROM schedules AS sc  
 			   WHERE t.schedule_id = sc.id 
@@ -198,22 +293,82 @@ func (s ticketStorage) Lock(t *Ticket, u *User) error {
 			   AND t.version = $2 
 			   AND state_id = 0
 			   RETURNING state_id, state_changed_at, t.version`
-	args0 := []any{t.ID, t.Version}
-	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&t.StateID, &t.StateChangedAt, &t.Version)
+	args1 := []any{t.ID, t.Version}
+	err = tx.QueryRowContext(ctx, query1, args1...).Scan(&t.StateID, &t.StateChangedAt, &t.Version)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
+	}
+	lock := TicketLock{
+		TicketID: t.ID,
+		UserID:   u.ID,
 	}
-	query1 := `INSERT INTO tickets_users(ticket_id, user_id)
-	           VALUES ($1, $2)`
-	args1 := []any{t.ID, u.ID}
-	_, err = tx.ExecContext(ctx, query1, args1...)
+	query2 := `INSERT INTO tickets_users(ticket_id, user_id, expires_at)
+	           SELECT t.id, $2, NOW() + make_interval(secs => COALESCE(cs.ticket_lock_seconds, $3))
+			   FROM tickets as t
+			   JOIN schedules as sc ON sc.id = t.schedule_id
+			   JOIN halls as h ON h.id = sc.hall_id
+			   LEFT JOIN cinema_settings as cs ON cs.cinema_id = h.cinema_id
+			   WHERE t.id = $1
+			   RETURNING expires_at, extended`
+	args2 := []any{t.ID, u.ID, lockDuration.Seconds()}
+	err = tx.QueryRowContext(ctx, query2, args2...).Scan(&lock.ExpiresAt, &lock.Extended)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
+	}
+	// the waitlisted user took the reserved ticket
+	query3 := `WITH reservation AS (
+	               DELETE FROM ticket_reservations
+			       WHERE ticket_id = $1
+			       RETURNING user_id
+			   )
+			   DELETE FROM waitlist_entries
+			   WHERE schedule_id = $2 AND user_id = $3 AND user_id IN (SELECT user_id FROM reservation)`
+	args3 := []any{t.ID, t.ScheduleID, u.ID}
+	_, err = tx.ExecContext(ctx, query3, args3...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 	err = tx.Commit()
-	return err
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// ExtendLock extends the hold of the ticket by lockDuration unless the cinema overrides it,
+// it returns nil if the hold doesn't exist, expired, was extended before or the user is checking out.
+func (s ticketStorage) ExtendLock(t *Ticket, u *User, lockDuration time.Duration) (*TicketLock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	lock := TicketLock{
+		TicketID: t.ID,
+		UserID:   u.ID,
+	}
+	query := `UPDATE tickets_users as tu
+	          SET expires_at = tu.expires_at + make_interval(secs => COALESCE(cs.ticket_lock_seconds, $3)), extended = true
+			  FROM tickets as t
+			  JOIN schedules as sc ON sc.id = t.schedule_id
+			  JOIN halls as h ON h.id = sc.hall_id
+			  LEFT JOIN cinema_settings as cs ON cs.cinema_id = h.cinema_id
+			  WHERE t.id = tu.ticket_id
+			  AND tu.ticket_id = $1
+			  AND tu.user_id = $2
+			  AND NOT tu.extended
+			  AND NOW() < tu.expires_at
+			  AND NOT EXISTS(SELECT 1 FROM checkout_sessions as c WHERE c.user_id = tu.user_id)
+			  RETURNING tu.expires_at, tu.extended`
+	args := []any{t.ID, u.ID, lockDuration.Seconds()}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&lock.ExpiresAt, &lock.Extended)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lock, nil
 }
 
 func (s ticketStorage) Unlock(t *Ticket, u *User) error {
@@ -290,8 +445,13 @@ func (s ticketStorage) UnlockAllExpired() (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	query0 := `DELETE FROM tickets_users as tu
-			   WHERE NOW() > tu.expires_at AND NOT EXISTS(SELECT 1 FROM checkout_sessions as cs WHERE cs.user_id = tu.user_id)`
+	query0 := `WITH expired AS (
+			       DELETE FROM tickets_users as tu
+			       WHERE NOW() > tu.expires_at AND NOT EXISTS(SELECT 1 FROM checkout_sessions as cs WHERE cs.user_id = tu.user_id)
+			       RETURNING tu.user_id, tu.ticket_id
+			   )
+			   INSERT INTO ticket_lock_expirations(user_id, ticket_id)
+			   SELECT user_id, ticket_id FROM expired`
 
 	result, err := tx.ExecContext(ctx, query0)
 	if err != nil {
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// TokenRevocation revokes the signed access tokens of a user or of a session that were issued before it.
+type TokenRevocation struct {
+	ID        int64     `json:"id"`
+	UserID    *int64    `json:"user_id,omitempty"`
+	SessionID *int64    `json:"session_id,omitempty"`
+	RevokedAt time.Time `json:"revoked_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TokenRevocationStorer interface {
+	Create(userID *int64, sessionID *int64, duration time.Duration) (*TokenRevocation, error)
+	GetAll() ([]TokenRevocation, error)
+	DeleteAllExpired() (int, error)
+}
+
+type tokenRevocationStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// Create revokes the tokens for the duration, it should be at least as long as the access tokens last.
+func (s tokenRevocationStorage) Create(userID *int64, sessionID *int64, duration time.Duration) (*TokenRevocation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	r := TokenRevocation{
+		UserID:    userID,
+		SessionID: sessionID,
+	}
+	query := `INSERT INTO token_revocations(user_id, session_id, expires_at)
+	          VALUES ($1, $2, NOW() + $3 * interval '1 second')
+			  RETURNING id, revoked_at, expires_at`
+	args := []any{userID, sessionID, duration.Seconds()}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&r.ID, &r.RevokedAt, &r.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetAll returns the revocations that didn't expire.
+func (s tokenRevocationStorage) GetAll() ([]TokenRevocation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT id, user_id, session_id, revoked_at, expires_at
+	          FROM token_revocations
+			  WHERE expires_at > NOW()`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var revocations []TokenRevocation
+	for rows.Next() {
+		var r TokenRevocation
+		err := rows.Scan(&r.ID, &r.UserID, &r.SessionID, &r.RevokedAt, &r.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		revocations = append(revocations, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return revocations, nil
+}
+
+func (s tokenRevocationStorage) DeleteAllExpired() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM token_revocations
+	          WHERE NOW() > expires_at`
+	result, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
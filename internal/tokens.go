@@ -8,6 +8,7 @@ import (
 	"encoding/base32"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/lib/pq"
@@ -19,6 +20,8 @@ const (
 	TokenScopeActivation TokenScope = iota
 	TokenScopeAuthentication
 	TokenScopePasswordReset
+	TokenScopeRefresh
+	TokenScopeTwoFactor
 )
 
 func (s TokenScope) String() string {
@@ -29,6 +32,10 @@ func (s TokenScope) String() string {
 		return "Authentication"
 	case TokenScopePasswordReset:
 		return "PasswordReset"
+	case TokenScopeRefresh:
+		return "Refresh"
+	case TokenScopeTwoFactor:
+		return "TwoFactor"
 	}
 	return fmt.Sprintf("TokenScope %d", s)
 }
@@ -45,6 +52,8 @@ func HashToken(token string) []byte {
 	return hash[:]
 }
 
+var ErrRefreshTokenReused = errors.New("refresh token was already used")
+
 type Token struct {
 	ID        int64      `json:"-"`
 	UserID    int64      `json:"user_id"`
@@ -53,9 +62,43 @@ type Token struct {
 	ExpiresAt time.Time  `json:"expires_at"`
 }
 
+// SessionTokens are the tokens of a login, the access token authenticates the requests
+// and the refresh token is exchanged for new tokens once the access token expires.
+type SessionTokens struct {
+	SessionID             int64
+	UserID                int64
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+// Session is a login of a user on a device.
+type Session struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"`
+}
+
+// SessionClient is where a session is used from.
+type SessionClient struct {
+	IP        string
+	UserAgent string
+}
+
 type TokenStorer interface {
 	Create(userID int64, scope TokenScope, token string, duration time.Duration) (*Token, error)
+	CreateSession(userID int64, client SessionClient, accessDuration time.Duration, refreshDuration time.Duration) (*SessionTokens, error)
+	Refresh(refreshToken string, client SessionClient, accessDuration time.Duration, refreshDuration time.Duration) (*SessionTokens, error)
 	GetUser(scope TokenScope, token string) (*User, error)
+	Authenticate(token string) (*User, *Session, error)
+	TouchSession(id int64, client SessionClient) error
+	GetSessions(userID int64) ([]Session, error)
+	DeleteSession(userID int64, id int64) (bool, error)
 	DeleteAll(userID int64, scopes []TokenScope) error
 	DeleteAllExpired() (int, error)
 }
@@ -87,20 +130,171 @@ func (s tokenStorage) Create(userID int64, scope TokenScope, token string, expir
 	return &t, nil
 }
 
+// CreateSession starts a new session for the user with an access token and a refresh token,
+// the other sessions of the user stay so the user can be logged in on many devices.
+func (s tokenStorage) CreateSession(userID int64, client SessionClient, accessDuration time.Duration, refreshDuration time.Duration) (*SessionTokens, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	t := SessionTokens{
+		UserID: userID,
+	}
+
+	query0 := `INSERT INTO sessions(user_id, ip, user_agent)
+	           VALUES ($1, $2, $3)
+			   RETURNING id`
+	args0 := []any{userID, client.IP, client.UserAgent}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&t.SessionID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = s.createSessionTokens(ctx, tx, &t, accessDuration, refreshDuration)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &t, tx.Commit()
+}
+
+// Refresh exchanges the refresh token for new tokens of the same session, the refresh token can only be used once.
+// It returns nil if the token is invalid and ErrRefreshTokenReused after revoking the session if the token was already used,
+// the returned tokens only have the session and the user then.
+func (s tokenStorage) Refresh(refreshToken string, client SessionClient, accessDuration time.Duration, refreshDuration time.Duration) (*SessionTokens, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		id     int64
+		usedAt *time.Time
+		t      SessionTokens
+	)
+	query0 := `SELECT t.id, t.user_id, t.session_id, t.used_at
+	           FROM tokens AS t
+			   INNER JOIN users AS u
+			   ON u.id = t.user_id
+			   WHERE t.scope_id = $1 AND t.hash = $2 AND t.expires_at > NOW() AND u.suspended_at IS NULL
+			   FOR UPDATE OF t`
+	args0 := []any{TokenScopeRefresh, HashToken(refreshToken)}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&id, &t.UserID, &t.SessionID, &usedAt)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if usedAt != nil {
+		query1 := `DELETE FROM sessions
+		           WHERE id = $1`
+		args1 := []any{t.SessionID}
+		_, err = tx.ExecContext(ctx, query1, args1...)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+		return &t, ErrRefreshTokenReused
+	}
+
+	query2 := `UPDATE tokens
+	           SET used_at = NOW()
+			   WHERE id = $1`
+	args2 := []any{id}
+	_, err = tx.ExecContext(ctx, query2, args2...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// the session only has one access token at a time
+	query3 := `DELETE FROM tokens
+	           WHERE session_id = $1 AND scope_id = $2`
+	args3 := []any{t.SessionID, TokenScopeAuthentication}
+	_, err = tx.ExecContext(ctx, query3, args3...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	query4 := `UPDATE sessions
+	           SET last_used_at = NOW(), ip = $1, user_agent = $2
+			   WHERE id = $3`
+	args4 := []any{client.IP, client.UserAgent, t.SessionID}
+	_, err = tx.ExecContext(ctx, query4, args4...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = s.createSessionTokens(ctx, tx, &t, accessDuration, refreshDuration)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &t, tx.Commit()
+}
+
+// createSessionTokens creates the refresh token of the session and its access token,
+// a zero access duration skips the access token for when the access tokens are signed instead of stored.
+func (s tokenStorage) createSessionTokens(ctx context.Context, tx *sql.Tx, t *SessionTokens, accessDuration time.Duration, refreshDuration time.Duration) error {
+	t.RefreshToken = GenerateToken()
+	t.RefreshTokenExpiresAt = time.Now().Add(refreshDuration)
+
+	query0 := `INSERT INTO tokens(user_id, scope_id, hash, expires_at, session_id)
+	           VALUES ($1, $2, $3, $4, $5)`
+	args0 := []any{t.UserID, TokenScopeRefresh, HashToken(t.RefreshToken), t.RefreshTokenExpiresAt, t.SessionID}
+	_, err := tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		return err
+	}
+
+	if accessDuration == 0 {
+		return nil
+	}
+
+	t.AccessToken = GenerateToken()
+	t.AccessTokenExpiresAt = time.Now().Add(accessDuration)
+
+	query1 := `INSERT INTO tokens(user_id, scope_id, hash, expires_at, session_id)
+	           VALUES ($1, $2, $3, $4, $5)`
+	args1 := []any{t.UserID, TokenScopeAuthentication, HashToken(t.AccessToken), t.AccessTokenExpiresAt, t.SessionID}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	return err
+}
+
 func (s tokenStorage) GetUser(scope TokenScope, token string) (*User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
 
 	var u User
 
-	query := `SELECT u.id, u.created_at, u.name, u.email, u.password_hash, u.is_activated, u.version
+	// suspended users don't get in even with a token that was created before the suspension
+	query := `SELECT u.id, u.created_at, u.name, u.email, u.password_hash, u.is_activated, u.version, u.password_reset_required
 	          FROM tokens as t
 			  INNER JOIN users as u
 			  ON t.user_id = u.id
-			  WHERE t.scope_id = $1 AND t.hash = $2 AND expires_at > NOW()`
+			  WHERE t.scope_id = $1 AND t.hash = $2 AND expires_at > NOW() AND u.suspended_at IS NULL`
 
 	args := []any{scope, HashToken(token)}
-	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.ID, &u.CreatedAt, &u.Name, &u.Email, &u.PasswordHash, &u.IsActivated, &u.Version)
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.ID, &u.CreatedAt, &u.Name, &u.Email, &u.PasswordHash, &u.IsActivated, &u.Version, &u.PasswordResetRequired)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -110,18 +304,159 @@ func (s tokenStorage) GetUser(scope TokenScope, token string) (*User, error) {
 	return &u, nil
 }
 
-func (s tokenStorage) DeleteAll(userID int64, scopes []TokenScope) error {
+// Authenticate returns the user of the access token and the token's session,
+// the session is nil for the tokens created before the sessions.
+func (s tokenStorage) Authenticate(token string) (*User, *Session, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
 
-	query := `DELETE FROM tokens
-	          WHERE user_id = $1 AND scope_id = ANY($2)`
+	var (
+		u         User
+		sessionID *int64
+		createdAt *time.Time
+		usedAt    *time.Time
+		ip        *string
+		userAgent *string
+	)
+
+	query := `SELECT u.id, u.created_at, u.name, u.email, u.password_hash, u.is_activated, u.version, u.password_reset_required,
+	                 s.id, s.created_at, s.last_used_at, s.ip, s.user_agent
+	          FROM tokens AS t
+			  INNER JOIN users AS u
+			  ON t.user_id = u.id
+			  LEFT JOIN sessions AS s
+			  ON t.session_id = s.id
+			  WHERE t.scope_id = $1 AND t.hash = $2 AND t.expires_at > NOW() AND u.suspended_at IS NULL`
+
+	args := []any{TokenScopeAuthentication, HashToken(token)}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.ID, &u.CreatedAt, &u.Name, &u.Email, &u.PasswordHash, &u.IsActivated, &u.Version, &u.PasswordResetRequired,
+		&sessionID, &createdAt, &usedAt, &ip, &userAgent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	if sessionID == nil {
+		return &u, nil, nil
+	}
+	session := Session{
+		ID:         *sessionID,
+		UserID:     u.ID,
+		CreatedAt:  *createdAt,
+		LastUsedAt: *usedAt,
+		IP:         *ip,
+		UserAgent:  *userAgent,
+	}
+	return &u, &session, nil
+}
 
-	args := []any{userID, pq.Array(scopes)}
+// TouchSession records that the session was just used from the client.
+func (s tokenStorage) TouchSession(id int64, client SessionClient) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `UPDATE sessions
+	          SET last_used_at = NOW(), ip = $1, user_agent = $2
+			  WHERE id = $3`
+	args := []any{client.IP, client.UserAgent, id}
 	_, err := s.db.ExecContext(ctx, query, args...)
 	return err
 }
 
+// GetSessions returns the sessions of the user that can still be refreshed, the latest used first.
+func (s tokenStorage) GetSessions(userID int64) ([]Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `SELECT s.id, s.created_at, s.last_used_at, s.ip, s.user_agent
+	          FROM sessions AS s
+			  WHERE s.user_id = $1 AND EXISTS (
+			      SELECT 1 FROM tokens AS t
+				  WHERE t.session_id = s.id AND t.scope_id = $2 AND t.used_at IS NULL AND t.expires_at > NOW()
+			  )
+			  ORDER BY s.last_used_at DESC, s.id DESC`
+	args := []any{userID, TokenScopeRefresh}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var sessions []Session
+	for rows.Next() {
+		session := Session{
+			UserID: userID,
+		}
+		err := rows.Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt, &session.IP, &session.UserAgent)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteSession revokes the session with its tokens and reports whether the user had it.
+func (s tokenStorage) DeleteSession(userID int64, id int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `DELETE FROM sessions
+	          WHERE id = $1 AND user_id = $2`
+	args := []any{id, userID}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// DeleteAll deletes the user's tokens of the scopes, the sessions left without tokens end with them.
+func (s tokenStorage) DeleteAll(userID int64, scopes []TokenScope) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query0 := `DELETE FROM tokens
+	           WHERE user_id = $1 AND scope_id = ANY($2)`
+	args0 := []any{userID, pq.Array(scopes)}
+	_, err = tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query1 := `DELETE FROM sessions AS s
+	           WHERE s.user_id = $1 AND NOT EXISTS (SELECT 1 FROM tokens AS t WHERE t.session_id = s.id)`
+	args1 := []any{userID}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (s tokenStorage) DeleteAllExpired() (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
@@ -137,5 +472,13 @@ func (s tokenStorage) DeleteAllExpired() (int, error) {
 	if err != nil {
 		return 0, err
 	}
+
+	// the sessions end once their refresh tokens expire
+	query = `DELETE FROM sessions AS s
+	         WHERE NOT EXISTS (SELECT 1 FROM tokens AS t WHERE t.session_id = s.id)`
+	_, err = s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
 	return int(n), nil
 }
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// the TOTP parameters of RFC 6238 that the authenticator apps support.
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is how many periods before and after the current one are accepted for the clock drift.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateTOTPSecret() []byte {
+	secret := make([]byte, 20)
+	_, _ = rand.Read(secret)
+	return secret
+}
+
+// EncodeTOTPSecret returns the secret as the authenticator apps expect it to be typed.
+func EncodeTOTPSecret(secret []byte) string {
+	return totpEncoding.EncodeToString(secret)
+}
+
+// TOTPProvisioningURI returns the otpauth URI the authenticator apps scan as a QR code.
+func TOTPProvisioningURI(issuer string, account string, secret []byte) string {
+	v := url.Values{}
+	v.Set("secret", EncodeTOTPSecret(secret))
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	// some authenticators show the + of the query encoding instead of a space
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(v.Encode(), "+", "%20")
+}
+
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	n := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, n%1_000_000)
+}
+
+// ValidateTOTP returns the time step the code belongs to, the step is used so the code can't be used twice.
+func ValidateTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns n one-time codes like "abcd-efgh" for when the authenticator is lost.
+func GenerateRecoveryCodes(n int) []string {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 5)
+		_, _ = rand.Read(b)
+		code := strings.ToLower(totpEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+	}
+	return codes
+}
+
+// NormalizeRecoveryCode makes the code match however it was typed.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) == 8 {
+		code = code[:4] + "-" + code[4:]
+	}
+	return code
+}
@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 secret of the RFC 6238 test vectors, the codes are their last 6 digits.
+var rfc6238Secret = []byte("12345678901234567890")
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(rfc6238Secret, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		unix     int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current period", "287082", 59, 1, true},
+		{"surrounding spaces", " 287082 ", 59, 1, true},
+		{"previous period", "287082", 89, 1, true},
+		{"next period", "287082", 29, 1, true},
+		{"too old", "287082", 119, 0, false},
+		{"too early", "081804", 1111111109 - 2*totpPeriod, 0, false},
+		{"wrong code", "287083", 59, 0, false},
+		{"short code", "28708", 59, 0, false},
+		{"long code", "2870820", 59, 0, false},
+		{"empty code", "", 59, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Movie Reservation", "user@example.com", rfc6238Secret)
+	if strings.Contains(uri, "+") {
+		t.Errorf("uri %q has a +, want %%20", uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("uri = %q, want otpauth://totp/", uri)
+	}
+	if u.Path != "/Movie Reservation:user@example.com" {
+		t.Errorf("label = %q, want %q", u.Path, "/Movie Reservation:user@example.com")
+	}
+	q := u.Query()
+	want := map[string]string{
+		"secret":    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"issuer":    "Movie Reservation",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s = %q, want %q", k, q.Get(k), v)
+		}
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	a, b := GenerateTOTPSecret(), GenerateTOTPSecret()
+	if len(a) != 20 {
+		t.Errorf("got a %d bytes secret, want 20", len(a))
+	}
+	if string(a) == string(b) {
+		t.Error("got the same secret twice")
+	}
+	if got := EncodeTOTPSecret(a); strings.Contains(got, "=") {
+		t.Errorf("encoded secret %q is padded", got)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes := GenerateRecoveryCodes(10)
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 9 || code[4] != '-' {
+			t.Errorf("code %q isn't like abcd-efgh", code)
+		}
+		if code != strings.ToLower(code) {
+			t.Errorf("code %q isn't lower case", code)
+		}
+		if NormalizeRecoveryCode(code) != code {
+			t.Errorf("code %q changes when normalized", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q is repeated", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"abcd-efgh", "abcd-efgh"},
+		{"ABCD-EFGH", "abcd-efgh"},
+		{"abcdefgh", "abcd-efgh"},
+		{"abcd efgh", "abcd-efgh"},
+		{"  AbCd EfGh  ", "abcd-efgh"},
+		{"abc", "abc"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TwoFactor is the TOTP enrollment of a user, it's only used to sign in once it's confirmed with a code.
+type TwoFactor struct {
+	UserID       int64
+	Secret       []byte
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+type TwoFactorStorer interface {
+	Get(userID int64) (*TwoFactor, error)
+	Enroll(userID int64, secret []byte) (*TwoFactor, error)
+	Confirm(userID int64, step int64, recoveryCodes []string) (bool, error)
+	UseStep(userID int64, step int64) (bool, error)
+	UseRecoveryCode(userID int64, code string) (bool, error)
+	SetRecoveryCodes(userID int64, recoveryCodes []string) error
+	CountRecoveryCodes(userID int64) (int, error)
+	Delete(userID int64) error
+}
+
+type twoFactorStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s twoFactorStorage) Get(userID int64) (*TwoFactor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	tf := TwoFactor{
+		UserID: userID,
+	}
+	query := `SELECT secret, confirmed_at, last_used_step, created_at
+	          FROM two_factors
+			  WHERE user_id = $1`
+	args := []any{userID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&tf.Secret, &tf.ConfirmedAt, &tf.LastUsedStep, &tf.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// Enroll starts an enrollment with the secret replacing an unconfirmed one,
+// it returns nil if the user already has a confirmed enrollment.
+func (s twoFactorStorage) Enroll(userID int64, secret []byte) (*TwoFactor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	tf := TwoFactor{
+		UserID: userID,
+		Secret: secret,
+	}
+	query := `INSERT INTO two_factors(user_id, secret)
+	          VALUES ($1, $2)
+			  ON CONFLICT (user_id) DO UPDATE
+			  SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+			  WHERE two_factors.confirmed_at IS NULL
+			  RETURNING created_at`
+	args := []any{userID, secret}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&tf.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// Confirm confirms the enrollment with the step of the code it was confirmed with and replaces the recovery codes,
+// it reports whether the enrollment was still unconfirmed.
+func (s twoFactorStorage) Confirm(userID int64, step int64, recoveryCodes []string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	query0 := `UPDATE two_factors
+	           SET confirmed_at = NOW(), last_used_step = $2
+			   WHERE user_id = $1 AND confirmed_at IS NULL`
+	args0 := []any{userID, step}
+	result, err := tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if n == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	err = s.setRecoveryCodes(ctx, tx, userID, recoveryCodes)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// UseStep records that a code of the step was used and reports whether it wasn't used before.
+func (s twoFactorStorage) UseStep(userID int64, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE two_factors
+	          SET last_used_step = $2
+			  WHERE user_id = $1 AND last_used_step < $2`
+	args := []any{userID, step}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// UseRecoveryCode uses up the recovery code and reports whether the user had it.
+func (s twoFactorStorage) UseRecoveryCode(userID int64, code string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `UPDATE recovery_codes
+	          SET used_at = NOW()
+			  WHERE user_id = $1 AND hash = $2 AND used_at IS NULL`
+	args := []any{userID, HashToken(NormalizeRecoveryCode(code))}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s twoFactorStorage) SetRecoveryCodes(userID int64, recoveryCodes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = s.setRecoveryCodes(ctx, tx, userID, recoveryCodes)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s twoFactorStorage) setRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64, recoveryCodes []string) error {
+	query0 := `DELETE FROM recovery_codes
+	           WHERE user_id = $1`
+	args0 := []any{userID}
+	_, err := tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		return err
+	}
+
+	hashes := make([][]byte, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashes[i] = HashToken(NormalizeRecoveryCode(code))
+	}
+	query1 := `INSERT INTO recovery_codes(user_id, hash)
+	           SELECT $1, UNNEST($2::bytea[])`
+	args1 := []any{userID, pq.Array(hashes)}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	return err
+}
+
+// CountRecoveryCodes returns how many recovery codes the user has left.
+func (s twoFactorStorage) CountRecoveryCodes(userID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT count(*)
+	          FROM recovery_codes
+			  WHERE user_id = $1 AND used_at IS NULL`
+	args := []any{userID}
+	var n int
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+// Delete turns the two-factor authentication off with the recovery codes.
+func (s twoFactorStorage) Delete(userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query0 := `DELETE FROM recovery_codes
+	           WHERE user_id = $1`
+	args0 := []any{userID}
+	_, err = tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query1 := `DELETE FROM two_factors
+	           WHERE user_id = $1`
+	args1 := []any{userID}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
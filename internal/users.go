@@ -4,7 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"log"
+	"math"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // User represents a user in the system
@@ -16,13 +20,20 @@ type User struct {
 	PasswordHash []byte    `json:"-"`            // PasswordHash
 	IsActivated  bool      `json:"is_activated"` // IsActivated
 	Version      int32     `json:"-"`            // Version
+
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`      // SuspendedAt
+	SuspensionReason      string     `json:"suspension_reason,omitempty"` // SuspensionReason
+	PasswordResetRequired bool       `json:"password_reset_required"`     // PasswordResetRequired
 }
 
 type UserStorer interface {
 	Create(name string, email string, passswordHash []byte) (*User, error)
 	GetByID(id int64) (*User, error)
 	GetByEmail(email string) (*User, error)
+	GetAll(email string, page int, pageSize int) ([]User, *MetaData, error)
 	Update(*User) error
+	Suspend(u *User, reason string) error
+	Unsuspend(u *User) error
 	Delete(*User) error
 }
 
@@ -59,11 +70,11 @@ func (s userStorage) GetByID(id int64) (*User, error) {
 	var u User
 	u.ID = id
 
-	query := `SELECT created_at, name, email, password_hash, is_activated, version
+	query := `SELECT created_at, name, email, password_hash, is_activated, version, suspended_at, suspension_reason, password_reset_required
 	          FROM users
 			  WHERE id = $1`
 	args := []any{id}
-	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.CreatedAt, &u.Name, &u.Email, &u.PasswordHash, &u.IsActivated, &u.Version)
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.CreatedAt, &u.Name, &u.Email, &u.PasswordHash, &u.IsActivated, &u.Version, &u.SuspendedAt, &u.SuspensionReason, &u.PasswordResetRequired)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -81,11 +92,11 @@ func (s userStorage) GetByEmail(email string) (*User, error) {
 		Email: email,
 	}
 
-	query := `SELECT id, created_at, name, password_hash, is_activated, version
+	query := `SELECT id, created_at, name, password_hash, is_activated, version, suspended_at, suspension_reason, password_reset_required
 	          FROM users
 			  WHERE email = $1`
 	args := []any{email}
-	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.ID, &u.CreatedAt, &u.Name, &u.PasswordHash, &u.IsActivated, &u.Version)
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.ID, &u.CreatedAt, &u.Name, &u.PasswordHash, &u.IsActivated, &u.Version, &u.SuspendedAt, &u.SuspensionReason, &u.PasswordResetRequired)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -95,15 +106,67 @@ func (s userStorage) GetByEmail(email string) (*User, error) {
 	return &u, err
 }
 
+// GetAll returns the users whose email contains the given email, all the users if it's empty.
+func (s userStorage) GetAll(email string, page int, pageSize int) ([]User, *MetaData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT count(*) OVER(), id, created_at, name, email, is_activated, version, suspended_at, suspension_reason, password_reset_required
+	          FROM users
+			  WHERE ($1::text = '' OR strpos(lower(email), lower($1::text)) > 0)
+			  ORDER BY id ASC
+			  LIMIT $2 OFFSET $3`
+	limit := pageSize
+	offset := (page - 1) * pageSize
+	args := []any{email, limit, offset}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	totalRecords := 0
+	var users []User
+	for rows.Next() {
+		var u User
+		err := rows.Scan(&totalRecords, &u.ID, &u.CreatedAt, &u.Name, &u.Email, &u.IsActivated, &u.Version, &u.SuspendedAt, &u.SuspensionReason, &u.PasswordResetRequired)
+		if err != nil {
+			return nil, nil, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metaData := &MetaData{}
+	if totalRecords != 0 {
+		metaData = &MetaData{
+			CurrentPage:  page,
+			PageSize:     pageSize,
+			FirstPage:    1,
+			LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+			TotalRecords: totalRecords,
+		}
+	}
+	return users, metaData, nil
+}
+
 func (s userStorage) Update(u *User) error {
 	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
 	defer cancel()
 
 	query := `UPDATE users
-	          SET name = $1, email = $2, password_hash = $3, is_activated = $4, version = version + 1
-			  WHERE id = $5 AND version = $6
+	          SET name = $1, email = $2, password_hash = $3, is_activated = $4, password_reset_required = $5, version = version + 1
+			  WHERE id = $6 AND version = $7
 			  RETURNING version`
-	args := []any{u.Name, u.Email, u.PasswordHash, u.IsActivated, u.ID, u.Version}
+	args := []any{u.Name, u.Email, u.PasswordHash, u.IsActivated, u.PasswordResetRequired, u.ID, u.Version}
 	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.Version)
 	return err
 
@@ -119,3 +182,65 @@ func (s userStorage) Delete(u *User) error {
 	_, err := s.db.ExecContext(ctx, query, args...)
 	return err
 }
+
+// Suspend suspends the user and deletes all of the user's tokens so the user is signed out right away.
+func (s userStorage) Suspend(u *User, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query0 := `UPDATE users
+	           SET suspended_at = NOW(), suspension_reason = $1, version = version + 1
+			   WHERE id = $2 AND version = $3
+			   RETURNING suspended_at, version`
+	args0 := []any{reason, u.ID, u.Version}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&u.SuspendedAt, &u.Version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	u.SuspensionReason = reason
+
+	query1 := `DELETE FROM sessions
+	           WHERE user_id = $1`
+	args1 := []any{u.ID}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// the activation and password reset tokens are kept so the user can still use them once unsuspended
+	query2 := `DELETE FROM tokens
+	           WHERE user_id = $1 AND scope_id = ANY($2)`
+	args2 := []any{u.ID, pq.Array([]TokenScope{TokenScopeAuthentication, TokenScopeRefresh})}
+	_, err = tx.ExecContext(ctx, query2, args2...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s userStorage) Unsuspend(u *User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+
+	query := `UPDATE users
+	          SET suspended_at = NULL, suspension_reason = '', version = version + 1
+			  WHERE id = $1 AND version = $2
+			  RETURNING version`
+	args := []any{u.ID, u.Version}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&u.Version)
+	if err != nil {
+		return err
+	}
+	u.SuspendedAt = nil
+	u.SuspensionReason = ""
+	return nil
+}
@@ -0,0 +1,238 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// WaitingRoom queues the users of a high demand schedule, at most BatchSize users
+// are admitted to lock tickets at the same time for AdmissionSeconds each.
+type WaitingRoom struct {
+	ScheduleID       int64 `json:"schedule_id"`
+	BatchSize        int32 `json:"batch_size"`
+	AdmissionSeconds int32 `json:"admission_seconds"`
+	Version          int32 `json:"version"`
+}
+
+type WaitingRoomEntry struct {
+	ID         int64      `json:"-"`
+	ScheduleID int64      `json:"schedule_id"`
+	UserID     int64      `json:"user_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AdmittedAt *time.Time `json:"admitted_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// number of users ahead in the queue plus one, it's zero once admitted
+	Position int64 `json:"position"`
+}
+
+func (e *WaitingRoomEntry) IsAdmitted() bool {
+	return e.AdmittedAt != nil && e.ExpiresAt != nil && time.Now().Before(*e.ExpiresAt)
+}
+
+func (e *WaitingRoomEntry) IsExpired() bool {
+	return e.ExpiresAt != nil && !time.Now().Before(*e.ExpiresAt)
+}
+
+type WaitingRoomStorer interface {
+	Set(room *WaitingRoom) error
+	Get(scheduleID int64) (*WaitingRoom, error)
+	GetForTicket(ticketID int64) (*WaitingRoom, error)
+	Delete(scheduleID int64) error
+	Join(scheduleID int64, userID int64, token string) (*WaitingRoomEntry, error)
+	GetEntry(scheduleID int64, userID int64) (*WaitingRoomEntry, error)
+	IsAdmitted(scheduleID int64, userID int64, token string) (bool, error)
+	AdmitAll() (int64, error)
+}
+
+type waitingRoomStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// Set opens a waiting room for the schedule or updates it.
+func (s waitingRoomStorage) Set(room *WaitingRoom) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `INSERT INTO waiting_rooms AS r (schedule_id, batch_size, admission_seconds)
+	          VALUES ($1, $2, $3)
+			  ON CONFLICT (schedule_id) DO UPDATE
+			  SET batch_size = $2, admission_seconds = $3, version = r.version + 1
+			  RETURNING version`
+	args := []any{room.ScheduleID, room.BatchSize, room.AdmissionSeconds}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&room.Version)
+	return err
+}
+
+func (s waitingRoomStorage) Get(scheduleID int64) (*WaitingRoom, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	room := WaitingRoom{
+		ScheduleID: scheduleID,
+	}
+	query := `SELECT batch_size, admission_seconds, version
+	          FROM waiting_rooms
+			  WHERE schedule_id = $1`
+	args := []any{scheduleID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&room.BatchSize, &room.AdmissionSeconds, &room.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &room, nil
+}
+
+// GetForTicket returns the waiting room of the ticket's schedule or nil if it doesn't have one.
+func (s waitingRoomStorage) GetForTicket(ticketID int64) (*WaitingRoom, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	var room WaitingRoom
+	query := `SELECT r.schedule_id, r.batch_size, r.admission_seconds, r.version
+	          FROM waiting_rooms as r
+			  JOIN tickets as t
+			  ON t.schedule_id = r.schedule_id
+			  WHERE t.id = $1`
+	args := []any{ticketID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&room.ScheduleID, &room.BatchSize, &room.AdmissionSeconds, &room.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &room, nil
+}
+
+func (s waitingRoomStorage) Delete(scheduleID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM waiting_rooms
+	          WHERE schedule_id = $1`
+	args := []any{scheduleID}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Join queues the user in the waiting room, a user who already joined keeps their place
+// and the token replaces the previous one, a user whose admission expired goes to the back of the queue.
+func (s waitingRoomStorage) Join(scheduleID int64, userID int64, token string) (*WaitingRoomEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	query0 := `DELETE FROM waiting_room_entries
+	           WHERE schedule_id = $1 AND user_id = $2 AND expires_at <= NOW()`
+	args0 := []any{scheduleID, userID}
+	_, err = tx.ExecContext(ctx, query0, args0...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	query1 := `INSERT INTO waiting_room_entries(schedule_id, user_id, hash)
+	           VALUES ($1, $2, $3)
+			   ON CONFLICT (schedule_id, user_id) DO UPDATE
+			   SET hash = EXCLUDED.hash`
+	args1 := []any{scheduleID, userID, HashToken(token)}
+	_, err = tx.ExecContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetEntry(scheduleID, userID)
+}
+
+func (s waitingRoomStorage) GetEntry(scheduleID int64, userID int64) (*WaitingRoomEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	e := WaitingRoomEntry{
+		ScheduleID: scheduleID,
+		UserID:     userID,
+	}
+	query := `SELECT e.id, e.created_at, e.admitted_at, e.expires_at,
+	          CASE WHEN e.admitted_at IS NULL
+			  THEN (SELECT count(*) + 1 FROM waiting_room_entries as a WHERE a.schedule_id = e.schedule_id AND a.admitted_at IS NULL AND a.id < e.id)
+			  ELSE 0 END
+	          FROM waiting_room_entries as e
+			  WHERE e.schedule_id = $1 AND e.user_id = $2`
+	args := []any{scheduleID, userID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&e.ID, &e.CreatedAt, &e.AdmittedAt, &e.ExpiresAt, &e.Position)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+// IsAdmitted reports whether the token admits the user to lock the tickets of the schedule.
+func (s waitingRoomStorage) IsAdmitted(scheduleID int64, userID int64, token string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT EXISTS(
+	              SELECT 1 FROM waiting_room_entries
+			      WHERE schedule_id = $1 AND user_id = $2 AND hash = $3 AND admitted_at IS NOT NULL AND NOW() < expires_at
+			  )`
+	args := []any{scheduleID, userID, HashToken(token)}
+	var admitted bool
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&admitted)
+	return admitted, err
+}
+
+// AdmitAll admits the users at the front of every waiting room until it has batch size admitted users,
+// it removes the expired admissions and it returns the number of admitted users.
+func (s waitingRoomStorage) AdmitAll() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+	query0 := `DELETE FROM waiting_room_entries
+	           WHERE expires_at <= NOW()`
+	_, err = tx.ExecContext(ctx, query0)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	query1 := `WITH queue AS (
+	               SELECT e.id, r.admission_seconds,
+			       row_number() OVER (PARTITION BY e.schedule_id ORDER BY e.id) as n,
+			       r.batch_size - (SELECT count(*) FROM waiting_room_entries as a WHERE a.schedule_id = e.schedule_id AND a.admitted_at IS NOT NULL) as free
+			       FROM waiting_room_entries as e
+			       JOIN waiting_rooms as r
+			       ON r.schedule_id = e.schedule_id
+			       WHERE e.admitted_at IS NULL
+			   )
+			   UPDATE waiting_room_entries as e
+			   SET admitted_at = NOW(), expires_at = NOW() + make_interval(secs => q.admission_seconds)
+			   FROM queue as q
+			   WHERE e.id = q.id AND q.n <= q.free`
+	result, err := tx.ExecContext(ctx, query1)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	err = tx.Commit()
+	return n, err
+}
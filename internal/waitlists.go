@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+type WaitlistEntry struct {
+	ID         int64      `json:"-"`
+	ScheduleID int64      `json:"schedule_id"`
+	UserID     int64      `json:"user_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+	// number of users ahead in the waitlist plus one, it's zero once notified
+	Position int64 `json:"position"`
+}
+
+// WaitlistOffer is a freed ticket reserved for a waitlisted user until it expires.
+type WaitlistOffer struct {
+	ScheduleID int64     `json:"schedule_id"`
+	TicketID   int64     `json:"ticket_id"`
+	UserID     int64     `json:"user_id"`
+	UserName   string    `json:"-"`
+	UserEmail  string    `json:"-"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type WaitlistStorer interface {
+	Join(scheduleID int64, userID int64) (*WaitlistEntry, error)
+	GetEntry(scheduleID int64, userID int64) (*WaitlistEntry, error)
+	Leave(scheduleID int64, userID int64) error
+	CountAvailable(scheduleID int64) (int64, error)
+	IsReservedFor(ticketID int64, userID int64) (bool, error)
+	OfferAll(window time.Duration) ([]WaitlistOffer, error)
+}
+
+type waitlistStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+// Join adds the user to the waitlist of the schedule, a user who already joined keeps their place.
+func (s waitlistStorage) Join(scheduleID int64, userID int64) (*WaitlistEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `INSERT INTO waitlist_entries(schedule_id, user_id)
+	          VALUES ($1, $2)
+			  ON CONFLICT DO NOTHING`
+	args := []any{scheduleID, userID}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetEntry(scheduleID, userID)
+}
+
+func (s waitlistStorage) GetEntry(scheduleID int64, userID int64) (*WaitlistEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	e := WaitlistEntry{
+		ScheduleID: scheduleID,
+		UserID:     userID,
+	}
+	query := `SELECT e.id, e.created_at, e.notified_at,
+	          CASE WHEN e.notified_at IS NULL
+			  THEN (SELECT count(*) + 1 FROM waitlist_entries as a WHERE a.schedule_id = e.schedule_id AND a.notified_at IS NULL AND a.id < e.id)
+			  ELSE 0 END
+	          FROM waitlist_entries as e
+			  WHERE e.schedule_id = $1 AND e.user_id = $2`
+	args := []any{scheduleID, userID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&e.ID, &e.CreatedAt, &e.NotifiedAt, &e.Position)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s waitlistStorage) Leave(scheduleID int64, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `DELETE FROM waitlist_entries
+	          WHERE schedule_id = $1 AND user_id = $2`
+	args := []any{scheduleID, userID}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// CountAvailable returns the number of tickets of the schedule that are neither locked, sold nor reserved.
+func (s waitlistStorage) CountAvailable(scheduleID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT count(*)
+	          FROM tickets as t
+			  WHERE t.schedule_id = $1 AND t.state_id = 0
+			  AND NOT EXISTS(SELECT 1 FROM ticket_reservations as r WHERE r.ticket_id = t.id AND NOW() < r.expires_at)`
+	args := []any{scheduleID}
+	var n int64
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+func (s waitlistStorage) IsReservedFor(ticketID int64, userID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT EXISTS(
+	              SELECT 1 FROM ticket_reservations
+			      WHERE ticket_id = $1 AND user_id = $2 AND NOW() < expires_at
+			  )`
+	args := []any{ticketID, userID}
+	var reserved bool
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&reserved)
+	return reserved, err
+}
+
+// OfferAll reserves the available tickets of the upcoming schedules for the waitlisted users in order,
+// one ticket per user for window. expired reservations are dropped with their waitlist entries
+// so the ticket goes to the next user or back to general sale.
+func (s waitlistStorage) OfferAll(window time.Duration) ([]WaitlistOffer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	query0 := `WITH expired AS (
+	               DELETE FROM ticket_reservations
+			       WHERE expires_at <= NOW()
+			       RETURNING ticket_id, user_id
+			   )
+			   DELETE FROM waitlist_entries as w
+			   USING expired as e
+			   JOIN tickets as t
+			   ON t.id = e.ticket_id
+			   WHERE w.schedule_id = t.schedule_id AND w.user_id = e.user_id`
+	_, err = tx.ExecContext(ctx, query0)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	query1 := `WITH available AS (
+	               SELECT t.id, t.schedule_id, row_number() OVER (PARTITION BY t.schedule_id ORDER BY t.id) as n
+			       FROM tickets as t
+			       JOIN schedules as sc
+			       ON sc.id = t.schedule_id
+			       WHERE t.state_id = 0 AND NOW() < sc.starts_at
+			       AND NOT EXISTS(SELECT 1 FROM ticket_reservations as r WHERE r.ticket_id = t.id)
+			   ),
+			   waiting AS (
+			       SELECT w.schedule_id, w.user_id, row_number() OVER (PARTITION BY w.schedule_id ORDER BY w.id) as n
+			       FROM waitlist_entries as w
+			       WHERE w.notified_at IS NULL
+			   ),
+			   offers AS (
+			       INSERT INTO ticket_reservations(ticket_id, user_id, expires_at)
+			       SELECT a.id, w.user_id, NOW() + make_interval(secs => $1)
+			       FROM available as a
+			       JOIN waiting as w
+			       ON w.schedule_id = a.schedule_id AND w.n = a.n
+			       RETURNING ticket_id, user_id, expires_at
+			   )
+			   UPDATE waitlist_entries as w
+			   SET notified_at = NOW()
+			   FROM offers as o
+			   JOIN tickets as t
+			   ON t.id = o.ticket_id
+			   JOIN users as u
+			   ON u.id = o.user_id
+			   WHERE w.schedule_id = t.schedule_id AND w.user_id = o.user_id
+			   RETURNING w.schedule_id, o.ticket_id, o.user_id, u.name, u.email, o.expires_at`
+	args1 := []any{window.Seconds()}
+	rows, err := tx.QueryContext(ctx, query1, args1...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+	var offers []WaitlistOffer
+	for rows.Next() {
+		var o WaitlistOffer
+		err := rows.Scan(&o.ScheduleID, &o.TicketID, &o.UserID, &o.UserName, &o.UserEmail, &o.ExpiresAt)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		offers = append(offers, o)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type WalletEntryKind int16
+
+const (
+	WalletEntryKindGiftCard WalletEntryKind = iota
+	WalletEntryKindPayment
+	WalletEntryKindPaymentRelease
+	WalletEntryKindRefund
+)
+
+func (k WalletEntryKind) String() string {
+	switch k {
+	case WalletEntryKindGiftCard:
+		return "GiftCard"
+	case WalletEntryKindPayment:
+		return "Payment"
+	case WalletEntryKindPaymentRelease:
+		return "PaymentRelease"
+	case WalletEntryKindRefund:
+		return "Refund"
+	}
+	return fmt.Sprintf("WalletEntryKind %d", k)
+}
+
+var (
+	ErrInsufficientBalance = errors.New("wallet balance is insufficient")
+)
+
+// WalletEntry is a line of the user's wallet ledger, credits are positive and debits are negative
+// and the balance is the sum of all the entries.
+type WalletEntry struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UserID     int64           `json:"user_id"`
+	Kind       WalletEntryKind `json:"kind"`
+	Amount     decimal.Decimal `json:"amount"`
+	GiftCardID *int64          `json:"gift_card_id,omitempty"`
+	OrderID    *int64          `json:"order_id,omitempty"`
+	ListingID  *int64          `json:"listing_id,omitempty"`
+}
+
+type WalletStorer interface {
+	GetBalance(userID int64) (decimal.Decimal, error)
+	GetEntries(userID int64, page int, pageSize int) ([]WalletEntry, *MetaData, error)
+	Pay(userID int64, orderID int64, amount decimal.Decimal) (*WalletEntry, error)
+}
+
+type walletStorage struct {
+	queryTimeout time.Duration
+	db           *sql.DB
+}
+
+func (s walletStorage) GetBalance(userID int64) (decimal.Decimal, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	var balance decimal.Decimal
+	query := `SELECT COALESCE(SUM(amount), 0)
+	          FROM wallet_entries
+			  WHERE user_id = $1`
+	args := []any{userID}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&balance)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return balance, nil
+}
+
+func (s walletStorage) GetEntries(userID int64, page int, pageSize int) ([]WalletEntry, *MetaData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	query := `SELECT count(*) OVER(), id, created_at, user_id, kind_id, amount, gift_card_id, order_id, listing_id
+	          FROM wallet_entries
+			  WHERE user_id = $1
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $2 OFFSET $3`
+	limit := pageSize
+	offset := (page - 1) * pageSize
+	args := []any{userID, limit, offset}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	totalRecords := 0
+	var entries []WalletEntry
+	for rows.Next() {
+		var e WalletEntry
+		err := rows.Scan(&totalRecords, &e.ID, &e.CreatedAt, &e.UserID, &e.Kind, &e.Amount, &e.GiftCardID, &e.OrderID, &e.ListingID)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metaData := &MetaData{}
+	if totalRecords != 0 {
+		metaData = &MetaData{
+			CurrentPage:  page,
+			PageSize:     pageSize,
+			FirstPage:    1,
+			LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+			TotalRecords: totalRecords,
+		}
+	}
+	return entries, metaData, nil
+}
+
+// Pay debits the amount paid from the wallet for the order, the debit is released
+// if the order's checkout session is deleted before it's fulfilled.
+func (s walletStorage) Pay(userID int64, orderID int64, amount decimal.Decimal) (*WalletEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+	defer cancel()
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	var balance decimal.Decimal
+	query0 := `SELECT COALESCE(SUM(amount), 0)
+	           FROM wallet_entries
+			   WHERE user_id = $1`
+	args0 := []any{userID}
+	err = tx.QueryRowContext(ctx, query0, args0...).Scan(&balance)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if balance.LessThan(amount) {
+		tx.Rollback()
+		return nil, ErrInsufficientBalance
+	}
+	e := WalletEntry{
+		UserID:  userID,
+		Kind:    WalletEntryKindPayment,
+		Amount:  amount.Neg(),
+		OrderID: &orderID,
+	}
+	query1 := `INSERT INTO wallet_entries(user_id, kind_id, amount, order_id)
+	           VALUES ($1, $2, $3, $4)
+			   RETURNING id, created_at`
+	args1 := []any{e.UserID, e.Kind, e.Amount, orderID}
+	err = tx.QueryRowContext(ctx, query1, args1...).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
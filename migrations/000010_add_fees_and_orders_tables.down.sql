@@ -0,0 +1,10 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS order_id;
+DROP INDEX IF EXISTS order_lines_order_id_idx;
+DROP INDEX IF EXISTS orders_user_id_idx;
+DROP TABLE IF EXISTS order_lines;
+DROP TABLE IF EXISTS order_line_kinds;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS order_statuses;
+DROP TABLE IF EXISTS fees;
+DROP TABLE IF EXISTS fee_bases;
+DROP TABLE IF EXISTS fee_kinds;
@@ -0,0 +1,91 @@
+CREATE TABLE IF NOT EXISTS fee_kinds (
+    id smallint PRIMARY KEY,
+    kind text NOT NULL UNIQUE
+);
+
+INSERT INTO fee_kinds(id, kind)
+VALUES (0, 'fee'),
+       (1, 'tax')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS fee_bases (
+    id smallint PRIMARY KEY,
+    basis text NOT NULL UNIQUE
+);
+
+INSERT INTO fee_bases(id, basis)
+VALUES (0, 'ticket'),
+       (1, 'order')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS fees (
+    id serial PRIMARY KEY,
+    cinema_id int NOT NULL REFERENCES cinemas(id) ON DELETE CASCADE,
+    hall_id int REFERENCES halls(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    kind_id smallint NOT NULL REFERENCES fee_kinds(id),
+    basis_id smallint NOT NULL REFERENCES fee_bases(id),
+    rate decimal(5, 2) NOT NULL DEFAULT 0,
+    amount decimal(6, 2) NOT NULL DEFAULT 0,
+    is_inclusive boolean NOT NULL DEFAULT false,
+    version int NOT NULL DEFAULT 1,
+    CONSTRAINT is_valid_fee CHECK (rate >= 0 AND amount >= 0 AND (kind_id = 1 OR is_inclusive = false))
+);
+
+CREATE TABLE IF NOT EXISTS order_statuses (
+    id smallint PRIMARY KEY,
+    status text NOT NULL UNIQUE
+);
+
+INSERT INTO order_statuses(id, status)
+VALUES (0, 'pending'),
+       (1, 'completed'),
+       (2, 'cancelled')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS orders (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users(id),
+    session_id text NOT NULL UNIQUE,
+    status_id smallint NOT NULL DEFAULT 0 REFERENCES order_statuses(id),
+    subtotal decimal(10, 2) NOT NULL,
+    fees_total decimal(10, 2) NOT NULL,
+    taxes_total decimal(10, 2) NOT NULL,
+    total decimal(10, 2) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS order_line_kinds (
+    id smallint PRIMARY KEY,
+    kind text NOT NULL UNIQUE
+);
+
+INSERT INTO order_line_kinds(id, kind)
+VALUES (0, 'ticket'),
+       (1, 'fee'),
+       (2, 'tax')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS order_lines (
+    id bigserial PRIMARY KEY,
+    order_id bigint NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    cinema_id int NOT NULL REFERENCES cinemas(id),
+    ticket_id bigint REFERENCES tickets(id),
+    kind_id smallint NOT NULL REFERENCES order_line_kinds(id),
+    name text NOT NULL,
+    amount decimal(10, 2) NOT NULL,
+    is_inclusive boolean NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders(user_id);
+CREATE INDEX IF NOT EXISTS order_lines_order_id_idx ON order_lines(order_id);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS order_id bigint REFERENCES orders(id);
+
+INSERT INTO permissions(code)
+VALUES
+('fees:read'),
+('fees:create'),
+('fees:update'),
+('fees:delete')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS invoices;
+ALTER TABLE orders DROP COLUMN IF EXISTS payment_reference;
+ALTER TABLE cinemas DROP COLUMN IF EXISTS last_invoice_number;
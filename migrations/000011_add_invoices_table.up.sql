@@ -0,0 +1,13 @@
+ALTER TABLE cinemas ADD COLUMN IF NOT EXISTS last_invoice_number bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_reference text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    order_id bigint NOT NULL REFERENCES orders(id),
+    cinema_id int NOT NULL REFERENCES cinemas(id),
+    number bigint NOT NULL,
+    CONSTRAINT unique_invoice_number UNIQUE (cinema_id, number),
+    CONSTRAINT unique_order_invoice UNIQUE (order_id, cinema_id)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS payment_events_status_id_idx;
+DROP TABLE IF EXISTS payment_events;
+DROP TABLE IF EXISTS payment_event_statuses;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS payment_event_statuses (
+    id smallint PRIMARY KEY,
+    status text NOT NULL UNIQUE
+);
+
+INSERT INTO payment_event_statuses(id, status)
+VALUES (0, 'pending'),
+       (1, 'processing'),
+       (2, 'processed'),
+       (3, 'failed')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS payment_events (
+    id text PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    type text NOT NULL,
+    data bytea NOT NULL,
+    status_id smallint NOT NULL DEFAULT 0 REFERENCES payment_event_statuses(id),
+    attempts int NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    processed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS payment_events_status_id_idx ON payment_events(status_id);
+
+INSERT INTO permissions(code)
+VALUES
+('payment_events:read'),
+('payment_events:replay')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payment_discrepancies;
+DROP TABLE IF EXISTS payment_discrepancy_kinds;
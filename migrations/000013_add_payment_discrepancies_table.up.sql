@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS payment_discrepancy_kinds (
+    id smallint PRIMARY KEY,
+    kind text NOT NULL UNIQUE
+);
+
+INSERT INTO payment_discrepancy_kinds(id, kind)
+VALUES (0, 'missed-fulfillment'),
+       (1, 'released-tickets'),
+       (2, 'amount-mismatch'),
+       (3, 'unknown-session')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS payment_discrepancies (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    kind_id smallint NOT NULL REFERENCES payment_discrepancy_kinds(id),
+    session_id text NOT NULL,
+    user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    details text NOT NULL,
+    resolved_at TIMESTAMPTZ,
+    CONSTRAINT unique_payment_discrepancy UNIQUE (session_id, kind_id)
+);
+
+INSERT INTO permissions(code)
+VALUES
+('payment_discrepancies:read'),
+('payment_discrepancies:update')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+ALTER TABLE checkout_sessions ALTER COLUMN expires_at SET DEFAULT NOW() + interval '10 minutes';
+
+ALTER TABLE tickets_users DROP COLUMN IF EXISTS extended;
+ALTER TABLE tickets_users ALTER COLUMN expires_at DROP NOT NULL;
+ALTER TABLE tickets_users ALTER COLUMN expires_at SET DEFAULT NOW() + interval '10 minutes';
+
+DROP TABLE IF EXISTS cinema_settings;
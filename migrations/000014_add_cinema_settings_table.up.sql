@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS cinema_settings (
+    cinema_id int PRIMARY KEY REFERENCES cinemas(id) ON DELETE CASCADE,
+    ticket_lock_seconds int CHECK (ticket_lock_seconds > 0),
+    checkout_session_seconds int CHECK (checkout_session_seconds > 0),
+    version int NOT NULL DEFAULT 1
+);
+
+ALTER TABLE tickets_users ALTER COLUMN expires_at DROP DEFAULT;
+ALTER TABLE tickets_users ALTER COLUMN expires_at SET NOT NULL;
+ALTER TABLE tickets_users ADD COLUMN IF NOT EXISTS extended boolean NOT NULL DEFAULT false;
+
+ALTER TABLE checkout_sessions ALTER COLUMN expires_at DROP DEFAULT;
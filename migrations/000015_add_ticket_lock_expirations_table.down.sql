@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ticket_lock_expirations;
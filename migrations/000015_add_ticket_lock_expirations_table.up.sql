@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS ticket_lock_expirations (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ticket_id bigint NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    expired_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS ticket_lock_expirations_user_id_expired_at_idx ON ticket_lock_expirations(user_id, expired_at);
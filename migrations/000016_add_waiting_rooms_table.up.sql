@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS waiting_rooms (
+    schedule_id bigint PRIMARY KEY REFERENCES schedules(id) ON DELETE CASCADE,
+    batch_size int NOT NULL CHECK (batch_size > 0),
+    admission_seconds int NOT NULL CHECK (admission_seconds > 0),
+    version int NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS waiting_room_entries (
+    id bigserial PRIMARY KEY,
+    schedule_id bigint NOT NULL REFERENCES waiting_rooms(schedule_id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hash bytea NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    admitted_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    CONSTRAINT unique_waiting_room_entry UNIQUE (schedule_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS waiting_room_entries_schedule_id_admitted_at_idx ON waiting_room_entries(schedule_id, admitted_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ticket_reservations;
+DROP TABLE IF EXISTS waitlist_entries;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id bigserial PRIMARY KEY,
+    schedule_id bigint NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMPTZ,
+    CONSTRAINT unique_waitlist_entry UNIQUE (schedule_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS ticket_reservations (
+    ticket_id bigint PRIMARY KEY REFERENCES tickets(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS ticket_transfers;
+DROP TABLE IF EXISTS ticket_transfer_statuses;
+
+ALTER TABLE cinema_settings DROP COLUMN IF EXISTS allow_ticket_transfers;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS code;
@@ -0,0 +1,29 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS code uuid NOT NULL UNIQUE DEFAULT gen_random_uuid();
+
+ALTER TABLE cinema_settings ADD COLUMN IF NOT EXISTS allow_ticket_transfers boolean NOT NULL DEFAULT true;
+
+CREATE TABLE IF NOT EXISTS ticket_transfer_statuses (
+    id smallint PRIMARY KEY,
+    status text NOT NULL UNIQUE
+);
+
+INSERT INTO ticket_transfer_statuses(id, status)
+VALUES (0, 'pending'),
+       (1, 'accepted'),
+       (2, 'cancelled')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS ticket_transfers (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ticket_id bigint NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    from_user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_email citext NOT NULL,
+    to_user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    hash bytea NOT NULL UNIQUE,
+    status_id smallint NOT NULL DEFAULT 0 REFERENCES ticket_transfer_statuses(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS ticket_transfers_ticket_id_idx ON ticket_transfers(ticket_id);
@@ -0,0 +1,7 @@
+ALTER TABLE tickets_users DROP COLUMN IF EXISTS listing_id;
+
+DROP TABLE IF EXISTS resale_listings;
+DROP TABLE IF EXISTS resale_listing_statuses;
+
+ALTER TABLE cinema_settings DROP COLUMN IF EXISTS resale_fee_rate;
+ALTER TABLE cinema_settings DROP COLUMN IF EXISTS resale_price_cap;
@@ -0,0 +1,34 @@
+ALTER TABLE cinema_settings ADD COLUMN IF NOT EXISTS resale_price_cap decimal(5, 2) CHECK (resale_price_cap >= 0);
+ALTER TABLE cinema_settings ADD COLUMN IF NOT EXISTS resale_fee_rate decimal(5, 2) NOT NULL DEFAULT 0 CHECK (resale_fee_rate >= 0);
+
+CREATE TABLE IF NOT EXISTS resale_listing_statuses (
+    id smallint PRIMARY KEY,
+    status text NOT NULL UNIQUE
+);
+
+INSERT INTO resale_listing_statuses(id, status)
+VALUES (0, 'active'),
+       (1, 'sold'),
+       (2, 'cancelled')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS resale_listings (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ticket_id bigint NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    seller_id bigint NOT NULL REFERENCES users(id),
+    order_id bigint NOT NULL REFERENCES orders(id),
+    price decimal(6, 2) NOT NULL CHECK (price > 0),
+    fee decimal(6, 2) NOT NULL CHECK (fee >= 0),
+    status_id smallint NOT NULL DEFAULT 0 REFERENCES resale_listing_statuses(id),
+    buyer_id bigint REFERENCES users(id),
+    buyer_order_id bigint REFERENCES orders(id),
+    sold_at TIMESTAMPTZ,
+    refund_id text,
+    paid_out_at TIMESTAMPTZ,
+    payout_error text NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS resale_listings_active_ticket_id_idx ON resale_listings(ticket_id) WHERE status_id = 0;
+
+ALTER TABLE tickets_users ADD COLUMN IF NOT EXISTS listing_id bigint REFERENCES resale_listings(id) ON DELETE CASCADE;
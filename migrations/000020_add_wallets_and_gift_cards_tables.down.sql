@@ -0,0 +1,11 @@
+ALTER TABLE resale_listings DROP COLUMN IF EXISTS payout_to_wallet;
+ALTER TABLE orders DROP COLUMN IF EXISTS wallet_amount;
+
+DROP INDEX IF EXISTS wallet_entries_order_id_idx;
+DROP INDEX IF EXISTS wallet_entries_user_id_idx;
+DROP TABLE IF EXISTS wallet_entries;
+DROP TABLE IF EXISTS wallet_entry_kinds;
+
+DROP INDEX IF EXISTS gift_cards_purchased_by_idx;
+DROP TABLE IF EXISTS gift_cards;
+DROP TABLE IF EXISTS gift_card_statuses;
@@ -0,0 +1,61 @@
+CREATE TABLE IF NOT EXISTS gift_card_statuses (
+    id smallint PRIMARY KEY,
+    status text NOT NULL UNIQUE
+);
+
+INSERT INTO gift_card_statuses(id, status)
+VALUES (0, 'pending'),
+       (1, 'active'),
+       (2, 'redeemed')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS gift_cards (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    hash bytea NOT NULL UNIQUE,
+    amount decimal(10, 2) NOT NULL CHECK (amount > 0),
+    status_id smallint NOT NULL DEFAULT 0 REFERENCES gift_card_statuses(id),
+    issued_by bigint REFERENCES users(id) ON DELETE SET NULL,
+    purchased_by bigint REFERENCES users(id) ON DELETE SET NULL,
+    session_id text UNIQUE,
+    payment_reference text NOT NULL DEFAULT '',
+    redeemed_by bigint REFERENCES users(id) ON DELETE SET NULL,
+    redeemed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS gift_cards_purchased_by_idx ON gift_cards(purchased_by);
+
+CREATE TABLE IF NOT EXISTS wallet_entry_kinds (
+    id smallint PRIMARY KEY,
+    kind text NOT NULL UNIQUE
+);
+
+INSERT INTO wallet_entry_kinds(id, kind)
+VALUES (0, 'gift_card'),
+       (1, 'payment'),
+       (2, 'payment_release'),
+       (3, 'refund')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS wallet_entries (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind_id smallint NOT NULL REFERENCES wallet_entry_kinds(id),
+    amount decimal(10, 2) NOT NULL CHECK (amount <> 0),
+    gift_card_id bigint REFERENCES gift_cards(id),
+    order_id bigint REFERENCES orders(id),
+    listing_id bigint REFERENCES resale_listings(id)
+);
+
+CREATE INDEX IF NOT EXISTS wallet_entries_user_id_idx ON wallet_entries(user_id);
+CREATE INDEX IF NOT EXISTS wallet_entries_order_id_idx ON wallet_entries(order_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS wallet_amount decimal(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE resale_listings ADD COLUMN IF NOT EXISTS payout_to_wallet boolean NOT NULL DEFAULT false;
+
+INSERT INTO permissions(code)
+VALUES
+('gift_cards:create')
+ON CONFLICT DO NOTHING;
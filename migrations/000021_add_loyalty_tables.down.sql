@@ -0,0 +1,7 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS loyalty_discount;
+ALTER TABLE orders DROP COLUMN IF EXISTS loyalty_points;
+
+DROP INDEX IF EXISTS loyalty_entries_earning_order_id_idx;
+DROP INDEX IF EXISTS loyalty_entries_user_id_idx;
+DROP TABLE IF EXISTS loyalty_entries;
+DROP TABLE IF EXISTS loyalty_entry_kinds;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS loyalty_entry_kinds (
+    id smallint PRIMARY KEY,
+    kind text NOT NULL UNIQUE
+);
+
+INSERT INTO loyalty_entry_kinds(id, kind)
+VALUES (0, 'earning'),
+       (1, 'redemption'),
+       (2, 'redemption_release'),
+       (3, 'expiration')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS loyalty_entries (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind_id smallint NOT NULL REFERENCES loyalty_entry_kinds(id),
+    points bigint NOT NULL,
+    order_id bigint REFERENCES orders(id),
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS loyalty_entries_user_id_idx ON loyalty_entries(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS loyalty_entries_earning_order_id_idx ON loyalty_entries(order_id) WHERE kind_id = 0;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS loyalty_points bigint NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS loyalty_discount decimal(10, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS order_lines_membership_id_idx;
+ALTER TABLE order_lines DROP COLUMN IF EXISTS membership_id;
+
+DROP INDEX IF EXISTS memberships_live_user_id_plan_id_idx;
+DROP INDEX IF EXISTS memberships_user_id_idx;
+DROP INDEX IF EXISTS membership_plans_cinema_id_idx;
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS membership_statuses;
+DROP TABLE IF EXISTS membership_plans;
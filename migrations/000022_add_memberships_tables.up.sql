@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS membership_plans (
+    id serial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    cinema_id int NOT NULL REFERENCES cinemas(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    price decimal(10, 2) NOT NULL,
+    tickets_per_period int,
+    price_reference text NOT NULL UNIQUE,
+    is_active boolean NOT NULL DEFAULT true,
+    version int NOT NULL DEFAULT 1,
+    CONSTRAINT is_valid_membership_plan CHECK (price > 0 AND (tickets_per_period IS NULL OR tickets_per_period > 0))
+);
+
+CREATE TABLE IF NOT EXISTS membership_statuses (
+    id smallint PRIMARY KEY,
+    status text NOT NULL UNIQUE
+);
+
+INSERT INTO membership_statuses(id, status)
+VALUES (0, 'incomplete'),
+       (1, 'active'),
+       (2, 'past_due'),
+       (3, 'canceled')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS memberships (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan_id int NOT NULL REFERENCES membership_plans(id) ON DELETE CASCADE,
+    status_id smallint NOT NULL DEFAULT 0 REFERENCES membership_statuses(id),
+    session_id text NOT NULL UNIQUE,
+    subscription_reference text UNIQUE,
+    period_start TIMESTAMPTZ,
+    period_end TIMESTAMPTZ,
+    cancel_at_period_end boolean NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS membership_plans_cinema_id_idx ON membership_plans(cinema_id);
+CREATE INDEX IF NOT EXISTS memberships_user_id_idx ON memberships(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS memberships_live_user_id_plan_id_idx ON memberships(user_id, plan_id) WHERE status_id <> 3;
+
+ALTER TABLE order_lines ADD COLUMN IF NOT EXISTS membership_id bigint REFERENCES memberships(id);
+
+CREATE INDEX IF NOT EXISTS order_lines_membership_id_idx ON order_lines(membership_id);
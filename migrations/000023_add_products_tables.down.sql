@@ -0,0 +1,11 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS pickup_code;
+ALTER TABLE order_lines DROP COLUMN IF EXISTS quantity;
+ALTER TABLE order_lines DROP COLUMN IF EXISTS product_id;
+
+DELETE FROM order_lines WHERE kind_id = 3;
+DELETE FROM order_line_kinds WHERE id = 3;
+
+DROP INDEX IF EXISTS products_cinema_id_idx;
+DROP TABLE IF EXISTS cart_products;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS product_categories;
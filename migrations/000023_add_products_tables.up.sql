@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS product_categories (
+    id smallint PRIMARY KEY,
+    category text NOT NULL UNIQUE
+);
+
+INSERT INTO product_categories(id, category)
+VALUES (0, 'snack'),
+       (1, 'drink'),
+       (2, 'combo'),
+       (3, 'merchandise')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS products (
+    id serial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    cinema_id int NOT NULL REFERENCES cinemas(id) ON DELETE CASCADE,
+    category_id smallint NOT NULL REFERENCES product_categories(id),
+    name text NOT NULL,
+    price decimal(10, 2) NOT NULL,
+    stock int NOT NULL DEFAULT 0,
+    version int NOT NULL DEFAULT 1,
+    CONSTRAINT is_valid_product CHECK (price > 0 AND stock >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS cart_products (
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id int NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity int NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, product_id),
+    CONSTRAINT is_valid_quantity CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS products_cinema_id_idx ON products(cinema_id);
+
+INSERT INTO order_line_kinds(id, kind)
+VALUES (3, 'product')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE order_lines ADD COLUMN IF NOT EXISTS product_id int REFERENCES products(id) ON DELETE SET NULL;
+ALTER TABLE order_lines ADD COLUMN IF NOT EXISTS quantity int NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS pickup_code text;
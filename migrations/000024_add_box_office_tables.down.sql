@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS orders_drawer_id_idx;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS drawer_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS cashier_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS payment_method_id;
+
+DROP INDEX IF EXISTS cash_drawers_open_idx;
+DROP TABLE IF EXISTS cash_drawers;
+DROP TABLE IF EXISTS payment_methods;
+DROP TABLE IF EXISTS cinema_cashiers;
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS cinema_cashiers (
+    cinema_id int NOT NULL REFERENCES cinemas(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cinema_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS payment_methods (
+    id smallint PRIMARY KEY,
+    method text NOT NULL UNIQUE
+);
+
+INSERT INTO payment_methods(id, method)
+VALUES (0, 'online'),
+       (1, 'cash'),
+       (2, 'card_present')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS cash_drawers (
+    id bigserial PRIMARY KEY,
+    cinema_id int NOT NULL REFERENCES cinemas(id) ON DELETE CASCADE,
+    cashier_id bigint NOT NULL REFERENCES users(id),
+    opened_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at TIMESTAMPTZ,
+    opening_float decimal(10, 2) NOT NULL DEFAULT 0,
+    expected_cash decimal(10, 2),
+    counted_cash decimal(10, 2),
+    CONSTRAINT is_valid_cash_drawer CHECK (opening_float >= 0 AND (closed_at IS NULL OR (expected_cash IS NOT NULL AND counted_cash >= 0)))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS cash_drawers_open_idx ON cash_drawers(cinema_id, cashier_id) WHERE closed_at IS NULL;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_method_id smallint NOT NULL DEFAULT 0 REFERENCES payment_methods(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cashier_id bigint REFERENCES users(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS drawer_id bigint REFERENCES cash_drawers(id);
+
+CREATE INDEX IF NOT EXISTS orders_drawer_id_idx ON orders(drawer_id);
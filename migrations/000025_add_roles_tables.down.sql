@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_roles_role_id_idx;
+DROP TABLE IF EXISTS users_roles;
+DROP TABLE IF EXISTS roles_permissions;
+DROP TABLE IF EXISTS roles;
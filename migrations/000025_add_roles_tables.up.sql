@@ -0,0 +1,72 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id serial PRIMARY KEY,
+    code text NOT NULL UNIQUE
+);
+
+INSERT INTO roles(code)
+VALUES
+('admin'),
+('cinema-owner'),
+('cinema-staff'),
+('customer')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+    role_id integer NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id integer NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS users_roles (
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id integer NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS users_roles_role_id_idx ON users_roles(role_id);
+
+INSERT INTO permissions(code)
+VALUES
+('roles:read'),
+('roles:grant'),
+('products:read'),
+('products:create'),
+('products:update'),
+('products:delete'),
+('box_office:sell')
+ON CONFLICT DO NOTHING;
+
+-- the admin has every permission, the migrations adding permissions grant them to the admin too.
+INSERT INTO roles_permissions(role_id, permission_id)
+SELECT r.id, p.id FROM roles AS r CROSS JOIN permissions AS p
+WHERE r.code = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles_permissions(role_id, permission_id)
+SELECT r.id, p.id FROM roles AS r CROSS JOIN permissions AS p
+WHERE r.code = 'cinema-owner' AND p.code IN (
+    'cinemas:read', 'cinemas:create', 'cinemas:update', 'cinemas:delete',
+    'halls:read', 'halls:create', 'halls:update', 'halls:delete',
+    'seats:read', 'seats:create', 'seats:update', 'seats:delete',
+    'schedules:read', 'schedules:create', 'schedules:update', 'schedules:delete',
+    'tickets:read', 'tickets:create', 'tickets:update', 'tickets:delete',
+    'fees:read', 'fees:create', 'fees:update', 'fees:delete',
+    'products:read', 'products:create', 'products:update', 'products:delete',
+    'box_office:sell'
+)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles_permissions(role_id, permission_id)
+SELECT r.id, p.id FROM roles AS r CROSS JOIN permissions AS p
+WHERE r.code = 'cinema-staff' AND p.code IN (
+    'cinemas:read', 'halls:read', 'seats:read', 'schedules:read', 'tickets:read', 'fees:read', 'products:read',
+    'box_office:sell'
+)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles_permissions(role_id, permission_id)
+SELECT r.id, p.id FROM roles AS r CROSS JOIN permissions AS p
+WHERE r.code = 'customer' AND p.code IN (
+    'cinemas:read', 'halls:read', 'seats:read', 'schedules:read', 'tickets:read', 'products:read'
+)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS cinema_cashiers (
+    cinema_id int NOT NULL REFERENCES cinemas(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cinema_id, user_id)
+);
+
+INSERT INTO cinema_cashiers(cinema_id, user_id, created_at)
+SELECT cinema_id, user_id, created_at FROM cinema_members WHERE role_id IN (0, 2)
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS cinema_members_user_id_idx;
+DROP TABLE IF EXISTS cinema_members;
+DROP TABLE IF EXISTS cinema_member_roles;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS cinema_member_roles (
+    id smallint PRIMARY KEY,
+    role text NOT NULL UNIQUE
+);
+
+INSERT INTO cinema_member_roles(id, role)
+VALUES (0, 'manager'),
+       (1, 'scheduler'),
+       (2, 'box_office')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS cinema_members (
+    cinema_id int NOT NULL REFERENCES cinemas(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id smallint NOT NULL REFERENCES cinema_member_roles(id),
+    invited_by bigint REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cinema_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS cinema_members_user_id_idx ON cinema_members(user_id);
+
+-- the cashiers are box office members now.
+INSERT INTO cinema_members(cinema_id, user_id, role_id, created_at)
+SELECT cinema_id, user_id, 2, created_at FROM cinema_cashiers
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS cinema_cashiers;
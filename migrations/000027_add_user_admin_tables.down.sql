@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS audit_entries_user_id_idx;
+DROP TABLE IF EXISTS audit_entries;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_required;
+ALTER TABLE users DROP COLUMN IF EXISTS suspension_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
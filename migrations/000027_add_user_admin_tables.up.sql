@@ -0,0 +1,25 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id bigserial PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    action text NOT NULL,
+    details text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS audit_entries_user_id_idx ON audit_entries(user_id);
+
+INSERT INTO permissions(code)
+VALUES
+('users:read'),
+('users:update')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles_permissions(role_id, permission_id)
+SELECT r.id, p.id FROM roles AS r CROSS JOIN permissions AS p
+WHERE r.code = 'admin' AND p.code IN ('users:read', 'users:update')
+ON CONFLICT DO NOTHING;
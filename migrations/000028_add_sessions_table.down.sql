@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS tokens_session_id_idx;
+
+ALTER TABLE tokens DROP COLUMN IF EXISTS used_at;
+ALTER TABLE tokens DROP COLUMN IF EXISTS session_id;
+
+DROP INDEX IF EXISTS sessions_user_id_idx;
+DROP TABLE IF EXISTS sessions;
+
+DELETE FROM tokens WHERE scope_id = 3;
+DELETE FROM token_scopes WHERE id = 3;
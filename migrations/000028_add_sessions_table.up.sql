@@ -0,0 +1,19 @@
+INSERT INTO token_scopes (id, scope)
+VALUES
+    (3, 'refresh')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions(user_id);
+
+-- the access and refresh tokens of a login belong to its session, revoking the session revokes them.
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS session_id bigint REFERENCES sessions(id) ON DELETE CASCADE;
+-- a refresh token is only used once, using it again means it was stolen.
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS tokens_session_id_idx ON tokens(session_id);
@@ -0,0 +1,3 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE sessions DROP COLUMN IF EXISTS ip;
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_used_at;
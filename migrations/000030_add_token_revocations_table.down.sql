@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS token_revocations_expires_at_idx;
+DROP TABLE IF EXISTS token_revocations;
@@ -0,0 +1,21 @@
+-- the signed access tokens aren't stored, they're revoked by user or by session until the last of them expires.
+CREATE TABLE IF NOT EXISTS token_revocations (
+    id bigserial PRIMARY KEY,
+    user_id bigint,
+    session_id bigint,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    CHECK (user_id IS NOT NULL OR session_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS token_revocations_expires_at_idx ON token_revocations(expires_at);
+
+INSERT INTO permissions(code)
+VALUES
+('tokens:revoke')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles_permissions(role_id, permission_id)
+SELECT r.id, p.id FROM roles AS r CROSS JOIN permissions AS p
+WHERE r.code = 'admin' AND p.code = 'tokens:revoke'
+ON CONFLICT DO NOTHING;
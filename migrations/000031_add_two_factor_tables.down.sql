@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS recovery_codes_user_id_idx;
+DROP TABLE IF EXISTS recovery_codes;
+
+DROP TABLE IF EXISTS two_factors;
+
+DELETE FROM tokens WHERE scope_id = 4;
+DELETE FROM token_scopes WHERE id = 4;
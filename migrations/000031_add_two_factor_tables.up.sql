@@ -0,0 +1,23 @@
+INSERT INTO token_scopes (id, scope)
+VALUES
+    (4, 'two-factor')
+ON CONFLICT DO NOTHING;
+
+-- the secret isn't usable until the user confirms the enrollment with a code from the authenticator.
+CREATE TABLE IF NOT EXISTS two_factors (
+    user_id bigint PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret bytea NOT NULL,
+    confirmed_at TIMESTAMPTZ,
+    -- the time step of the last code that was used so a code can't be used twice.
+    last_used_step bigint NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hash bytea NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes(user_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS identities_user_id_idx;
+DROP TABLE IF EXISTS identities;
+
+DROP INDEX IF EXISTS oidc_states_expires_at_idx;
+DROP TABLE IF EXISTS oidc_states;
@@ -0,0 +1,24 @@
+-- a login started with an OpenID Connect issuer, it's completed with the state the issuer sends back.
+CREATE TABLE IF NOT EXISTS oidc_states (
+    id bigserial PRIMARY KEY,
+    hash bytea NOT NULL UNIQUE,
+    provider text NOT NULL,
+    nonce text NOT NULL,
+    code_verifier text NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS oidc_states_expires_at_idx ON oidc_states(expires_at);
+
+-- the accounts at the OpenID Connect issuers the users sign in with.
+CREATE TABLE IF NOT EXISTS identities (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider text NOT NULL,
+    subject text NOT NULL,
+    email text NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS identities_user_id_idx ON identities(user_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS login_throttles_last_failure_at_idx;
+DROP TABLE IF EXISTS login_throttles;
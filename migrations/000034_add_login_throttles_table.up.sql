@@ -0,0 +1,11 @@
+-- the failed logins of an account (kind 0, keyed by the email) or of an IP (kind 1).
+CREATE TABLE IF NOT EXISTS login_throttles (
+    kind smallint NOT NULL,
+    key text NOT NULL,
+    failures integer NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (kind, key)
+);
+
+CREATE INDEX IF NOT EXISTS login_throttles_last_failure_at_idx ON login_throttles(last_failure_at);
@@ -0,0 +1,2 @@
+-- when the event was last claimed, the events stuck processing after a crash are claimed again once it's too old.
+ALTER TABLE payment_events ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ;
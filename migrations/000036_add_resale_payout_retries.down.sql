@@ -0,0 +1,5 @@
+DELETE FROM payment_discrepancies WHERE kind_id = 4;
+DELETE FROM payment_discrepancy_kinds WHERE id = 4;
+
+ALTER TABLE resale_listings DROP COLUMN IF EXISTS next_payout_at;
+ALTER TABLE resale_listings DROP COLUMN IF EXISTS payout_attempts;
@@ -0,0 +1,6 @@
+ALTER TABLE resale_listings ADD COLUMN IF NOT EXISTS payout_attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE resale_listings ADD COLUMN IF NOT EXISTS next_payout_at TIMESTAMPTZ;
+
+INSERT INTO payment_discrepancy_kinds(id, kind)
+VALUES (4, 'failed-payout')
+ON CONFLICT DO NOTHING;
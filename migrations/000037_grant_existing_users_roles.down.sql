@@ -0,0 +1,3 @@
+DELETE FROM users_roles AS ur
+USING roles AS r
+WHERE r.id = ur.role_id AND r.code IN ('customer', 'cinema-owner', 'cinema-staff');
@@ -0,0 +1,15 @@
+-- the users who signed up before the roles were granted get the roles they'd have now.
+INSERT INTO users_roles(user_id, role_id)
+SELECT u.id, r.id FROM users AS u CROSS JOIN roles AS r
+WHERE r.code = 'customer'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO users_roles(user_id, role_id)
+SELECT DISTINCT c.owner_id, r.id FROM cinemas AS c CROSS JOIN roles AS r
+WHERE r.code = 'cinema-owner'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO users_roles(user_id, role_id)
+SELECT DISTINCT cm.user_id, r.id FROM cinema_members AS cm CROSS JOIN roles AS r
+WHERE r.code = 'cinema-staff'
+ON CONFLICT DO NOTHING;